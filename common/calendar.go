@@ -0,0 +1,222 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarShorthands are the named calendar events, e.g. OnCalendar=daily
+var calendarShorthands = map[string]bool{
+	"minutely": true, "hourly": true, "daily": true, "weekly": true, "monthly": true,
+	"quarterly": true, "semiannually": true, "yearly": true, "annually": true,
+}
+
+var weekdays = map[string]int{
+	"mon": 1, "monday": 1, "tue": 2, "tuesday": 2, "wed": 3, "wednesday": 3, "thu": 4, "thursday": 4,
+	"fri": 5, "friday": 5, "sat": 6, "saturday": 6, "sun": 7, "sunday": 7,
+}
+
+// ValidateCalendar checks that expr is a calendar event expression, as described in systemd.time(7), e.g.
+// "Mon..Fri *-*-* 09:00:00", "*-*-01 04:00 UTC", "*:0/15" or "weekly"
+func ValidateCalendar(expr string) error {
+	tokens := strings.Fields(expr)
+	if len(tokens) == 0 {
+		return fmt.Errorf("calendar expression is empty")
+	}
+	if len(tokens) > 1 && isTimezone(tokens[len(tokens)-1]) {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 1 && calendarShorthands[tokens[0]] {
+		return nil
+	}
+	if len(tokens) == 1 && strings.HasPrefix(tokens[0], "@") {
+		if _, err := strconv.ParseUint(tokens[0][1:], 10, 64); err != nil {
+			return fmt.Errorf("%q is not a UNIX timestamp", tokens[0])
+		}
+		return nil
+	}
+
+	// Days of the week are the only component which starts with a letter
+	if c := tokens[0][0]; (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		if err := validateWeekdays(tokens[0]); err != nil {
+			return err
+		}
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && strings.ContainsAny(tokens[0], "-~") && !strings.Contains(tokens[0], ":") {
+		if err := validateDate(tokens[0]); err != nil {
+			return err
+		}
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && strings.Contains(tokens[0], ":") {
+		if err := validateTime(tokens[0]); err != nil {
+			return err
+		}
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 {
+		return fmt.Errorf("unexpected %q, expected [weekdays] [year-month-day] [hour:minute[:second]] [timezone]", tokens[0])
+	}
+	return nil
+}
+
+// isTimezone returns true if tz is UTC or a location in the time zone database, e.g. Europe/Berlin
+func isTimezone(tz string) bool {
+	if tz == "UTC" {
+		return true
+	}
+	if _, ok := weekdays[strings.ToLower(tz)]; ok || tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+func validateWeekdays(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		start, end, isRange := strings.Cut(item, "..")
+		if !isRange {
+			// A single dash is accepted as a range too, e.g. Mon-Fri
+			start, end, isRange = strings.Cut(item, "-")
+		}
+		from, ok := weekdays[strings.ToLower(start)]
+		if !ok {
+			return fmt.Errorf("%q is not a day of the week", start)
+		}
+		if !isRange {
+			continue
+		}
+		to, ok := weekdays[strings.ToLower(end)]
+		if !ok {
+			return fmt.Errorf("%q is not a day of the week", end)
+		}
+		if to < from {
+			return fmt.Errorf("weekday range %s is reversed", item)
+		}
+	}
+	return nil
+}
+
+// validateDate checks [year-]month-day, where the day may be counted from the end of the month with month~day
+func validateDate(spec string) error {
+	var parts []string
+	reverse := strings.Contains(spec, "~")
+	if reverse {
+		head, day, _ := strings.Cut(spec, "~")
+		parts = append(strings.Split(head, "-"), day)
+	} else {
+		parts = strings.Split(spec, "-")
+	}
+	switch len(parts) {
+	case 2:
+		parts = append([]string{"*"}, parts...)
+	case 3:
+	default:
+		return fmt.Errorf("date %q must be year-month-day or month-day", spec)
+	}
+	if err := validateChain(parts[0], "year", 1970, 2199, false); err != nil {
+		return err
+	}
+	if err := validateChain(parts[1], "month", 1, 12, false); err != nil {
+		return err
+	}
+	return validateChain(parts[2], "day", 1, 31, false)
+}
+
+func validateTime(spec string) error {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return fmt.Errorf("time %q must be hour:minute or hour:minute:second", spec)
+	}
+	if err := validateChain(parts[0], "hour", 0, 23, false); err != nil {
+		return err
+	}
+	if err := validateChain(parts[1], "minute", 0, 59, false); err != nil {
+		return err
+	}
+	if len(parts) == 3 {
+		return validateChain(parts[2], "second", 0, 59, true)
+	}
+	return nil
+}
+
+// validateChain checks a comma separated list of values, ranges (start..end) and repetitions (start/repeat), or *
+func validateChain(chain string, field string, min int, max int, fractional bool) error {
+	for _, item := range strings.Split(chain, ",") {
+		value, repeat, repeated := strings.Cut(item, "/")
+		if repeated {
+			r, err := parseCalendarValue(repeat, fractional)
+			if err != nil || r <= 0 {
+				return fmt.Errorf("%s repetition %q must be a positive number", field, repeat)
+			}
+		}
+		if value == "*" {
+			continue
+		}
+		start, end, isRange := strings.Cut(value, "..")
+		from, err := parseCalendarValue(start, fractional)
+		if err != nil || from < float64(min) || from > float64(max) {
+			return fmt.Errorf("%s %q must be between %d and %d", field, start, min, max)
+		}
+		if !isRange {
+			continue
+		}
+		to, err := parseCalendarValue(end, fractional)
+		if err != nil || to < float64(min) || to > float64(max) {
+			return fmt.Errorf("%s %q must be between %d and %d", field, end, min, max)
+		}
+		if to < from {
+			return fmt.Errorf("%s range %s is reversed", field, value)
+		}
+	}
+	return nil
+}
+
+var calendarNumberPattern = regexp.MustCompile(`^[0-9]+$`)
+var calendarFractionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,6})?$`)
+
+func parseCalendarValue(v string, fractional bool) (float64, error) {
+	if fractional && calendarFractionPattern.MatchString(v) || calendarNumberPattern.MatchString(v) {
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("%q is not a number", v)
+}
+
+// timespanUnits are the units accepted in a time span, e.g. 1h 30min, see systemd.time(7)
+var timespanUnits = map[string]bool{
+	"usec": true, "us": true, "µs": true, "msec": true, "ms": true,
+	"seconds": true, "second": true, "sec": true, "s": true,
+	"minutes": true, "minute": true, "min": true, "m": true,
+	"hours": true, "hour": true, "hr": true, "h": true,
+	"days": true, "day": true, "d": true, "weeks": true, "week": true, "w": true,
+	"months": true, "month": true, "M": true, "years": true, "year": true, "y": true,
+}
+
+var timespanPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([^0-9\s.]*)\s*`)
+
+// ValidateTimespan checks that expr is a time span, as described in systemd.time(7), e.g. "90", "5min" or "1h 30min"
+// A number without a unit is in seconds.
+func ValidateTimespan(expr string) error {
+	rest := strings.TrimSpace(expr)
+	if rest == "" {
+		return fmt.Errorf("time span is empty")
+	}
+	if rest == "infinity" {
+		return nil
+	}
+	for rest != "" {
+		m := timespanPattern.FindStringSubmatch(rest)
+		if m == nil {
+			return fmt.Errorf("unexpected %q, expected a number followed by a unit such as s, min or h", rest)
+		}
+		if m[2] != "" && !timespanUnits[m[2]] {
+			return fmt.Errorf("%q is not a time unit", m[2])
+		}
+		rest = rest[len(m[0]):]
+	}
+	return nil
+}
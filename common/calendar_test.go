@@ -0,0 +1,68 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestValidateCalendar(t *testing.T) {
+	valid := []string{
+		"daily",
+		"weekly UTC",
+		"Mon..Fri *-*-* 09:00:00",
+		"Mon-Fri 09:00",
+		"Sat,Sun 10:00",
+		"Mon",
+		"*-*-01 04:00 UTC",
+		"*:0/15",
+		"*-*-* *:*:00",
+		"2024-12-25",
+		"12-25 08:30",
+		"*-02~01 23:00",
+		"*-*-1,15 12:00:00",
+		"*-1..6-1 00:00",
+		"*:*:0/2.5",
+		"Wed 18:00 Europe/Berlin",
+		"@1700000000",
+	}
+	for _, expr := range valid {
+		if err := ValidateCalendar(expr); err != nil {
+			t.Errorf("expected %q to be valid, got %s", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"sometimes",
+		"Mon..Funday",
+		"Fri..Mon 09:00",
+		"*-13-01",
+		"*-*-32",
+		"25:00",
+		"*:60",
+		"*:0/0",
+		"*:*:*:*",
+		"2024-12",
+		"1969-01-01",
+		"*-*-* 09:00 Mars/Olympus",
+		"@yesterday",
+		"*-*-* 10..08:00",
+	}
+	for _, expr := range invalid {
+		if err := ValidateCalendar(expr); err == nil {
+			t.Errorf("expected %q to be invalid", expr)
+		}
+	}
+}
+
+func TestValidateTimespan(t *testing.T) {
+	for _, expr := range []string{"90", "5min", "1h 30min", "1h30min", "2.5s", "1 week", "infinity", "100ms"} {
+		if err := ValidateTimespan(expr); err != nil {
+			t.Errorf("expected %q to be valid, got %s", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "soon", "5 fortnights", "min", "-5s", "1.s"} {
+		if err := ValidateTimespan(expr); err == nil {
+			t.Errorf("expected %q to be invalid", expr)
+		}
+	}
+}
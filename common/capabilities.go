@@ -0,0 +1,44 @@
+package common
+
+// APIVersion is the newest version of the REST API spoken by this build of the agent and provider
+const APIVersion = 1
+
+// MinAPIVersion is the oldest API version this build still speaks
+// The agent keeps serving every version in between, so that older providers in the field continue to work.
+const MinAPIVersion = 1
+
+const (
+	ModuleZfs     = "zfs"
+	ModuleSystemd = "systemd"
+	ModuleJournal = "journal"
+	ModuleLogin   = "login"
+)
+
+// File modules write files on the host, and are only enabled while the agent's user is able to write them
+const (
+	// ModuleUnitFiles writes unit files and their drop-ins to the agent's unit directory
+	ModuleUnitFiles = "unit_files"
+	// ModuleConfigFiles writes drop-ins of the files in ConfigFiles
+	ModuleConfigFiles = "config_files"
+	ModuleTmpfiles    = "tmpfiles"
+	ModuleSysusers    = "sysusers"
+)
+
+type ModuleCapability struct {
+	Enabled bool   `json:"enabled"`
+	Version string `json:"version,omitempty"`
+}
+
+type CapabilitiesResponse struct {
+	AgentVersion string `json:"agent_version"`
+	APIVersion   int    `json:"api_version"`
+	// MinAPIVersion is absent from agents which predate the compatibility window
+	MinAPIVersion int                         `json:"min_api_version,omitempty"`
+	Modules       map[string]ModuleCapability `json:"modules"`
+}
+
+// HasModule returns true if the agent has the given module enabled
+func (c CapabilitiesResponse) HasModule(name string) bool {
+	m, ok := c.Modules[name]
+	return ok && m.Enabled
+}
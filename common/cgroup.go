@@ -0,0 +1,161 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Infinity is the value of a cgroup limit which is not set, as systemd reports it
+const Infinity uint64 = math.MaxUint64
+
+// MaxIOWeight is the highest IOWeight, the default being 100
+const MaxIOWeight = 10000
+
+// cgroupUnitTypes are the types of unit which systemd runs in a cgroup of their own
+var cgroupUnitTypes = []string{"service", "slice", "scope", "socket", "mount", "swap"}
+
+// HasCgroup returns true if systemd runs the named unit in a cgroup, so that its resources can be controlled
+func HasCgroup(unit string) bool {
+	for _, t := range cgroupUnitTypes {
+		if strings.HasSuffix(unit, "."+t) {
+			return true
+		}
+	}
+	return false
+}
+
+var sizeSuffixes = []string{"K", "M", "G", "T", "P", "E"}
+
+var memorySizePattern = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]+))?([KMGTPE]?)$`)
+
+// ParseSize parses a size in bytes, as accepted by MemoryMax, e.g. 512M or 1.5G, where suffixes are powers of 1024
+// infinity removes the limit.
+func ParseSize(size string) (uint64, error) {
+	if size == "infinity" {
+		return Infinity, nil
+	}
+	m := memorySizePattern.FindStringSubmatch(size)
+	if m == nil {
+		return 0, fmt.Errorf("%q is not a size, such as 512M or 2G, or infinity", size)
+	}
+	multiplier := big.NewInt(1)
+	for _, s := range sizeSuffixes {
+		if m[3] == "" {
+			break
+		}
+		multiplier.Lsh(multiplier, 10)
+		if s == m[3] {
+			break
+		}
+	}
+	// Like systemd, a fraction is rounded down to a whole number of bytes
+	bytes, _ := new(big.Int).SetString(m[1]+m[2], 10)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(m[2]))), nil)
+	bytes.Mul(bytes, multiplier).Quo(bytes, scale)
+	if !bytes.IsUint64() || bytes.Uint64() == Infinity {
+		return 0, fmt.Errorf("%q is too large", size)
+	}
+	return bytes.Uint64(), nil
+}
+
+// FormatSize formats a size with the largest suffix which represents it exactly
+func FormatSize(size uint64) string {
+	if size == Infinity {
+		return "infinity"
+	}
+	suffix := ""
+	for _, s := range sizeSuffixes {
+		if size == 0 || size%1024 != 0 {
+			break
+		}
+		size /= 1024
+		suffix = s
+	}
+	return strconv.FormatUint(size, 10) + suffix
+}
+
+var cpuQuotaPattern = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]{1,2}))?%$`)
+
+// ParseCPUQuota parses a CPUQuota, a percentage of one CPU's time with up to two decimals, e.g. 150%
+// The quota is returned as the CPU time allowed per second, in microseconds, as systemd's CPUQuotaPerSecUSec holds it.
+func ParseCPUQuota(quota string) (uint64, error) {
+	m := cpuQuotaPattern.FindStringSubmatch(quota)
+	if m == nil {
+		return 0, fmt.Errorf("%q is not a percentage, such as 50%% or 150%%", quota)
+	}
+	percent, err := strconv.ParseUint(m[1], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is too large", quota)
+	}
+	// Each hundredth of a percent is 100 microseconds of CPU time per second
+	usec := percent * 10000
+	if m[2] != "" {
+		fraction, _ := strconv.ParseUint((m[2] + "0")[:2], 10, 64)
+		usec += fraction * 100
+	}
+	if usec == 0 {
+		return 0, fmt.Errorf("CPU quota must be more than 0%%")
+	}
+	return usec, nil
+}
+
+// FormatCPUQuota formats the CPU time allowed per second, in microseconds, as a percentage
+func FormatCPUQuota(usec uint64) string {
+	s := strconv.FormatUint(usec/10000, 10)
+	if fraction := usec % 10000 / 100; fraction != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%02d", fraction), "0")
+	}
+	return s + "%"
+}
+
+// ParseTasksMax parses the number of tasks a unit may have, or infinity to remove the limit
+func ParseTasksMax(tasks string) (uint64, error) {
+	if tasks == "infinity" {
+		return Infinity, nil
+	}
+	n, err := strconv.ParseUint(tasks, 10, 64)
+	if err != nil || n == 0 || n == Infinity {
+		return 0, fmt.Errorf("%q is not a positive number of tasks, or infinity", tasks)
+	}
+	return n, nil
+}
+
+// FormatTasksMax formats the number of tasks a unit may have
+func FormatTasksMax(tasks uint64) string {
+	if tasks == Infinity {
+		return "infinity"
+	}
+	return strconv.FormatUint(tasks, 10)
+}
+
+// ResourceControlRequest changes the cgroup limits of a unit with SetUnitProperties, absent fields are left as they
+// are, and Infinity removes a limit
+type ResourceControlRequest struct {
+	// Runtime changes are lost when the host reboots, others are persisted by systemd in /etc/systemd/system.control
+	Runtime   bool    `json:"runtime"`
+	MemoryMax *uint64 `json:"memory_max,omitempty"`
+	// CPUQuotaPerSecUSec is the CPU time the unit may use each second, in microseconds
+	CPUQuotaPerSecUSec *uint64 `json:"cpu_quota_per_sec_usec,omitempty"`
+	IOWeight           *uint64 `json:"io_weight,omitempty"`
+	TasksMax           *uint64 `json:"tasks_max,omitempty"`
+}
+
+// ResourceControlResponse is the cgroup of a unit, with its limits and its current usage
+type ResourceControlResponse struct {
+	Name string `json:"name"`
+	// ControlGroup is the path of the cgroup below /sys/fs/cgroup, empty if the unit isn't running
+	ControlGroup string `json:"control_group"`
+	// The limits are Infinity if they are not set
+	MemoryMax          uint64 `json:"memory_max"`
+	CPUQuotaPerSecUSec uint64 `json:"cpu_quota_per_sec_usec"`
+	IOWeight           uint64 `json:"io_weight"`
+	TasksMax           uint64 `json:"tasks_max"`
+	// The usage is absent if the unit isn't running, or its accounting is disabled
+	MemoryCurrent *uint64 `json:"memory_current,omitempty"`
+	CPUUsageNSec  *uint64 `json:"cpu_usage_nsec,omitempty"`
+	TasksCurrent  *uint64 `json:"tasks_current,omitempty"`
+}
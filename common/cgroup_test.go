@@ -0,0 +1,96 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	valid := map[string]uint64{
+		"0":        0,
+		"4000":     4000,
+		"1K":       1024,
+		"512M":     512 << 20,
+		"2G":       2 << 30,
+		"1T":       1 << 40,
+		"infinity": Infinity,
+	}
+	for size, expected := range valid {
+		actual, err := ParseSize(size)
+		if err != nil {
+			t.Errorf("expected %q to be valid, got %s", size, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("expected %q to be %d bytes, got %d", size, expected, actual)
+		}
+		if formatted := FormatSize(actual); formatted != size {
+			t.Errorf("expected %d bytes to be formatted as %q, got %q", actual, size, formatted)
+		}
+	}
+	if size, _ := ParseSize("1.5G"); FormatSize(size) != "1536M" {
+		t.Errorf("expected 1.5G to be formatted as 1536M, got %q", FormatSize(size))
+	}
+
+	for _, size := range []string{"", "512MB", "1.G", "-1", "50%", "16E", "99999999999999999999"} {
+		if _, err := ParseSize(size); err == nil {
+			t.Errorf("expected %q to be invalid", size)
+		}
+	}
+}
+
+func TestParseCPUQuota(t *testing.T) {
+	valid := map[string]uint64{
+		"50%":    500000,
+		"150%":   1500000,
+		"12.5%":  125000,
+		"0.25%":  2500,
+		"1000%":  10000000,
+		"33.33%": 333300,
+	}
+	for quota, expected := range valid {
+		actual, err := ParseCPUQuota(quota)
+		if err != nil {
+			t.Errorf("expected %q to be valid, got %s", quota, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("expected %q to be %dus per second, got %d", quota, expected, actual)
+		}
+		if formatted := FormatCPUQuota(actual); formatted != quota {
+			t.Errorf("expected %dus per second to be formatted as %q, got %q", actual, quota, formatted)
+		}
+	}
+
+	for _, quota := range []string{"", "50", "0%", "1.234%", "-5%", "half"} {
+		if _, err := ParseCPUQuota(quota); err == nil {
+			t.Errorf("expected %q to be invalid", quota)
+		}
+	}
+}
+
+func TestParseTasksMax(t *testing.T) {
+	if tasks, err := ParseTasksMax("512"); err != nil || tasks != 512 {
+		t.Errorf("expected 512 tasks, got %d, %v", tasks, err)
+	}
+	if tasks, err := ParseTasksMax("infinity"); err != nil || tasks != Infinity {
+		t.Errorf("expected no limit, got %d, %v", tasks, err)
+	}
+	for _, tasks := range []string{"", "0", "-1", "10%", "many"} {
+		if _, err := ParseTasksMax(tasks); err == nil {
+			t.Errorf("expected %q to be invalid", tasks)
+		}
+	}
+}
+
+func TestHasCgroup(t *testing.T) {
+	for _, unit := range []string{"nginx.service", "app.slice", "getty@tty1.service", "ssh.socket", "home.mount"} {
+		if !HasCgroup(unit) {
+			t.Errorf("expected %s to have a cgroup", unit)
+		}
+	}
+	for _, unit := range []string{"backup.timer", "multi-user.target", "dev-sda.device", "app.path"} {
+		if HasCgroup(unit) {
+			t.Errorf("expected %s to have no cgroup", unit)
+		}
+	}
+}
@@ -6,21 +6,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Client struct {
 	client *http.Client
 	host   string
 	port   int
+	// apiVersion is set by Negotiate while other requests may be in flight
+	apiVersion atomic.Int32
+	timeout    time.Duration
+	maxRetries int
+	retryWait  time.Duration
+
+	capsMu  sync.Mutex
+	caps    *CapabilitiesResponse
+	capsAt  time.Time
+	capsTTL time.Duration
 }
 
+// capabilitiesTTL is how long the agent's capabilities are cached, as they change while backends come and go
+const capabilitiesTTL = 10 * time.Second
+
 func NewClient(host string) *Client {
-	return &Client{
-		client: &http.Client{},
-		host:   host,
-		port:   8080,
+	c := &Client{
+		client:     &http.Client{},
+		host:       host,
+		port:       8080,
+		timeout:    DefaultRequestTimeout,
+		maxRetries: DefaultMaxRetries,
+		retryWait:  minRetryWait,
+		capsTTL:    capabilitiesTTL,
 	}
+	c.apiVersion.Store(APIVersion)
+	return c
 }
 
 func (c *Client) WithPort(port int) *Client {
@@ -28,73 +54,508 @@ func (c *Client) WithPort(port int) *Client {
 	return c
 }
 
-// ZFS
+// WithTimeout sets the timeout of each attempt at a request, including reading the response body
+// It only applies to requests whose context has no deadline, a timeout of zero means no timeout
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	c.timeout = timeout
+	return c
+}
 
-func (c *Client) ZfsCreatePool(ctx context.Context, create ZpoolCreateRequest) (ZPoolResponse, error) {
-	var result ZPoolResponse
-	body, err := json.Marshal(create)
+// WithMaxRetries sets how many times an idempotent request is retried when the agent is unreachable or unavailable
+func (c *Client) WithMaxRetries(retries int) *Client {
+	c.maxRetries = retries
+	return c
+}
+
+// Dialer opens a connection to the agent, addr is the agent's host and port
+type Dialer func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+// WithDialer replaces the transport used to reach the agent, for example to tunnel requests over SSH
+func (c *Client) WithDialer(dial Dialer) *Client {
+	c.client.Transport = &http.Transport{
+		DialContext:         dial,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return c
+}
+
+// Address returns the host and port of the agent
+func (c *Client) Address() string {
+	return net.JoinHostPort(c.host, strconv.Itoa(c.port))
+}
+
+// Health
+
+// Ready queries the agent readiness endpoint
+// An agent with an unavailable dependency is not an error, callers should check the returned status
+func (c *Client) Ready(ctx context.Context) (ReadinessResponse, error) {
+	var result ReadinessResponse
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl()+"/readyz", nil)
 	if err != nil {
 		return result, err
 	}
-	url := c.createUrl("zfs", "zpool")
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
+	resp, err := c.doRequest(req, http.StatusServiceUnavailable)
 	if err != nil {
 		return result, err
 	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		b, _ := io.ReadAll(resp.Body)
+		return result, fmt.Errorf("unexpected readiness response (%d): %s", resp.StatusCode, string(b))
+	}
+	err = DecodeInto(resp, &result)
+	return result, err
+}
+
+// Capabilities returns the modules and versions supported by the agent
+// The result is cached briefly, so that the many resources of a plan share it, but modules are enabled and disabled
+// while the agent runs, as backend services start and stop and its access to files changes. Callers which find a
+// module disabled should call RefreshCapabilities before giving up on it.
+func (c *Client) Capabilities(ctx context.Context) (CapabilitiesResponse, error) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	if c.caps != nil && time.Since(c.capsAt) < c.capsTTL {
+		return *c.caps, nil
+	}
 
-	resp, err := c.doRequest(req)
+	var result CapabilitiesResponse
+	err := c.call(ctx, http.MethodGet, c.baseUrl()+"/capabilities", nil, http.StatusOK, &result)
 	if err != nil {
-		return result, err
+		return result, fmt.Errorf("failed to get agent capabilities: %w", err)
 	}
-	if resp.StatusCode != http.StatusCreated {
-		defer resp.Body.Close()
-		b, _ := io.ReadAll(resp.Body)
-		e := fmt.Errorf("failed to create user. error: %s", string(b))
-		return result, e
+	c.caps = &result
+	c.capsAt = time.Now()
+	return result, nil
+}
+
+// RefreshCapabilities fetches the agent's capabilities again, rather than using the cached result
+func (c *Client) RefreshCapabilities(ctx context.Context) (CapabilitiesResponse, error) {
+	c.capsMu.Lock()
+	c.caps = nil
+	c.capsMu.Unlock()
+	return c.Capabilities(ctx)
+}
+
+// Negotiate selects the newest API version supported by both the client and the agent
+// Returns an IncompatibleAPIError if there is none
+func (c *Client) Negotiate(ctx context.Context) (int, error) {
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return 0, err
+	}
+	min := caps.MinAPIVersion
+	if min == 0 {
+		min = caps.APIVersion
+	}
+	version, err := NegotiateAPIVersion(min, caps.APIVersion)
+	if err != nil {
+		return 0, err
+	}
+	c.apiVersion.Store(int32(version))
+	return version, nil
+}
+
+// ZFS
+
+func (c *Client) ZfsCreatePool(ctx context.Context, create ZpoolCreateRequest) (ZPoolResponse, error) {
+	var result ZPoolResponse
+	err := c.call(ctx, http.MethodPost, c.createUrl("zfs", "zpool"), create, http.StatusCreated, &result)
+	if err != nil {
+		return result, fmt.Errorf("failed to create zpool %s: %w", create.Name, err)
 	}
-	DecodeInto(resp, &result)
 	return result, nil
 }
 
 func (c *Client) ZfsGetPools(ctx context.Context) (ZpoolListResponse, error) {
-
 	var result ZpoolListResponse
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.createUrl("zfs", "zpool"), nil)
+	err := c.call(ctx, http.MethodGet, c.createUrl("zfs", "zpool"), nil, http.StatusOK, &result)
+	return result, err
+}
+
+func (c *Client) ZfsGetPool(ctx context.Context, name string) (ZPoolResponse, error) {
+	var pool ZPoolResponse
+	endpoint := fmt.Sprintf("%s/%s", c.createUrl("zfs", "zpool"), url.PathEscape(name))
+	err := c.call(ctx, http.MethodGet, endpoint, nil, http.StatusOK, &pool)
+	return pool, err
+}
+
+func (c *Client) ZfsDestroyPool(ctx context.Context, name string) error {
+	endpoint := fmt.Sprintf("%s/%s", c.createUrl("zfs", "zpool"), url.PathEscape(name))
+	return c.call(ctx, http.MethodDelete, endpoint, nil, http.StatusNoContent, nil)
+}
+
+// Systemd
+
+// SystemdListUnits returns the units in any of the given states whose names match any of the glob patterns
+// Empty states or patterns match every unit.
+func (c *Client) SystemdListUnits(ctx context.Context, states []string, patterns []string) (UnitListResponse, error) {
+	var result UnitListResponse
+	query := url.Values{"state": states, "pattern": patterns}
+	endpoint := c.createUrl("systemd", "units")
+	if q := query.Encode(); q != "" {
+		endpoint += "?" + q
+	}
+	err := c.call(ctx, http.MethodGet, endpoint, nil, http.StatusOK, &result)
+	return result, err
+}
+
+func (c *Client) SystemdGetUnitFile(ctx context.Context, name string) (UnitFileResponse, error) {
+	var unit UnitFileResponse
+	err := c.call(ctx, http.MethodGet, c.unitFileUrl(name), nil, http.StatusOK, &unit)
+	return unit, err
+}
+
+// SystemdWriteUnitFile creates or replaces a unit file, the agent reloads systemd once it is written
+func (c *Client) SystemdWriteUnitFile(ctx context.Context, name string, content string) (UnitFileResponse, error) {
+	var unit UnitFileResponse
+	err := c.call(ctx, http.MethodPut, c.unitFileUrl(name), UnitFileRequest{Content: content}, http.StatusOK, &unit)
 	if err != nil {
-		return result, err
+		return unit, fmt.Errorf("failed to write unit file %s: %w", name, err)
 	}
-	resp, err := c.doRequest(req)
+	return unit, nil
+}
+
+func (c *Client) SystemdDeleteUnitFile(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, c.unitFileUrl(name), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) SystemdGetUnitState(ctx context.Context, name string) (UnitStateResponse, error) {
+	var state UnitStateResponse
+	err := c.call(ctx, http.MethodGet, c.unitFileUrl(name)+"/state", nil, http.StatusOK, &state)
+	return state, err
+}
+
+// SystemdSetUnitState changes the state of a unit, waiting for it to start or stop
+func (c *Client) SystemdSetUnitState(ctx context.Context, name string, state UnitStateRequest) (UnitStateResponse, error) {
+	var result UnitStateResponse
+	err := c.call(ctx, http.MethodPut, c.unitFileUrl(name)+"/state", state, http.StatusOK, &result)
 	if err != nil {
-		return result, err
+		return result, fmt.Errorf("failed to change the state of unit %s: %w", name, err)
 	}
+	return result, nil
+}
 
-	err = DecodeInto[ZpoolListResponse](resp, &result)
+// SystemdRestartUnit restarts a unit, waiting for it to start again
+func (c *Client) SystemdRestartUnit(ctx context.Context, name string) (UnitStateResponse, error) {
+	var result UnitStateResponse
+	err := c.call(ctx, http.MethodPost, c.unitFileUrl(name)+"/restart", nil, http.StatusOK, &result)
+	if err != nil {
+		return result, fmt.Errorf("failed to restart unit %s: %w", name, err)
+	}
+	return result, nil
+}
+
+func (c *Client) SystemdGetTimer(ctx context.Context, name string) (TimerResponse, error) {
+	var timer TimerResponse
+	err := c.call(ctx, http.MethodGet, c.unitFileUrl(name)+"/timer", nil, http.StatusOK, &timer)
+	return timer, err
+}
+
+func (c *Client) SystemdGetUnitResources(ctx context.Context, name string) (ResourceControlResponse, error) {
+	var resources ResourceControlResponse
+	err := c.call(ctx, http.MethodGet, c.unitFileUrl(name)+"/resources", nil, http.StatusOK, &resources)
+	return resources, err
+}
+
+// SystemdSetUnitResources changes the cgroup limits of a unit, which apply straight away
+func (c *Client) SystemdSetUnitResources(ctx context.Context, name string, request ResourceControlRequest) (ResourceControlResponse, error) {
+	var result ResourceControlResponse
+	err := c.call(ctx, http.MethodPut, c.unitFileUrl(name)+"/resources", request, http.StatusOK, &result)
+	if err != nil {
+		return result, fmt.Errorf("failed to set the resource limits of unit %s: %w", name, err)
+	}
+	return result, nil
+}
+
+func (c *Client) SystemdGetDropIn(ctx context.Context, unit string, name string) (DropInResponse, error) {
+	var dropIn DropInResponse
+	err := c.call(ctx, http.MethodGet, c.dropInUrl(unit, name), nil, http.StatusOK, &dropIn)
+	return dropIn, err
+}
+
+// SystemdWriteDropIn creates or replaces a drop-in, the agent reloads systemd once it is written
+func (c *Client) SystemdWriteDropIn(ctx context.Context, unit string, name string, content string) (DropInResponse, error) {
+	var dropIn DropInResponse
+	err := c.call(ctx, http.MethodPut, c.dropInUrl(unit, name), UnitFileRequest{Content: content}, http.StatusOK, &dropIn)
+	if err != nil {
+		return dropIn, fmt.Errorf("failed to write drop-in %s of unit %s: %w", name, unit, err)
+	}
+	return dropIn, nil
+}
+
+func (c *Client) SystemdDeleteDropIn(ctx context.Context, unit string, name string) error {
+	return c.call(ctx, http.MethodDelete, c.dropInUrl(unit, name), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) SystemdGetConfigDropIn(ctx context.Context, file string, name string) (ConfigDropInResponse, error) {
+	var dropIn ConfigDropInResponse
+	err := c.call(ctx, http.MethodGet, c.configDropInUrl(file, name), nil, http.StatusOK, &dropIn)
+	return dropIn, err
+}
+
+// SystemdWriteConfigDropIn creates or replaces a drop-in of a configuration file, such as journald, the agent
+// restarts the daemon which reads the file if the drop-in changed
+func (c *Client) SystemdWriteConfigDropIn(ctx context.Context, file string, name string, content string) (ConfigDropInResponse, error) {
+	var dropIn ConfigDropInResponse
+	err := c.call(ctx, http.MethodPut, c.configDropInUrl(file, name), UnitFileRequest{Content: content}, http.StatusOK, &dropIn)
+	if err != nil {
+		return dropIn, fmt.Errorf("failed to write drop-in %s of %s: %w", name, file, err)
+	}
+	return dropIn, nil
+}
+
+func (c *Client) SystemdDeleteConfigDropIn(ctx context.Context, file string, name string) error {
+	return c.call(ctx, http.MethodDelete, c.configDropInUrl(file, name), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) SystemdGetTmpfiles(ctx context.Context, name string) (TmpfilesResponse, error) {
+	var tmpfiles TmpfilesResponse
+	err := c.call(ctx, http.MethodGet, c.namedUrl("tmpfiles", name), nil, http.StatusOK, &tmpfiles)
+	return tmpfiles, err
+}
+
+// SystemdWriteTmpfiles creates or replaces a tmpfiles.d file, the agent creates the paths it declares straight away
+func (c *Client) SystemdWriteTmpfiles(ctx context.Context, name string, content string) (TmpfilesResponse, error) {
+	var tmpfiles TmpfilesResponse
+	err := c.call(ctx, http.MethodPut, c.namedUrl("tmpfiles", name), UnitFileRequest{Content: content}, http.StatusOK, &tmpfiles)
+	if err != nil {
+		return tmpfiles, fmt.Errorf("failed to write tmpfiles.d file %s: %w", name, err)
+	}
+	return tmpfiles, nil
+}
+
+func (c *Client) SystemdDeleteTmpfiles(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, c.namedUrl("tmpfiles", name), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) SystemdGetSysusers(ctx context.Context, name string) (SysusersResponse, error) {
+	var sysusers SysusersResponse
+	err := c.call(ctx, http.MethodGet, c.namedUrl("sysusers", name), nil, http.StatusOK, &sysusers)
+	return sysusers, err
+}
+
+// SystemdWriteSysusers creates or replaces a sysusers.d file, the agent creates the users and groups it declares
+// straight away
+func (c *Client) SystemdWriteSysusers(ctx context.Context, name string, content string) (SysusersResponse, error) {
+	var sysusers SysusersResponse
+	err := c.call(ctx, http.MethodPut, c.namedUrl("sysusers", name), UnitFileRequest{Content: content}, http.StatusOK, &sysusers)
+	if err != nil {
+		return sysusers, fmt.Errorf("failed to write sysusers.d file %s: %w", name, err)
+	}
+	return sysusers, nil
+}
+
+func (c *Client) SystemdDeleteSysusers(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, c.namedUrl("sysusers", name), nil, http.StatusNoContent, nil)
+}
+
+// Journal
+
+// JournalGetEntries returns the journal entries matching query, oldest first
+func (c *Client) JournalGetEntries(ctx context.Context, query JournalQuery) (JournalResponse, error) {
+	var result JournalResponse
+	values := url.Values{"unit": query.Units}
+	if query.Priority != "" {
+		values.Set("priority", query.Priority)
+	}
+	if query.Since != nil {
+		values.Set("since", query.Since.Format(time.RFC3339Nano))
+	}
+	if query.Until != nil {
+		values.Set("until", query.Until.Format(time.RFC3339Nano))
+	}
+	if query.Boot != "" {
+		values.Set("boot", query.Boot)
+	}
+	if query.Cursor != "" {
+		values.Set("cursor", query.Cursor)
+	}
+	if query.Lines > 0 {
+		values.Set("lines", strconv.Itoa(query.Lines))
+	}
+	endpoint := fmt.Sprintf("%s/v%d/journal", c.baseUrl(), c.apiVersion.Load())
+	if q := values.Encode(); q != "" {
+		endpoint += "?" + q
+	}
+	err := c.call(ctx, http.MethodGet, endpoint, nil, http.StatusOK, &result)
 	return result, err
+}
+
+// Login
 
+// LoginGetBoot returns the host's current boot ID, and whether it needs rebooting
+func (c *Client) LoginGetBoot(ctx context.Context) (BootResponse, error) {
+	var boot BootResponse
+	err := c.call(ctx, http.MethodGet, c.createUrl("login", "boot"), nil, http.StatusOK, &boot)
+	return boot, err
 }
 
-func (c *Client) ZfsGetPool(ctx context.Context, name string) (ZPoolResponse, error) {
-	var pool ZPoolResponse
-	url := fmt.Sprintf("%s/%s", c.createUrl("zfs", "zpool"), name)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// LoginReboot schedules a reboot of the host, replacing any which is already scheduled
+// The agent replies before the host goes down, callers wait for LoginGetBoot to report a new boot ID.
+func (c *Client) LoginReboot(ctx context.Context, request RebootRequest) (RebootResponse, error) {
+	var result RebootResponse
+	err := c.call(ctx, http.MethodPost, c.createUrl("login", "reboot"), request, http.StatusAccepted, &result)
 	if err != nil {
-		return pool, err
+		return result, fmt.Errorf("failed to schedule reboot: %w", err)
 	}
+	return result, nil
+}
 
-	resp, err := c.doRequest(req)
+// LoginCancelReboot cancels the scheduled reboot, if there is one
+func (c *Client) LoginCancelReboot(ctx context.Context) error {
+	return c.call(ctx, http.MethodDelete, c.createUrl("login", "reboot"), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) dropInUrl(unit string, name string) string {
+	return fmt.Sprintf("%s/dropins/%s", c.unitFileUrl(unit), url.PathEscape(name))
+}
+
+func (c *Client) configDropInUrl(file string, name string) string {
+	return fmt.Sprintf("%s/%s/dropins/%s", c.createUrl("systemd", "config"), url.PathEscape(file), url.PathEscape(name))
+}
+
+// namedUrl returns the URL of a named file of the systemd module, e.g. a tmpfiles.d file
+func (c *Client) namedUrl(resource string, name string) string {
+	return fmt.Sprintf("%s/%s", c.createUrl("systemd", resource), url.PathEscape(name))
+}
+
+func (c *Client) unitFileUrl(name string) string {
+	return fmt.Sprintf("%s/%s", c.createUrl("systemd", "units"), url.PathEscape(name))
+}
+
+// call sends body as JSON, if it is not nil, and decodes the response into result, if it is not nil
+// Any status other than expected is returned as an APIError
+func (c *Client) call(ctx context.Context, method string, endpoint string, body any, expected int, result any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
 	if err != nil {
-		return pool, err
+		return err
 	}
 
-	err = DecodeInto[ZPoolResponse](resp, &pool)
-	return pool, err
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if err := checkResponse(resp, expected); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
 }
 
-func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
+// doRequest sends the request, retrying idempotent requests which fail to reach the agent or get a 502, 503 or 504
+// Statuses listed in accept are returned to the caller without retrying.
+// The caller must close the body of the returned response, every other response body is closed here.
+func (c *Client) doRequest(req *http.Request, accept ...int) (*http.Response, error) {
 	req.Header.Set("Content-Type", "application/json")
-	return c.client.Do(req)
+	req.Header.Set(APIVersionHeader, strconv.Itoa(int(c.apiVersion.Load())))
+	ctx := req.Context()
+	retries := c.maxRetries
+	if !idempotent(req.Method) {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.attempt(req)
+		if err != nil {
+			if attempt >= retries || !retryableError(ctx, err) {
+				return nil, err
+			}
+			if err := sleep(ctx, backoff(c.retryWait, attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Check the version before the body, so that an incompatible agent is reported as such, rather than a decode failure
+		if err := checkAPIVersion(resp); err != nil && !retryableStatus(resp.StatusCode) {
+			closeBody(resp)
+			return nil, err
+		}
+		if attempt >= retries || !retryableStatus(resp.StatusCode) || slices.Contains(accept, resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait, ok := retryAfter(resp)
+		if !ok {
+			wait = backoff(c.retryWait, attempt)
+		}
+		closeBody(resp)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends the request once
+// The attempt is bounded by the client timeout, or by the request context's deadline if that is sooner, and the
+// bound is sent to the agent, so that it can abandon the operation once the attempt has been given up on.
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	cancel := context.CancelFunc(func() {})
+	timeout, bounded := c.timeout, c.timeout > 0
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); !bounded || remaining < timeout {
+			timeout, bounded = remaining, true
+		}
+	}
+	if bounded {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		req.Header.Set(TimeoutHeader, strconv.FormatInt(timeout.Milliseconds(), 10))
+	} else {
+		req.Header.Del(TimeoutHeader)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the attempt's context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// closeBody drains and closes the response body, so that the connection can be reused
+func closeBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func (c *Client) baseUrl() string {
+	return "http://" + c.Address()
 }
 
 func (c *Client) createUrl(module string, resource string) string {
-	return fmt.Sprintf("http://%s:%d/%s/%s", c.host, c.port, module, resource)
+	return fmt.Sprintf("%s/v%d/%s/%s", c.baseUrl(), c.apiVersion.Load(), module, resource)
 }
@@ -0,0 +1,343 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for an agent serving h, which speaks the current API version
+func newTestClient(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	return newUnversionedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(APIVersion))
+		w.Header().Set(MinAPIVersionHeader, strconv.Itoa(MinAPIVersion))
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// newUnversionedTestClient returns a client for an agent serving h, which is responsible for setting the version headers
+func newUnversionedTestClient(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(u.Hostname()).WithPort(port)
+}
+
+func TestCapabilitiesAreCached(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		Encode(w, r, http.StatusOK, CapabilitiesResponse{
+			AgentVersion: "test",
+			APIVersion:   APIVersion,
+			Modules:      map[string]ModuleCapability{ModuleZfs: {Enabled: true, Version: "1.0"}},
+		})
+	}))
+
+	for i := 0; i < 2; i++ {
+		caps, err := client.Capabilities(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !caps.HasModule(ModuleZfs) {
+			t.Errorf("expected zfs module to be enabled")
+		}
+		if caps.HasModule("missing") {
+			t.Errorf("expected unknown module to be disabled")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected capabilities to be fetched once, got %d", calls)
+	}
+}
+
+func TestCapabilitiesAreRefreshed(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// The module is enabled once its backend starts, after the first request
+		Encode(w, r, http.StatusOK, CapabilitiesResponse{
+			APIVersion: APIVersion,
+			Modules:    map[string]ModuleCapability{ModuleZfs: {Enabled: calls > 1}},
+		})
+	}))
+
+	caps, err := client.Capabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.HasModule(ModuleZfs) {
+		t.Fatalf("expected zfs module to be disabled")
+	}
+	caps, err = client.RefreshCapabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.HasModule(ModuleZfs) {
+		t.Errorf("expected refreshed capabilities to enable the zfs module")
+	}
+
+	client.capsTTL = 0
+	if _, err := client.Capabilities(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected expired capabilities to be fetched again, got %d calls", calls)
+	}
+}
+
+func TestIncompatibleAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max string
+	}{
+		{"unversioned", "", ""},
+		{"too new", strconv.Itoa(APIVersion + 1), strconv.Itoa(APIVersion + 2)},
+		{"too old", "", strconv.Itoa(MinAPIVersion - 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newUnversionedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.max != "" {
+					w.Header().Set(APIVersionHeader, tt.max)
+				}
+				if tt.min != "" {
+					w.Header().Set(MinAPIVersionHeader, tt.min)
+				}
+				// Not a valid pool list, the version must be checked before the body is decoded
+				w.Write([]byte("<html>"))
+			}))
+
+			_, err := client.ZfsGetPools(context.Background())
+			var incompatible *IncompatibleAPIError
+			if !errors.As(err, &incompatible) {
+				t.Fatalf("expected an IncompatibleAPIError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	var requested string
+	client := newUnversionedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(APIVersion+1))
+		w.Header().Set(MinAPIVersionHeader, strconv.Itoa(MinAPIVersion))
+		if r.URL.Path == "/capabilities" {
+			Encode(w, r, http.StatusOK, CapabilitiesResponse{APIVersion: APIVersion + 1, MinAPIVersion: MinAPIVersion})
+			return
+		}
+		requested = r.Header.Get(APIVersionHeader) + " " + r.URL.Path
+		Encode(w, r, http.StatusOK, ZpoolListResponse{})
+	}))
+
+	version, err := client.Negotiate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != APIVersion {
+		t.Errorf("expected to negotiate version %d, got %d", APIVersion, version)
+	}
+	if _, err := client.ZfsGetPools(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprintf("%d /v%d/zfs/zpool", APIVersion, APIVersion); requested != expected {
+		t.Errorf("expected request %q, got %q", expected, requested)
+	}
+}
+
+func TestNegotiateConcurrent(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/capabilities" {
+			Encode(w, r, http.StatusOK, CapabilitiesResponse{APIVersion: APIVersion, MinAPIVersion: MinAPIVersion})
+			return
+		}
+		Encode(w, r, http.StatusOK, ZpoolListResponse{})
+	}))
+
+	// Resources share a client, so the version may be negotiated while other requests are in flight
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Negotiate(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := client.ZfsGetPools(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		failures int
+		status   int
+		attempts int
+		success  bool
+	}{
+		{"recovers", http.MethodGet, 2, http.StatusServiceUnavailable, 3, true},
+		{"bad gateway", http.MethodDelete, 1, http.StatusBadGateway, 2, true},
+		{"exhausted", http.MethodGet, 10, http.StatusGatewayTimeout, DefaultMaxRetries + 1, false},
+		{"not idempotent", http.MethodPost, 1, http.StatusServiceUnavailable, 1, false},
+		{"not retryable", http.MethodGet, 1, http.StatusInternalServerError, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tt.failures {
+					Encode(w, r, tt.status, ErrorResponse{Error: "unavailable"})
+					return
+				}
+				Encode(w, r, http.StatusOK, ZPoolResponse{Name: "tank"})
+			}))
+			client.retryWait = time.Millisecond
+
+			err := client.call(context.Background(), tt.method, client.createUrl("zfs", "zpool"), nil, http.StatusOK, nil)
+			if tt.success && err != nil {
+				t.Errorf("expected success, got %s", err)
+			}
+			var apiErr *APIError
+			if !tt.success && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.status) {
+				t.Errorf("expected a %d error, got %v", tt.status, err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryConnectionError(t *testing.T) {
+	client := newTestClient(t, http.NotFoundHandler())
+	// Nothing listens on the discard port
+	client.port = 9
+	client.retryWait = time.Millisecond
+	client.WithMaxRetries(2)
+
+	start := time.Now()
+	_, err := client.ZfsGetPools(context.Background())
+	if err == nil {
+		t.Fatal("expected a connection error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected retries to back off briefly, took %s", time.Since(start))
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	client.WithTimeout(50 * time.Millisecond).WithMaxRetries(0)
+
+	_, err := client.ZfsGetPools(context.Background())
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
+
+func TestRequestTimeoutWithDeadline(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	headers := make(chan string, 2)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get(TimeoutHeader)
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	client.WithTimeout(50 * time.Millisecond).WithMaxRetries(0)
+
+	// The attempt is bounded by the client timeout, even though the operation has longer to run
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	_, err := client.ZfsGetPools(ctx)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the attempt to time out after 50ms, took %s", time.Since(start))
+	}
+	if header := <-headers; header != "50" {
+		t.Errorf("expected the agent to be sent a timeout of 50ms, got %q", header)
+	}
+
+	// A sooner deadline bounds the attempt instead
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WithTimeout(time.Minute).ZfsGetPools(ctx); err == nil {
+		t.Error("expected the request to fail at the deadline")
+	}
+	header := <-headers
+	if timeout, err := strconv.Atoi(header); err != nil || timeout > 20 {
+		t.Errorf("expected the agent to be sent the time remaining, got %q", header)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		wait   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		wait, ok := retryAfter(resp)
+		if wait != tt.wait || ok != tt.ok {
+			t.Errorf("Retry-After %q: expected (%s, %t), got (%s, %t)", tt.header, tt.wait, tt.ok, wait, ok)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 100; attempt++ {
+		wait := backoff(minRetryWait, attempt)
+		if wait <= 0 || wait > maxRetryWait {
+			t.Errorf("attempt %d: backoff %s out of range", attempt, wait)
+		}
+	}
+}
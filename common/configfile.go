@@ -0,0 +1,201 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigFile is a systemd configuration file, such as journald.conf, which the agent extends with drop-ins
+// written to <Dir>/<name>.conf. Only the keys known for the file are accepted, so that a typo fails at plan time
+// rather than being ignored by the daemon.
+type ConfigFile struct {
+	// Dir is the drop-in directory on the host
+	Dir string
+	// Section is the only section the file has, e.g. Journal
+	Section string
+	// Unit is restarted when a drop-in changes, empty if the file is read afresh whenever it is needed
+	Unit string
+	// Keys validate the values of each known key
+	Keys map[string]ConfigValue
+}
+
+// ConfigValue checks the value assigned to a key
+// An empty value is always accepted, as it resets the key to its default.
+type ConfigValue func(value string) error
+
+// ConfigFiles are the configuration files which accept drop-ins, by name
+var ConfigFiles = map[string]ConfigFile{
+	"journald": {
+		Dir:     "/etc/systemd/journald.conf.d",
+		Section: "Journal",
+		Unit:    "systemd-journald.service",
+		Keys: map[string]ConfigValue{
+			"Storage":              oneOf("volatile", "persistent", "auto", "none"),
+			"Compress":             anyOf(boolValue, sizeValue),
+			"Seal":                 boolValue,
+			"SplitMode":            oneOf("uid", "none"),
+			"SyncIntervalSec":      ValidateTimespan,
+			"RateLimitIntervalSec": ValidateTimespan,
+			"RateLimitBurst":       uintValue,
+			"SystemMaxUse":         sizeValue,
+			"SystemKeepFree":       sizeValue,
+			"SystemMaxFileSize":    sizeValue,
+			"SystemMaxFiles":       uintValue,
+			"RuntimeMaxUse":        sizeValue,
+			"RuntimeKeepFree":      sizeValue,
+			"RuntimeMaxFileSize":   sizeValue,
+			"RuntimeMaxFiles":      uintValue,
+			"MaxRetentionSec":      ValidateTimespan,
+			"MaxFileSec":           ValidateTimespan,
+			"ForwardToSyslog":      boolValue,
+			"ForwardToKMsg":        boolValue,
+			"ForwardToConsole":     boolValue,
+			"ForwardToWall":        boolValue,
+			"TTYPath":              pathValue,
+			"MaxLevelStore":        priorityValue,
+			"MaxLevelSyslog":       priorityValue,
+			"MaxLevelKMsg":         priorityValue,
+			"MaxLevelConsole":      priorityValue,
+			"MaxLevelWall":         priorityValue,
+			"LineMax":              sizeValue,
+			"ReadKMsg":             boolValue,
+			"Audit":                boolValue,
+		},
+	},
+	// systemd-coredump is started for each crash, and reads its configuration then
+	"coredump": {
+		Dir:     "/etc/systemd/coredump.conf.d",
+		Section: "Coredump",
+		Keys: map[string]ConfigValue{
+			"Storage":         oneOf("none", "external", "journal"),
+			"Compress":        boolValue,
+			"ProcessSizeMax":  sizeValue,
+			"ExternalSizeMax": sizeValue,
+			"JournalSizeMax":  sizeValue,
+			"MaxUse":          sizeValue,
+			"KeepFree":        sizeValue,
+		},
+	},
+}
+
+// ConfigFileNames returns the names of the configuration files which accept drop-ins, sorted
+func ConfigFileNames() []string {
+	names := make([]string, 0, len(ConfigFiles))
+	for name := range ConfigFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that content is a drop-in of the file, which only assigns known keys with valid values
+func (f ConfigFile) Validate(content string) error {
+	sections, err := ParseUnit(content)
+	if err != nil {
+		return err
+	}
+	for _, s := range sections {
+		if s.Name != f.Section {
+			return fmt.Errorf("unknown section [%s], expected [%s]", s.Name, f.Section)
+		}
+		for _, e := range s.Entries {
+			check, ok := f.Keys[e.Key]
+			if !ok {
+				return fmt.Errorf("unknown key %s in [%s], expected one of %v", e.Key, s.Name, f.KeyNames())
+			}
+			if e.Value == "" {
+				continue
+			}
+			if err := check(e.Value); err != nil {
+				return fmt.Errorf("invalid %s: %w", e.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// KeyNames returns the known keys of the file, sorted
+func (f ConfigFile) KeyNames() []string {
+	keys := make([]string, 0, len(f.Keys))
+	for k := range f.Keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func oneOf(values ...string) ConfigValue {
+	return func(value string) error {
+		if !slices.Contains(values, value) {
+			return fmt.Errorf("%q must be one of %v", value, values)
+		}
+		return nil
+	}
+}
+
+// anyOf accepts a value which any of the checks accepts, reporting the error of the last one otherwise
+func anyOf(checks ...ConfigValue) ConfigValue {
+	return func(value string) error {
+		var err error
+		for _, check := range checks {
+			if err = check(value); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+}
+
+// boolValue accepts the booleans understood by systemd's parse_boolean
+func boolValue(value string) error {
+	switch strings.ToLower(value) {
+	case "1", "yes", "y", "true", "t", "on", "0", "no", "n", "false", "f", "off":
+		return nil
+	}
+	return fmt.Errorf("%q is not a boolean, such as yes or no", value)
+}
+
+func uintValue(value string) error {
+	if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+		return fmt.Errorf("%q is not a non-negative integer", value)
+	}
+	return nil
+}
+
+var sizePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?\s*[KMGTPE]?B?$`)
+
+// sizeValue accepts a size in bytes, optionally with a base 1024 suffix, e.g. 512M or 1.5G
+func sizeValue(value string) error {
+	if !sizePattern.MatchString(value) {
+		return fmt.Errorf("%q is not a size, such as 512M or 1G", value)
+	}
+	return nil
+}
+
+func pathValue(value string) error {
+	if !strings.HasPrefix(value, "/") {
+		return fmt.Errorf("%q is not an absolute path", value)
+	}
+	return nil
+}
+
+func priorityValue(value string) error {
+	_, err := ParsePriority(value)
+	return err
+}
+
+// ConfigDropInResponse is a drop-in of a configuration file, written by the agent
+type ConfigDropInResponse struct {
+	// File is the name of the configuration file, e.g. journald
+	File string `json:"file"`
+	Name string `json:"name"`
+	// Path is the location of the drop-in on the host
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Restarted is the unit which was restarted to apply the change, absent if none was needed
+	Restarted string `json:"restarted,omitempty"`
+}
@@ -0,0 +1,31 @@
+package common
+
+import "testing"
+
+func TestConfigFileValidate(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		valid   bool
+	}{
+		{"journald", "[Journal]\nStorage=persistent\nSystemMaxUse=1.5G\nRateLimitIntervalSec=30s\nRateLimitBurst=10000\n", true},
+		{"journald", "[Journal]\nForwardToSyslog=no\nMaxLevelStore=warning\nCompress=4K\n", true},
+		{"journald", "# Reset to the default\n[Journal]\nSystemMaxUse=\n", true},
+		{"journald", "[Journal]\nStorage=disk\n", false},
+		{"journald", "[Journal]\nRateLimitBurst=-1\n", false},
+		{"journald", "[Journal]\nRateLimitIntervalSec=soon\n", false},
+		{"journald", "[Journal]\nForwardToSyslog=maybe\n", false},
+		{"journald", "[Journal]\nMaxLevelStore=loud\n", false},
+		{"journald", "[Journal]\nTTYPath=tty12\n", false},
+		{"journald", "[Journal]\nStorage=auto\n[Service]\nRestart=always\n", false},
+		{"coredump", "[Coredump]\nStorage=external\nMaxUse=10G\nProcessSizeMax=2G\n", true},
+		{"coredump", "[Coredump]\nSystemMaxUse=1G\n", false},
+		{"coredump", "Storage=none\n", false},
+	}
+	for _, tt := range tests {
+		err := ConfigFiles[tt.file].Validate(tt.content)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s %q: expected valid=%v, got %v", tt.file, tt.content, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned by the client when the requested object does not exist on the host
+var ErrNotFound = errors.New("not found")
+
+// ErrorResponse is the body returned by the agent for any failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Action is the polkit action which was denied, if the request failed authorization
+	Action string `json:"action,omitempty"`
+	// Journal is the last lines logged by a unit which failed to start, stop or restart
+	Journal []string `json:"journal,omitempty"`
+}
+
+// APIError is returned by the client when the agent responds with an unexpected status
+type APIError struct {
+	StatusCode int
+	Message    string
+	Action     string
+	Journal    []string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("agent returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap allows a 404 to be checked with errors.Is(err, ErrNotFound)
+func (e *APIError) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkResponse returns an APIError if the response status is not the expected one
+func checkResponse(resp *http.Response, expected int) error {
+	if resp.StatusCode == expected {
+		return nil
+	}
+	b, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(b)),
+	}
+	var body ErrorResponse
+	if json.Unmarshal(b, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+		apiErr.Action = body.Action
+		apiErr.Journal = body.Journal
+	}
+	return apiErr
+}
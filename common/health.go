@@ -0,0 +1,20 @@
+package common
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	// StatusDisabled is reported for a module whose service has not started, which doesn't affect readiness
+	StatusDisabled = "disabled"
+)
+
+type DependencyStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// JournalPriorities are the syslog priorities of journal entries, from the most to the least severe
+var JournalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// BootIDPattern matches the boots accepted by journal queries: a boot ID, an offset such as -1 for the previous boot,
+// or a boot ID followed by an offset
+var BootIDPattern = regexp.MustCompile(`^([0-9a-f]{32}([+-][0-9]+)?|[+-]?[0-9]+)$`)
+
+// MaxJournalLines is the most entries returned by a single journal query
+const MaxJournalLines = 10000
+
+// DefaultJournalLines is the number of entries returned when a query doesn't say
+const DefaultJournalLines = 100
+
+// ParsePriority accepts a priority by name, e.g. err, or by number, from 0 (emerg) to 7 (debug)
+func ParsePriority(priority string) (int, error) {
+	if i := slices.Index(JournalPriorities, priority); i >= 0 {
+		return i, nil
+	}
+	if p, err := strconv.Atoi(priority); err == nil && p >= 0 && p < len(JournalPriorities) {
+		return p, nil
+	}
+	return 0, fmt.Errorf("priority %q must be one of %v, or a number from 0 to 7", priority, JournalPriorities)
+}
+
+// JournalQuery selects journal entries, zero fields match every entry
+type JournalQuery struct {
+	// Units matches entries logged by, or about, any of the units
+	Units []string
+	// Priority matches entries of this priority or more severe, by name or number
+	Priority string
+	Since    *time.Time
+	Until    *time.Time
+	// Boot matches entries of a single boot, see BootIDPattern
+	Boot string
+	// Cursor matches entries after the one with this cursor, to page through the journal
+	Cursor string
+	// Lines is the most entries returned, the most recent ones unless Since or Cursor is set
+	Lines int
+}
+
+type JournalResponse struct {
+	Entries []JournalEntry `json:"entries"`
+	// Cursor is that of the last entry, or of the request if there are none, to continue reading where this left off
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type JournalEntry struct {
+	Cursor string    `json:"cursor"`
+	Time   time.Time `json:"time"`
+	BootID string    `json:"boot_id"`
+	// Unit is the systemd unit which logged the entry, empty for the kernel and processes outside of a unit
+	Unit       string `json:"unit"`
+	Priority   int    `json:"priority"`
+	Identifier string `json:"identifier"`
+	PID        int    `json:"pid"`
+	Hostname   string `json:"hostname"`
+	Message    string `json:"message"`
+}
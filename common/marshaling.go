@@ -28,3 +28,10 @@ func DecodeInto[T any](r *http.Response, v *T) error {
 	}
 	return nil
 }
+
+// DecodeRequest decodes the JSON body of an incoming request
+func DecodeRequest[T any](r *http.Request) (T, error) {
+	var v T
+	err := json.NewDecoder(r.Body).Decode(&v)
+	return v, err
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// TimeOfDayPattern matches a time of day on the 24 hour clock, e.g. 02:30
+var TimeOfDayPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):([0-5][0-9])$`)
+
+// ParseTimeOfDay returns the minutes since midnight of a time of day given as HH:MM, e.g. 02:30
+func ParseTimeOfDay(s string) (int, error) {
+	m := TimeOfDayPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("%q is not a time of day of the form HH:MM, from 00:00 to 23:59", s)
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	return hours*60 + minutes, nil
+}
+
+// MaintenanceWindow is a daily period in which the host may be rebooted, in the host's local time
+// A window whose end is earlier than its start runs past midnight, e.g. 23:00 to 01:00.
+type MaintenanceWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Validate checks that the start and end are times of day, and that the window isn't empty
+func (w MaintenanceWindow) Validate() error {
+	start, err := ParseTimeOfDay(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := ParseTimeOfDay(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("maintenance window from %s to %s is empty", w.Start, w.End)
+	}
+	return nil
+}
+
+// Next returns t if it falls within the window, otherwise the start of the next window after t, in t's location
+func (w MaintenanceWindow) Next(t time.Time) (time.Time, error) {
+	if err := w.Validate(); err != nil {
+		return time.Time{}, err
+	}
+	start, _ := ParseTimeOfDay(w.Start)
+	end, _ := ParseTimeOfDay(w.End)
+	minute := t.Hour()*60 + t.Minute()
+	if start < end && minute >= start && minute < end || start > end && (minute >= start || minute < end) {
+		return t, nil
+	}
+	next := time.Date(t.Year(), t.Month(), t.Day(), start/60, start%60, 0, 0, t.Location())
+	if next.Before(t) {
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, start/60, start%60, 0, 0, t.Location())
+	}
+	return next, nil
+}
+
+// BootResponse describes the host's current boot, and whether it needs rebooting
+type BootResponse struct {
+	// BootID is the kernel's random boot ID, as 32 hex digits, which changes on every boot
+	BootID   string    `json:"boot_id"`
+	BootedAt time.Time `json:"booted_at"`
+	// Kernel is the release of the running kernel, e.g. 6.1.0-21-amd64
+	Kernel         string `json:"kernel"`
+	RebootRequired bool   `json:"reboot_required"`
+	// Reasons explain why a reboot is required, e.g. a newer kernel being installed
+	Reasons []string `json:"reasons"`
+	// Packages are those listed in /run/reboot-required.pkgs as needing the reboot
+	Packages   []string            `json:"packages"`
+	Inhibitors []InhibitorResponse `json:"inhibitors"`
+	// ScheduledReboot is absent unless logind has a reboot scheduled
+	ScheduledReboot *time.Time `json:"scheduled_reboot,omitempty"`
+}
+
+// InhibitorResponse is a lock taken with logind to delay or block shutdown, sleep or other operations
+type InhibitorResponse struct {
+	// What is a colon separated list of the inhibited operations, e.g. shutdown:sleep
+	What string `json:"what"`
+	Who  string `json:"who"`
+	Why  string `json:"why"`
+	// Mode is either block or delay
+	Mode string `json:"mode"`
+	UID  uint32 `json:"uid"`
+	PID  uint32 `json:"pid"`
+}
+
+// RebootRequest schedules a reboot of the host through logind
+type RebootRequest struct {
+	// DelaySec is the least number of seconds to wait before rebooting
+	DelaySec int64 `json:"delay_sec,omitempty"`
+	// Window, if set, postpones the reboot until the next time the host's clock is in the window, after the delay
+	Window *MaintenanceWindow `json:"window,omitempty"`
+	// IgnoreInhibitors schedules the reboot even if an inhibitor blocks shutdown
+	IgnoreInhibitors bool `json:"ignore_inhibitors,omitempty"`
+}
+
+type RebootResponse struct {
+	// BootID is that of the boot which is ending, the host has rebooted once it reports a different one
+	BootID      string    `json:"boot_id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	valid := map[string]int{"00:00": 0, "02:30": 150, "23:59": 1439}
+	for s, expected := range valid {
+		actual, err := ParseTimeOfDay(s)
+		if err != nil {
+			t.Errorf("expected %q to be valid, got %s", s, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("expected %q to be %d minutes, got %d", s, expected, actual)
+		}
+	}
+	for _, s := range []string{"", "2:30", "24:00", "12:60", "02:30:00", "noon"} {
+		if _, err := ParseTimeOfDay(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestMaintenanceWindowNext(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		window   MaintenanceWindow
+		t        time.Time
+		expected time.Time
+	}{
+		{"within", MaintenanceWindow{Start: "02:00", End: "04:00"}, at(10, 3, 15), at(10, 3, 15)},
+		{"at start", MaintenanceWindow{Start: "02:00", End: "04:00"}, at(10, 2, 0), at(10, 2, 0)},
+		{"before", MaintenanceWindow{Start: "02:00", End: "04:00"}, at(10, 1, 0), at(10, 2, 0)},
+		{"at end", MaintenanceWindow{Start: "02:00", End: "04:00"}, at(10, 4, 0), at(11, 2, 0)},
+		{"after", MaintenanceWindow{Start: "02:00", End: "04:00"}, at(10, 12, 0), at(11, 2, 0)},
+		{"past midnight before", MaintenanceWindow{Start: "23:00", End: "01:00"}, at(10, 22, 0), at(10, 23, 0)},
+		{"past midnight late", MaintenanceWindow{Start: "23:00", End: "01:00"}, at(10, 23, 30), at(10, 23, 30)},
+		{"past midnight early", MaintenanceWindow{Start: "23:00", End: "01:00"}, at(11, 0, 30), at(11, 0, 30)},
+		{"past midnight after", MaintenanceWindow{Start: "23:00", End: "01:00"}, at(11, 1, 0), at(11, 23, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := tt.window.Next(tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !next.Equal(tt.expected) {
+				t.Errorf("expected %s, got %s", tt.expected, next)
+			}
+		})
+	}
+
+	for _, window := range []MaintenanceWindow{{Start: "02:00", End: "02:00"}, {Start: "2am", End: "04:00"}, {Start: "02:00"}} {
+		if _, err := window.Next(at(10, 0, 0)); err == nil {
+			t.Errorf("expected %+v to be invalid", window)
+		}
+	}
+}
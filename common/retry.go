@@ -0,0 +1,86 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// TimeoutHeader carries the milliseconds remaining until the client gives up on the request
+	TimeoutHeader = "X-Linux-Request-Timeout"
+
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultMaxRetries     = 3
+
+	minRetryWait = 250 * time.Millisecond
+	maxRetryWait = 10 * time.Second
+)
+
+// idempotent returns true for the methods which can safely be sent more than once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryableStatus returns true for responses indicating that the agent, or a proxy in front of it, is temporarily unavailable
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableError returns true if the request failed to reach the agent, rather than being cancelled by the caller
+func retryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// backoff returns how long to wait before the given retry, using exponential backoff with full jitter
+func backoff(base time.Duration, attempt int) time.Duration {
+	wait := maxRetryWait
+	if attempt < 16 {
+		wait = min(base<<attempt, maxRetryWait)
+	}
+	return rand.N(wait) + 1
+}
+
+// retryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d, returning early with an error if ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig describes how to reach the agent through an SSH server on its host
+type SSHConfig struct {
+	// Host and Port of the SSH server
+	Host string
+	Port int
+	User string
+	// PrivateKey is a PEM encoded private key, used in addition to the SSH agent when UseAgent is set
+	PrivateKey string
+	UseAgent   bool
+	// HostKey is the expected host key in authorized_keys format
+	// If empty, the host key is verified against KnownHostsFile, or ~/.ssh/known_hosts
+	HostKey        string
+	KnownHostsFile string
+	// Socket is the path of the agent's unix socket on the host
+	// If empty, connections are forwarded to the agent's port on the host's loopback interface
+	Socket string
+}
+
+// SSHDialer forwards connections to the agent through a single, shared SSH connection
+type SSHDialer struct {
+	addr   string
+	socket string
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewSSHDialer validates the configuration and loads the credentials
+// The SSH connection itself is established by the first dial
+func NewSSHDialer(cfg SSHConfig) (*SSHDialer, error) {
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.UseAgent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, errors.New("SSH agent authentication requested, but SSH_AUTH_SOCK is not set")
+		}
+		auth = append(auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				return nil, fmt.Errorf("cannot connect to SSH agent: %w", err)
+			}
+			defer conn.Close()
+			return agent.NewClient(conn).Signers()
+		}))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("no SSH authentication method configured, set a private key or use the SSH agent")
+	}
+
+	hostKeyCallback, err := hostKeyCallback(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	return &SSHDialer{
+		addr:   net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		socket: cfg.Socket,
+		config: &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+		},
+	}, nil
+}
+
+// DialContext opens a connection to the agent, addr is only used when forwarding to the agent's port
+func (d *SSHDialer) DialContext(ctx context.Context, _ string, addr string) (net.Conn, error) {
+	network, target := "unix", d.socket
+	if target == "" {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		network, target = "tcp", net.JoinHostPort("localhost", port)
+	}
+
+	for attempt := 0; ; attempt++ {
+		client, err := d.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := client.Dial(network, target)
+		if err == nil {
+			return conn, nil
+		}
+		// A broken SSH connection is discarded and reestablished once
+		if !d.reset(client) || attempt > 0 {
+			return nil, fmt.Errorf("cannot forward to agent at %s: %w", target, err)
+		}
+	}
+}
+
+// Close closes the SSH connection
+func (d *SSHDialer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client == nil {
+		return nil
+	}
+	err := d.client.Close()
+	d.client = nil
+	return err
+}
+
+// connect returns the shared SSH connection, establishing it if necessary
+func (d *SSHDialer) connect(ctx context.Context) (*ssh.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to SSH server %s: %w", d.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, d.addr, d.config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s failed: %w", d.addr, err)
+	}
+	// Clear the handshake deadline, the connection is shared between requests
+	conn.SetDeadline(time.Time{})
+	d.client = ssh.NewClient(c, chans, reqs)
+	return d.client, nil
+}
+
+// reset discards the SSH connection if it has broken, so that the next dial reconnects
+// Returns true if the connection was discarded
+func (d *SSHDialer) reset(client *ssh.Client) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client != client {
+		return true
+	}
+	if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+		client.Close()
+		d.client = nil
+		return true
+	}
+	return false
+}
+
+func hostKeyCallback(cfg SSHConfig) (ssh.HostKeyCallback, error) {
+	if cfg.HostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse host key: %w", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	path := cfg.KnownHostsFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate known_hosts, set the host key explicitly: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load known hosts from %s, set the host key explicitly: %w", path, err)
+	}
+	return callback, nil
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common/sshtest"
+)
+
+func newSSHServer(t *testing.T) (*sshtest.Server, string) {
+	t.Helper()
+	key, pub, err := sshtest.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := sshtest.NewServer("terraform", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	return srv, key
+}
+
+func agentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(APIVersion))
+		Encode(w, r, http.StatusOK, ZpoolListResponse{Pools: []ZPoolResponse{{Name: "tank"}}})
+	})
+}
+
+func newSSHClient(t *testing.T, cfg SSHConfig, port int) *Client {
+	t.Helper()
+	dialer, err := NewSSHDialer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dialer.Close() })
+	return NewClient(cfg.Host).WithPort(port).WithDialer(dialer.DialContext).WithMaxRetries(0)
+}
+
+func TestSSHForwardToPort(t *testing.T) {
+	ssh, key := newSSHServer(t)
+	agent := httptest.NewServer(agentHandler())
+	defer agent.Close()
+	_, port, _ := net.SplitHostPort(agent.Listener.Addr().String())
+	agentPort, _ := strconv.Atoi(port)
+
+	client := newSSHClient(t, SSHConfig{
+		Host:       ssh.Host(),
+		Port:       ssh.Port(),
+		User:       "terraform",
+		PrivateKey: key,
+		HostKey:    ssh.HostKey(),
+	}, agentPort)
+
+	pools, err := client.ZfsGetPools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools.Pools) != 1 || pools.Pools[0].Name != "tank" {
+		t.Errorf("unexpected pools %v", pools)
+	}
+	if ssh.Forwards() != 1 {
+		t.Errorf("expected 1 forwarded connection, got %d", ssh.Forwards())
+	}
+
+	// The SSH connection is reestablished after it drops
+	ssh.Disconnect()
+	if _, err := client.ZfsGetPools(context.Background()); err != nil {
+		t.Fatalf("expected to reconnect, got %s", err)
+	}
+}
+
+func TestSSHForwardToSocket(t *testing.T) {
+	ssh, key := newSSHServer(t)
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := httptest.NewUnstartedServer(agentHandler())
+	agent.Listener = l
+	agent.Start()
+	defer agent.Close()
+
+	client := newSSHClient(t, SSHConfig{
+		Host:       ssh.Host(),
+		Port:       ssh.Port(),
+		User:       "terraform",
+		PrivateKey: key,
+		HostKey:    ssh.HostKey(),
+		Socket:     socket,
+	}, 8080)
+
+	if _, err := client.ZfsGetPools(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSSHRejectsUnknownHostKey(t *testing.T) {
+	ssh, key := newSSHServer(t)
+	other, _ := newSSHServer(t)
+
+	client := newSSHClient(t, SSHConfig{
+		Host:       ssh.Host(),
+		Port:       ssh.Port(),
+		User:       "terraform",
+		PrivateKey: key,
+		HostKey:    other.HostKey(),
+	}, 8080)
+
+	_, err := client.ZfsGetPools(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+		t.Errorf("expected a host key mismatch, got %v", err)
+	}
+}
+
+func TestSSHConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  SSHConfig
+		err  string
+	}{
+		{"no auth", SSHConfig{Host: "example.com", HostKey: "ssh-ed25519 AAAA"}, "no SSH authentication method"},
+		{"bad key", SSHConfig{Host: "example.com", PrivateKey: "not a key"}, "cannot parse private key"},
+		{"missing known hosts", SSHConfig{Host: "example.com", UseAgent: true, KnownHostsFile: "/nonexistent"}, ""},
+	}
+	t.Setenv("SSH_AUTH_SOCK", "/nonexistent/agent.sock")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSSHDialer(tt.cfg)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.err != "" && !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected %q, got %s", tt.err, err)
+			}
+		})
+	}
+}
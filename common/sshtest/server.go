@@ -0,0 +1,195 @@
+// Package sshtest runs an in-process SSH server which only supports port and unix socket forwarding,
+// for testing the SSH transport without a real sshd.
+package sshtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// GenerateKey returns a new PEM encoded private key and its public key
+func GenerateKey() (string, ssh.PublicKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return "", nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(pem.EncodeToMemory(block)), signer.PublicKey(), nil
+}
+
+// Server is an SSH server listening on a local port
+type Server struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.PublicKey
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	forwards int
+}
+
+// NewServer starts a server which accepts the given user, authenticated with the given public key
+// Callers must Close the server when they are done with it
+func NewServer(user string, authorized ssh.PublicKey) (*Server, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == user && bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown public key for %s", meta.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: l, config: config, hostKey: signer.PublicKey()}
+	go s.serve()
+	return s, nil
+}
+
+// Host returns the address the server is listening on
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the server is listening on
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// HostKey returns the server's public key in authorized_keys format
+func (s *Server) HostKey() string {
+	return string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(s.hostKey)))
+}
+
+// Forwards returns the number of forwarded connections the server has accepted
+func (s *Server) Forwards() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.forwards
+}
+
+// Disconnect closes every client connection, as if the network had dropped, but keeps listening
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.listener.Close()
+	s.Disconnect()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for ch := range chans {
+		var network, addr string
+		switch ch.ChannelType() {
+		case "direct-tcpip":
+			var payload struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			if err := ssh.Unmarshal(ch.ExtraData(), &payload); err != nil {
+				ch.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			network, addr = "tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+		case "direct-streamlocal@openssh.com":
+			var payload struct {
+				Path      string
+				Reserved0 string
+				Reserved1 uint32
+			}
+			if err := ssh.Unmarshal(ch.ExtraData(), &payload); err != nil {
+				ch.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			network, addr = "unix", payload.Path
+		default:
+			ch.Reject(ssh.UnknownChannelType, "only forwarding is supported")
+			continue
+		}
+		go s.forward(ch, network, addr)
+	}
+}
+
+func (s *Server) forward(ch ssh.NewChannel, network string, addr string) {
+	target, err := net.Dial(network, addr)
+	if err != nil {
+		ch.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := ch.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	s.mu.Lock()
+	s.forwards++
+	s.mu.Unlock()
+
+	go func() {
+		io.Copy(target, channel)
+		target.Close()
+	}()
+	io.Copy(channel, target)
+	channel.Close()
+}
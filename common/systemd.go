@@ -0,0 +1,102 @@
+package common
+
+import (
+	"regexp"
+	"time"
+)
+
+// UnitNamePattern matches the names of the unit files managed by the agent
+// A name is a unit prefix, optionally followed by an @ and a template instance, and a unit type suffix.
+var UnitNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\-]+(@[A-Za-z0-9:_.\\-]*)?\.(service|socket|device|mount|automount|swap|target|path|timer|slice|scope)$`)
+
+// MaxUnitNameLength is the longest unit name accepted by systemd
+const MaxUnitNameLength = 255
+
+// DropInNamePattern matches the names of drop-ins, which are written to <unit>.d/<name>.conf
+var DropInNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.@-]*$`)
+
+// MaxDropInNameLength leaves room for the .conf suffix within the longest file name
+const MaxDropInNameLength = 250
+
+// UnitGlobPattern matches the glob patterns accepted when listing units, e.g. *.service or getty@*
+var UnitGlobPattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\@*?\[\]!-]+$`)
+
+// UnitStatePattern matches the load, active and sub states accepted when listing units, e.g. failed or running
+var UnitStatePattern = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+type UnitFileRequest struct {
+	Content string `json:"content"`
+}
+
+type UnitFileResponse struct {
+	Name string `json:"name"`
+	// Path is the location of the unit file on the host
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// UnitStateRequest is the desired state of a unit, absent fields are left as they are
+type UnitStateRequest struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	Active  *bool `json:"active,omitempty"`
+	Masked  *bool `json:"masked,omitempty"`
+}
+
+type UnitStateResponse struct {
+	Name          string `json:"name"`
+	LoadState     string `json:"load_state"`
+	ActiveState   string `json:"active_state"`
+	SubState      string `json:"sub_state"`
+	UnitFileState string `json:"unit_file_state"`
+	// Result is the result of the last run of a service, e.g. success or exit-code
+	Result  string `json:"result,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+	Masked  bool   `json:"masked"`
+}
+
+// UnitListResponse lists the units matching the state and name filters of the request
+type UnitListResponse struct {
+	Units []UnitStatusResponse `json:"units"`
+}
+
+type UnitStatusResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	// FragmentPath is the unit file the unit was loaded from, empty if it has none
+	FragmentPath string `json:"fragment_path"`
+	// MainPID is the main process of a running service, zero otherwise
+	MainPID uint32 `json:"main_pid"`
+}
+
+// DropInResponse is a drop-in override of a unit, written by the agent
+type DropInResponse struct {
+	Unit string `json:"unit"`
+	Name string `json:"name"`
+	// Path is the location of the drop-in on the host
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Shadowed lists the settings of the drop-in which are overridden by other drop-ins, that systemd applies later
+	Shadowed []ShadowedSetting `json:"shadowed"`
+}
+
+// ShadowedSetting is a key set by a drop-in, which another drop-in sets again
+type ShadowedSetting struct {
+	// Path is the location of the drop-in which overrides the setting
+	Path    string `json:"path"`
+	Section string `json:"section"`
+	Key     string `json:"key"`
+}
+
+type TimerResponse struct {
+	Name string `json:"name"`
+	// Unit is the unit which the timer activates
+	Unit string `json:"unit"`
+	// NextElapse is absent if the timer isn't scheduled by a calendar event
+	NextElapse *time.Time `json:"next_elapse,omitempty"`
+	// LastTrigger is absent if the timer has never elapsed
+	LastTrigger *time.Time `json:"last_trigger,omitempty"`
+}
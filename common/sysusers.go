@@ -0,0 +1,170 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SysusersDir is where the agent writes sysusers.d(5) declarations
+const SysusersDir = "/etc/sysusers.d"
+
+// SysusersLine is a line of a sysusers.d file, creating a system user or group
+// Fields which aren't set are written as -, leaving them to their default.
+type SysusersLine struct {
+	// Type is u to create a user and its group, g to create a group, m to add a user to a group, or r to reserve an
+	// ID range for the other lines
+	Type string
+	// Name is the user or group, and is empty for an r line
+	Name string
+	// ID is the UID or GID, e.g. 990, 990:adm or a path whose owner is used, the group of an m line, or the range of
+	// an r line, e.g. 500-900. An ID is allocated if empty.
+	ID    string
+	GECOS string
+	Home  string
+	Shell string
+}
+
+var (
+	// SysusersNamePattern matches the user and group names which systemd-sysusers accepts
+	SysusersNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]{0,30}$`)
+	sysusersIDPattern    = regexp.MustCompile(`^[0-9]+(:[a-zA-Z0-9_][a-zA-Z0-9_-]*)?$`)
+	sysusersRangePattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?$`)
+)
+
+// ParseSysusers parses the content of a sysusers.d file, dropping comments and blank lines
+func ParseSysusers(content string) ([]SysusersLine, error) {
+	var lines []SysusersLine
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields, rest, err := splitFields(text, 6)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if rest != "" {
+			return nil, fmt.Errorf("line %d: trailing garbage %q", lineNo, rest)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected a type and a name, got %q", lineNo, text)
+		}
+		fields = append(fields, make([]string, 6-len(fields))...)
+		line := SysusersLine{
+			Type:  fields[0],
+			Name:  dashEmpty(fields[1]),
+			ID:    dashEmpty(fields[2]),
+			GECOS: dashEmpty(fields[3]),
+			Home:  dashEmpty(fields[4]),
+			Shell: dashEmpty(fields[5]),
+		}
+		if err := line.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// Validate checks the fields of the line, according to its type
+func (l SysusersLine) Validate() error {
+	switch l.Type {
+	case "u", "g", "m":
+		if !SysusersNamePattern.MatchString(l.Name) {
+			return fmt.Errorf("%q is not a valid user or group name", l.Name)
+		}
+	case "r":
+		if l.Name != "" {
+			return fmt.Errorf("the name of an r line must be -, got %q", l.Name)
+		}
+	default:
+		return fmt.Errorf("unknown type %q, expected one of u, g, m or r", l.Type)
+	}
+
+	switch {
+	case l.Type == "m":
+		if !SysusersNamePattern.MatchString(l.ID) {
+			return fmt.Errorf("%q is not a valid group name", l.ID)
+		}
+	case l.Type == "r":
+		if !sysusersRangePattern.MatchString(l.ID) {
+			return fmt.Errorf("%q is not an ID range, such as 500-900", l.ID)
+		}
+	case l.ID == "" || strings.HasPrefix(l.ID, "/"):
+	case l.Type == "g" && strings.Contains(l.ID, ":"):
+		return fmt.Errorf("%q is not a GID or a path", l.ID)
+	default:
+		id, _, _ := strings.Cut(l.ID, ":")
+		if _, err := strconv.ParseUint(id, 10, 32); err != nil || !sysusersIDPattern.MatchString(l.ID) {
+			return fmt.Errorf("%q is not a UID, UID:GID or a path", l.ID)
+		}
+	}
+
+	if l.Type != "u" && (l.GECOS != "" || l.Home != "" || l.Shell != "") {
+		return fmt.Errorf("only u lines may set a GECOS, home or shell")
+	}
+	for _, p := range []string{l.Home, l.Shell} {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("path %q is not absolute", p)
+		}
+	}
+	return nil
+}
+
+// RenderSysusers formats the lines of a sysusers.d file, one per line, quoting fields which contain spaces
+func RenderSysusers(lines []SysusersLine) string {
+	var b strings.Builder
+	for _, l := range lines {
+		fields := []string{l.Type, l.Name, l.ID, l.GECOS, l.Home, l.Shell}
+		// Trailing defaults are left out, as in the files shipped by distributions
+		for len(fields) > 2 && fields[len(fields)-1] == "" {
+			fields = fields[:len(fields)-1]
+		}
+		for i, f := range fields {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(quoteField(f))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SysusersResponse is a sysusers.d file written by the agent, along with the users and groups it declares
+type SysusersResponse struct {
+	Name string `json:"name"`
+	// Path is the location of the file on the host
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Users and Groups are those declared by the file's u, g and m lines, in order, after it was applied
+	Users  []UserState  `json:"users"`
+	Groups []GroupState `json:"groups"`
+}
+
+// UserState is a user account on the host
+type UserState struct {
+	Name   string `json:"name"`
+	Exists bool   `json:"exists"`
+	UID    int    `json:"uid"`
+	// Group is the name of the user's primary group
+	Group string `json:"group,omitempty"`
+	GID   int    `json:"gid"`
+	Home  string `json:"home,omitempty"`
+	Shell string `json:"shell,omitempty"`
+}
+
+// GroupState is a group on the host
+type GroupState struct {
+	Name   string `json:"name"`
+	Exists bool   `json:"exists"`
+	GID    int    `json:"gid"`
+	// Members are the users with the group as a supplementary group
+	Members []string `json:"members"`
+}
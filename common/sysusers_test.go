@@ -0,0 +1,61 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSysusers(t *testing.T) {
+	content := `# Install to /usr/lib/sysusers.d/linux-agent.conf
+u linux-agent - "Terraform Linux agent" /var/lib/linux-agent
+u app 990:990 "Web application" /srv/app /usr/sbin/nologin
+g deploy /srv/app
+m linux-agent systemd-journal
+r - 500-900
+`
+	lines, err := ParseSysusers(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SysusersLine{
+		{Type: "u", Name: "linux-agent", GECOS: "Terraform Linux agent", Home: "/var/lib/linux-agent"},
+		{Type: "u", Name: "app", ID: "990:990", GECOS: "Web application", Home: "/srv/app", Shell: "/usr/sbin/nologin"},
+		{Type: "g", Name: "deploy", ID: "/srv/app"},
+		{Type: "m", Name: "linux-agent", ID: "systemd-journal"},
+		{Type: "r", ID: "500-900"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %+v, got %+v", expected, lines)
+	}
+
+	rendered := RenderSysusers(lines)
+	if !strings.HasPrefix(rendered, "u linux-agent - \"Terraform Linux agent\" /var/lib/linux-agent\n") {
+		t.Errorf("unexpected rendering %q", rendered)
+	}
+	reparsed, err := ParseSysusers(rendered)
+	if err != nil || !reflect.DeepEqual(reparsed, lines) {
+		t.Errorf("expected %q to parse back to the same lines, got %+v: %v", rendered, reparsed, err)
+	}
+}
+
+func TestParseSysusersInvalid(t *testing.T) {
+	tests := []string{
+		"x app",
+		"u 9app",
+		"u app abc",
+		"u app 99999999999",
+		"g app 990:990",
+		"g app - \"Application\"",
+		"m app",
+		"r app 500-900",
+		"r - 500-",
+		"u app - - srv/app",
+		"u app - - /srv/app /bin/sh extra",
+	}
+	for _, content := range tests {
+		if _, err := ParseSysusers(content); err == nil {
+			t.Errorf("expected %q to be rejected", content)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TmpfilesDir is where the agent writes tmpfiles.d(5) declarations
+const TmpfilesDir = "/etc/tmpfiles.d"
+
+// TmpfilesLine is a line of a tmpfiles.d(5) file, creating, cleaning up or adjusting a path
+// Fields which aren't set are written as -, leaving them to their default.
+type TmpfilesLine struct {
+	// Type is the action, e.g. d to create a directory, optionally followed by modifiers such as + or !
+	Type string
+	Path string
+	// Mode is the octal access mode, e.g. 0755
+	Mode  string
+	User  string
+	Group string
+	// Age is the time span after which files below the path are cleaned up, e.g. 10d
+	Age string
+	// Argument depends on the type, e.g. the target of a symlink or the content of a file
+	Argument string
+}
+
+// tmpfilesTypes are the line types understood by systemd-tmpfiles
+const tmpfilesTypes = "fwdDevqQpLcbCxXrRzZtThHaA"
+
+// tmpfilesModifiers may follow the type, e.g. L+ replaces an existing path and d! only applies at boot
+const tmpfilesModifiers = "+!-=~^"
+
+var (
+	tmpfilesModePattern  = regexp.MustCompile(`^[~:]?[0-7]{3,4}$`)
+	tmpfilesOwnerPattern = regexp.MustCompile(`^:?[a-zA-Z0-9_][a-zA-Z0-9_.-]*\$?$`)
+)
+
+// ParseTmpfiles parses the content of a tmpfiles.d file, dropping comments and blank lines
+func ParseTmpfiles(content string) ([]TmpfilesLine, error) {
+	var lines []TmpfilesLine
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		// The argument is the rest of the line, so that e.g. the content written by an f line may contain spaces
+		fields, rest, err := splitFields(text, 6)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected a type and a path, got %q", lineNo, text)
+		}
+		fields = append(fields, make([]string, 6-len(fields))...)
+		line := TmpfilesLine{
+			Type:     fields[0],
+			Path:     fields[1],
+			Mode:     dashEmpty(fields[2]),
+			User:     dashEmpty(fields[3]),
+			Group:    dashEmpty(fields[4]),
+			Age:      dashEmpty(fields[5]),
+			Argument: dashEmpty(rest),
+		}
+		if err := line.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// Validate checks the fields of the line, without resolving users or specifiers such as %h
+func (l TmpfilesLine) Validate() error {
+	if l.Type == "" || !strings.ContainsRune(tmpfilesTypes, rune(l.Type[0])) {
+		return fmt.Errorf("unknown type %q, expected one of %s", l.Type, strings.Join(strings.Split(tmpfilesTypes, ""), ", "))
+	}
+	if strings.Trim(l.Type[1:], tmpfilesModifiers) != "" {
+		return fmt.Errorf("unknown modifier in type %q, expected any of %s", l.Type, tmpfilesModifiers)
+	}
+	if !strings.HasPrefix(l.Path, "/") && !strings.HasPrefix(l.Path, "%") {
+		return fmt.Errorf("path %q is not absolute", l.Path)
+	}
+	if l.Mode != "" && !tmpfilesModePattern.MatchString(l.Mode) {
+		return fmt.Errorf("mode %q is not an octal mode, such as 0755", l.Mode)
+	}
+	for _, owner := range []string{l.User, l.Group} {
+		if owner != "" && !tmpfilesOwnerPattern.MatchString(owner) {
+			return fmt.Errorf("%q is not a user or group name or ID", owner)
+		}
+	}
+	if l.Age != "" {
+		age := strings.TrimPrefix(l.Age, "~")
+		if err := ValidateTimespan(age); err != nil {
+			return fmt.Errorf("invalid age %q: %w", l.Age, err)
+		}
+	}
+	return nil
+}
+
+// Managed returns true if the line creates the path, rather than only adjusting or cleaning up what is there
+func (l TmpfilesLine) Managed() bool {
+	return strings.ContainsRune("fwdDevqQpLcbC", rune(l.Type[0]))
+}
+
+// Literal returns true if the path is a single path, without globs or specifiers
+func (l TmpfilesLine) Literal() bool {
+	return !strings.ContainsAny(l.Path, "*?[%")
+}
+
+// RenderTmpfiles formats the lines of a tmpfiles.d file, one per line, quoting fields which contain spaces
+func RenderTmpfiles(lines []TmpfilesLine) string {
+	var b strings.Builder
+	for _, l := range lines {
+		fields := []string{l.Type, l.Path, l.Mode, l.User, l.Group, l.Age}
+		for i, f := range fields {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(quoteField(f))
+		}
+		if l.Argument != "" {
+			b.WriteByte(' ')
+			b.WriteString(l.Argument)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// TmpfilesResponse is a tmpfiles.d file written by the agent, along with the state of the paths it declares
+type TmpfilesResponse struct {
+	Name string `json:"name"`
+	// Path is the location of the file on the host
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Paths are the literal paths of the file's lines, in order, after it was applied
+	Paths []PathState `json:"paths"`
+}
+
+// PathState is the type, mode and ownership of a path on the host
+type PathState struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	// Type is one of directory, file, symlink, fifo, device, socket or other, and is absent if the path doesn't exist
+	Type string `json:"type,omitempty"`
+	// Mode is the octal permissions, e.g. 0755
+	Mode string `json:"mode,omitempty"`
+	// User and Group are names, or IDs if they have no name on the host
+	User  string `json:"user,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// splitFields splits the first n whitespace separated fields of line, which may be quoted with ' or " and contain
+// C-style escapes, returning the rest of the line unparsed
+func splitFields(line string, n int) ([]string, string, error) {
+	var fields []string
+	rest := strings.TrimLeft(line, " \t")
+	for len(fields) < n && rest != "" {
+		var field strings.Builder
+		var quote byte
+		i := 0
+	scan:
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			switch {
+			case c == '\\' && i+1 < len(rest):
+				i++
+				field.WriteByte(unescape(rest[i]))
+			case quote != 0 && c == quote:
+				quote = 0
+			case quote != 0:
+				field.WriteByte(c)
+			case c == '\'' || c == '"':
+				quote = c
+			case c == ' ' || c == '\t':
+				break scan
+			default:
+				field.WriteByte(c)
+			}
+		}
+		if quote != 0 {
+			return nil, "", fmt.Errorf("unterminated quote in %q", line)
+		}
+		fields = append(fields, field.String())
+		rest = strings.TrimLeft(rest[i:], " \t")
+	}
+	return fields, rest, nil
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	}
+	return c
+}
+
+// quoteField returns - for an empty field, and quotes one containing spaces or quotes
+func quoteField(f string) string {
+	if f == "" {
+		return "-"
+	}
+	if !strings.ContainsAny(f, " \t\"'\\") {
+		return f
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`, "\n", `\n`)
+	return `"` + r.Replace(f) + `"`
+}
+
+// dashEmpty returns an empty string for -, which leaves a field to its default
+func dashEmpty(f string) string {
+	if f == "-" {
+		return ""
+	}
+	return f
+}
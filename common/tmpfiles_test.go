@@ -0,0 +1,56 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTmpfiles(t *testing.T) {
+	content := `# Cache for the web application
+d /var/cache/app 0750 www-data www-data 10d
+L+ /etc/app/current - - - - /opt/app/releases/42
+f /etc/motd.d/app 0644 root root - "Managed by Terraform"
+
+z /var/log/app* ~0640 - adm
+`
+	lines, err := ParseTmpfiles(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TmpfilesLine{
+		{Type: "d", Path: "/var/cache/app", Mode: "0750", User: "www-data", Group: "www-data", Age: "10d"},
+		{Type: "L+", Path: "/etc/app/current", Argument: "/opt/app/releases/42"},
+		{Type: "f", Path: "/etc/motd.d/app", Mode: "0644", User: "root", Group: "root", Argument: `"Managed by Terraform"`},
+		{Type: "z", Path: "/var/log/app*", Mode: "~0640", Group: "adm"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %+v, got %+v", expected, lines)
+	}
+	if lines[3].Literal() || lines[3].Managed() || !lines[0].Managed() {
+		t.Errorf("unexpected classification of %+v", lines)
+	}
+
+	rendered := RenderTmpfiles(lines)
+	reparsed, err := ParseTmpfiles(rendered)
+	if err != nil || !reflect.DeepEqual(reparsed, lines) {
+		t.Errorf("expected %q to parse back to the same lines, got %+v: %v", rendered, reparsed, err)
+	}
+}
+
+func TestParseTmpfilesInvalid(t *testing.T) {
+	tests := []string{
+		"d",
+		"y /var/cache/app",
+		"d? /var/cache/app",
+		"d var/cache/app",
+		"d /var/cache/app 0999",
+		"d /var/cache/app - www@data",
+		"d /var/cache/app - - - soon",
+		`f /etc/motd "unterminated`,
+	}
+	for _, content := range tests {
+		if _, err := ParseTmpfiles(content); err == nil {
+			t.Errorf("expected %q to be rejected", content)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// UnitSection is a section of a systemd unit file, e.g. [Service]
+type UnitSection struct {
+	Name    string
+	Entries []UnitEntry
+}
+
+// UnitEntry is a single assignment within a section
+// A key may be assigned more than once, e.g. ExecStartPre, so entries are kept in order
+type UnitEntry struct {
+	Key   string
+	Value string
+}
+
+// Values returns every value assigned to key, in order
+func (s UnitSection) Values(key string) []string {
+	var values []string
+	for _, e := range s.Entries {
+		if e.Key == key {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// ParseUnit parses the content of a systemd unit file, or any file in the same format such as journald.conf
+// Comments and blank lines are dropped, and continuation lines are joined with a space, as systemd does.
+// A section which appears more than once is returned once for each time it appears.
+func ParseUnit(content string) ([]UnitSection, error) {
+	var sections []UnitSection
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNo := 0
+	var pending string
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if pending == "" && (line == "" || line[0] == '#' || line[0] == ';') {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line = pending + line
+		pending = ""
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				return nil, fmt.Errorf("line %d: invalid section header %q", lineNo, line)
+			}
+			sections = append(sections, UnitSection{Name: line[1 : len(line)-1]})
+			continue
+		}
+		if len(sections) == 0 {
+			return nil, fmt.Errorf("line %d: assignment outside of a section", lineNo)
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected key=value, got %q", lineNo, line)
+		}
+		s := &sections[len(sections)-1]
+		s.Entries = append(s.Entries, UnitEntry{Key: key, Value: strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("line %d: unterminated continuation line", lineNo)
+	}
+	return sections, nil
+}
+
+// RenderUnit formats sections as a unit file, with a blank line between each section
+func RenderUnit(sections []UnitSection) string {
+	var b strings.Builder
+	for i, s := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", s.Name)
+		for _, e := range s.Entries {
+			fmt.Fprintf(&b, "%s=%s\n", e.Key, e.Value)
+		}
+	}
+	return b.String()
+}
+
+// EscapeSpecifiers escapes % as %%, so that systemd doesn't expand the value's specifiers, see systemd.unit(5)
+func EscapeSpecifiers(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+// UnescapeSpecifiers reverses EscapeSpecifiers, leaving any other specifier as written since it can't be resolved here
+func UnescapeSpecifiers(value string) string {
+	return strings.ReplaceAll(value, "%%", "%")
+}
+
+// QuoteUnitWord quotes value as a single word of a setting such as Environment=, which systemd splits into words
+// It follows systemd's C-style escapes rather than Go's, so non-ASCII characters are written as they are.
+// Specifiers are not escaped, see EscapeSpecifiers.
+func QuoteUnitWord(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < ' ' || c == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// SplitUnitWords splits the value of a setting such as Environment= into words, as systemd does
+// Words are separated by whitespace, may be quoted with single or double quotes and may contain C-style escapes.
+func SplitUnitWords(value string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case c == '\\':
+			n, err := unescapeUnit(&word, value[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			inWord = true
+		case quote != 0:
+			word.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", value)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// unitEscapes are the single character escapes systemd accepts, see systemd.syntax(7)
+var unitEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v', 's': ' ',
+	'\\': '\\', '"': '"', '\'': '\'',
+}
+
+// unescapeUnit writes the character escaped at the start of rest, the text after a backslash, returning its length
+func unescapeUnit(b *strings.Builder, rest string) (int, error) {
+	if rest == "" {
+		return 0, fmt.Errorf("dangling backslash")
+	}
+	if c, ok := unitEscapes[rest[0]]; ok {
+		b.WriteByte(c)
+		return 1, nil
+	}
+	var digits, base int
+	switch rest[0] {
+	case 'x':
+		digits, base = 2, 16
+	case 'u':
+		digits, base = 4, 16
+	case 'U':
+		digits, base = 8, 16
+	case '0', '1', '2', '3':
+		digits, base = 3, 8
+	default:
+		return 0, fmt.Errorf("invalid escape \\%c", rest[0])
+	}
+	start := 1
+	if base == 8 {
+		start = 0
+	}
+	if len(rest) < start+digits {
+		return 0, fmt.Errorf("truncated escape \\%s", rest)
+	}
+	code, err := strconv.ParseUint(rest[start:start+digits], base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape \\%s", rest[:start+digits])
+	}
+	if rest[0] == 'u' || rest[0] == 'U' {
+		if !utf8.ValidRune(rune(code)) {
+			return 0, fmt.Errorf("invalid escape \\%s", rest[:start+digits])
+		}
+		b.WriteRune(rune(code))
+	} else {
+		b.WriteByte(byte(code))
+	}
+	return start + digits, nil
+}
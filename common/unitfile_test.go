@@ -0,0 +1,151 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUnit(t *testing.T) {
+	content := `# A comment
+[Unit]
+Description=Example service
+After=network.target
+
+; Another comment
+[Service]
+ExecStartPre=/bin/true
+ExecStartPre=/bin/echo \
+  starting
+ExecStart = /usr/bin/example --flag=value
+Environment=
+`
+	sections, err := ParseUnit(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []UnitSection{
+		{Name: "Unit", Entries: []UnitEntry{
+			{Key: "Description", Value: "Example service"},
+			{Key: "After", Value: "network.target"},
+		}},
+		{Name: "Service", Entries: []UnitEntry{
+			{Key: "ExecStartPre", Value: "/bin/true"},
+			{Key: "ExecStartPre", Value: "/bin/echo  starting"},
+			{Key: "ExecStart", Value: "/usr/bin/example --flag=value"},
+			{Key: "Environment", Value: ""},
+		}},
+	}
+	if !reflect.DeepEqual(sections, expected) {
+		t.Errorf("expected %+v, got %+v", expected, sections)
+	}
+	if values := sections[1].Values("ExecStartPre"); len(values) != 2 {
+		t.Errorf("expected 2 ExecStartPre values, got %v", values)
+	}
+}
+
+func TestParseUnitErrors(t *testing.T) {
+	for _, content := range []string{
+		"Description=outside a section\n",
+		"[Unit\nDescription=x\n",
+		"[]\n",
+		"[Unit]\nnot an assignment\n",
+		"[Unit]\n=value\n",
+		"[Unit]\nDescription=dangling \\",
+	} {
+		if _, err := ParseUnit(content); err == nil {
+			t.Errorf("expected an error parsing %q", content)
+		}
+	}
+}
+
+func TestRenderUnit(t *testing.T) {
+	sections := []UnitSection{
+		{Name: "Unit", Entries: []UnitEntry{{Key: "Description", Value: "Example"}}},
+		{Name: "Install", Entries: []UnitEntry{{Key: "WantedBy", Value: "multi-user.target"}}},
+	}
+	expected := "[Unit]\nDescription=Example\n\n[Install]\nWantedBy=multi-user.target\n"
+	content := RenderUnit(sections)
+	if content != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+
+	parsed, err := ParseUnit(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, sections) {
+		t.Errorf("expected render and parse to round trip, got %+v", parsed)
+	}
+}
+
+func TestQuoteUnitWord(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected string
+	}{
+		{"TARGET=s3://backups", `"TARGET=s3://backups"`},
+		{`MSG=say "hi" \ bye`, `"MSG=say \"hi\" \\ bye"`},
+		{"GREETING=grüß dich ☃", `"GREETING=grüß dich ☃"`},
+		{"LINES=a\nb\tc\x01", `"LINES=a\nb\tc\x01"`},
+		{"RATE=100%", `"RATE=100%"`},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			quoted := QuoteUnitWord(tc.value)
+			if quoted != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, quoted)
+			}
+			words, err := SplitUnitWords(quoted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(words, []string{tc.value}) {
+				t.Errorf("expected quoting to round trip, got %q", words)
+			}
+		})
+	}
+}
+
+func TestSplitUnitWords(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected []string
+	}{
+		{"", nil},
+		{"A=1 B=2", []string{"A=1", "B=2"}},
+		{`"A=one two"  'B=three "four"'`, []string{"A=one two", `B=three "four"`}},
+		{`A="x y"z`, []string{"A=x yz"}},
+		{`A=\x41\101ü\s`, []string{"A=AAü "}},
+	} {
+		words, err := SplitUnitWords(tc.value)
+		if err != nil {
+			t.Errorf("unexpected error splitting %q: %s", tc.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, tc.expected) {
+			t.Errorf("expected %q to split into %q, got %q", tc.value, tc.expected, words)
+		}
+	}
+
+	for _, value := range []string{`"unterminated`, `dangling\`, `bad\q`, `short\x4`} {
+		if _, err := SplitUnitWords(value); err == nil {
+			t.Errorf("expected an error splitting %q", value)
+		}
+	}
+}
+
+func TestEscapeSpecifiers(t *testing.T) {
+	for value, expected := range map[string]string{
+		"/bin/echo 100%":     "/bin/echo 100%%",
+		"date +%Y-%m-%d":     "date +%%Y-%%m-%%d",
+		"no specifiers":      "no specifiers",
+		"already %% doubled": "already %%%% doubled",
+	} {
+		escaped := EscapeSpecifiers(value)
+		if escaped != expected {
+			t.Errorf("expected %q to escape to %q, got %q", value, expected, escaped)
+		}
+		if unescaped := UnescapeSpecifiers(escaped); unescaped != value {
+			t.Errorf("expected escaping %q to round trip, got %q", value, unescaped)
+		}
+	}
+}
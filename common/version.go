@@ -0,0 +1,54 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// APIVersionHeader carries the API version requested by the client, and the newest version served by the agent
+	APIVersionHeader = "X-Linux-Api-Version"
+	// MinAPIVersionHeader carries the oldest API version still served by the agent
+	MinAPIVersionHeader = "X-Linux-Min-Api-Version"
+)
+
+// IncompatibleAPIError is returned by the client when the agent does not serve any API version the client speaks
+type IncompatibleAPIError struct {
+	// AgentMin and AgentMax are the versions served by the agent, both are 0 if the agent predates API versioning
+	AgentMin int
+	AgentMax int
+}
+
+func (e *IncompatibleAPIError) Error() string {
+	if e.AgentMax == 0 {
+		return fmt.Sprintf("agent does not report an API version, this provider requires API version %d to %d", MinAPIVersion, APIVersion)
+	}
+	return fmt.Sprintf("agent serves API versions %d to %d, this provider requires API version %d to %d", e.AgentMin, e.AgentMax, MinAPIVersion, APIVersion)
+}
+
+// NegotiateAPIVersion returns the newest API version spoken by both this build and an agent serving versions min to max
+func NegotiateAPIVersion(min int, max int) (int, error) {
+	version := max
+	if version > APIVersion {
+		version = APIVersion
+	}
+	if version < min || version < MinAPIVersion {
+		return 0, &IncompatibleAPIError{AgentMin: min, AgentMax: max}
+	}
+	return version, nil
+}
+
+// checkAPIVersion verifies that the response came from an agent which serves an API version this build speaks
+func checkAPIVersion(resp *http.Response) error {
+	max, err := strconv.Atoi(resp.Header.Get(APIVersionHeader))
+	if err != nil {
+		return &IncompatibleAPIError{}
+	}
+	min, err := strconv.Atoi(resp.Header.Get(MinAPIVersionHeader))
+	if err != nil {
+		min = max
+	}
+	_, err = NegotiateAPIVersion(min, max)
+	return err
+}
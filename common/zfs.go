@@ -1,5 +1,11 @@
 package common
 
+import "regexp"
+
+// ZpoolNamePattern matches the names accepted by zpool create
+// Names must begin with a letter, and may only contain alphanumerics, underscore, hyphen, colon and period
+var ZpoolNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.:-]*$`)
+
 type ZpoolCreateRequest struct {
 	Name string `json:"name"`
 }
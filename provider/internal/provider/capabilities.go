@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// fileModules are enabled by the agent while it can write their files, rather than once a service starts
+var fileModules = map[string]bool{
+	common.ModuleUnitFiles:   true,
+	common.ModuleConfigFiles: true,
+	common.ModuleTmpfiles:    true,
+	common.ModuleSysusers:    true,
+}
+
+// requireModule adds an error diagnostic if the agent does not have the given backend module enabled
+// Returns true if the module is available
+func requireModule(ctx context.Context, client *common.Client, module string, diags *diag.Diagnostics) bool {
+	caps, err := client.Capabilities(ctx)
+	if err != nil {
+		diags.AddError("Failed to get agent capabilities", fmt.Sprintf("Unable to determine which modules the agent at %s supports: %s", client.Address(), err))
+		return false
+	}
+	if !caps.HasModule(module) {
+		// The cached answer may predate the backend starting, so check again before rejecting the module
+		caps, err = client.RefreshCapabilities(ctx)
+		if err != nil {
+			diags.AddError("Failed to get agent capabilities", fmt.Sprintf("Unable to determine which modules the agent at %s supports: %s", client.Address(), err))
+			return false
+		}
+	}
+	if !caps.HasModule(module) {
+		hint := "Ensure the backend service is installed and running on the host, the agent enables the module once it starts."
+		if fileModules[module] {
+			hint = "Ensure the agent's user can write the module's files on the host, the agent logs the path it cannot write " +
+				"and enables the module as soon as it can."
+		}
+		diags.AddError(
+			"Unsupported Linux agent module",
+			fmt.Sprintf("The agent at %s (version %s) does not have the %s module enabled. %s",
+				client.Address(), caps.AgentVersion, module, hint),
+		)
+		return false
+	}
+	return true
+}
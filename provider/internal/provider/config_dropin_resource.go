@@ -0,0 +1,525 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                   = &configDropInResource{}
+	_ resource.ResourceWithImportState    = &configDropInResource{}
+	_ resource.ResourceWithModifyPlan     = &configDropInResource{}
+	_ resource.ResourceWithValidateConfig = &configDropInResource{}
+)
+
+// configKind is the Terraform type of a setting, which is written to the drop-in as systemd expects
+type configKind int
+
+const (
+	configString configKind = iota
+	// configBool is written as yes or no
+	configBool
+	configInt
+)
+
+// configSetting is a key of a configuration file exposed as an attribute of its own
+type configSetting struct {
+	attribute   string
+	key         string
+	kind        configKind
+	description string
+}
+
+// configDropInResource manages a drop-in of one of the configuration files in common.ConfigFiles, such as
+// journald.conf. Its schema is built from the settings, so that each file only differs in the keys it exposes.
+type configDropInResource struct {
+	clients     *clientPool
+	typeName    string
+	file        string
+	description string
+	settings    []configSetting
+}
+
+// attributeGetter is satisfied by the config, plan and state
+type attributeGetter interface {
+	GetAttribute(ctx context.Context, p path.Path, target interface{}) diag.Diagnostics
+}
+
+func (r *configDropInResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *configDropInResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName
+}
+
+func (r *configDropInResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	config := common.ConfigFiles[r.file]
+	attributes := map[string]schema.Attribute{
+		"id": schema.StringAttribute{
+			Computed:    true,
+			Description: "The name of the drop-in",
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"host": hostResourceAttribute(),
+		"name": schema.StringAttribute{
+			Required: true,
+			Description: fmt.Sprintf("Name of the drop-in, without the .conf suffix, e.g. 50-retention. "+
+				"Drop-ins in %s are applied in the order of their names, and a later one overrides the keys it sets again.", config.Dir),
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+			Validators: []validator.String{
+				stringvalidator.LengthAtMost(common.MaxDropInNameLength),
+				stringvalidator.RegexMatches(common.DropInNamePattern, "must be a file name without slashes, such as 50-retention"),
+			},
+		},
+		"settings": schema.MapAttribute{
+			Optional:    true,
+			ElementType: types.StringType,
+			Description: fmt.Sprintf("Other keys of the [%s] section, which must be one of %s. "+
+				"Keys with an attribute of their own must be set with it.", config.Section, strings.Join(config.KeyNames(), ", ")),
+		},
+		"content": schema.StringAttribute{
+			Computed:    true,
+			Description: "The rendered drop-in",
+		},
+		"path": schema.StringAttribute{
+			Computed:    true,
+			Description: "Location of the drop-in on the host",
+		},
+	}
+	for _, s := range r.settings {
+		description := fmt.Sprintf("%s, written as %s", s.description, s.key)
+		switch s.kind {
+		case configBool:
+			attributes[s.attribute] = schema.BoolAttribute{Optional: true, Description: description}
+		case configInt:
+			attributes[s.attribute] = schema.Int64Attribute{Optional: true, Description: description}
+		default:
+			attributes[s.attribute] = schema.StringAttribute{Optional: true, Description: description}
+		}
+	}
+
+	restart := "The daemon reads the drop-in when it next runs."
+	if config.Unit != "" {
+		restart = fmt.Sprintf("%s is restarted, if it is running, whenever the drop-in changes.", config.Unit)
+	}
+	resp.Schema = schema.Schema{
+		Description: fmt.Sprintf("%s, written to %s/<name>.conf. Only the keys systemd knows are accepted. %s", r.description, config.Dir, restart),
+		Attributes:  attributes,
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *configDropInResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	config := common.ConfigFiles[r.file]
+	values, known := r.values(ctx, req.Config, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, s := range r.settings {
+		if v, ok := values[s.key]; ok && v != "" {
+			if err := config.Keys[s.key](v); err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root(s.attribute), "Invalid Configuration Value", fmt.Sprintf("Invalid %s: %s", s.key, err))
+			}
+		}
+	}
+
+	var settings map[string]types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("settings"), &settings)...)
+	for key, v := range settings {
+		p := path.Root("settings").AtMapKey(key)
+		if s, ok := r.setting(key); ok {
+			resp.Diagnostics.AddAttributeError(p, "Conflicting Configuration Key", fmt.Sprintf("Set %s with the %s attribute instead.", key, s.attribute))
+			continue
+		}
+		check, ok := config.Keys[key]
+		if !ok {
+			resp.Diagnostics.AddAttributeError(p, "Unknown Configuration Key",
+				fmt.Sprintf("%s is not a key of the [%s] section, expected one of %s.", key, config.Section, strings.Join(config.KeyNames(), ", ")))
+			continue
+		}
+		if known && v.ValueString() != "" {
+			if err := check(v.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(p, "Invalid Configuration Value", fmt.Sprintf("Invalid %s: %s", key, err))
+			}
+		}
+	}
+}
+
+func (r *configDropInResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics) ||
+		!requireModule(ctx, client, common.ModuleConfigFiles, &resp.Diagnostics) {
+		return
+	}
+
+	content, known := r.render(ctx, req.Plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	value := types.StringUnknown()
+	if known {
+		value = types.StringValue(content)
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content"), value)...)
+
+	var name types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("name"), &name)...)
+	if !name.IsUnknown() {
+		p := filepath.Join(common.ConfigFiles[r.file].Dir, name.ValueString()+".conf")
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("path"), p)...)
+	}
+}
+
+func (r *configDropInResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var host, name types.String
+	var t timeouts.Value
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("host"), &host)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("name"), &name)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("timeouts"), &t)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := t.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	// Refuse to overwrite a drop-in written by hand or by another configuration, it should be imported instead
+	_, err := client.SystemdGetConfigDropIn(ctx, r.file, name.ValueString())
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Drop-in already exists",
+			fmt.Sprintf("The drop-in %s of %s already exists on the host. Import it to manage it with Terraform.", name.ValueString(), r.file))
+		return
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to create drop-in", fmt.Sprintf("Unable to check for an existing drop-in %s of %s. Unexpected error: %s", name.ValueString(), r.file, err))
+		return
+	}
+
+	tflog.Debug(ctx, "Writing configuration drop-in", map[string]any{"file": r.file, "name": name.ValueString()})
+	resp.State.Raw = req.Plan.Raw
+	r.write(ctx, client, req.Plan, &resp.State, &resp.Diagnostics)
+}
+
+func (r *configDropInResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var host, id, content types.String
+	var t timeouts.Value
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("host"), &host)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("id"), &id)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("content"), &content)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("timeouts"), &t)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := t.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := id.ValueString()
+	tflog.Debug(ctx, "Fetching configuration drop-in", map[string]any{"file": r.file, "name": name})
+	dropIn, err := client.SystemdGetConfigDropIn(ctx, r.file, name)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Drop-in no longer exists, removing from state", map[string]any{"file": r.file, "name": name})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read drop-in", fmt.Sprintf("Unable to read drop-in %s of %s. Unexpected error: %s", name, r.file, err))
+		return
+	}
+
+	// The attributes are only rebuilt when the drop-in changed on the host, or was just imported, so that equivalent
+	// values, such as true and yes, don't show as a diff
+	if content.ValueString() != dropIn.Content {
+		r.fromContent(ctx, dropIn.Content, &resp.State, &resp.Diagnostics)
+	}
+	r.fromResponse(ctx, dropIn, &resp.State, &resp.Diagnostics)
+}
+
+func (r *configDropInResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var host types.String
+	var t timeouts.Value
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("host"), &host)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("timeouts"), &t)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := t.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing configuration drop-in", map[string]any{"file": r.file})
+	resp.State.Raw = req.Plan.Raw
+	r.write(ctx, client, req.Plan, &resp.State, &resp.Diagnostics)
+}
+
+func (r *configDropInResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var host, name types.String
+	var t timeouts.Value
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("host"), &host)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("name"), &name)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("timeouts"), &t)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := t.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Deleting configuration drop-in", map[string]any{"file": r.file, "name": name.ValueString()})
+	err := client.SystemdDeleteConfigDropIn(ctx, r.file, name.ValueString())
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete drop-in",
+			unitFailureDetail(fmt.Sprintf("Unable to delete drop-in %s of %s. Unexpected error: %s", name.ValueString(), r.file, err), err))
+	}
+}
+
+func (r *configDropInResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// write creates or replaces the drop-in rendered from the plan, recording the result in the state
+func (r *configDropInResource) write(ctx context.Context, client *common.Client, plan attributeGetter, state attributeSetter, diags *diag.Diagnostics) {
+	var name types.String
+	diags.Append(plan.GetAttribute(ctx, path.Root("name"), &name)...)
+	content, _ := r.render(ctx, plan, diags)
+	if diags.HasError() {
+		return
+	}
+	dropIn, err := client.SystemdWriteConfigDropIn(ctx, r.file, name.ValueString(), content)
+	if err != nil {
+		diags.AddError("Failed to write drop-in", unitFailureDetail(fmt.Sprintf("Unable to write drop-in. Unexpected error: %s", err), err))
+		return
+	}
+	if dropIn.Restarted != "" {
+		tflog.Info(ctx, "Restarted unit to apply the drop-in", map[string]any{"unit": dropIn.Restarted})
+	}
+	r.fromResponse(ctx, dropIn, state, diags)
+}
+
+// attributeSetter is satisfied by the plan and state
+type attributeSetter interface {
+	attributeGetter
+	SetAttribute(ctx context.Context, p path.Path, val interface{}) diag.Diagnostics
+}
+
+func (r *configDropInResource) fromResponse(ctx context.Context, dropIn common.ConfigDropInResponse, state attributeSetter, diags *diag.Diagnostics) {
+	diags.Append(state.SetAttribute(ctx, path.Root("id"), dropIn.Name)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("name"), dropIn.Name)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("content"), dropIn.Content)...)
+	diags.Append(state.SetAttribute(ctx, path.Root("path"), dropIn.Path)...)
+}
+
+// setting returns the setting with an attribute of its own for key, if there is one
+func (r *configDropInResource) setting(key string) (configSetting, bool) {
+	for _, s := range r.settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return configSetting{}, false
+}
+
+// values returns the keys which are set, with their values as written to the drop-in
+// Returns false if any value is not yet known
+func (r *configDropInResource) values(ctx context.Context, attrs attributeGetter, diags *diag.Diagnostics) (map[string]string, bool) {
+	values := make(map[string]string)
+	known := true
+	for _, s := range r.settings {
+		p := path.Root(s.attribute)
+		switch s.kind {
+		case configBool:
+			var v types.Bool
+			diags.Append(attrs.GetAttribute(ctx, p, &v)...)
+			if v.IsUnknown() {
+				known = false
+			} else if !v.IsNull() {
+				values[s.key] = "no"
+				if v.ValueBool() {
+					values[s.key] = "yes"
+				}
+			}
+		case configInt:
+			var v types.Int64
+			diags.Append(attrs.GetAttribute(ctx, p, &v)...)
+			if v.IsUnknown() {
+				known = false
+			} else if !v.IsNull() {
+				values[s.key] = strconv.FormatInt(v.ValueInt64(), 10)
+			}
+		default:
+			var v types.String
+			diags.Append(attrs.GetAttribute(ctx, p, &v)...)
+			if v.IsUnknown() {
+				known = false
+			} else if !v.IsNull() {
+				values[s.key] = v.ValueString()
+			}
+		}
+	}
+
+	var settings types.Map
+	diags.Append(attrs.GetAttribute(ctx, path.Root("settings"), &settings)...)
+	if settings.IsUnknown() {
+		return values, false
+	}
+	var other map[string]types.String
+	diags.Append(settings.ElementsAs(ctx, &other, false)...)
+	for k, v := range other {
+		if v.IsUnknown() {
+			known = false
+			continue
+		}
+		if _, ok := r.setting(k); !ok {
+			values[k] = v.ValueString()
+		}
+	}
+	return values, known
+}
+
+// render formats the drop-in, with its keys sorted so that the output is stable
+// Returns false if any value is not yet known
+func (r *configDropInResource) render(ctx context.Context, attrs attributeGetter, diags *diag.Diagnostics) (string, bool) {
+	values, known := r.values(ctx, attrs, diags)
+	if !known || diags.HasError() {
+		return "", false
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	section := common.UnitSection{Name: common.ConfigFiles[r.file].Section}
+	for _, k := range keys {
+		section.Entries = append(section.Entries, common.UnitEntry{Key: k, Value: values[k]})
+	}
+	return common.RenderUnit([]common.UnitSection{section}), true
+}
+
+// fromContent sets the attributes from the content of the drop-in on the host
+// A key assigned more than once takes its last value, as systemd does.
+func (r *configDropInResource) fromContent(ctx context.Context, content string, state attributeSetter, diags *diag.Diagnostics) {
+	sections, err := common.ParseUnit(content)
+	if err != nil {
+		diags.AddError("Failed to read drop-in", fmt.Sprintf("Unable to parse the drop-in on the host: %s", err))
+		return
+	}
+	values := make(map[string]string)
+	for _, s := range sections {
+		for _, e := range s.Entries {
+			values[e.Key] = e.Value
+		}
+	}
+
+	for _, s := range r.settings {
+		p := path.Root(s.attribute)
+		v, ok := values[s.key]
+		delete(values, s.key)
+		switch s.kind {
+		case configBool:
+			value := types.BoolNull()
+			if ok {
+				b := strings.ToLower(v)
+				value = types.BoolValue(b == "1" || b == "yes" || b == "y" || b == "true" || b == "t" || b == "on")
+			}
+			diags.Append(state.SetAttribute(ctx, p, value)...)
+		case configInt:
+			value := types.Int64Null()
+			if i, err := strconv.ParseInt(v, 10, 64); ok && err == nil {
+				value = types.Int64Value(i)
+			}
+			diags.Append(state.SetAttribute(ctx, p, value)...)
+		default:
+			value := types.StringNull()
+			if ok {
+				value = types.StringValue(v)
+			}
+			diags.Append(state.SetAttribute(ctx, p, value)...)
+		}
+	}
+
+	settings := types.MapNull(types.StringType)
+	if len(values) > 0 {
+		var d diag.Diagnostics
+		settings, d = types.MapValueFrom(ctx, types.StringType, values)
+		diags.Append(d...)
+	}
+	diags.Append(state.SetAttribute(ctx, path.Root("settings"), settings)...)
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+const connectionSSH = "ssh"
+
+// ConnectionModel configures how the provider reaches the agent, when it is not exposed on the network
+type ConnectionModel struct {
+	Type           types.String `tfsdk:"type"`
+	User           types.String `tfsdk:"user"`
+	Port           types.Int32  `tfsdk:"port"`
+	PrivateKey     types.String `tfsdk:"private_key"`
+	Agent          types.Bool   `tfsdk:"agent"`
+	HostKey        types.String `tfsdk:"host_key"`
+	KnownHostsFile types.String `tfsdk:"known_hosts_file"`
+	AgentSocket    types.String `tfsdk:"agent_socket"`
+}
+
+// connectionAttribute is a nested attribute rather than a block. The framework only reserves alias and version
+// as provider attribute names, but it checks every block, including the provider's, against the names reserved in
+// resources, and connection is one of them.
+func connectionAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		Description: "Tunnel requests to the agent through SSH, rather than connecting to its port directly." +
+			" The SSH server is the provider host.",
+		Attributes: map[string]schema.Attribute{
+			"type": schema.StringAttribute{
+				Required:    true,
+				Description: "Connection type, currently only ssh is supported.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(connectionSSH),
+				},
+			},
+			"user": schema.StringAttribute{
+				Required:    true,
+				Description: "User to log in as.",
+			},
+			"port": schema.Int32Attribute{
+				Optional:    true,
+				Description: "Port of the SSH server. Defaults to 22.",
+				Validators: []validator.Int32{
+					int32validator.Between(1, 65535),
+				},
+			},
+			"private_key": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "PEM encoded private key to authenticate with.",
+			},
+			"agent": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Authenticate with the keys held by the SSH agent at SSH_AUTH_SOCK.",
+			},
+			"host_key": schema.StringAttribute{
+				Optional:    true,
+				Description: "Expected public key of the SSH server, in authorized_keys format. If not set, the host key is verified against known_hosts_file.",
+			},
+			"known_hosts_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path of the known_hosts file used to verify the SSH server. Defaults to ~/.ssh/known_hosts.",
+			},
+			"agent_socket": schema.StringAttribute{
+				Optional: true,
+				Description: "Path of the agent's unix socket on the host, e.g. /run/linux-agent/agent.sock." +
+					" If not set, connections are forwarded to the agent's port on the host's loopback interface.",
+			},
+		},
+	}
+}
+
+// dialer returns the transport described by the connection block, or nil if it is absent
+func (c *ConnectionModel) dialer(host string, diags *diag.Diagnostics) common.Dialer {
+	if c == nil {
+		return nil
+	}
+	p := path.Root("connection")
+	for name, v := range map[string]interface{ IsUnknown() bool }{
+		"type": c.Type, "user": c.User, "port": c.Port, "private_key": c.PrivateKey,
+		"agent": c.Agent, "host_key": c.HostKey, "known_hosts_file": c.KnownHostsFile, "agent_socket": c.AgentSocket,
+	} {
+		if v.IsUnknown() {
+			diags.AddAttributeError(p.AtName(name), "Unknown SSH Connection Value",
+				"The provider cannot connect to the Linux agent as the connection "+name+" is not known until apply.")
+		}
+	}
+	if diags.HasError() {
+		return nil
+	}
+
+	cfg := common.SSHConfig{
+		Host:           host,
+		User:           c.User.ValueString(),
+		PrivateKey:     c.PrivateKey.ValueString(),
+		UseAgent:       c.Agent.ValueBool(),
+		HostKey:        c.HostKey.ValueString(),
+		KnownHostsFile: c.KnownHostsFile.ValueString(),
+		Socket:         c.AgentSocket.ValueString(),
+	}
+	if !c.Port.IsNull() {
+		cfg.Port = int(c.Port.ValueInt32())
+	}
+	d, err := common.NewSSHDialer(cfg)
+	if err != nil {
+		diags.AddAttributeError(p, "Invalid SSH Connection", fmt.Sprintf("The provider cannot connect to %s over SSH: %s", host, err))
+		return nil
+	}
+	return d.DialContext
+}
@@ -1,6 +1,16 @@
 package provider
 
+import "time"
+
 const (
 	EnvHost = "LINUX_HOST"
 	EnvPort = "LINUX_PORT"
 )
+
+// Default resource timeouts, which can be overridden with a timeouts block
+const (
+	defaultCreateTimeout = 20 * time.Minute
+	defaultReadTimeout   = 5 * time.Minute
+	defaultUpdateTimeout = 20 * time.Minute
+	defaultDeleteTimeout = 20 * time.Minute
+)
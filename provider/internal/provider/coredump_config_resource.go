@@ -0,0 +1,22 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+func NewCoredumpConfigResource() resource.Resource {
+	return &configDropInResource{
+		typeName:    "coredump_config",
+		file:        "coredump",
+		description: "Drop-in of the systemd-coredump configuration, controlling whether core dumps are kept and how much space they use",
+		settings: []configSetting{
+			{attribute: "storage", key: "Storage", description: "Where core dumps are stored: none, external or journal"},
+			{attribute: "compress", key: "Compress", kind: configBool, description: "Whether core dumps are compressed"},
+			{attribute: "process_size_max", key: "ProcessSizeMax", description: "Largest core dump which is processed, e.g. 2G"},
+			{attribute: "external_size_max", key: "ExternalSizeMax", description: "Largest core dump which is stored on disk"},
+			{attribute: "journal_size_max", key: "JournalSizeMax", description: "Largest core dump which is stored in the journal"},
+			{attribute: "max_use", key: "MaxUse", description: "Disk space the core dumps on disk may use"},
+			{attribute: "keep_free", key: "KeepFree", description: "Disk space the core dumps on disk leave free"},
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccCoredumpConfigResource(t *testing.T) {
+	config := providerConfig() + `
+	resource "linux_coredump_config" "test" {
+	  name     = "50-storage"
+	  storage  = "external"
+	  compress = true
+	  max_use  = "10G"
+	}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, ok := testAgent.Config.DropIn("coredump", "50-storage"); ok {
+				return fmt.Errorf("drop-in 50-storage still exists")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					testAgent.Config.SetDropIn("coredump", "50-storage", "[Coredump]\nStorage=none\n")
+				},
+				Config:      config,
+				ExpectError: regexp.MustCompile("Drop-in already exists"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Config.Reset()
+				},
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_coredump_config.test", "path", "/etc/systemd/coredump.conf.d/50-storage.conf"),
+					resource.TestCheckResourceAttr("linux_coredump_config.test", "content", "[Coredump]\nCompress=yes\nMaxUse=10G\nStorage=external\n"),
+					testAccCheckConfigDropIn("coredump", "50-storage", "[Coredump]\nCompress=yes\nMaxUse=10G\nStorage=external\n"),
+				),
+			},
+			{
+				// A drop-in removed on the host is written again
+				PreConfig: func() {
+					testAgent.Config.Reset()
+				},
+				Config: config,
+				Check:  testAccCheckConfigDropIn("coredump", "50-storage", "[Coredump]\nCompress=yes\nMaxUse=10G\nStorage=external\n"),
+			},
+		},
+	})
+}
@@ -2,10 +2,14 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/nickrobison/terraform-linux-provider/common"
 )
 
 func UnexpectedDataSourceConfigureType(
@@ -14,7 +18,7 @@ func UnexpectedDataSourceConfigureType(
 	resp.Diagnostics.AddError(
 		"Unexpected Data Source Configure Type",
 		fmt.Sprintf(
-			"Expected *Client, got: %T. Please report this issue to the provider developers.",
+			"Expected *provider.clientPool, got: %T. Please report this issue to the provider developers.",
 			req.ProviderData,
 		),
 	)
@@ -23,5 +27,49 @@ func UnexpectedDataSourceConfigureType(
 func ProviderDataError(data any, diags *diag.Diagnostics) {
 	diags.AddError(
 		"Unexpected Resource Configure Type",
-		fmt.Sprintf("Expected *common.Client, got: %T. Please report this issue to the provider developers.", data))
+		fmt.Sprintf("Expected *provider.clientPool, got: %T. Please report this issue to the provider developers.", data))
+}
+
+func AgentNotReadyError(ready common.ReadinessResponse, diags *diag.Diagnostics) {
+	var detail strings.Builder
+	detail.WriteString("The Linux agent reported that one or more of its dependencies are unavailable:\n")
+	for _, dep := range ready.Dependencies {
+		if dep.Status == common.StatusOK {
+			fmt.Fprintf(&detail, "\n  %s: %s %s", dep.Name, dep.Status, dep.Version)
+		} else if dep.Status == common.StatusDisabled {
+			fmt.Fprintf(&detail, "\n  %s: %s", dep.Name, dep.Status)
+		} else {
+			fmt.Fprintf(&detail, "\n  %s: %s (%s)", dep.Name, dep.Status, dep.Error)
+		}
+	}
+	diags.AddError("Linux Agent Not Ready", detail.String())
+}
+
+func IncompatibleAgentError(client *common.Client, err *common.IncompatibleAPIError, diags *diag.Diagnostics) {
+	upgrade := "Upgrade the agent on the host, or use an older release of the provider."
+	if err.AgentMin > common.APIVersion {
+		upgrade = "Upgrade the provider, or use an older release of the agent."
+	}
+	diags.AddError(
+		"Incompatible Linux Agent",
+		fmt.Sprintf("The Linux agent at %s is not compatible with this version of the provider: %s.\n\n%s", client.Address(), err, upgrade),
+	)
+}
+
+// unitFailureDetail appends the journal lines the agent returned for a unit which failed to start, stop or restart
+func unitFailureDetail(detail string, err error) string {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && len(apiErr.Journal) > 0 {
+		detail += "\n\nLast journal lines:\n" + strings.Join(apiErr.Journal, "\n")
+	}
+	return detail
+}
+
+// rebootBlockedDetail explains how to reboot anyway when the agent refused because an inhibitor blocks shutdown
+func rebootBlockedDetail(detail string, err error) string {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		detail += "\n\nSet ignore_inhibitors = true to reboot anyway, which needs the org.freedesktop.login1.reboot-ignore-inhibit polkit action."
+	}
+	return detail
 }
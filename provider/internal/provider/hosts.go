@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	dschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	rschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// HostModel is an agent in the provider's hosts map
+type HostModel struct {
+	Address types.String `tfsdk:"address"`
+	Port    types.Int32  `tfsdk:"port"`
+}
+
+func hostsAttribute() schema.MapNestedAttribute {
+	return schema.MapNestedAttribute{
+		Optional: true,
+		Description: "Agents which resources and data sources can target by name with their host attribute." +
+			" Each host is reached with the provider's connection, request_timeout and max_retries settings.",
+		Validators: []validator.Map{
+			mapvalidator.KeysAre(stringvalidator.LengthAtLeast(1)),
+		},
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"address": schema.StringAttribute{
+					Required:    true,
+					Description: "Hostname of the agent.",
+				},
+				"port": schema.Int32Attribute{
+					Optional:    true,
+					Description: "TCP port of the agent. Defaults to the provider's port.",
+					Validators: []validator.Int32{
+						int32validator.Between(1, 65535),
+					},
+				},
+			},
+		},
+	}
+}
+
+const hostDescription = "Name of the host in the provider's hosts map which manages this object." +
+	" Defaults to the provider's host."
+
+func hostResourceAttribute() rschema.StringAttribute {
+	return rschema.StringAttribute{
+		Optional:    true,
+		Description: hostDescription,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+}
+
+func hostDataSourceAttribute() dschema.StringAttribute {
+	return dschema.StringAttribute{
+		Optional:    true,
+		Description: hostDescription,
+	}
+}
+
+// importHostID imports an object by its ID, which may be prefixed by the name of its host, e.g. web1/tank
+func importHostID(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostCompositeID(ctx, req, resp, 1)
+}
+
+// importHostCompositeID imports an object whose ID is made of parts separated by slashes, e.g. nginx.service/override,
+// which may be prefixed by the name of its host, e.g. web1/nginx.service/override
+func importHostCompositeID(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse, parts int) {
+	id := req.ID
+	if strings.Count(id, "/") >= parts {
+		host, rest, _ := strings.Cut(id, "/")
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("host"), host)...)
+		id = rest
+	}
+	if strings.Count(id, "/") != parts-1 || slices.Contains(strings.Split(id, "/"), "") {
+		resp.Diagnostics.AddError("Unexpected Import Identifier",
+			fmt.Sprintf("Expected an ID of %d parts separated by slashes, optionally prefixed by a host name, got %q.", parts, req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+}
+
+// pooledClient is the client of a single host, which is checked on first use
+type pooledClient struct {
+	mu        sync.Mutex
+	client    *common.Client
+	connected bool
+}
+
+// clientPool holds a client for the provider's host and each of its named hosts
+// A client only connects when it is first used, so that planning changes to one host doesn't need every host in the
+// inventory to be reachable
+type clientPool struct {
+	clients map[string]*pooledClient
+}
+
+func newClientPool() *clientPool {
+	return &clientPool{clients: make(map[string]*pooledClient)}
+}
+
+// add registers the client of the named host, the empty name is the provider's host
+func (p *clientPool) add(name string, client *common.Client) {
+	p.clients[name] = &pooledClient{client: client}
+}
+
+// get returns the client of the given host, connecting to it if this is its first use
+// Returns nil, and adds an error diagnostic, if the host is not configured or is unusable
+func (p *clientPool) get(ctx context.Context, host types.String, diags *diag.Diagnostics) *common.Client {
+	name := host.ValueString()
+	pc, ok := p.clients[name]
+	if !ok {
+		if name == "" {
+			diags.AddAttributeError(path.Root("host"), "Missing Linux host",
+				"The provider has no default host, set host to one of the names in the provider's hosts map.")
+		} else {
+			diags.AddAttributeError(path.Root("host"), "Unknown Linux host",
+				fmt.Sprintf("%q is not one of the names in the provider's hosts map.", name))
+		}
+		return nil
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if !pc.connected {
+		if name != "" {
+			ctx = tflog.SetField(ctx, "host", name)
+		}
+		if !connect(ctx, pc.client, diags) {
+			return nil
+		}
+		pc.connected = true
+	}
+	return pc.client
+}
+
+// forPlan returns the client of the host a planned resource is managed by
+// Returns nil if the resource is being destroyed, or its host is not yet known
+func (p *clientPool) forPlan(ctx context.Context, plan tfsdk.Plan, diags *diag.Diagnostics) *common.Client {
+	if plan.Raw.IsNull() {
+		return nil
+	}
+	var host types.String
+	diags.Append(plan.GetAttribute(ctx, path.Root("host"), &host)...)
+	if diags.HasError() || host.IsUnknown() {
+		return nil
+	}
+	return p.get(ctx, host, diags)
+}
+
+// connect checks that the agent is ready and negotiates the API version to use with it
+// Returns false, and adds an error diagnostic, if the agent is unusable
+func connect(ctx context.Context, client *common.Client, diags *diag.Diagnostics) bool {
+	ready, err := client.Ready(ctx)
+	var incompatible *common.IncompatibleAPIError
+	if errors.As(err, &incompatible) {
+		IncompatibleAgentError(client, incompatible, diags)
+		return false
+	}
+	if err != nil {
+		diags.AddError(
+			"Unable to reach Linux agent",
+			fmt.Sprintf("The provider could not query the readiness of the Linux agent at %s: %s", client.Address(), err),
+		)
+		return false
+	}
+	if ready.Status != common.StatusOK {
+		AgentNotReadyError(ready, diags)
+		return false
+	}
+
+	version, err := client.Negotiate(ctx)
+	if errors.As(err, &incompatible) {
+		IncompatibleAgentError(client, incompatible, diags)
+		return false
+	}
+	if err != nil {
+		diags.AddError(
+			"Unable to negotiate API version",
+			fmt.Sprintf("The provider could not determine which API versions the Linux agent at %s supports: %s", client.Address(), err),
+		)
+		return false
+	}
+	tflog.Debug(ctx, "Negotiated agent API version", map[string]any{"address": client.Address(), "api_version": version})
+	return true
+}
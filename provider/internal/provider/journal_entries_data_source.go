@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ datasource.DataSource                     = &journalEntriesDataSource{}
+	_ datasource.DataSourceWithConfigure        = &journalEntriesDataSource{}
+	_ datasource.DataSourceWithConfigValidators = &journalEntriesDataSource{}
+)
+
+type journalEntriesDataSource struct {
+	clients *clientPool
+}
+
+type journalEntriesDataSourceModel struct {
+	Host     types.String            `tfsdk:"host"`
+	Units    []types.String          `tfsdk:"units"`
+	Priority types.String            `tfsdk:"priority"`
+	Since    types.String            `tfsdk:"since"`
+	Until    types.String            `tfsdk:"until"`
+	Boot     types.String            `tfsdk:"boot"`
+	Cursor   types.String            `tfsdk:"cursor"`
+	Lines    types.Int64             `tfsdk:"lines"`
+	Entries  []journalEntryDataModel `tfsdk:"entries"`
+	// NextCursor continues reading where this left off, when passed as the cursor of another read
+	NextCursor types.String `tfsdk:"next_cursor"`
+}
+
+type journalEntryDataModel struct {
+	Cursor     types.String `tfsdk:"cursor"`
+	Time       types.String `tfsdk:"time"`
+	BootID     types.String `tfsdk:"boot_id"`
+	Unit       types.String `tfsdk:"unit"`
+	Priority   types.Int64  `tfsdk:"priority"`
+	Identifier types.String `tfsdk:"identifier"`
+	PID        types.Int64  `tfsdk:"pid"`
+	Hostname   types.String `tfsdk:"hostname"`
+	Message    types.String `tfsdk:"message"`
+}
+
+func NewJournalEntriesDataSource() datasource.DataSource {
+	return &journalEntriesDataSource{}
+}
+
+func (d *journalEntriesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		UnexpectedDataSourceConfigureType(ctx, req, resp)
+	}
+	d.clients = clients
+}
+
+func (d *journalEntriesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_journal_entries"
+}
+
+func (d *journalEntriesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Read entries from the systemd journal of the host, e.g. to check what a service logged during the last apply",
+		Attributes: map[string]schema.Attribute{
+			"host": hostDataSourceAttribute(),
+			"units": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Only read entries logged by any of these units, e.g. nginx.service",
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(
+						stringvalidator.LengthAtMost(common.MaxUnitNameLength),
+						stringvalidator.RegexMatches(common.UnitNamePattern, "must be a unit name, such as nginx.service"),
+					),
+				},
+			},
+			"priority": schema.StringAttribute{
+				Optional:    true,
+				Description: fmt.Sprintf("Only read entries of this priority or more severe, one of %v, or a number from 0 to 7", common.JournalPriorities),
+				Validators:  []validator.String{priorityValidator{}},
+			},
+			"since": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only read entries logged at or after this RFC 3339 timestamp, returning the first ones rather than the most recent",
+				Validators:  []validator.String{timestampValidator{}},
+			},
+			"until": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only read entries logged at or before this RFC 3339 timestamp",
+				Validators:  []validator.String{timestampValidator{}},
+			},
+			"boot": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only read entries of this boot, by ID or offset, e.g. 0 for the current boot or -1 for the previous one",
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(common.BootIDPattern, "must be a boot ID or offset, such as -1"),
+				},
+			},
+			"cursor": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only read entries after the one with this cursor, e.g. the next_cursor of another read, returning the first ones rather than the most recent",
+			},
+			"lines": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("Most entries to read, defaults to %d", common.DefaultJournalLines),
+				Validators:  []validator.Int64{int64validator.Between(1, common.MaxJournalLines)},
+			},
+			"entries": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "Matching entries, oldest first",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"cursor":  computed("Cursor of the entry"),
+						"time":    computed("RFC 3339 timestamp the entry was logged at"),
+						"boot_id": computed("ID of the boot the entry was logged in"),
+						"unit":    computed("Unit which logged the entry, empty for the kernel and processes outside of a unit"),
+						"priority": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Priority of the entry, from 0 (emerg) to 7 (debug)",
+						},
+						"identifier": computed("Syslog identifier of the entry, or the command which logged it if there is none"),
+						"pid": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Process which logged the entry, zero for the kernel",
+						},
+						"hostname": computed("Hostname the entry was logged on"),
+						"message":  computed("Message of the entry"),
+					},
+				},
+			},
+			"next_cursor": computed("Cursor of the last entry, or the given cursor if there are none, to read the entries logged since"),
+		},
+	}
+}
+
+func (d *journalEntriesDataSource) ConfigValidators(_ context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.Conflicting(path.MatchRoot("since"), path.MatchRoot("cursor")),
+	}
+}
+
+func (d *journalEntriesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state journalEntriesDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	client := d.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleJournal, &resp.Diagnostics) {
+		return
+	}
+
+	query := common.JournalQuery{
+		Priority: state.Priority.ValueString(),
+		Boot:     state.Boot.ValueString(),
+		Cursor:   state.Cursor.ValueString(),
+		Lines:    int(state.Lines.ValueInt64()),
+	}
+	for _, unit := range state.Units {
+		query.Units = append(query.Units, unit.ValueString())
+	}
+	// The values were checked by the attribute validators
+	if !state.Since.IsNull() {
+		since, _ := time.Parse(time.RFC3339, state.Since.ValueString())
+		query.Since = &since
+	}
+	if !state.Until.IsNull() {
+		until, _ := time.Parse(time.RFC3339, state.Until.ValueString())
+		query.Until = &until
+	}
+
+	journal, err := client.JournalGetEntries(ctx, query)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read journal", fmt.Sprintf("Unable to read the journal. Unexpected error: %s", err))
+		return
+	}
+
+	state.Entries = []journalEntryDataModel{}
+	for _, entry := range journal.Entries {
+		state.Entries = append(state.Entries, journalEntryDataModel{
+			Cursor:     types.StringValue(entry.Cursor),
+			Time:       types.StringValue(entry.Time.Format(time.RFC3339Nano)),
+			BootID:     types.StringValue(entry.BootID),
+			Unit:       types.StringValue(entry.Unit),
+			Priority:   types.Int64Value(int64(entry.Priority)),
+			Identifier: types.StringValue(entry.Identifier),
+			PID:        types.Int64Value(int64(entry.PID)),
+			Hostname:   types.StringValue(entry.Hostname),
+			Message:    types.StringValue(entry.Message),
+		})
+	}
+	state.NextCursor = types.StringValue(journal.Cursor)
+
+	diags := resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
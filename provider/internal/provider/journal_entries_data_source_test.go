@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+)
+
+func TestAccJournalEntriesDataSource(t *testing.T) {
+	const (
+		previousBoot = "0f3c5e7a9b1d4f6a8c0e2b4d6f8a1c3e"
+		currentBoot  = "6b1e0b5c3e2a4f7d9c8b1a2e3f4d5c6b"
+	)
+	logged := time.Date(2024, 6, 10, 6, 0, 0, 0, time.UTC)
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Journal.AddEntries(
+				journal.Entry{Time: logged, BootID: previousBoot, Unit: "nginx.service", Priority: 6, Identifier: "nginx", PID: 812, Hostname: "web1", Message: "started"},
+				journal.Entry{Time: logged.Add(time.Hour), BootID: currentBoot, Unit: "init.scope", Priority: 6, Identifier: "systemd", PID: 1, Hostname: "web1", Message: "Starting nginx.service..."},
+				journal.Entry{Time: logged.Add(time.Hour + time.Second), BootID: currentBoot, Unit: "nginx.service", Priority: 3, Identifier: "nginx", PID: 4242, Hostname: "web1", Message: "bind() to 0.0.0.0:80 failed (98: Address already in use)"},
+				journal.Entry{Time: logged.Add(time.Hour + 2*time.Second), BootID: currentBoot, Unit: "nginx.service", Priority: 6, Identifier: "nginx", PID: 4242, Hostname: "web1", Message: "still could not bind()"},
+			)
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				data "linux_journal_entries" "invalid" {
+				  priority = "loud"
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Priority"),
+			},
+			{
+				Config: providerConfig() + `
+				data "linux_journal_entries" "invalid" {
+				  since  = "2024-06-10T06:00:00Z"
+				  cursor = "s=test;i=1"
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Attribute Combination"),
+			},
+			{
+				Config: providerConfig() + `
+				data "linux_journal_entries" "errors" {
+				  units    = ["nginx.service"]
+				  priority = "err"
+				  boot     = "0"
+				}
+
+				data "linux_journal_entries" "since" {
+				  since = "2024-06-10T07:00:00Z"
+				  lines = 2
+				}
+
+				data "linux_journal_entries" "after" {
+				  cursor = data.linux_journal_entries.since.next_cursor
+				}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.linux_journal_entries.errors", "entries.#", "1"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.errors", "entries.0.message", "bind() to 0.0.0.0:80 failed (98: Address already in use)"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.errors", "entries.0.time", "2024-06-10T07:00:01Z"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.errors", "entries.0.boot_id", currentBoot),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.errors", "entries.0.priority", "3"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.errors", "entries.0.pid", "4242"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.since", "entries.#", "2"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.since", "entries.0.identifier", "systemd"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.since", "next_cursor", "s=test;i=3"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.after", "entries.#", "1"),
+					resource.TestCheckResourceAttr("data.linux_journal_entries.after", "entries.0.message", "still could not bind()"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+func NewJournaldConfigResource() resource.Resource {
+	return &configDropInResource{
+		typeName:    "journald_config",
+		file:        "journald",
+		description: "Drop-in of the journald configuration, limiting how much of the journal is kept and where it is forwarded",
+		settings: []configSetting{
+			{attribute: "storage", key: "Storage", description: "Where the journal is stored: volatile, persistent, auto or none"},
+			{attribute: "system_max_use", key: "SystemMaxUse", description: "Disk space the persistent journal may use, e.g. 1G"},
+			{attribute: "system_keep_free", key: "SystemKeepFree", description: "Disk space the persistent journal leaves free"},
+			{attribute: "system_max_file_size", key: "SystemMaxFileSize", description: "Size of a persistent journal file before it is rotated"},
+			{attribute: "runtime_max_use", key: "RuntimeMaxUse", description: "Memory the volatile journal in /run may use"},
+			{attribute: "max_retention_sec", key: "MaxRetentionSec", description: "Time after which journal entries are removed, e.g. 1month"},
+			{attribute: "rate_limit_interval_sec", key: "RateLimitIntervalSec", description: "Interval over which the messages of a service are rate limited, e.g. 30s"},
+			{attribute: "rate_limit_burst", key: "RateLimitBurst", kind: configInt, description: "Messages a service may log in each interval before the rest are dropped"},
+			{attribute: "forward_to_syslog", key: "ForwardToSyslog", kind: configBool, description: "Whether messages are forwarded to a syslog daemon"},
+			{attribute: "compress", key: "Compress", kind: configBool, description: "Whether large entries are compressed"},
+			{attribute: "max_level_store", key: "MaxLevelStore", description: "Lowest priority stored in the journal, e.g. info"},
+		},
+	}
+}
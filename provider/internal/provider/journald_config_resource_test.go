@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+func TestAccJournaldConfigResource(t *testing.T) {
+	config := func(maxUse string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_journald_config" "test" {
+		  name              = "50-retention"
+		  storage           = "persistent"
+		  system_max_use    = "%s"
+		  rate_limit_burst  = 10000
+		  forward_to_syslog = false
+
+		  settings = {
+		    MaxFileSec = "1week"
+		  }
+		}
+		`, maxUse)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "systemd-journald.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "static",
+			})
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, ok := testAgent.Config.DropIn("journald", "50-retention"); ok {
+				return fmt.Errorf("drop-in 50-retention still exists")
+			}
+			// Restarted after being written twice and deleted
+			if restarts := testAgent.Systemd.Restarts("systemd-journald.service"); restarts != 3 {
+				return fmt.Errorf("expected journald to be restarted 3 times, got %d", restarts)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_journald_config" "test" {
+				  name     = "50-retention"
+				  storage  = "disk"
+				  settings = {
+				    SystemMaxUsage = "1G"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile(`(?s)Invalid Storage.*SystemMaxUsage is not a key of the \[Journal\] section`),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_journald_config" "test" {
+				  name           = "50-retention"
+				  system_max_use = "1G"
+				  settings = {
+				    SystemMaxUse = "2G"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Set SystemMaxUse with the system_max_use attribute"),
+			},
+			{
+				Config: config("1G"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_journald_config.test", "id", "50-retention"),
+					resource.TestCheckResourceAttr("linux_journald_config.test", "path", "/etc/systemd/journald.conf.d/50-retention.conf"),
+					testAccCheckConfigDropIn("journald", "50-retention",
+						"[Journal]\nForwardToSyslog=no\nMaxFileSec=1week\nRateLimitBurst=10000\nStorage=persistent\nSystemMaxUse=1G\n"),
+					testAccCheckUnitRestarts("systemd-journald.service", 1),
+				),
+			},
+			{
+				ResourceName:      "linux_journald_config.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				// Changes made on the host are reverted
+				PreConfig: func() {
+					testAgent.Config.SetDropIn("journald", "50-retention", "[Journal]\nStorage=volatile\n")
+				},
+				Config: config("2G"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_journald_config.test", "storage", "persistent"),
+					testAccCheckConfigDropIn("journald", "50-retention",
+						"[Journal]\nForwardToSyslog=no\nMaxFileSec=1week\nRateLimitBurst=10000\nStorage=persistent\nSystemMaxUse=2G\n"),
+					testAccCheckUnitRestarts("systemd-journald.service", 2),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckConfigDropIn checks the content of a drop-in of a configuration file on the test agent
+func testAccCheckConfigDropIn(file string, name string, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		content, ok := testAgent.Config.DropIn(file, name)
+		if !ok {
+			return fmt.Errorf("drop-in %s of %s does not exist", name, file)
+		}
+		if content != expected {
+			return fmt.Errorf("expected drop-in %s of %s to contain %q, got %q", name, file, expected, content)
+		}
+		return nil
+	}
+}
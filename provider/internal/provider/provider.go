@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework-validators/int32validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
@@ -25,8 +26,12 @@ type LinuxProvider struct {
 }
 
 type LinuxProviderModel struct {
-	Host types.String `tfsdk:"host"`
-	Port types.Int32  `tfsdk:"port"`
+	Host           types.String     `tfsdk:"host"`
+	Port           types.Int32      `tfsdk:"port"`
+	RequestTimeout types.String     `tfsdk:"request_timeout"`
+	MaxRetries     types.Int32      `tfsdk:"max_retries"`
+	Connection     *ConnectionModel `tfsdk:"connection"`
+	Hosts          types.Map        `tfsdk:"hosts"`
 }
 
 const (
@@ -43,7 +48,7 @@ func (p *LinuxProvider) Schema(ctx context.Context, req provider.SchemaRequest,
 		Attributes: map[string]schema.Attribute{
 			"host": schema.StringAttribute{
 				Optional: true,
-				Description: "This is the hostname for the API connection." +
+				Description: "This is the hostname for the API connection, used by resources which don't name one of the hosts." +
 					" May also be provided via " + EnvHost + " environment variable.",
 			},
 			"port": schema.Int32Attribute{
@@ -54,6 +59,26 @@ func (p *LinuxProvider) Schema(ctx context.Context, req provider.SchemaRequest,
 					int32validator.Between(1, 65535),
 				},
 			},
+			"request_timeout": schema.StringAttribute{
+				Optional: true,
+				Description: "Timeout of each attempt at an API request, as a duration such as 30s." +
+					" It also bounds resource operations which the agent completes within a single request, such as starting a unit," +
+					" within the resource's own timeouts. Defaults to " + common.DefaultRequestTimeout.String() + ".",
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"max_retries": schema.Int32Attribute{
+				Optional: true,
+				Description: "Number of times a request which is safe to repeat, a read, update or delete, is retried when the agent is unreachable" +
+					" or temporarily unavailable. Creates are never retried, as the first attempt may have succeeded." +
+					" Defaults to " + strconv.Itoa(common.DefaultMaxRetries) + ".",
+				Validators: []validator.Int32{
+					int32validator.AtLeast(0),
+				},
+			},
+			"connection": connectionAttribute(),
+			"hosts":      hostsAttribute(),
 		},
 	}
 }
@@ -90,22 +115,29 @@ func (p *LinuxProvider) Configure(ctx context.Context, req provider.ConfigureReq
 	if !config.Host.IsNull() {
 		host = config.Host.ValueString()
 	}
-	if host == "" {
+	hosts := make(map[string]HostModel)
+	if !config.Hosts.IsNull() {
+		resp.Diagnostics.Append(config.Hosts.ElementsAs(ctx, &hosts, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if host == "" && len(hosts) == 0 {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("address"),
 			"Missing Linux address target",
 			"The provider cannot create the Linux client as there is a missing or empty value for the Linux address."+
 				" Set the value in the configuration or use the "+EnvHost+" environment variable."+
-				" If either is already set, ensure the value is not empty.",
+				" If either is already set, ensure the value is not empty."+
+				" Alternatively, configure the hosts which resources target by name.",
 		)
 
 		return
 	}
 
-	client := common.NewClient(host)
-
+	port := 0
 	if !config.Port.IsNull() {
-		client.WithPort(int(config.Port.ValueInt32()))
+		port = int(config.Port.ValueInt32())
 	} else if v := os.Getenv(EnvPort); v != "" {
 		d, err := strconv.Atoi(v)
 		if err != nil {
@@ -116,25 +148,83 @@ func (p *LinuxProvider) Configure(ctx context.Context, req provider.ConfigureReq
 					"So the variable is not used", err),
 			)
 		} else {
-			client.WithPort(d)
+			port = d
+		}
+	}
+
+	newClient := func(address string, port int) *common.Client {
+		client := common.NewClient(address)
+		if dial := config.Connection.dialer(address, &resp.Diagnostics); dial != nil {
+			client.WithDialer(dial)
 		}
+		if port != 0 {
+			client.WithPort(port)
+		}
+		if !config.RequestTimeout.IsNull() && !config.RequestTimeout.IsUnknown() {
+			// Already checked by durationValidator
+			timeout, _ := time.ParseDuration(config.RequestTimeout.ValueString())
+			client.WithTimeout(timeout)
+		}
+		if !config.MaxRetries.IsNull() && !config.MaxRetries.IsUnknown() {
+			client.WithMaxRetries(int(config.MaxRetries.ValueInt32()))
+		}
+		return client
 	}
 
-	ctx = tflog.SetField(ctx, "host", host)
-	tflog.Info(ctx, "Created client")
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	clients := newClientPool()
+	for name, h := range hosts {
+		if h.Address.IsUnknown() || h.Port.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(path.Root("hosts").AtMapKey(name), "Unknown Linux Host",
+				fmt.Sprintf("%s for the Linux host %s. Either target apply the source of the value first, or set the value statically in the configuration.", unknownValueErrorMessage, name))
+			continue
+		}
+		hostPort := port
+		if !h.Port.IsNull() {
+			hostPort = int(h.Port.ValueInt32())
+		}
+		clients.add(name, newClient(h.Address.ValueString(), hostPort))
+	}
+	if host != "" {
+		clients.add("", newClient(host, port))
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	tflog.Info(ctx, "Created clients", map[string]any{"hosts": len(hosts)})
+
+	// Check the provider's host up front, as it is used by any resource which doesn't name a host
+	if host != "" {
+		ctx = tflog.SetField(ctx, "host", host)
+		if clients.get(ctx, types.StringNull(), &resp.Diagnostics) == nil {
+			return
+		}
+	}
+	resp.DataSourceData = clients
+	resp.ResourceData = clients
 }
 
 func (p *LinuxProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewZpoolResource,
+		NewSystemdUnitResource,
+		NewSystemdServiceResource,
+		NewSystemdDropInResource,
+		NewSystemdTimerResource,
+		NewJournaldConfigResource,
+		NewCoredumpConfigResource,
+		NewTmpfilesResource,
+		NewSysusersResource,
+		NewSystemdSliceResource,
+		NewRebootResource,
 	}
 }
 
 func (p *LinuxProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewZpoolDataSource,
+		NewSystemdUnitsDataSource,
+		NewJournalEntriesDataSource,
+		NewRebootRequiredDataSource,
 	}
 }
 
@@ -1,17 +1,44 @@
 package provider
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/common/sshtest"
+	"github.com/nickrobison/terraform-linux-provider/server/api/apitest"
 )
 
-const (
-	providerConfig = `
+// testAgent serves the agent API in-process for every acceptance test, so that they run without a real host
+var testAgent *apitest.Agent
+
+func TestMain(m *testing.M) {
+	testAgent = apitest.NewAgent()
+	code := m.Run()
+	testAgent.Close()
+	os.Exit(code)
+}
+
+// providerConfig points the provider at the in-process test agent
+func providerConfig() string {
+	return fmt.Sprintf(`
 provider "linux" {
-  host = "localhost"
+  host = %q
+  port = %d
+}
+`, testAgent.Host(), testAgent.Port())
 }
-`
-)
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
 // acceptance testing. The factory function will be invoked for every Terraform
@@ -20,3 +47,273 @@ provider "linux" {
 var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
 	"linux": providerserver.NewProtocol6WithError(New("test")()),
 }
+
+// testAccPreCheck sweeps any state left on the test agent by a previous test
+func testAccPreCheck(t *testing.T) {
+	t.Helper()
+	testAgent.Reset()
+}
+
+// testAccSlowBackend makes every backend call take at least d
+func testAccSlowBackend(d time.Duration) func() {
+	return func() {
+		testAgent.Zfs.SetDelay(d)
+	}
+}
+
+// testAccFailZfs lets the next n calls to the given zfs backend method succeed, then fails the rest
+func testAccFailZfs(method string, n int, err error) func() {
+	return func() {
+		testAgent.Zfs.FailAfter(method, n, err)
+	}
+}
+
+// TestProviderSchema checks the provider schema passes the framework's own validation, which rejects a connection block
+func TestProviderSchema(t *testing.T) {
+	ctx := context.Background()
+	resp := &provider.SchemaResponse{}
+	New("test")().Schema(ctx, provider.SchemaRequest{}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("schema diagnostics: %v", resp.Diagnostics)
+	}
+	if diags := resp.Schema.ValidateImplementation(ctx); diags.HasError() {
+		t.Fatalf("invalid schema: %v", diags)
+	}
+}
+
+func TestAccProviderIncompatibleAgent(t *testing.T) {
+	// An agent which predates API versioning
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "ok", "dependencies": []}`))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+				provider "linux" {
+				  host = %q
+				  port = %s
+				}
+
+				data "linux_zpools" "pools" {}
+				`, u.Hostname(), u.Port()),
+				ExpectError: regexp.MustCompile("Incompatible Linux Agent"),
+			},
+		},
+	})
+}
+
+func TestAccProviderRequestTimeout(t *testing.T) {
+	config := func(timeout string) string {
+		return fmt.Sprintf(`
+		provider "linux" {
+		  host            = %q
+		  port            = %d
+		  request_timeout = %q
+		  max_retries     = 0
+		}
+
+		data "linux_zpools" "pools" {}
+		`, testAgent.Host(), testAgent.Port(), timeout)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config("soon"),
+				ExpectError: regexp.MustCompile("Invalid Duration"),
+			},
+			{
+				PreConfig:   testAccSlowBackend(time.Second),
+				Config:      config("100ms"),
+				ExpectError: regexp.MustCompile("Unable to reach Linux agent"),
+			},
+			{
+				PreConfig: testAccSlowBackend(0),
+				Config:    config("5s"),
+				Check:     resource.TestCheckResourceAttr("data.linux_zpools.pools", "zpools.#", "0"),
+			},
+		},
+	})
+}
+
+func TestAccProviderRequestTimeoutResource(t *testing.T) {
+	config := func(timeout string) string {
+		return fmt.Sprintf(`
+		provider "linux" {
+		  host            = %q
+		  port            = %d
+		  request_timeout = %q
+		  max_retries     = 0
+		}
+
+		resource "linux_zpool" "test" {
+		  name = "tank"
+		}
+		`, testAgent.Host(), testAgent.Port(), timeout)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckZpoolDestroyed,
+		Steps: []resource.TestStep{
+			{
+				// Each attempt is bounded by request_timeout, even though the create timeout is much longer
+				PreConfig:   func() { testAgent.Zfs.SetMethodDelay("CreatePool", 5*time.Second) },
+				Config:      config("200ms"),
+				ExpectError: regexp.MustCompile("Failed to create zpool"),
+			},
+			{
+				PreConfig: func() { testAgent.Zfs.SetMethodDelay("CreatePool", 0) },
+				Config:    config("5s"),
+				Check:     resource.TestCheckResourceAttr("linux_zpool.test", "id", "tank"),
+			},
+		},
+	})
+}
+
+func TestAccProviderSSHConnection(t *testing.T) {
+	key, pub, err := sshtest.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := sshtest.NewServer("terraform", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	config := func(hostKey string) string {
+		return fmt.Sprintf(`
+		provider "linux" {
+		  host = %q
+		  port = %d
+
+		  connection = {
+		    type        = "ssh"
+		    user        = "terraform"
+		    port        = %d
+		    private_key = %q
+		    host_key    = %q
+		  }
+		}
+
+		resource "linux_zpool" "test" {
+		  name = "tank"
+		}
+		`, srv.Host(), testAgent.Port(), srv.Port(), key, hostKey)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Runs first, since the post-test destroy uses the last config
+			{
+				Config:      config(pub.Type() + " AAAAC3NzaC1lZDI1NTE5AAAAIBm3Ez2qkS6kDbO6lFWzSu8dd9VThXIS6vP4XGd8cCZu"),
+				ExpectError: regexp.MustCompile("host key mismatch"),
+			},
+			{
+				Config: config(srv.HostKey()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_zpool.test", "name", "tank"),
+					testAccCheckZpools("tank"),
+					func(*terraform.State) error {
+						if srv.Forwards() == 0 {
+							return fmt.Errorf("expected requests to be forwarded over SSH")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccProviderHosts(t *testing.T) {
+	other := apitest.NewAgent()
+	defer other.Close()
+
+	config := fmt.Sprintf(`
+	provider "linux" {
+	  hosts = {
+	    web1 = {
+	      address = %q
+	      port    = %d
+	    }
+	    web2 = {
+	      address = %q
+	      port    = %d
+	    }
+	  }
+	}
+
+	resource "linux_zpool" "test" {
+	  count = 2
+
+	  host = "web${count.index + 1}"
+	  name = "tank${count.index + 1}"
+	}
+
+	data "linux_zpools" "web2" {
+	  host = "web2"
+
+	  depends_on = [linux_zpool.test]
+	}
+	`, testAgent.Host(), testAgent.Port(), other.Host(), other.Port())
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if pools := other.Zfs.Pools(); len(pools) != 0 {
+				return fmt.Errorf("expected no zpools on web2, got %v", pools)
+			}
+			return testAccCheckZpoolDestroyed(nil)
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_zpool.test.0", "host", "web1"),
+					resource.TestCheckResourceAttr("linux_zpool.test.1", "host", "web2"),
+					resource.TestCheckResourceAttr("data.linux_zpools.web2", "zpools.#", "1"),
+					testAccCheckZpools("tank1"),
+					func(*terraform.State) error {
+						if pools := other.Zfs.Pools(); len(pools) != 1 || pools[0] != "tank2" {
+							return fmt.Errorf("expected zpool tank2 on web2, got %v", pools)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:      "linux_zpool.test[1]",
+				ImportState:       true,
+				ImportStateId:     "web2/tank2",
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"timeouts",
+				},
+			},
+			{
+				Config: config + `
+				data "linux_zpools" "missing" {
+				  host = "web3"
+				}
+				`,
+				ExpectError: regexp.MustCompile("Unknown Linux host"),
+			},
+		},
+	})
+}
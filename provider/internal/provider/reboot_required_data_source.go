@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ datasource.DataSource              = &rebootRequiredDataSource{}
+	_ datasource.DataSourceWithConfigure = &rebootRequiredDataSource{}
+)
+
+type rebootRequiredDataSource struct {
+	clients *clientPool
+}
+
+type rebootRequiredDataSourceModel struct {
+	Host           types.String         `tfsdk:"host"`
+	RebootRequired types.Bool           `tfsdk:"reboot_required"`
+	Reasons        []types.String       `tfsdk:"reasons"`
+	Packages       []types.String       `tfsdk:"packages"`
+	Kernel         types.String         `tfsdk:"kernel"`
+	BootID         types.String         `tfsdk:"boot_id"`
+	BootedAt       types.String         `tfsdk:"booted_at"`
+	Inhibitors     []inhibitorDataModel `tfsdk:"inhibitors"`
+	// ScheduledReboot is null unless a reboot is scheduled
+	ScheduledReboot types.String `tfsdk:"scheduled_reboot"`
+}
+
+type inhibitorDataModel struct {
+	What types.String `tfsdk:"what"`
+	Who  types.String `tfsdk:"who"`
+	Why  types.String `tfsdk:"why"`
+	Mode types.String `tfsdk:"mode"`
+	UID  types.Int64  `tfsdk:"uid"`
+	PID  types.Int64  `tfsdk:"pid"`
+}
+
+func NewRebootRequiredDataSource() datasource.DataSource {
+	return &rebootRequiredDataSource{}
+}
+
+func (d *rebootRequiredDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		UnexpectedDataSourceConfigureType(ctx, req, resp)
+	}
+	d.clients = clients
+}
+
+func (d *rebootRequiredDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_reboot_required"
+}
+
+func (d *rebootRequiredDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Check whether the host needs rebooting, e.g. to gate a linux_reboot on it",
+		Attributes: map[string]schema.Attribute{
+			"host": hostDataSourceAttribute(),
+			"reboot_required": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the host needs rebooting, true if there are any reasons",
+			},
+			"reasons": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Why the host needs rebooting: /run/reboot-required exists, or a kernel other than the running one is installed",
+			},
+			"packages": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Packages which asked for the reboot, as listed in /run/reboot-required.pkgs",
+			},
+			"kernel":    computed("Release of the running kernel, e.g. 6.1.0-21-amd64"),
+			"boot_id":   computed("ID of the current boot, which changes every time the host boots"),
+			"booted_at": computed("RFC 3339 timestamp the host booted at"),
+			"inhibitors": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "Locks taken with logind to delay or block shutdown, sleep and other operations, see systemd-inhibit(1)",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"what": computed("Colon separated list of the inhibited operations, e.g. shutdown:sleep"),
+						"who":  computed("Program which took the lock"),
+						"why":  computed("Reason the lock was taken"),
+						"mode": computed("Either block, which stops the operation, or delay"),
+						"uid": schema.Int64Attribute{
+							Computed:    true,
+							Description: "User which took the lock",
+						},
+						"pid": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Process which took the lock",
+						},
+					},
+				},
+			},
+			"scheduled_reboot": computed("RFC 3339 timestamp of the reboot scheduled with logind, null if there is none"),
+		},
+	}
+}
+
+func (d *rebootRequiredDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state rebootRequiredDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	client := d.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleLogin, &resp.Diagnostics) {
+		return
+	}
+
+	boot, err := client.LoginGetBoot(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read boot", fmt.Sprintf("Unable to check whether the host needs rebooting. Unexpected error: %s", err))
+		return
+	}
+
+	state.RebootRequired = types.BoolValue(boot.RebootRequired)
+	state.Reasons = stringValues(boot.Reasons)
+	state.Packages = stringValues(boot.Packages)
+	state.Kernel = types.StringValue(boot.Kernel)
+	state.BootID = types.StringValue(boot.BootID)
+	state.BootedAt = types.StringValue(boot.BootedAt.Format(time.RFC3339))
+	state.Inhibitors = []inhibitorDataModel{}
+	for _, i := range boot.Inhibitors {
+		state.Inhibitors = append(state.Inhibitors, inhibitorDataModel{
+			What: types.StringValue(i.What),
+			Who:  types.StringValue(i.Who),
+			Why:  types.StringValue(i.Why),
+			Mode: types.StringValue(i.Mode),
+			UID:  types.Int64Value(int64(i.UID)),
+			PID:  types.Int64Value(int64(i.PID)),
+		})
+	}
+	state.ScheduledReboot = types.StringNull()
+	if boot.ScheduledReboot != nil {
+		state.ScheduledReboot = types.StringValue(boot.ScheduledReboot.Format(time.RFC3339))
+	}
+
+	diags := resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func stringValues(values []string) []types.String {
+	result := make([]types.String, 0, len(values))
+	for _, v := range values {
+		result = append(result, types.StringValue(v))
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/nickrobison/terraform-linux-provider/server/login"
+)
+
+func TestAccRebootRequiredDataSource(t *testing.T) {
+	const bootID = "0f6bc4e0a5d2483d9c0e8a6b1d9f1c2e"
+	booted := time.Date(2024, 6, 10, 6, 0, 0, 0, time.UTC)
+	// A reboot in the past would happen before the data source reads the boot
+	scheduled := time.Now().Add(48 * time.Hour).Truncate(time.Second).UTC()
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				data "linux_reboot_required" "test" {}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "reboot_required", "false"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "reasons.#", "0"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "inhibitors.#", "0"),
+					resource.TestCheckNoResourceAttr("data.linux_reboot_required.test", "scheduled_reboot"),
+				),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Login.SetBoot(login.Boot{
+						ID:       bootID,
+						BootedAt: booted,
+						Kernel:   "6.1.0-21-amd64",
+						Reasons: []string{
+							"/run/reboot-required exists",
+							"kernel 6.1.0-22-amd64 is installed, but 6.1.0-21-amd64 is running",
+						},
+						Packages:        []string{"linux-image-6.1.0-22-amd64", "libc6"},
+						Inhibitors:      []login.Inhibitor{{What: "shutdown:sleep", Who: "apt", Why: "Upgrading packages", Mode: "block", UID: 0, PID: 4242}},
+						ScheduledReboot: scheduled,
+					})
+				},
+				Config: providerConfig() + `
+				data "linux_reboot_required" "test" {}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "reboot_required", "true"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "reasons.#", "2"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "reasons.1", "kernel 6.1.0-22-amd64 is installed, but 6.1.0-21-amd64 is running"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "packages.#", "2"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "packages.0", "linux-image-6.1.0-22-amd64"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "kernel", "6.1.0-21-amd64"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "boot_id", bootID),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "booted_at", "2024-06-10T06:00:00Z"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "inhibitors.#", "1"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "inhibitors.0.who", "apt"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "inhibitors.0.mode", "block"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "inhibitors.0.pid", "4242"),
+					resource.TestCheckResourceAttr("data.linux_reboot_required.test", "scheduled_reboot", scheduled.Format(time.RFC3339)),
+				),
+			},
+		},
+	})
+}
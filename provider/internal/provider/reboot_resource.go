@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                   = &RebootResource{}
+	_ resource.ResourceWithModifyPlan     = &RebootResource{}
+	_ resource.ResourceWithValidateConfig = &RebootResource{}
+)
+
+// rebootPollInterval is how often the agent is asked for the boot ID once the reboot is due
+var rebootPollInterval = 5 * time.Second
+
+type RebootResource struct {
+	clients *clientPool
+}
+
+type RebootResourceModel struct {
+	ID                types.String            `tfsdk:"id"`
+	Host              types.String            `tfsdk:"host"`
+	Triggers          types.Map               `tfsdk:"triggers"`
+	Delay             types.String            `tfsdk:"delay"`
+	MaintenanceWindow *MaintenanceWindowModel `tfsdk:"maintenance_window"`
+	IgnoreInhibitors  types.Bool              `tfsdk:"ignore_inhibitors"`
+	PreviousBootID    types.String            `tfsdk:"previous_boot_id"`
+	BootID            types.String            `tfsdk:"boot_id"`
+	RebootedAt        types.String            `tfsdk:"rebooted_at"`
+	Timeouts          timeouts.Value          `tfsdk:"timeouts"`
+}
+
+// MaintenanceWindowModel is a daily period in the host's local time
+type MaintenanceWindowModel struct {
+	Start types.String `tfsdk:"start"`
+	End   types.String `tfsdk:"end"`
+}
+
+func NewRebootResource() resource.Resource {
+	return &RebootResource{}
+}
+
+func (r *RebootResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *RebootResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_reboot"
+}
+
+func (r *RebootResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	timeOfDay := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Required:    true,
+			Description: description,
+			Validators: []validator.String{
+				stringvalidator.RegexMatches(common.TimeOfDayPattern, "must be a time of day on the 24 hour clock, such as 02:00"),
+			},
+		}
+	}
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Reboots the host through logind when it is created, then waits for the agent to come back with a new boot ID. " +
+			"The host is rebooted again whenever triggers change, e.g. to the installed kernel version. Changes to the other arguments " +
+			"only apply to the next reboot, and destroying the resource does nothing. The create timeout must cover the delay and " +
+			"any wait for the maintenance window.",
+		Attributes: map[string]schema.Attribute{
+			"id":   computed("The boot ID of the host after the reboot"),
+			"host": hostResourceAttribute(),
+			"triggers": schema.MapAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Arbitrary values which reboot the host again when they change, e.g. the kernel reported by linux_reboot_required",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"delay": schema.StringAttribute{
+				Optional:    true,
+				Description: "How long to wait before rebooting, e.g. 5m, to give users notice. The host reboots after at least 5 seconds.",
+				Validators:  []validator.String{durationValidator{}},
+			},
+			"maintenance_window": schema.SingleNestedAttribute{
+				Optional: true,
+				Description: "Daily period in the host's local time in which it may reboot. If the delay ends outside of it, the reboot is " +
+					"postponed until it next opens. A window whose end is earlier than its start runs past midnight.",
+				Attributes: map[string]schema.Attribute{
+					"start": timeOfDay("Time of day the window opens, e.g. 02:00"),
+					"end":   timeOfDay("Time of day the window closes, e.g. 04:00"),
+				},
+			},
+			"ignore_inhibitors": schema.BoolAttribute{
+				Optional: true,
+				Description: "Whether to reboot even if a program holds a logind inhibitor blocking shutdown, which also needs the " +
+					"org.freedesktop.login1.reboot-ignore-inhibit polkit action. Otherwise the reboot fails. Defaults to false.",
+			},
+			"previous_boot_id": computed("The boot ID of the host before the reboot"),
+			"boot_id":          computed("The boot ID of the host after the reboot, which can be used to read its journal"),
+			"rebooted_at":      computed("RFC 3339 timestamp the host booted at after the reboot"),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+			}),
+		},
+	}
+}
+
+func (r *RebootResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var window *MaintenanceWindowModel
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("maintenance_window"), &window)...)
+	if resp.Diagnostics.HasError() || window == nil || window.Start.IsUnknown() || window.End.IsUnknown() {
+		return
+	}
+	if window.Start.ValueString() == window.End.ValueString() {
+		resp.Diagnostics.AddAttributeError(path.Root("maintenance_window"), "Invalid Maintenance Window",
+			fmt.Sprintf("The maintenance window opens and closes at %s, so the host would never reboot.", window.Start.ValueString()))
+	}
+}
+
+func (r *RebootResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client != nil {
+		requireModule(ctx, client, common.ModuleLogin, &resp.Diagnostics)
+	}
+}
+
+func (r *RebootResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan RebootResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	request := common.RebootRequest{IgnoreInhibitors: plan.IgnoreInhibitors.ValueBool()}
+	if !plan.Delay.IsNull() {
+		// The value was checked by the attribute validator
+		delay, _ := time.ParseDuration(plan.Delay.ValueString())
+		request.DelaySec = int64(delay.Round(time.Second) / time.Second)
+	}
+	if plan.MaintenanceWindow != nil {
+		request.Window = &common.MaintenanceWindow{Start: plan.MaintenanceWindow.Start.ValueString(), End: plan.MaintenanceWindow.End.ValueString()}
+	}
+
+	tflog.Debug(ctx, "Scheduling reboot", map[string]any{"delay_sec": request.DelaySec, "ignore_inhibitors": request.IgnoreInhibitors})
+	scheduled, err := client.LoginReboot(ctx, request)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to reboot host", rebootBlockedDetail(fmt.Sprintf("Unable to schedule the reboot of %s. Unexpected error: %s", client.Address(), err), err))
+		return
+	}
+	tflog.Info(ctx, "Scheduled reboot", map[string]any{"boot_id": scheduled.BootID, "scheduled_at": scheduled.ScheduledAt.Format(time.RFC3339)})
+
+	boot := waitForReboot(ctx, client, scheduled, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = types.StringValue(boot.BootID)
+	plan.PreviousBootID = types.StringValue(scheduled.BootID)
+	plan.BootID = types.StringValue(boot.BootID)
+	plan.RebootedAt = types.StringValue(boot.BootedAt.Format(time.RFC3339))
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// waitForReboot waits for the scheduled reboot, then polls the agent until it reports a new boot ID
+// The agent is unreachable while the host reboots, so errors are only reported if the host doesn't come back in time.
+// A reboot which hasn't happened by then is cancelled, so that the host doesn't reboot after the apply has failed.
+func waitForReboot(ctx context.Context, client *common.Client, scheduled common.RebootResponse, diags *diag.Diagnostics) common.BootResponse {
+	wait := time.Until(scheduled.ScheduledAt)
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			detail := fmt.Sprintf("The host %s did not come back with a new boot ID before the timeout.", client.Address())
+			if lastErr != nil {
+				detail += fmt.Sprintf(" Last error: %s", lastErr)
+			}
+			cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), common.DefaultRequestTimeout)
+			defer cancel()
+			if boot, err := client.LoginGetBoot(cancelCtx); err == nil && boot.BootID == scheduled.BootID {
+				if err := client.LoginCancelReboot(cancelCtx); err == nil {
+					detail += fmt.Sprintf(" The reboot scheduled at %s was cancelled, increase the create timeout to wait for it.",
+						scheduled.ScheduledAt.Format(time.RFC3339))
+				}
+			}
+			diags.AddError("Timed out waiting for reboot", detail)
+			return common.BootResponse{}
+		case <-time.After(wait):
+		}
+		wait = rebootPollInterval
+
+		boot, err := client.LoginGetBoot(ctx)
+		if err != nil {
+			tflog.Debug(ctx, "Waiting for the agent to come back", map[string]any{"error": err.Error()})
+			lastErr = err
+			continue
+		}
+		if boot.BootID != scheduled.BootID {
+			tflog.Info(ctx, "Host rebooted", map[string]any{"boot_id": boot.BootID})
+			return boot
+		}
+	}
+}
+
+// Read leaves the state alone, since the reboot has already happened and there is nothing on the host to refresh
+func (r *RebootResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state RebootResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Update only records the new arguments, which apply to the next reboot
+func (r *RebootResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan RebootResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete only removes the resource from the state, a reboot can't be undone
+func (r *RebootResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/server/login"
+)
+
+func TestAccRebootResource(t *testing.T) {
+	interval := rebootPollInterval
+	rebootPollInterval = 100 * time.Millisecond
+	t.Cleanup(func() { rebootPollInterval = interval })
+
+	config := func(kernel string, ignoreInhibitors bool) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_reboot" "test" {
+		  triggers = {
+		    kernel = %q
+		  }
+		  ignore_inhibitors = %t
+		}
+		`, kernel, ignoreInhibitors)
+	}
+	var previousBoot string
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			boot, _ := testAgent.Login.Boot(context.Background())
+			previousBoot = boot.ID
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_reboot" "test" {
+				  maintenance_window = {
+				    start = "02:00"
+				    end   = "02:00"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Maintenance Window"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_reboot" "test" {
+				  maintenance_window = {
+				    start = "2am"
+				    end   = "04:00"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("must be a time of day"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Login.SetBoot(login.Boot{
+						Kernel:     "6.1.0-21-amd64",
+						Reasons:    []string{"kernel 6.1.0-22-amd64 is installed, but 6.1.0-21-amd64 is running"},
+						Inhibitors: []login.Inhibitor{{What: "shutdown:sleep", Who: "apt", Why: "Upgrading packages", Mode: "block", PID: 4242}},
+					})
+				},
+				Config:      config("6.1.0-22-amd64", false),
+				ExpectError: regexp.MustCompile(`reboot is blocked by apt(.|\s)+ignore_inhibitors\s+=\s+true`),
+			},
+			{
+				Config: config("6.1.0-22-amd64", true),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckRebooted(1),
+					resource.TestCheckResourceAttrWith("linux_reboot.test", "previous_boot_id", func(value string) error {
+						if value != previousBoot {
+							return fmt.Errorf("expected previous boot %s, got %s", previousBoot, value)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttrSet("linux_reboot.test", "rebooted_at"),
+				),
+			},
+			{
+				// Other arguments only apply to the next reboot
+				Config: config("6.1.0-22-amd64", false),
+				Check:  testAccCheckRebooted(1),
+			},
+			{
+				Config: config("6.1.0-23-amd64", false),
+				Check:  testAccCheckRebooted(2),
+			},
+		},
+	})
+}
+
+// testAccCheckRebooted checks that the host rebooted n times, and that the resource records its current boot
+func testAccCheckRebooted(n int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if reboots := testAgent.Login.Reboots(); reboots != n {
+			return fmt.Errorf("expected %d reboots, got %d", n, reboots)
+		}
+		boot, err := testAgent.Login.Boot(context.Background())
+		if err != nil {
+			return err
+		}
+		return resource.ComposeAggregateTestCheckFunc(
+			resource.TestCheckResourceAttr("linux_reboot.test", "id", boot.ID),
+			resource.TestCheckResourceAttr("linux_reboot.test", "boot_id", boot.ID),
+		)(s)
+	}
+}
@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// ResourceControlModel is the cgroup limits of a unit, limits which are not set are left as they are
+type ResourceControlModel struct {
+	MemoryMax types.String `tfsdk:"memory_max"`
+	CPUQuota  types.String `tfsdk:"cpu_quota"`
+	IOWeight  types.Int64  `tfsdk:"io_weight"`
+	TasksMax  types.String `tfsdk:"tasks_max"`
+	Runtime   types.Bool   `tfsdk:"runtime"`
+}
+
+var cgroupAttrTypes = map[string]attr.Type{
+	"path":           types.StringType,
+	"memory_current": types.Int64Type,
+	"cpu_usage_nsec": types.Int64Type,
+	"tasks_current":  types.Int64Type,
+}
+
+func resourceControlAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		Description: "cgroup limits of the unit, which are applied straight away with SetUnitProperties, without restarting it. " +
+			"Limits which are removed from the configuration are reset.",
+		Attributes: map[string]schema.Attribute{
+			"memory_max": schema.StringAttribute{
+				Optional:    true,
+				Description: "MemoryMax, the most memory the unit may use, e.g. 512M or 2G, or infinity",
+				Validators:  []validator.String{sizeValidator{}},
+			},
+			"cpu_quota": schema.StringAttribute{
+				Optional:    true,
+				Description: "CPUQuota, the share of one CPU's time the unit may use, e.g. 50%, or 200% for two CPUs",
+				Validators:  []validator.String{cpuQuotaValidator{}},
+			},
+			"io_weight": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("IOWeight, the unit's share of block IO, from 1 to %d. systemd's default is 100.", common.MaxIOWeight),
+				Validators:  []validator.Int64{int64validator.Between(1, common.MaxIOWeight)},
+			},
+			"tasks_max": schema.StringAttribute{
+				Optional:    true,
+				Description: "TasksMax, the most processes and threads the unit may have, or infinity",
+				Validators:  []validator.String{tasksMaxValidator{}},
+			},
+			"runtime": schema.BoolAttribute{
+				Optional: true,
+				Description: "Whether the limits only last until the host reboots. " +
+					"Otherwise systemd persists them as drop-ins in /etc/systemd/system.control. Defaults to false.",
+			},
+		},
+	}
+}
+
+func cgroupAttribute() schema.SingleNestedAttribute {
+	usage := func(description string) schema.Int64Attribute {
+		return schema.Int64Attribute{
+			Computed:    true,
+			Description: description + " Null if the unit isn't running, or its accounting is disabled.",
+		}
+	}
+	return schema.SingleNestedAttribute{
+		Computed:    true,
+		Description: "The unit's cgroup and its current usage, as of the last refresh. Null for units which have no cgroup, such as timers.",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Computed:    true,
+				Description: "Path of the cgroup below the cgroup root, e.g. /system.slice/nginx.service. Null if the unit isn't running.",
+			},
+			"memory_current": usage("Memory used by the cgroup, in bytes."),
+			"cpu_usage_nsec": usage("CPU time used by the cgroup, in nanoseconds."),
+			"tasks_current":  usage("Number of tasks in the cgroup."),
+		},
+	}
+}
+
+// limits returns the configured limits, unset limits are nil
+func (m *ResourceControlModel) limits() (memoryMax, cpuQuota, ioWeight, tasksMax *uint64) {
+	if m == nil {
+		return nil, nil, nil, nil
+	}
+	parse := func(v types.String, parser func(string) (uint64, error)) *uint64 {
+		if v.IsNull() || v.IsUnknown() {
+			return nil
+		}
+		// The values were checked by the validators
+		n, err := parser(v.ValueString())
+		if err != nil {
+			return nil
+		}
+		return &n
+	}
+	if !m.IOWeight.IsNull() && !m.IOWeight.IsUnknown() {
+		w := uint64(m.IOWeight.ValueInt64())
+		ioWeight = &w
+	}
+	return parse(m.MemoryMax, common.ParseSize), parse(m.CPUQuota, common.ParseCPUQuota), ioWeight, parse(m.TasksMax, common.ParseTasksMax)
+}
+
+// resourceControlRequest returns the planned limits, and resets those which were previously set but no longer are
+// Unchanged limits are set again, in case they were changed on the host. Returns false if there is nothing to set.
+func resourceControlRequest(plan *ResourceControlModel, previous *ResourceControlModel) (common.ResourceControlRequest, bool) {
+	planned := make([]*uint64, 4)
+	planned[0], planned[1], planned[2], planned[3] = plan.limits()
+	current := make([]*uint64, 4)
+	current[0], current[1], current[2], current[3] = previous.limits()
+
+	infinity := common.Infinity
+	changed := false
+	for i := range planned {
+		if planned[i] == nil && current[i] != nil {
+			planned[i] = &infinity
+		}
+		changed = changed || planned[i] != nil
+	}
+	request := common.ResourceControlRequest{
+		MemoryMax:          planned[0],
+		CPUQuotaPerSecUSec: planned[1],
+		IOWeight:           planned[2],
+		TasksMax:           planned[3],
+	}
+	switch {
+	case plan != nil:
+		request.Runtime = plan.Runtime.ValueBool()
+	case previous != nil:
+		// Limits are reset where they were stored
+		request.Runtime = previous.Runtime.ValueBool()
+	}
+	return request, changed
+}
+
+// applyResourceControl changes the cgroup limits of a unit from the previous ones to the planned ones
+// Limits which were persisted are reset first if they become runtime limits, or the other way round, since systemd
+// keeps the two apart.
+func applyResourceControl(ctx context.Context, client *common.Client, name string, plan *ResourceControlModel, previous *ResourceControlModel, diags *diag.Diagnostics) {
+	if plan != nil && previous != nil && plan.Runtime.ValueBool() != previous.Runtime.ValueBool() {
+		if request, changed := resourceControlRequest(nil, previous); changed {
+			tflog.Debug(ctx, "Resetting resource limits", map[string]any{"name": name, "runtime": request.Runtime})
+			if _, err := client.SystemdSetUnitResources(ctx, name, request); err != nil {
+				diags.AddError("Failed to set resource limits", fmt.Sprintf("Unable to reset the resource limits of unit %s. Unexpected error: %s", name, err))
+				return
+			}
+		}
+		previous = nil
+	}
+	request, changed := resourceControlRequest(plan, previous)
+	if !changed {
+		return
+	}
+	tflog.Debug(ctx, "Setting resource limits", map[string]any{"name": name, "runtime": request.Runtime})
+	if _, err := client.SystemdSetUnitResources(ctx, name, request); err != nil {
+		diags.AddError("Failed to set resource limits", fmt.Sprintf("Unable to set the resource limits of unit %s. Unexpected error: %s", name, err))
+	}
+}
+
+// fromResponse reports limits which were changed on the host, limits which are not configured are not reported
+func (m *ResourceControlModel) fromResponse(resources common.ResourceControlResponse) {
+	if m == nil {
+		return
+	}
+	memoryMax, cpuQuota, ioWeight, tasksMax := m.limits()
+	if memoryMax != nil && *memoryMax != resources.MemoryMax {
+		m.MemoryMax = types.StringValue(common.FormatSize(resources.MemoryMax))
+	}
+	if cpuQuota != nil && *cpuQuota != resources.CPUQuotaPerSecUSec {
+		m.CPUQuota = types.StringNull()
+		if resources.CPUQuotaPerSecUSec != common.Infinity {
+			m.CPUQuota = types.StringValue(common.FormatCPUQuota(resources.CPUQuotaPerSecUSec))
+		}
+	}
+	if ioWeight != nil && *ioWeight != resources.IOWeight {
+		m.IOWeight = types.Int64Null()
+		if resources.IOWeight <= common.MaxIOWeight {
+			m.IOWeight = types.Int64Value(int64(resources.IOWeight))
+		}
+	}
+	if tasksMax != nil && *tasksMax != resources.TasksMax {
+		m.TasksMax = types.StringValue(common.FormatTasksMax(resources.TasksMax))
+	}
+}
+
+// cgroupValue returns the cgroup attribute of a unit's resources
+func cgroupValue(resources common.ResourceControlResponse, diags *diag.Diagnostics) types.Object {
+	usage := func(v *uint64) types.Int64 {
+		if v == nil || *v > math.MaxInt64 {
+			return types.Int64Null()
+		}
+		return types.Int64Value(int64(*v))
+	}
+	cgroupPath := types.StringNull()
+	if resources.ControlGroup != "" {
+		cgroupPath = types.StringValue(resources.ControlGroup)
+	}
+	v, d := types.ObjectValue(cgroupAttrTypes, map[string]attr.Value{
+		"path":           cgroupPath,
+		"memory_current": usage(resources.MemoryCurrent),
+		"cpu_usage_nsec": usage(resources.CPUUsageNSec),
+		"tasks_current":  usage(resources.TasksCurrent),
+	})
+	diags.Append(d...)
+	return v
+}
+
+// readResources reports limits of the unit which were changed on the host, and returns its cgroup
+func readResources(ctx context.Context, client *common.Client, name string, model *ResourceControlModel, diags *diag.Diagnostics) types.Object {
+	if !common.HasCgroup(name) {
+		return types.ObjectNull(cgroupAttrTypes)
+	}
+	resources, err := client.SystemdGetUnitResources(ctx, name)
+	if err != nil {
+		diags.AddError("Failed to read resource limits", fmt.Sprintf("Unable to read the cgroup of unit %s. Unexpected error: %s", name, err))
+		return types.ObjectNull(cgroupAttrTypes)
+	}
+	model.fromResponse(resources)
+	return cgroupValue(resources, diags)
+}
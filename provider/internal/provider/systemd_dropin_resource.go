@@ -0,0 +1,397 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                     = &SystemdDropInResource{}
+	_ resource.ResourceWithImportState      = &SystemdDropInResource{}
+	_ resource.ResourceWithModifyPlan       = &SystemdDropInResource{}
+	_ resource.ResourceWithConfigValidators = &SystemdDropInResource{}
+)
+
+type SystemdDropInResource struct {
+	clients *clientPool
+}
+
+type SystemdDropInResourceModel struct {
+	ID              types.String   `tfsdk:"id"`
+	Host            types.String   `tfsdk:"host"`
+	UnitName        types.String   `tfsdk:"unit_name"`
+	Name            types.String   `tfsdk:"name"`
+	Content         types.String   `tfsdk:"content"`
+	Unit            types.Map      `tfsdk:"unit"`
+	Service         types.Map      `tfsdk:"service"`
+	RestartOnChange types.Bool     `tfsdk:"restart_on_change"`
+	Path            types.String   `tfsdk:"path"`
+	ShadowedBy      types.List     `tfsdk:"shadowed_by"`
+	Timeouts        timeouts.Value `tfsdk:"timeouts"`
+}
+
+func NewSystemdDropInResource() resource.Resource {
+	return &SystemdDropInResource{}
+}
+
+func (r *SystemdDropInResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *SystemdDropInResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_systemd_dropin"
+}
+
+func (r *SystemdDropInResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A drop-in which overrides settings of a unit, written to <unit>.d/<name>.conf in the agent's unit directory. " +
+			"systemd is reloaded whenever the drop-in is written or deleted. Settings which other drop-ins override are reported as warnings.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The unit and drop-in names, separated by a slash, e.g. nginx.service/override",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"unit_name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the unit to override, e.g. nginx.service",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxUnitNameLength),
+					stringvalidator.RegexMatches(common.UnitNamePattern, "must be a unit name with a type suffix, such as example.service"),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required: true,
+				Description: "Name of the drop-in, without the .conf suffix, e.g. 50-limits. " +
+					"systemd applies the drop-ins of a unit in the order of their names.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxDropInNameLength),
+					stringvalidator.RegexMatches(common.DropInNamePattern, "must be a file name without slashes, such as override"),
+				},
+			},
+			"content": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: "Raw content of the drop-in. Conflicts with the unit and service sections, " +
+					"when they are used this is the rendered drop-in.",
+				Validators: []validator.String{
+					unitFileValidator{},
+				},
+			},
+			"unit":    unitSectionAttribute("Unit"),
+			"service": unitSectionAttribute("Service"),
+			"restart_on_change": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Restart the unit, if it is active, whenever the drop-in is written or deleted.",
+			},
+			"path": schema.StringAttribute{
+				Computed:    true,
+				Description: "Location of the drop-in on the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"shadowed_by": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Settings of the drop-in which other drop-ins override, as <path>: [<section>] <key>",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *SystemdDropInResource) ConfigValidators(_ context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.AtLeastOneOf(path.MatchRoot("content"), path.MatchRoot("unit"), path.MatchRoot("service")),
+		resourcevalidator.Conflicting(path.MatchRoot("content"), path.MatchRoot("unit")),
+		resourcevalidator.Conflicting(path.MatchRoot("content"), path.MatchRoot("service")),
+	}
+}
+
+func (r *SystemdDropInResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics) ||
+		!requireModule(ctx, client, common.ModuleUnitFiles, &resp.Diagnostics) {
+		return
+	}
+
+	var plan SystemdDropInResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || !structuredSections(plan.sections()) {
+		return
+	}
+	content, known := renderSections(ctx, plan.sections(), &resp.Diagnostics)
+	if known {
+		plan.Content = types.StringValue(content)
+	} else {
+		plan.Content = types.StringUnknown()
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content"), plan.Content)...)
+}
+
+func (r *SystemdDropInResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan SystemdDropInResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	unit, name := plan.UnitName.ValueString(), plan.Name.ValueString()
+	// Refuse to overwrite a drop-in written by hand or by another configuration, it should be imported instead
+	_, err := client.SystemdGetDropIn(ctx, unit, name)
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Drop-in already exists",
+			fmt.Sprintf("The drop-in %s of unit %s already exists on the host. Import it to manage it with Terraform.", name, unit))
+		return
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to create drop-in", fmt.Sprintf("Unable to check for an existing drop-in %s of unit %s. Unexpected error: %s", name, unit, err))
+		return
+	}
+
+	tflog.Debug(ctx, "Writing drop-in", map[string]any{"unit": unit, "name": name})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if plan.RestartOnChange.ValueBool() {
+		restartIfActive(ctx, client, unit, &resp.Diagnostics)
+	}
+}
+
+func (r *SystemdDropInResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state SystemdDropInResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	unit, name, _ := strings.Cut(state.ID.ValueString(), "/")
+	tflog.Debug(ctx, "Fetching drop-in", map[string]any{"id": state.ID.ValueString()})
+	dropIn, err := client.SystemdGetDropIn(ctx, unit, name)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Drop-in no longer exists, removing from state", map[string]any{"id": state.ID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read drop-in", fmt.Sprintf("Unable to read drop-in %s of unit %s. Unexpected error: %s", name, unit, err))
+		return
+	}
+
+	if structuredSections(state.sections()) && state.Content.ValueString() != dropIn.Content {
+		sectionsFromContent(ctx, dropIn.Content, state.sections(), &resp.Diagnostics)
+	}
+	state.fromResponse(ctx, dropIn, &resp.Diagnostics)
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdDropInResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state SystemdDropInResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing drop-in", map[string]any{"id": plan.ID.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if plan.RestartOnChange.ValueBool() && plan.Content.ValueString() != state.Content.ValueString() {
+		restartIfActive(ctx, client, plan.UnitName.ValueString(), &resp.Diagnostics)
+	}
+}
+
+func (r *SystemdDropInResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state SystemdDropInResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	unit, name := state.UnitName.ValueString(), state.Name.ValueString()
+	tflog.Debug(ctx, "Deleting drop-in", map[string]any{"id": state.ID.ValueString()})
+	err := client.SystemdDeleteDropIn(ctx, unit, name)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete drop-in", fmt.Sprintf("Unable to delete drop-in %s of unit %s. Unexpected error: %s", name, unit, err))
+		return
+	}
+	if state.RestartOnChange.ValueBool() {
+		restartIfActive(ctx, client, unit, &resp.Diagnostics)
+	}
+}
+
+func (r *SystemdDropInResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostCompositeID(ctx, req, resp, 2)
+}
+
+// write creates or replaces the drop-in with the planned content, and records the result in plan
+func (r *SystemdDropInResource) write(ctx context.Context, client *common.Client, plan *SystemdDropInResourceModel, diags *diag.Diagnostics) {
+	content := plan.Content.ValueString()
+	if structuredSections(plan.sections()) {
+		content, _ = renderSections(ctx, plan.sections(), diags)
+		if diags.HasError() {
+			return
+		}
+	}
+	dropIn, err := client.SystemdWriteDropIn(ctx, plan.UnitName.ValueString(), plan.Name.ValueString(), content)
+	if err != nil {
+		diags.AddError("Failed to write drop-in", fmt.Sprintf("Unable to write drop-in. Unexpected error: %s", err))
+		return
+	}
+	plan.fromResponse(ctx, dropIn, diags)
+}
+
+// sections returns the attributes of the structured schema, in the order they are written
+func (m *SystemdDropInResourceModel) sections() []unitSection {
+	return []unitSection{{"Unit", &m.Unit}, {"Service", &m.Service}}
+}
+
+// fromResponse records the drop-in in the model, warning about any of its settings which are overridden
+func (m *SystemdDropInResourceModel) fromResponse(ctx context.Context, dropIn common.DropInResponse, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(dropIn.Unit + "/" + dropIn.Name)
+	m.UnitName = types.StringValue(dropIn.Unit)
+	m.Name = types.StringValue(dropIn.Name)
+	m.Content = types.StringValue(dropIn.Content)
+	m.Path = types.StringValue(dropIn.Path)
+
+	shadowed := make([]string, 0, len(dropIn.Shadowed))
+	for _, s := range dropIn.Shadowed {
+		shadowed = append(shadowed, fmt.Sprintf("%s: [%s] %s", s.Path, s.Section, s.Key))
+	}
+	v, d := types.ListValueFrom(ctx, types.StringType, shadowed)
+	diags.Append(d...)
+	m.ShadowedBy = v
+	if len(shadowed) > 0 {
+		diags.AddWarning("Drop-in settings are overridden",
+			fmt.Sprintf("systemd applies other drop-ins of %s after %s, which set these keys again:\n\n%s\n\n"+
+				"Rename the drop-in so that it sorts after them, or remove the settings from the other drop-ins.",
+				dropIn.Unit, dropIn.Path, strings.Join(shadowed, "\n")))
+	}
+}
+
+// restartIfActive restarts the unit so that a changed drop-in takes effect, units which aren't running are left alone
+func restartIfActive(ctx context.Context, client *common.Client, unit string, diags *diag.Diagnostics) {
+	state, err := client.SystemdGetUnitState(ctx, unit)
+	if errors.Is(err, common.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		diags.AddError("Failed to restart unit", fmt.Sprintf("Unable to read the state of unit %s. Unexpected error: %s", unit, err))
+		return
+	}
+	if !state.Active {
+		return
+	}
+	tflog.Debug(ctx, "Restarting unit, as its drop-in changed", map[string]any{"name": unit})
+	if _, err := client.SystemdRestartUnit(ctx, unit); err != nil {
+		diags.AddError("Failed to restart unit", unitFailureDetail(fmt.Sprintf("Unable to restart unit %s: %s", unit, err), err))
+	}
+}
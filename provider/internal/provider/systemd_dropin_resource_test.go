@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+func TestAccSystemdDropInResource(t *testing.T) {
+	config := func(memory string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_systemd_dropin" "test" {
+		  unit_name         = "nginx.service"
+		  name              = "50-limits"
+		  restart_on_change = true
+
+		  service = {
+		    MemoryMax = "%s"
+		    TasksMax  = "100"
+		  }
+		}
+		`, memory)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled",
+			})
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, ok := testAgent.Systemd.DropIn("nginx.service", "50-limits"); ok {
+				return fmt.Errorf("drop-in 50-limits still exists")
+			}
+			// Restarted after being written twice and deleted
+			if restarts := testAgent.Systemd.Restarts("nginx.service"); restarts != 3 {
+				return fmt.Errorf("expected nginx.service to be restarted 3 times, got %d", restarts)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("512M"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_dropin.test", "id", "nginx.service/50-limits"),
+					resource.TestCheckResourceAttr("linux_systemd_dropin.test", "path", "/etc/systemd/system/nginx.service.d/50-limits.conf"),
+					resource.TestCheckResourceAttr("linux_systemd_dropin.test", "shadowed_by.#", "0"),
+					testAccCheckDropIn("nginx.service", "50-limits", "[Service]\nMemoryMax=512M\nTasksMax=100\n"),
+					testAccCheckUnitRestarts("nginx.service", 1),
+				),
+			},
+			{
+				ResourceName:            "linux_systemd_dropin.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"service", "restart_on_change"},
+			},
+			{
+				// An unmanaged drop-in which sorts later overrides MemoryMax
+				PreConfig: func() {
+					testAgent.Systemd.SetDropIn("nginx.service", "90-local", "[Service]\nMemoryMax=2G\n")
+				},
+				Config: config("1G"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_dropin.test", "shadowed_by.#", "1"),
+					resource.TestCheckResourceAttr("linux_systemd_dropin.test", "shadowed_by.0",
+						"/etc/systemd/system/nginx.service.d/90-local.conf: [Service] MemoryMax"),
+					testAccCheckDropIn("nginx.service", "50-limits", "[Service]\nMemoryMax=1G\nTasksMax=100\n"),
+					testAccCheckUnitRestarts("nginx.service", 2),
+				),
+			},
+		},
+	})
+}
+
+func TestAccSystemdDropInResourceDrift(t *testing.T) {
+	config := providerConfig() + `
+	resource "linux_systemd_dropin" "test" {
+	  unit_name = "example.service"
+	  name      = "override"
+	  content   = "[Service]\nEnvironment=DEBUG=1\n"
+	}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				// restart_on_change isn't set, and the unit isn't loaded anyway
+				Check: testAccCheckUnitRestarts("example.service", 0),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Systemd.SetDropIn("example.service", "override", "[Service]\nEnvironment=DEBUG=0\n")
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check:  testAccCheckDropIn("example.service", "override", "[Service]\nEnvironment=DEBUG=1\n"),
+			},
+			{
+				ResourceName:  "linux_systemd_dropin.test",
+				ImportState:   true,
+				ImportStateId: "example.service",
+				ExpectError:   regexp.MustCompile("Unexpected Import Identifier"),
+			},
+		},
+	})
+}
+
+func TestAccSystemdDropInResourceInvalid(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_dropin" "test" {
+				  unit_name = "example.service"
+				  name      = "../override"
+				  content   = "[Service]\nEnvironment=DEBUG=1\n"
+				}
+				`,
+				ExpectError: regexp.MustCompile("must be a file name"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Systemd.SetDropIn("example.service", "override", "[Service]\nEnvironment=DEBUG=0\n")
+				},
+				Config: providerConfig() + `
+				resource "linux_systemd_dropin" "test" {
+				  unit_name = "example.service"
+				  name      = "override"
+				  content   = "[Service]\nEnvironment=DEBUG=1\n"
+				}
+				`,
+				ExpectError: regexp.MustCompile("Drop-in already exists"),
+			},
+		},
+	})
+}
+
+// testAccCheckDropIn checks the content of a drop-in on the test agent
+func testAccCheckDropIn(unit string, name string, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		content, ok := testAgent.Systemd.DropIn(unit, name)
+		if !ok {
+			return fmt.Errorf("drop-in %s of unit %s does not exist", name, unit)
+		}
+		if content != expected {
+			return fmt.Errorf("expected drop-in %s of unit %s to contain %q, got %q", name, unit, expected, content)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,379 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                   = &SystemdServiceResource{}
+	_ resource.ResourceWithImportState    = &SystemdServiceResource{}
+	_ resource.ResourceWithModifyPlan     = &SystemdServiceResource{}
+	_ resource.ResourceWithValidateConfig = &SystemdServiceResource{}
+)
+
+type SystemdServiceResource struct {
+	clients *clientPool
+}
+
+type SystemdServiceResourceModel struct {
+	ID            types.String `tfsdk:"id"`
+	Host          types.String `tfsdk:"host"`
+	Name          types.String `tfsdk:"name"`
+	Enabled       types.Bool   `tfsdk:"enabled"`
+	Active        types.Bool   `tfsdk:"active"`
+	Masked        types.Bool   `tfsdk:"masked"`
+	Triggers      types.Map    `tfsdk:"triggers"`
+	ActiveState   types.String `tfsdk:"active_state"`
+	SubState      types.String `tfsdk:"sub_state"`
+	UnitFileState types.String `tfsdk:"unit_file_state"`
+	// ResourceControl is nil if no limits are configured
+	ResourceControl *ResourceControlModel `tfsdk:"resource_control"`
+	Cgroup          types.Object          `tfsdk:"cgroup"`
+	Timeouts        timeouts.Value        `tfsdk:"timeouts"`
+}
+
+func NewSystemdServiceResource() resource.Resource {
+	return &SystemdServiceResource{}
+}
+
+func (r *SystemdServiceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *SystemdServiceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_systemd_service"
+}
+
+func (r *SystemdServiceResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	state := func(description string) schema.BoolAttribute {
+		return schema.BoolAttribute{
+			Optional:    true,
+			Computed:    true,
+			Description: description + " If not set, the current state is left as it is and reported.",
+			PlanModifiers: []planmodifier.Bool{
+				boolplanmodifier.UseStateForUnknown(),
+			},
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "The enablement and activation state of a systemd unit, which may be a vendor unit or one managed by linux_systemd_unit. " +
+			"Destroying the resource leaves the unit in its current state, apart from resetting the limits set by resource_control.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the unit",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the unit, including its type suffix, e.g. nginx.service",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxUnitNameLength),
+					stringvalidator.RegexMatches(common.UnitNamePattern, "must be a unit name with a type suffix, such as example.service"),
+				},
+			},
+			"enabled": state("Whether the unit is started at boot, or by whichever unit it is installed into."),
+			"active":  state("Whether the unit is running. Starting a unit waits for it to finish starting."),
+			"masked":  state("Whether the unit is masked, so that it cannot be started. Masking a running unit stops it. Conflicts with enabled and active being true."),
+			"triggers": schema.MapAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Arbitrary values which restart the unit when they change, if it is active, e.g. the content of its configuration file.",
+			},
+			"active_state": schema.StringAttribute{
+				Computed:    true,
+				Description: "Activation state of the unit, e.g. active, inactive or failed",
+			},
+			"sub_state": schema.StringAttribute{
+				Computed:    true,
+				Description: "Type specific activation state of the unit, e.g. running or exited",
+			},
+			"unit_file_state": schema.StringAttribute{
+				Computed:    true,
+				Description: "Enablement state of the unit file, e.g. enabled, disabled, static or masked",
+			},
+			"resource_control": resourceControlAttribute(),
+			"cgroup":           cgroupAttribute(),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *SystemdServiceResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config SystemdServiceResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if name := config.Name; config.ResourceControl != nil && !name.IsUnknown() && !common.HasCgroup(name.ValueString()) {
+		resp.Diagnostics.AddAttributeError(path.Root("resource_control"), "Invalid Resource Control",
+			fmt.Sprintf("%s has no cgroup, resource limits can only be set on service, slice, scope, socket, mount and swap units.", name.ValueString()))
+	}
+	if !config.Masked.ValueBool() {
+		return
+	}
+	for name, v := range map[string]types.Bool{"enabled": config.Enabled, "active": config.Active} {
+		if v.ValueBool() {
+			resp.Diagnostics.AddAttributeError(path.Root(name), "Invalid Unit State",
+				fmt.Sprintf("A masked unit cannot be %s, set %s to false or remove it.", name, name))
+		}
+	}
+}
+
+func (r *SystemdServiceResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client != nil {
+		requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics)
+	}
+}
+
+func (r *SystemdServiceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan SystemdServiceResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := plan.Name.ValueString()
+	_, err := client.SystemdGetUnitState(ctx, name)
+	if errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Unit not found",
+			fmt.Sprintf("systemd has no unit %s on the host. Install it, or write its unit file with linux_systemd_unit first.", name))
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to manage unit", fmt.Sprintf("Unable to read the state of unit %s. Unexpected error: %s", name, err))
+		return
+	}
+
+	// Limits are set first, so that a unit which is started runs within them from the beginning
+	applyResourceControl(ctx, client, name, plan.ResourceControl, nil, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Debug(ctx, "Setting unit state", map[string]any{"name": name})
+	unit, err := client.SystemdSetUnitState(ctx, name, plan.request())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to change unit state", unitFailureDetail(fmt.Sprintf("Unable to change the state of unit %s: %s", name, err), err))
+		return
+	}
+
+	plan.ID = types.StringValue(name)
+	plan.fromResponse(unit)
+	plan.Cgroup = readResources(ctx, client, name, nil, &resp.Diagnostics)
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdServiceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state SystemdServiceResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := state.ID.ValueString()
+	tflog.Debug(ctx, "Fetching unit state", map[string]any{"id": name})
+	unit, err := client.SystemdGetUnitState(ctx, name)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Unit no longer exists, removing from state", map[string]any{"id": name})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read unit state", fmt.Sprintf("Unable to read the state of unit %s. Unexpected error: %s", name, err))
+		return
+	}
+
+	state.Name = types.StringValue(unit.Name)
+	state.fromResponse(unit)
+	state.Cgroup = readResources(ctx, client, name, state.ResourceControl, &resp.Diagnostics)
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdServiceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state SystemdServiceResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := plan.Name.ValueString()
+	applyResourceControl(ctx, client, name, plan.ResourceControl, state.ResourceControl, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Debug(ctx, "Setting unit state", map[string]any{"name": name})
+	unit, err := client.SystemdSetUnitState(ctx, name, plan.request())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to change unit state", unitFailureDetail(fmt.Sprintf("Unable to change the state of unit %s: %s", name, err), err))
+		return
+	}
+
+	// A unit which was only just started already runs with the new configuration
+	if !plan.Triggers.Equal(state.Triggers) && unit.Active && state.Active.ValueBool() {
+		tflog.Debug(ctx, "Restarting unit, as its triggers changed", map[string]any{"name": name})
+		unit, err = client.SystemdRestartUnit(ctx, name)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to restart unit", unitFailureDetail(fmt.Sprintf("Unable to restart unit %s: %s", name, err), err))
+			return
+		}
+	}
+
+	plan.fromResponse(unit)
+	plan.Cgroup = readResources(ctx, client, name, nil, &resp.Diagnostics)
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete only forgets the unit, stopping or disabling it would be surprising for vendor units
+// Resource limits are reset though, since they were only ever set by Terraform.
+func (r *SystemdServiceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state SystemdServiceResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	name := state.ID.ValueString()
+	tflog.Debug(ctx, "Leaving unit in its current state", map[string]any{"id": name})
+	if state.ResourceControl == nil {
+		return
+	}
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+	request, changed := resourceControlRequest(nil, state.ResourceControl)
+	if !changed {
+		return
+	}
+	tflog.Debug(ctx, "Resetting resource limits", map[string]any{"id": name, "runtime": request.Runtime})
+	_, err := client.SystemdSetUnitResources(ctx, name, request)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to reset resource limits", fmt.Sprintf("Unable to reset the resource limits of unit %s. Unexpected error: %s", name, err))
+	}
+}
+
+func (r *SystemdServiceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// request returns the configured state, attributes which are not set are left as they are
+func (m *SystemdServiceResourceModel) request() common.UnitStateRequest {
+	field := func(v types.Bool) *bool {
+		if v.IsNull() || v.IsUnknown() {
+			return nil
+		}
+		b := v.ValueBool()
+		return &b
+	}
+	return common.UnitStateRequest{
+		Enabled: field(m.Enabled),
+		Active:  field(m.Active),
+		Masked:  field(m.Masked),
+	}
+}
+
+func (m *SystemdServiceResourceModel) fromResponse(unit common.UnitStateResponse) {
+	m.Enabled = types.BoolValue(unit.Enabled)
+	m.Active = types.BoolValue(unit.Active)
+	m.Masked = types.BoolValue(unit.Masked)
+	m.ActiveState = types.StringValue(unit.ActiveState)
+	m.SubState = types.StringValue(unit.SubState)
+	m.UnitFileState = types.StringValue(unit.UnitFileState)
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+func TestAccSystemdServiceResource(t *testing.T) {
+	config := func(version string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_systemd_unit" "test" {
+		  name    = "example.service"
+		  content = "[Service]\nExecStart=/usr/bin/example\n\n[Install]\nWantedBy=multi-user.target\n"
+		}
+
+		resource "linux_systemd_service" "test" {
+		  name    = linux_systemd_unit.test.name
+		  enabled = true
+		  active  = true
+
+		  triggers = {
+		    config = "%s"
+		  }
+		}
+		`, version)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("v1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "id", "example.service"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "masked", "false"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "active_state", "active"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "sub_state", "running"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "unit_file_state", "enabled"),
+					testAccCheckUnitRestarts("example.service", 0),
+				),
+			},
+			{
+				ResourceName:            "linux_systemd_service.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"triggers"},
+			},
+			{
+				Config: config("v2"),
+				Check:  testAccCheckUnitRestarts("example.service", 1),
+			},
+			{
+				// A unit stopped on the host is started again
+				PreConfig: func() {
+					state, _ := testAgent.Systemd.UnitState("example.service")
+					state.ActiveState, state.SubState = "inactive", "dead"
+					testAgent.Systemd.AddUnit(state)
+				},
+				Config: config("v2"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "active_state", "active"),
+					testAccCheckUnitRestarts("example.service", 1),
+				),
+			},
+		},
+	})
+}
+
+func TestAccSystemdServiceResourceVendorUnit(t *testing.T) {
+	config := providerConfig() + `
+	resource "linux_systemd_service" "test" {
+	  name   = "ssh.service"
+	  masked = true
+	}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "ssh.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled",
+			})
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// Unset attributes report the unit's state
+				Config: providerConfig() + `
+				resource "linux_systemd_service" "stopped" {
+				  name   = "ssh.service"
+				  active = false
+				}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_service.stopped", "active", "false"),
+					resource.TestCheckResourceAttr("linux_systemd_service.stopped", "enabled", "true"),
+				),
+			},
+			{
+				// Masking a running unit stops it
+				PreConfig: func() {
+					state, _ := testAgent.Systemd.UnitState("ssh.service")
+					state.ActiveState, state.SubState = "active", "running"
+					testAgent.Systemd.AddUnit(state)
+				},
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "unit_file_state", "masked"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "active", "false"),
+					func(*terraform.State) error {
+						// Destroying the resource leaves the unit as it is
+						if _, ok := testAgent.Systemd.UnitState("ssh.service"); !ok {
+							return fmt.Errorf("expected ssh.service to still be loaded")
+						}
+						return nil
+					},
+				),
+			},
+		},
+		CheckDestroy: func(*terraform.State) error {
+			state, ok := testAgent.Systemd.UnitState("ssh.service")
+			if !ok || !state.Masked() {
+				return fmt.Errorf("expected ssh.service to be left masked, got %+v", state)
+			}
+			return nil
+		},
+	})
+}
+
+func TestAccSystemdServiceResourceFailure(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "broken.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "static",
+			})
+			testAgent.Systemd.FailUnit("broken.service", "exit-code")
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_service" "test" {
+				  name   = "broken.service"
+				  active = true
+				}
+				`,
+				ExpectError: regexp.MustCompile(`(?s)unit result exit-code.*Last journal lines:.*status=1/FAILURE`),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_service" "test" {
+				  name   = "missing.service"
+				  active = true
+				}
+				`,
+				ExpectError: regexp.MustCompile("Unit not found"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_service" "test" {
+				  name    = "broken.service"
+				  masked  = true
+				  enabled = true
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Unit State"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_service" "test" {
+				  name             = "backup.timer"
+				  resource_control = { memory_max = "512M" }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Resource Control"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_service" "test" {
+				  name             = "broken.service"
+				  resource_control = { cpu_quota = "150" }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid CPU Quota"),
+			},
+		},
+	})
+}
+
+func TestAccSystemdServiceResourceResourceControl(t *testing.T) {
+	config := func(resourceControl string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_systemd_service" "test" {
+		  name             = "nginx.service"
+		  resource_control = %s
+		}
+		`, resourceControl)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled",
+			})
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			return testAccCheckResources("nginx.service", common.Infinity, common.Infinity, common.Infinity, true)(nil)
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config(`{ memory_max = "512M", cpu_quota = "150%", io_weight = 200 }`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "resource_control.memory_max", "512M"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "cgroup.path", "/system.slice/nginx.service"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "cgroup.memory_current", "8388608"),
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "cgroup.tasks_current", "1"),
+					testAccCheckResources("nginx.service", 512<<20, 1500000, 200, false),
+				),
+			},
+			{
+				ResourceName:            "linux_systemd_service.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"resource_control"},
+			},
+			{
+				// Limits changed on the host are set again
+				PreConfig: func() {
+					resources, _ := testAgent.Systemd.Resources("nginx.service")
+					resources.MemoryMax = 1 << 30
+					testAgent.Systemd.SetResources(resources)
+				},
+				Config: config(`{ memory_max = "512M", cpu_quota = "150%", io_weight = 200 }`),
+				Check:  testAccCheckResources("nginx.service", 512<<20, 1500000, 200, false),
+			},
+			{
+				// Removed limits are reset, and runtime limits replace the persisted ones
+				Config: config(`{ memory_max = "1.5G", runtime = true }`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_service.test", "resource_control.memory_max", "1.5G"),
+					testAccCheckResources("nginx.service", 1536<<20, common.Infinity, common.Infinity, true),
+				),
+			},
+			{
+				Config: config("null"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("linux_systemd_service.test", "resource_control"),
+					testAccCheckResources("nginx.service", common.Infinity, common.Infinity, common.Infinity, true),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckResources checks the cgroup limits of a unit on the test agent, and whether they were set at runtime
+func testAccCheckResources(name string, memoryMax uint64, cpuQuota uint64, ioWeight uint64, runtime bool) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		resources, actualRuntime := testAgent.Systemd.Resources(name)
+		if resources.MemoryMax != memoryMax || resources.CPUQuotaPerSecUSec != cpuQuota || resources.IOWeight != ioWeight {
+			return fmt.Errorf("expected %s to have MemoryMax %d, CPUQuotaPerSecUSec %d and IOWeight %d, got %+v", name, memoryMax, cpuQuota, ioWeight, resources)
+		}
+		if actualRuntime != runtime {
+			return fmt.Errorf("expected the limits of %s to be set with runtime %t", name, runtime)
+		}
+		return nil
+	}
+}
+
+// testAccCheckUnitRestarts checks how many times a unit has been restarted on the test agent
+func testAccCheckUnitRestarts(name string, expected int) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if restarts := testAgent.Systemd.Restarts(name); restarts != expected {
+			return fmt.Errorf("expected %s to be restarted %d times, got %d", name, expected, restarts)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,403 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                = &SystemdSliceResource{}
+	_ resource.ResourceWithImportState = &SystemdSliceResource{}
+	_ resource.ResourceWithModifyPlan  = &SystemdSliceResource{}
+)
+
+type SystemdSliceResource struct {
+	clients *clientPool
+}
+
+type SystemdSliceResourceModel struct {
+	ID          types.String `tfsdk:"id"`
+	Host        types.String `tfsdk:"host"`
+	Name        types.String `tfsdk:"name"`
+	Description types.String `tfsdk:"description"`
+	// ResourceControl is nil if no limits are configured
+	ResourceControl *ResourceControlModel `tfsdk:"resource_control"`
+	Unit            types.String          `tfsdk:"unit"`
+	Content         types.String          `tfsdk:"content"`
+	Path            types.String          `tfsdk:"path"`
+	ActiveState     types.String          `tfsdk:"active_state"`
+	Cgroup          types.Object          `tfsdk:"cgroup"`
+	Timeouts        timeouts.Value        `tfsdk:"timeouts"`
+}
+
+// sliceNamePattern matches a slice name without its suffix, where each dash separated part names a parent slice
+var sliceNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.]+(-[A-Za-z0-9:_.]+)*$`)
+
+func NewSystemdSliceResource() resource.Resource {
+	return &SystemdSliceResource{}
+}
+
+func (r *SystemdSliceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *SystemdSliceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_systemd_slice"
+}
+
+func (r *SystemdSliceResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "A systemd slice, written as <name>.slice in the agent's unit directory and started, which groups units so that their resources are limited together. " +
+			"Units are placed in the slice with Slice=<name>.slice in their [Service] section. " +
+			"Destroying the resource resets the slice's limits and deletes its unit file, but leaves it running until the units in it stop, since stopping it would stop them too.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the slice",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the slice without its suffix, e.g. app. Dashes nest slices, so app-web is placed within app.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxUnitNameLength - len(".slice")),
+					stringvalidator.RegexMatches(sliceNamePattern, "must be a slice name without a type suffix, such as app or app-web"),
+				},
+			},
+			"description": schema.StringAttribute{
+				Optional:    true,
+				Description: "Description of the slice",
+			},
+			"resource_control": resourceControlAttribute(),
+			"unit": schema.StringAttribute{
+				Computed:    true,
+				Description: "Name of the slice unit, e.g. app.slice, for the Slice= setting of the units placed in it",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"content":      computed("Rendered content of the slice unit file"),
+			"path":         computed("Path of the slice unit file on the host"),
+			"active_state": computed("Activation state of the slice, e.g. active"),
+			"cgroup":       cgroupAttribute(),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *SystemdSliceResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics) {
+		return
+	}
+
+	var plan SystemdSliceResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Name.IsNull() {
+		return
+	}
+	// Always plan the rendered content, so that a file edited on the host is rewritten even if the description hasn't changed
+	content, known := plan.render()
+	plan.Content = types.StringUnknown()
+	if known {
+		plan.Content = types.StringValue(content)
+	}
+	if !plan.Name.IsUnknown() {
+		plan.Unit = types.StringValue(plan.sliceUnit())
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content"), plan.Content)...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("unit"), plan.Unit)...)
+}
+
+func (r *SystemdSliceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan SystemdSliceResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	// Refuse to overwrite a slice written by hand or by another configuration, it should be imported instead
+	name := plan.sliceUnit()
+	_, err := client.SystemdGetUnitFile(ctx, name)
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Unit file already exists",
+			fmt.Sprintf("The unit file %s already exists on the host. Import the slice to manage it with Terraform.", name))
+		return
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to create slice", fmt.Sprintf("Unable to check for an existing unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+
+	tflog.Debug(ctx, "Writing slice", map[string]any{"name": name})
+	r.write(ctx, client, &plan, nil, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdSliceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state SystemdSliceResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	state.Name = state.ID
+	name := state.sliceUnit()
+	tflog.Debug(ctx, "Fetching slice", map[string]any{"id": state.ID.ValueString()})
+	file, err := client.SystemdGetUnitFile(ctx, name)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Slice unit file no longer exists, removing from state", map[string]any{"id": state.ID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read slice", fmt.Sprintf("Unable to read unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+	if state.Content.ValueString() != file.Content {
+		// Report the drift in terms of the description, other keys only show up in the content
+		state.fromContent(ctx, file.Content)
+	}
+	state.Unit = types.StringValue(name)
+	state.Content = types.StringValue(file.Content)
+	state.Path = types.StringValue(file.Path)
+
+	r.readState(ctx, client, &state, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdSliceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state SystemdSliceResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing slice", map[string]any{"name": plan.sliceUnit()})
+	r.write(ctx, client, &plan, state.ResourceControl, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete leaves the slice running, since stopping it would stop every unit in it
+func (r *SystemdSliceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state SystemdSliceResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := state.sliceUnit()
+	// Persisted limits would otherwise apply again to a slice of the same name
+	if request, changed := resourceControlRequest(nil, state.ResourceControl); changed {
+		tflog.Debug(ctx, "Resetting resource limits", map[string]any{"id": state.ID.ValueString(), "runtime": request.Runtime})
+		_, err := client.SystemdSetUnitResources(ctx, name, request)
+		if err != nil && !errors.Is(err, common.ErrNotFound) {
+			resp.Diagnostics.AddError("Failed to delete slice", fmt.Sprintf("Unable to reset the resource limits of slice %s. Unexpected error: %s", name, err))
+			return
+		}
+	}
+
+	tflog.Debug(ctx, "Deleting slice", map[string]any{"id": state.ID.ValueString()})
+	err := client.SystemdDeleteUnitFile(ctx, name)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete slice", fmt.Sprintf("Unable to delete unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+}
+
+func (r *SystemdSliceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// write creates or replaces the slice unit file, sets its limits and starts it
+// The limits are set before the slice is started, so that units in it never run without them.
+func (r *SystemdSliceResource) write(ctx context.Context, client *common.Client, plan *SystemdSliceResourceModel, previous *ResourceControlModel, diags *diag.Diagnostics) {
+	name := plan.sliceUnit()
+	content, _ := plan.render()
+	file, err := client.SystemdWriteUnitFile(ctx, name, content)
+	if err != nil {
+		diags.AddError("Failed to write slice", fmt.Sprintf("Unable to write unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+
+	applyResourceControl(ctx, client, name, plan.ResourceControl, previous, diags)
+	if diags.HasError() {
+		return
+	}
+
+	active := true
+	_, err = client.SystemdSetUnitState(ctx, name, common.UnitStateRequest{Active: &active})
+	if err != nil {
+		diags.AddError("Failed to start slice", unitFailureDetail(fmt.Sprintf("Unable to start slice %s: %s", name, err), err))
+		return
+	}
+
+	plan.ID = plan.Name
+	plan.Unit = types.StringValue(name)
+	plan.Content = types.StringValue(file.Content)
+	plan.Path = types.StringValue(file.Path)
+	r.readState(ctx, client, plan, diags)
+}
+
+// readState records the activation state of the slice and its cgroup, and reports limits changed on the host
+func (r *SystemdSliceResource) readState(ctx context.Context, client *common.Client, m *SystemdSliceResourceModel, diags *diag.Diagnostics) {
+	name := m.sliceUnit()
+	unit, err := client.SystemdGetUnitState(ctx, name)
+	if err != nil {
+		diags.AddError("Failed to read slice", fmt.Sprintf("Unable to read the state of slice %s. Unexpected error: %s", name, err))
+		return
+	}
+	m.ActiveState = types.StringValue(unit.ActiveState)
+	m.Cgroup = readResources(ctx, client, name, m.ResourceControl, diags)
+}
+
+func (m *SystemdSliceResourceModel) sliceUnit() string {
+	return m.Name.ValueString() + ".slice"
+}
+
+// render formats the slice unit file, returns false if the description is not yet known
+func (m *SystemdSliceResourceModel) render() (string, bool) {
+	// The section is written even if it is empty, since systemd treats an empty unit file as masked
+	section := common.UnitSection{Name: "Unit"}
+	if m.Description.IsUnknown() {
+		return "", false
+	}
+	if !m.Description.IsNull() {
+		section.Entries = append(section.Entries, common.UnitEntry{Key: "Description", Value: m.Description.ValueString()})
+	}
+	return common.RenderUnit([]common.UnitSection{section}), true
+}
+
+// fromContent replaces the description with the one parsed from the unit file
+func (m *SystemdSliceResourceModel) fromContent(ctx context.Context, content string) {
+	sections, err := common.ParseUnit(content)
+	if err != nil {
+		tflog.Warn(ctx, "Slice on the host is not a valid unit file, only reporting drift in its content", map[string]any{"error": err.Error()})
+		return
+	}
+	// The last assignment wins, as it does for systemd
+	m.Description = types.StringNull()
+	for _, s := range sections {
+		if values := s.Values("Description"); s.Name == "Unit" && len(values) > 0 {
+			m.Description = types.StringValue(values[len(values)-1])
+		}
+	}
+}
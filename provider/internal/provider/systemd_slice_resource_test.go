@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func TestAccSystemdSliceResource(t *testing.T) {
+	config := func(description string, memoryMax string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_systemd_slice" "test" {
+		  name        = "app-web"
+		  description = %q
+
+		  resource_control = {
+		    memory_max = %q
+		    tasks_max  = "512"
+		  }
+		}
+		`, description, memoryMax)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, ok := testAgent.Systemd.UnitFile("app-web.slice"); ok {
+				return fmt.Errorf("unit file app-web.slice still exists")
+			}
+			if resources, _ := testAgent.Systemd.Resources("app-web.slice"); resources.MemoryMax != common.Infinity || resources.TasksMax != common.Infinity {
+				return fmt.Errorf("expected the limits of app-web.slice to be reset, got %+v", resources)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					testAgent.Systemd.SetUnitFile("app-web.slice", "[Unit]\n")
+				},
+				Config:      config("Web applications", "2G"),
+				ExpectError: regexp.MustCompile("Unit file already exists"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Systemd.Reset()
+				},
+				Config: config("Web applications", "2G"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "id", "app-web"),
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "unit", "app-web.slice"),
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "path", "/etc/systemd/system/app-web.slice"),
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "content", "[Unit]\nDescription=Web applications\n"),
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "active_state", "active"),
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "cgroup.path", "/app-web.slice"),
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "cgroup.tasks_current", "1"),
+					testAccCheckSliceLimits("app-web.slice", 2<<30, 512),
+				),
+			},
+			{
+				ResourceName:            "linux_systemd_slice.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"resource_control"},
+			},
+			{
+				Config: config("Web applications", "4G"),
+				Check:  testAccCheckSliceLimits("app-web.slice", 4<<30, 512),
+			},
+			{
+				// Changes made on the host are reverted
+				PreConfig: func() {
+					testAgent.Systemd.SetUnitFile("app-web.slice", "[Unit]\nDescription=Edited\n")
+					resources, _ := testAgent.Systemd.Resources("app-web.slice")
+					resources.TasksMax = common.Infinity
+					testAgent.Systemd.SetResources(resources)
+				},
+				Config: config("Web applications", "4G"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_slice.test", "description", "Web applications"),
+					testAccCheckSliceLimits("app-web.slice", 4<<30, 512),
+					func(*terraform.State) error {
+						if content, _ := testAgent.Systemd.UnitFile("app-web.slice"); content != "[Unit]\nDescription=Web applications\n" {
+							return fmt.Errorf("expected app-web.slice to be written again, got %q", content)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckSliceLimits checks the memory and tasks limits of a slice on the test agent
+func testAccCheckSliceLimits(name string, memoryMax uint64, tasksMax uint64) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		resources, _ := testAgent.Systemd.Resources(name)
+		if resources.MemoryMax != memoryMax || resources.TasksMax != tasksMax {
+			return fmt.Errorf("expected %s to have MemoryMax %d and TasksMax %d, got %+v", name, memoryMax, tasksMax, resources)
+		}
+		return nil
+	}
+}
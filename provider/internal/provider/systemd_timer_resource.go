@@ -0,0 +1,679 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                     = &SystemdTimerResource{}
+	_ resource.ResourceWithImportState      = &SystemdTimerResource{}
+	_ resource.ResourceWithModifyPlan       = &SystemdTimerResource{}
+	_ resource.ResourceWithConfigValidators = &SystemdTimerResource{}
+)
+
+type SystemdTimerResource struct {
+	clients *clientPool
+}
+
+type SystemdTimerResourceModel struct {
+	ID                 types.String       `tfsdk:"id"`
+	Host               types.String       `tfsdk:"host"`
+	Name               types.String       `tfsdk:"name"`
+	Description        types.String       `tfsdk:"description"`
+	OnCalendar         types.List         `tfsdk:"on_calendar"`
+	OnBootSec          types.String       `tfsdk:"on_boot_sec"`
+	OnStartupSec       types.String       `tfsdk:"on_startup_sec"`
+	OnActiveSec        types.String       `tfsdk:"on_active_sec"`
+	OnUnitActiveSec    types.String       `tfsdk:"on_unit_active_sec"`
+	OnUnitInactiveSec  types.String       `tfsdk:"on_unit_inactive_sec"`
+	Persistent         types.Bool         `tfsdk:"persistent"`
+	RandomizedDelaySec types.String       `tfsdk:"randomized_delay_sec"`
+	AccuracySec        types.String       `tfsdk:"accuracy_sec"`
+	Service            *TimerServiceModel `tfsdk:"service"`
+	TimerContent       types.String       `tfsdk:"timer_content"`
+	ServiceContent     types.String       `tfsdk:"service_content"`
+	NextElapse         types.String       `tfsdk:"next_elapse"`
+	LastTrigger        types.String       `tfsdk:"last_trigger"`
+	Timeouts           timeouts.Value     `tfsdk:"timeouts"`
+}
+
+// TimerServiceModel is the oneshot service which the timer starts
+type TimerServiceModel struct {
+	ExecStart        types.String `tfsdk:"exec_start"`
+	User             types.String `tfsdk:"user"`
+	Group            types.String `tfsdk:"group"`
+	WorkingDirectory types.String `tfsdk:"working_directory"`
+	Environment      types.Map    `tfsdk:"environment"`
+}
+
+// timerNamePattern matches the name shared by the timer and its service, which can't be a template
+var timerNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\-]+$`)
+
+var environmentNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// timerSpan is a time span key of the [Timer] section, with the attribute holding it
+type timerSpan struct {
+	key   string
+	value *types.String
+}
+
+func NewSystemdTimerResource() resource.Resource {
+	return &SystemdTimerResource{}
+}
+
+func (r *SystemdTimerResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *SystemdTimerResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_systemd_timer"
+}
+
+func (r *SystemdTimerResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	span := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Optional:    true,
+			Description: description + " A time span such as 90, 5min or 1h 30min.",
+			Validators: []validator.String{
+				timespanValidator{},
+			},
+		}
+	}
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "A systemd timer together with the service it starts, written as <name>.timer and <name>.service in the agent's unit directory. " +
+			"The timer is enabled and started, and is stopped and disabled when the resource is destroyed. Changes made to either file on the host are reported as drift. " +
+			"Values are written literally, escaping % so that systemd doesn't expand them as specifiers.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the timer",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the timer and its service, without a type suffix, e.g. backup",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxUnitNameLength - len(".service")),
+					stringvalidator.RegexMatches(timerNamePattern, "must be a unit name without a type suffix, such as backup"),
+				},
+			},
+			"description": schema.StringAttribute{
+				Optional:    true,
+				Description: "Description of the timer and its service",
+			},
+			"on_calendar": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Calendar events which start the service, e.g. daily or Mon..Fri *-*-* 09:00, see systemd.time(7)",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ValueStringsAre(calendarValidator{}),
+				},
+			},
+			"on_boot_sec":          span("Start the service this long after the machine booted."),
+			"on_startup_sec":       span("Start the service this long after systemd started."),
+			"on_active_sec":        span("Start the service this long after the timer was started."),
+			"on_unit_active_sec":   span("Start the service this long after it was last started."),
+			"on_unit_inactive_sec": span("Start the service this long after it last finished."),
+			"persistent": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Start the service immediately if a calendar event was missed while the timer was inactive, e.g. while the machine was off",
+			},
+			"randomized_delay_sec": span("Delay each start by a random time up to this long, to spread load across hosts."),
+			"accuracy_sec":         span("How far systemd may move each start, to coalesce wake-ups. Defaults to 1min."),
+			"service": schema.SingleNestedAttribute{
+				Required:    true,
+				Description: "The oneshot service started by the timer",
+				Attributes: map[string]schema.Attribute{
+					"exec_start": schema.StringAttribute{
+						Required:    true,
+						Description: "Command line to run, e.g. /usr/local/bin/backup --all",
+						Validators: []validator.String{
+							stringvalidator.LengthAtLeast(1),
+						},
+					},
+					"user": schema.StringAttribute{
+						Optional:    true,
+						Description: "User to run the command as. Defaults to root.",
+					},
+					"group": schema.StringAttribute{
+						Optional:    true,
+						Description: "Group to run the command as",
+					},
+					"working_directory": schema.StringAttribute{
+						Optional:    true,
+						Description: "Working directory of the command",
+					},
+					"environment": schema.MapAttribute{
+						Optional:    true,
+						ElementType: types.StringType,
+						Description: "Environment variables of the command",
+						Validators: []validator.Map{
+							mapvalidator.KeysAre(stringvalidator.RegexMatches(environmentNamePattern, "must be an environment variable name")),
+						},
+					},
+				},
+			},
+			"timer_content":   computed("Rendered content of the timer unit file"),
+			"service_content": computed("Rendered content of the service unit file"),
+			"next_elapse":     computed("When the timer next elapses for a calendar event, in RFC 3339 format. Empty if no calendar event is scheduled."),
+			"last_trigger":    computed("When the timer last elapsed, in RFC 3339 format. Empty if it never has."),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *SystemdTimerResource) ConfigValidators(_ context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.AtLeastOneOf(
+			path.MatchRoot("on_calendar"),
+			path.MatchRoot("on_boot_sec"),
+			path.MatchRoot("on_startup_sec"),
+			path.MatchRoot("on_active_sec"),
+			path.MatchRoot("on_unit_active_sec"),
+			path.MatchRoot("on_unit_inactive_sec"),
+		),
+	}
+}
+
+func (r *SystemdTimerResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics) ||
+		!requireModule(ctx, client, common.ModuleUnitFiles, &resp.Diagnostics) {
+		return
+	}
+
+	var plan SystemdTimerResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Name.IsNull() {
+		return
+	}
+	// Always plan the rendered content, so that a file edited on the host is rewritten even if the attributes haven't changed
+	timer, service, known := plan.render(ctx, &resp.Diagnostics)
+	if known {
+		plan.TimerContent = types.StringValue(timer)
+		plan.ServiceContent = types.StringValue(service)
+	} else {
+		plan.TimerContent = types.StringUnknown()
+		plan.ServiceContent = types.StringUnknown()
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("timer_content"), plan.TimerContent)...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("service_content"), plan.ServiceContent)...)
+}
+
+func (r *SystemdTimerResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan SystemdTimerResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	// Refuse to overwrite units written by hand or by another configuration, they should be imported instead
+	for _, name := range []string{plan.serviceUnit(), plan.timerUnit()} {
+		_, err := client.SystemdGetUnitFile(ctx, name)
+		if err == nil {
+			resp.Diagnostics.AddAttributeError(path.Root("name"), "Unit file already exists",
+				fmt.Sprintf("The unit file %s already exists on the host. Import the timer to manage it with Terraform.", name))
+			return
+		}
+		if !errors.Is(err, common.ErrNotFound) {
+			resp.Diagnostics.AddError("Failed to create timer", fmt.Sprintf("Unable to check for an existing unit file %s. Unexpected error: %s", name, err))
+			return
+		}
+	}
+
+	tflog.Debug(ctx, "Writing timer", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdTimerResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state SystemdTimerResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	state.Name = state.ID
+	timerUnit, serviceUnit := state.timerUnit(), state.serviceUnit()
+	tflog.Debug(ctx, "Fetching timer", map[string]any{"id": state.ID.ValueString()})
+	timerFile, err := client.SystemdGetUnitFile(ctx, timerUnit)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Timer unit file no longer exists, removing from state", map[string]any{"id": state.ID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read timer", fmt.Sprintf("Unable to read unit file %s. Unexpected error: %s", timerUnit, err))
+		return
+	}
+	// A missing service file is drift, rather than a deleted timer, and is written again by the next apply
+	serviceFile, err := client.SystemdGetUnitFile(ctx, serviceUnit)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to read timer", fmt.Sprintf("Unable to read unit file %s. Unexpected error: %s", serviceUnit, err))
+		return
+	}
+
+	if state.TimerContent.ValueString() != timerFile.Content || state.ServiceContent.ValueString() != serviceFile.Content {
+		// Report the drift in terms of the attributes, keys which they can't represent only show up in the content
+		state.fromContent(ctx, timerFile.Content, serviceFile.Content, &resp.Diagnostics)
+	}
+	state.TimerContent = types.StringValue(timerFile.Content)
+	state.ServiceContent = types.StringValue(serviceFile.Content)
+
+	r.readSchedule(ctx, client, &state, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdTimerResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan SystemdTimerResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing timer", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdTimerResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state SystemdTimerResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	timerUnit := state.timerUnit()
+	tflog.Debug(ctx, "Stopping timer", map[string]any{"id": state.ID.ValueString()})
+	disabled := false
+	_, err := client.SystemdSetUnitState(ctx, timerUnit, common.UnitStateRequest{Enabled: &disabled, Active: &disabled})
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete timer", fmt.Sprintf("Unable to stop timer %s: %s", timerUnit, err))
+		return
+	}
+
+	// The timer goes first, so that it can't start a service which no longer exists
+	for _, name := range []string{timerUnit, state.serviceUnit()} {
+		err := client.SystemdDeleteUnitFile(ctx, name)
+		if err != nil && !errors.Is(err, common.ErrNotFound) {
+			resp.Diagnostics.AddError("Failed to delete timer", fmt.Sprintf("Unable to delete unit file %s. Unexpected error: %s", name, err))
+			return
+		}
+	}
+}
+
+func (r *SystemdTimerResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// write creates or replaces both unit files with the planned content, then enables and starts the timer
+// systemd reloads the timer's schedule along with its unit file, so a running timer doesn't need restarting.
+func (r *SystemdTimerResource) write(ctx context.Context, client *common.Client, plan *SystemdTimerResourceModel, diags *diag.Diagnostics) {
+	timer, service, _ := plan.render(ctx, diags)
+	if diags.HasError() {
+		return
+	}
+	// The service goes first, so that the timer never starts a stale one
+	serviceFile, err := client.SystemdWriteUnitFile(ctx, plan.serviceUnit(), service)
+	if err != nil {
+		diags.AddError("Failed to write timer", fmt.Sprintf("Unable to write unit file %s. Unexpected error: %s", plan.serviceUnit(), err))
+		return
+	}
+	timerFile, err := client.SystemdWriteUnitFile(ctx, plan.timerUnit(), timer)
+	if err != nil {
+		diags.AddError("Failed to write timer", fmt.Sprintf("Unable to write unit file %s. Unexpected error: %s", plan.timerUnit(), err))
+		return
+	}
+
+	enabled := true
+	_, err = client.SystemdSetUnitState(ctx, plan.timerUnit(), common.UnitStateRequest{Enabled: &enabled, Active: &enabled})
+	if err != nil {
+		diags.AddError("Failed to start timer", unitFailureDetail(fmt.Sprintf("Unable to enable and start timer %s: %s", plan.timerUnit(), err), err))
+		return
+	}
+
+	plan.ID = plan.Name
+	plan.TimerContent = types.StringValue(timerFile.Content)
+	plan.ServiceContent = types.StringValue(serviceFile.Content)
+	r.readSchedule(ctx, client, plan, diags)
+}
+
+// readSchedule records when the timer next elapses, and when it last did
+func (r *SystemdTimerResource) readSchedule(ctx context.Context, client *common.Client, m *SystemdTimerResourceModel, diags *diag.Diagnostics) {
+	name := m.timerUnit()
+	timer, err := client.SystemdGetTimer(ctx, name)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		diags.AddError("Failed to read timer", fmt.Sprintf("Unable to read the schedule of timer %s. Unexpected error: %s", name, err))
+		return
+	}
+	format := func(t *time.Time) types.String {
+		if t == nil {
+			return types.StringValue("")
+		}
+		return types.StringValue(t.UTC().Format(time.RFC3339))
+	}
+	m.NextElapse = format(timer.NextElapse)
+	m.LastTrigger = format(timer.LastTrigger)
+}
+
+func (m *SystemdTimerResourceModel) timerUnit() string {
+	return m.Name.ValueString() + ".timer"
+}
+
+func (m *SystemdTimerResourceModel) serviceUnit() string {
+	return m.Name.ValueString() + ".service"
+}
+
+func (m *SystemdTimerResourceModel) spans() []timerSpan {
+	return []timerSpan{
+		{"OnBootSec", &m.OnBootSec},
+		{"OnStartupSec", &m.OnStartupSec},
+		{"OnActiveSec", &m.OnActiveSec},
+		{"OnUnitActiveSec", &m.OnUnitActiveSec},
+		{"OnUnitInactiveSec", &m.OnUnitInactiveSec},
+		{"RandomizedDelaySec", &m.RandomizedDelaySec},
+		{"AccuracySec", &m.AccuracySec},
+	}
+}
+
+// render formats the timer and service unit files, returns false if any attribute is not yet known
+func (m *SystemdTimerResourceModel) render(ctx context.Context, diags *diag.Diagnostics) (string, string, bool) {
+	var description []common.UnitSection
+	if !m.Description.IsNull() {
+		if m.Description.IsUnknown() {
+			return "", "", false
+		}
+		description = []common.UnitSection{{Name: "Unit", Entries: []common.UnitEntry{{Key: "Description", Value: common.EscapeSpecifiers(m.Description.ValueString())}}}}
+	}
+
+	timer := common.UnitSection{Name: "Timer"}
+	if m.OnCalendar.IsUnknown() {
+		return "", "", false
+	}
+	var calendar []types.String
+	diags.Append(m.OnCalendar.ElementsAs(ctx, &calendar, false)...)
+	for _, event := range calendar {
+		if event.IsUnknown() {
+			return "", "", false
+		}
+		timer.Entries = append(timer.Entries, common.UnitEntry{Key: "OnCalendar", Value: event.ValueString()})
+	}
+	for _, span := range m.spans() {
+		if span.value.IsUnknown() {
+			return "", "", false
+		}
+		if !span.value.IsNull() {
+			timer.Entries = append(timer.Entries, common.UnitEntry{Key: span.key, Value: span.value.ValueString()})
+		}
+	}
+	if m.Persistent.IsUnknown() {
+		return "", "", false
+	}
+	if !m.Persistent.IsNull() {
+		timer.Entries = append(timer.Entries, common.UnitEntry{Key: "Persistent", Value: strconv.FormatBool(m.Persistent.ValueBool())})
+	}
+	install := common.UnitSection{Name: "Install", Entries: []common.UnitEntry{{Key: "WantedBy", Value: "timers.target"}}}
+
+	if m.Service == nil {
+		return "", "", false
+	}
+	service := common.UnitSection{Name: "Service", Entries: []common.UnitEntry{{Key: "Type", Value: "oneshot"}}}
+	for _, field := range []struct {
+		key   string
+		value types.String
+	}{
+		{"ExecStart", m.Service.ExecStart},
+		{"User", m.Service.User},
+		{"Group", m.Service.Group},
+		{"WorkingDirectory", m.Service.WorkingDirectory},
+	} {
+		if field.value.IsUnknown() {
+			return "", "", false
+		}
+		if !field.value.IsNull() {
+			service.Entries = append(service.Entries, common.UnitEntry{Key: field.key, Value: common.EscapeSpecifiers(field.value.ValueString())})
+		}
+	}
+	if m.Service.Environment.IsUnknown() {
+		return "", "", false
+	}
+	var environment map[string]types.String
+	diags.Append(m.Service.Environment.ElementsAs(ctx, &environment, false)...)
+	keys := make([]string, 0, len(environment))
+	for k, v := range environment {
+		if v.IsUnknown() {
+			return "", "", false
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		service.Entries = append(service.Entries, common.UnitEntry{Key: "Environment", Value: common.EscapeSpecifiers(common.QuoteUnitWord(k + "=" + environment[k].ValueString()))})
+	}
+	if diags.HasError() {
+		return "", "", false
+	}
+
+	return common.RenderUnit(append(description, timer, install)), common.RenderUnit(append(description, service)), true
+}
+
+// fromContent replaces the attributes with those parsed from the unit files
+func (m *SystemdTimerResourceModel) fromContent(ctx context.Context, timerContent string, serviceContent string, diags *diag.Diagnostics) {
+	timer, err := common.ParseUnit(timerContent)
+	if err != nil {
+		tflog.Warn(ctx, "Timer on the host is not a valid unit file, only reporting drift in its content", map[string]any{"error": err.Error()})
+		return
+	}
+	service, err := common.ParseUnit(serviceContent)
+	if err != nil {
+		tflog.Warn(ctx, "Service on the host is not a valid unit file, only reporting drift in its content", map[string]any{"error": err.Error()})
+		return
+	}
+	// The last assignment wins, as it does for systemd
+	last := func(sections []common.UnitSection, section string, key string) types.String {
+		value := types.StringNull()
+		for _, s := range sections {
+			if s.Name != section {
+				continue
+			}
+			if values := s.Values(key); len(values) > 0 {
+				value = types.StringValue(common.UnescapeSpecifiers(values[len(values)-1]))
+			}
+		}
+		return value
+	}
+	values := func(sections []common.UnitSection, section string, key string) []string {
+		var values []string
+		for _, s := range sections {
+			if s.Name == section {
+				for _, v := range s.Values(key) {
+					values = append(values, common.UnescapeSpecifiers(v))
+				}
+			}
+		}
+		return values
+	}
+
+	m.Description = last(timer, "Unit", "Description")
+	if calendar := values(timer, "Timer", "OnCalendar"); len(calendar) > 0 {
+		v, d := types.ListValueFrom(ctx, types.StringType, calendar)
+		diags.Append(d...)
+		m.OnCalendar = v
+	} else {
+		m.OnCalendar = types.ListNull(types.StringType)
+	}
+	for _, span := range m.spans() {
+		*span.value = last(timer, "Timer", span.key)
+	}
+	m.Persistent = types.BoolNull()
+	if v := last(timer, "Timer", "Persistent"); !v.IsNull() {
+		// systemd also accepts yes and on, see systemd.syntax(7)
+		switch strings.ToLower(v.ValueString()) {
+		case "1", "yes", "true", "on":
+			m.Persistent = types.BoolValue(true)
+		default:
+			m.Persistent = types.BoolValue(false)
+		}
+	}
+
+	m.Service = &TimerServiceModel{
+		ExecStart:        last(service, "Service", "ExecStart"),
+		User:             last(service, "Service", "User"),
+		Group:            last(service, "Service", "Group"),
+		WorkingDirectory: last(service, "Service", "WorkingDirectory"),
+		Environment:      types.MapNull(types.StringType),
+	}
+	if m.Service.ExecStart.IsNull() {
+		// exec_start is required, an empty command still reports the drift
+		m.Service.ExecStart = types.StringValue("")
+	}
+	environment := make(map[string]string)
+	for _, line := range values(service, "Service", "Environment") {
+		// A line may hold several assignments, and an empty one resets the list
+		assignments, err := common.SplitUnitWords(line)
+		if err != nil {
+			tflog.Warn(ctx, "Ignoring invalid Environment line in the service", map[string]any{"error": err.Error()})
+			continue
+		}
+		if len(assignments) == 0 {
+			clear(environment)
+		}
+		for _, assignment := range assignments {
+			if k, v, ok := strings.Cut(assignment, "="); ok {
+				environment[k] = v
+			}
+		}
+	}
+	if len(environment) > 0 {
+		v, d := types.MapValueFrom(ctx, types.StringType, environment)
+		diags.Append(d...)
+		m.Service.Environment = v
+	}
+}
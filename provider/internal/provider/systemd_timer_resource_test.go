@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+func TestAccSystemdTimerResource(t *testing.T) {
+	config := func(calendar string) string {
+		return providerConfig() + `
+		resource "linux_systemd_timer" "test" {
+		  name                 = "backup"
+		  description          = "Nightly backup"
+		  on_calendar          = ["` + calendar + `"]
+		  persistent           = true
+		  randomized_delay_sec = "30min"
+
+		  service = {
+		    exec_start = "/usr/local/bin/backup --all"
+		    user       = "backup"
+		    environment = {
+		      TARGET = "s3://backups"
+		    }
+		  }
+		}
+		`
+	}
+	timer := func(calendar string) string {
+		return "[Unit]\nDescription=Nightly backup\n\n" +
+			"[Timer]\nOnCalendar=" + calendar + "\nRandomizedDelaySec=30min\nPersistent=true\n\n" +
+			"[Install]\nWantedBy=timers.target\n"
+	}
+	service := "[Unit]\nDescription=Nightly backup\n\n" +
+		"[Service]\nType=oneshot\nExecStart=/usr/local/bin/backup --all\nUser=backup\nEnvironment=\"TARGET=s3://backups\"\n"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: resource.ComposeAggregateTestCheckFunc(
+			testAccCheckUnitFileDestroyed("backup.timer"),
+			testAccCheckUnitFileDestroyed("backup.service"),
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: config("*-*-* 02:00:00"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_timer.test", "id", "backup"),
+					resource.TestMatchResourceAttr("linux_systemd_timer.test", "next_elapse", regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T`)),
+					resource.TestCheckResourceAttr("linux_systemd_timer.test", "last_trigger", ""),
+					testAccCheckUnitFile("backup.timer", timer("*-*-* 02:00:00")),
+					testAccCheckUnitFile("backup.service", service),
+					func(*terraform.State) error {
+						state, ok := testAgent.Systemd.UnitState("backup.timer")
+						if !ok || !state.Enabled() || !state.Active() {
+							return fmt.Errorf("expected backup.timer to be enabled and active, got %+v", state)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:      "linux_systemd_timer.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				PreConfig: func() {
+					last := time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)
+					testAgent.Systemd.SetTimer(systemd.TimerState{Name: "backup.timer", Unit: "backup.service", LastTrigger: last})
+				},
+				Config: config("Mon..Fri *-*-* 03:00"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_timer.test", "last_trigger", "2024-06-01T02:00:00Z"),
+					resource.TestCheckResourceAttr("linux_systemd_timer.test", "next_elapse", ""),
+					testAccCheckUnitFile("backup.timer", timer("Mon..Fri *-*-* 03:00")),
+				),
+			},
+			{
+				// A file edited on the host is reported as drift, and rewritten
+				PreConfig: func() {
+					testAgent.Systemd.SetUnitFile("backup.service", "[Service]\nExecStart=/bin/true\n")
+				},
+				Config:             config("Mon..Fri *-*-* 03:00"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config("Mon..Fri *-*-* 03:00"),
+				Check:  testAccCheckUnitFile("backup.service", service),
+			},
+		},
+	})
+}
+
+func TestAccSystemdTimerResourceEscaping(t *testing.T) {
+	service := "[Unit]\nDescription=100%% of the backups\n\n" +
+		"[Service]\nType=oneshot\nExecStart=/bin/sh -c \"backup --date=$(date +%%F)\"\n" +
+		"Environment=\"GREETING=grüß dich\"\n" +
+		"Environment=\"MESSAGE=say \\\"hi\\\" to 50%%\"\n"
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_timer" "test" {
+				  name        = "escaped"
+				  description = "100% of the backups"
+				  on_calendar = ["daily"]
+
+				  service = {
+				    exec_start = "/bin/sh -c \"backup --date=$(date +%F)\""
+				    environment = {
+				      GREETING = "grüß dich"
+				      MESSAGE  = "say \"hi\" to 50%"
+				    }
+				  }
+				}
+				`,
+				Check: testAccCheckUnitFile("escaped.service", service),
+			},
+			{
+				ResourceName:      "linux_systemd_timer.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccSystemdTimerResourceInvalid(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_timer" "test" {
+				  name        = "backup"
+				  on_calendar = ["*-*-* 25:00"]
+				  service = {
+				    exec_start = "/bin/true"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Calendar Expression"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_timer" "test" {
+				  name        = "backup"
+				  on_boot_sec = "5 fortnights"
+				  service = {
+				    exec_start = "/bin/true"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Time Span"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_timer" "test" {
+				  name = "backup"
+				  service = {
+				    exec_start = "/bin/true"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Missing Attribute Configuration"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_timer" "test" {
+				  name        = "backup@"
+				  on_calendar = ["daily"]
+				  service = {
+				    exec_start = "/bin/true"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("must be a unit name without a type suffix"),
+			},
+		},
+	})
+}
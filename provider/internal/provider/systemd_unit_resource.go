@@ -0,0 +1,335 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                     = &SystemdUnitResource{}
+	_ resource.ResourceWithImportState      = &SystemdUnitResource{}
+	_ resource.ResourceWithModifyPlan       = &SystemdUnitResource{}
+	_ resource.ResourceWithConfigValidators = &SystemdUnitResource{}
+)
+
+type SystemdUnitResource struct {
+	clients *clientPool
+}
+
+type SystemdUnitResourceModel struct {
+	ID       types.String   `tfsdk:"id"`
+	Host     types.String   `tfsdk:"host"`
+	Name     types.String   `tfsdk:"name"`
+	Content  types.String   `tfsdk:"content"`
+	Unit     types.Map      `tfsdk:"unit"`
+	Service  types.Map      `tfsdk:"service"`
+	Install  types.Map      `tfsdk:"install"`
+	Path     types.String   `tfsdk:"path"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+func NewSystemdUnitResource() resource.Resource {
+	return &SystemdUnitResource{}
+}
+
+func (r *SystemdUnitResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *SystemdUnitResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_systemd_unit"
+}
+
+func (r *SystemdUnitResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A unit file in the agent's unit directory, usually /etc/systemd/system. " +
+			"systemd is reloaded whenever the file is written or deleted. Changes made to the file on the host are reported as drift.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the unit",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the unit, including its type suffix, e.g. example.service",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxUnitNameLength),
+					stringvalidator.RegexMatches(common.UnitNamePattern, "must be a unit name with a type suffix, such as example.service"),
+				},
+			},
+			"content": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: "Raw content of the unit file. Conflicts with the unit, service and install sections, " +
+					"when they are used this is the rendered unit file.",
+				Validators: []validator.String{
+					unitFileValidator{},
+				},
+			},
+			"unit":    unitSectionAttribute("Unit"),
+			"service": unitSectionAttribute("Service"),
+			"install": unitSectionAttribute("Install"),
+			"path": schema.StringAttribute{
+				Computed:    true,
+				Description: "Location of the unit file on the host",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *SystemdUnitResource) ConfigValidators(_ context.Context) []resource.ConfigValidator {
+	sections := []path.Expression{path.MatchRoot("unit"), path.MatchRoot("service"), path.MatchRoot("install")}
+	return []resource.ConfigValidator{
+		resourcevalidator.AtLeastOneOf(append(sections, path.MatchRoot("content"))...),
+		resourcevalidator.Conflicting(path.MatchRoot("content"), path.MatchRoot("unit")),
+		resourcevalidator.Conflicting(path.MatchRoot("content"), path.MatchRoot("service")),
+		resourcevalidator.Conflicting(path.MatchRoot("content"), path.MatchRoot("install")),
+	}
+}
+
+func (r *SystemdUnitResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics) ||
+		!requireModule(ctx, client, common.ModuleUnitFiles, &resp.Diagnostics) {
+		return
+	}
+
+	var plan SystemdUnitResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || !structuredSections(plan.sections()) {
+		return
+	}
+	// Always plan the rendered content, so that a file edited on the host is rewritten even if the sections haven't changed
+	content, known := renderSections(ctx, plan.sections(), &resp.Diagnostics)
+	if known {
+		plan.Content = types.StringValue(content)
+	} else {
+		plan.Content = types.StringUnknown()
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content"), plan.Content)...)
+}
+
+func (r *SystemdUnitResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan SystemdUnitResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := plan.Name.ValueString()
+	// Refuse to overwrite a unit written by hand or by another configuration, it should be imported instead
+	_, err := client.SystemdGetUnitFile(ctx, name)
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Unit file already exists",
+			fmt.Sprintf("The unit file %s already exists on the host. Import it to manage it with Terraform.", name))
+		return
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to create unit file", fmt.Sprintf("Unable to check for an existing unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+
+	tflog.Debug(ctx, "Writing unit file", map[string]any{"name": name})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdUnitResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state SystemdUnitResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := state.ID.ValueString()
+	tflog.Debug(ctx, "Fetching unit file", map[string]any{"id": name})
+	unit, err := client.SystemdGetUnitFile(ctx, name)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Unit file no longer exists, removing from state", map[string]any{"id": name})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read unit file", fmt.Sprintf("Unable to read unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+
+	state.ID = types.StringValue(unit.Name)
+	state.Name = types.StringValue(unit.Name)
+	state.Path = types.StringValue(unit.Path)
+	if structuredSections(state.sections()) && state.Content.ValueString() != unit.Content {
+		// Report the drift in terms of the sections, where the file can still be represented by them
+		sectionsFromContent(ctx, unit.Content, state.sections(), &resp.Diagnostics)
+	}
+	state.Content = types.StringValue(unit.Content)
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdUnitResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan SystemdUnitResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing unit file", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SystemdUnitResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state SystemdUnitResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	name := state.ID.ValueString()
+	tflog.Debug(ctx, "Deleting unit file", map[string]any{"id": name})
+	err := client.SystemdDeleteUnitFile(ctx, name)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete unit file", fmt.Sprintf("Unable to delete unit file %s. Unexpected error: %s", name, err))
+		return
+	}
+}
+
+func (r *SystemdUnitResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// write creates or replaces the unit file with the planned content, and records the result in plan
+func (r *SystemdUnitResource) write(ctx context.Context, client *common.Client, plan *SystemdUnitResourceModel, diags *diag.Diagnostics) {
+	content := plan.Content.ValueString()
+	if structuredSections(plan.sections()) {
+		content, _ = renderSections(ctx, plan.sections(), diags)
+		if diags.HasError() {
+			return
+		}
+	}
+	unit, err := client.SystemdWriteUnitFile(ctx, plan.Name.ValueString(), content)
+	if err != nil {
+		diags.AddError("Failed to write unit file", fmt.Sprintf("Unable to write unit file. Unexpected error: %s", err))
+		return
+	}
+	plan.ID = types.StringValue(unit.Name)
+	plan.Content = types.StringValue(unit.Content)
+	plan.Path = types.StringValue(unit.Path)
+}
+
+// sections returns the attributes of the structured schema, in the order they are written
+func (m *SystemdUnitResourceModel) sections() []unitSection {
+	return []unitSection{{"Unit", &m.Unit}, {"Service", &m.Service}, {"Install", &m.Install}}
+}
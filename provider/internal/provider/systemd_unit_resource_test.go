@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccSystemdUnitResource(t *testing.T) {
+	config := func(description string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_systemd_unit" "test" {
+		  name    = "example.service"
+		  content = <<-EOT
+		    [Unit]
+		    Description=%s
+
+		    [Service]
+		    ExecStart=/usr/bin/example
+		  EOT
+		}
+		`, description)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckUnitFileDestroyed("example.service"),
+		Steps: []resource.TestStep{
+			{
+				Config: config("Example"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_unit.test", "id", "example.service"),
+					resource.TestCheckResourceAttr("linux_systemd_unit.test", "path", "/etc/systemd/system/example.service"),
+					testAccCheckUnitFile("example.service", "[Unit]\nDescription=Example\n\n[Service]\nExecStart=/usr/bin/example\n"),
+				),
+			},
+			{
+				ResourceName:      "linux_systemd_unit.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: config("Changed"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckUnitFile("example.service", "[Unit]\nDescription=Changed\n\n[Service]\nExecStart=/usr/bin/example\n"),
+					func(*terraform.State) error {
+						// Written twice and never deleted
+						if reloads := testAgent.Systemd.Reloads(); reloads != 2 {
+							return fmt.Errorf("expected 2 reloads, got %d", reloads)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccSystemdUnitResourceStructured(t *testing.T) {
+	config := providerConfig() + `
+	resource "linux_systemd_unit" "test" {
+	  name = "example.service"
+
+	  unit = {
+	    Description = "Example"
+	    After       = "network.target"
+	  }
+	  service = {
+	    ExecStartPre = "/bin/mkdir -p /var/lib/example\n/bin/chown example /var/lib/example"
+	    ExecStart    = "/usr/bin/example"
+	  }
+	  install = {
+	    WantedBy = "multi-user.target"
+	  }
+	}
+	`
+	expected := "[Unit]\nAfter=network.target\nDescription=Example\n\n" +
+		"[Service]\nExecStart=/usr/bin/example\nExecStartPre=/bin/mkdir -p /var/lib/example\nExecStartPre=/bin/chown example /var/lib/example\n\n" +
+		"[Install]\nWantedBy=multi-user.target\n"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckUnitFileDestroyed("example.service"),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_systemd_unit.test", "content", expected),
+					testAccCheckUnitFile("example.service", expected),
+				),
+			},
+			{
+				ResourceName:            "linux_systemd_unit.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"unit", "service", "install"},
+			},
+			{
+				// A file edited on the host is reported as drift in its sections, and rewritten
+				PreConfig: func() {
+					testAgent.Systemd.SetUnitFile("example.service", "[Unit]\nDescription=Edited\n")
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check:  testAccCheckUnitFile("example.service", expected),
+			},
+		},
+	})
+}
+
+func TestAccSystemdUnitResourceDrift(t *testing.T) {
+	config := providerConfig() + `
+	resource "linux_systemd_unit" "test" {
+	  name    = "example.service"
+	  content = "[Service]\nExecStart=/usr/bin/example\n"
+	}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckUnitFileDestroyed("example.service"),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+			},
+			{
+				PreConfig: func() {
+					testAgent.Systemd.SetUnitFile("example.service", "[Service]\nExecStart=/usr/bin/something-else\n")
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check:  testAccCheckUnitFile("example.service", "[Service]\nExecStart=/usr/bin/example\n"),
+			},
+			{
+				// A unit file deleted on the host is recreated
+				PreConfig: func() {
+					testAgent.Systemd.Reset()
+				},
+				Config: config,
+				Check:  testAccCheckUnitFile("example.service", "[Service]\nExecStart=/usr/bin/example\n"),
+			},
+		},
+	})
+}
+
+func TestAccSystemdUnitResourceInvalid(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_unit" "test" {
+				  name    = "example"
+				  content = "[Service]\nExecStart=/bin/true\n"
+				}
+				`,
+				ExpectError: regexp.MustCompile("must be a unit name"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_unit" "test" {
+				  name    = "example.service"
+				  content = "ExecStart=/bin/true\n"
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Unit File"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_unit" "test" {
+				  name    = "example.service"
+				  content = "[Service]\nExecStart=/bin/true\n"
+				  service = {
+				    ExecStart = "/bin/true"
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Attribute Combination"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Systemd.SetUnitFile("example.service", "[Service]\nExecStart=/bin/false\n")
+				},
+				Config: providerConfig() + `
+				resource "linux_systemd_unit" "test" {
+				  name    = "example.service"
+				  content = "[Service]\nExecStart=/bin/true\n"
+				}
+				`,
+				ExpectError: regexp.MustCompile("Unit file already exists"),
+			},
+		},
+	})
+}
+
+// testAccCheckUnitFile checks the content of a unit file on the test agent
+func testAccCheckUnitFile(name string, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		content, ok := testAgent.Systemd.UnitFile(name)
+		if !ok {
+			return fmt.Errorf("unit file %s does not exist", name)
+		}
+		if content != expected {
+			return fmt.Errorf("expected unit file %s to contain %q, got %q", name, expected, content)
+		}
+		return nil
+	}
+}
+
+func testAccCheckUnitFileDestroyed(name string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if _, ok := testAgent.Systemd.UnitFile(name); ok {
+			return fmt.Errorf("unit file %s still exists", name)
+		}
+		return nil
+	}
+}
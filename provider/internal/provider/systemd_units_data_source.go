@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ datasource.DataSource              = &systemdUnitsDataSource{}
+	_ datasource.DataSourceWithConfigure = &systemdUnitsDataSource{}
+)
+
+type systemdUnitsDataSource struct {
+	clients *clientPool
+}
+
+type systemdUnitsDataSourceModel struct {
+	Host     types.String           `tfsdk:"host"`
+	States   []types.String         `tfsdk:"states"`
+	Patterns []types.String         `tfsdk:"patterns"`
+	Units    []systemdUnitDataModel `tfsdk:"units"`
+}
+
+type systemdUnitDataModel struct {
+	Name         types.String `tfsdk:"name"`
+	Description  types.String `tfsdk:"description"`
+	LoadState    types.String `tfsdk:"load_state"`
+	ActiveState  types.String `tfsdk:"active_state"`
+	SubState     types.String `tfsdk:"sub_state"`
+	FragmentPath types.String `tfsdk:"fragment_path"`
+	MainPID      types.Int64  `tfsdk:"main_pid"`
+}
+
+func NewSystemdUnitsDataSource() datasource.DataSource {
+	return &systemdUnitsDataSource{}
+}
+
+func (d *systemdUnitsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		UnexpectedDataSourceConfigureType(ctx, req, resp)
+	}
+	d.clients = clients
+}
+
+func (d *systemdUnitsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_systemd_units"
+}
+
+func (d *systemdUnitsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "List the units loaded by systemd on the host, e.g. to check that none have failed",
+		Attributes: map[string]schema.Attribute{
+			"host": hostDataSourceAttribute(),
+			"states": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Only list units in any of these load, active or sub states, e.g. failed or running",
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(stringvalidator.RegexMatches(common.UnitStatePattern, "must be a unit state, such as failed")),
+				},
+			},
+			"patterns": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Only list units whose names match any of these glob patterns, e.g. *.service or getty@*",
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(
+						stringvalidator.LengthAtMost(common.MaxUnitNameLength),
+						stringvalidator.RegexMatches(common.UnitGlobPattern, "must be a glob pattern of unit names, such as *.service"),
+					),
+				},
+			},
+			"units": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "Matching units, sorted by name",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name":          computed("Name of the unit, e.g. nginx.service"),
+						"description":   computed("Description of the unit"),
+						"load_state":    computed("Load state of the unit, e.g. loaded, not-found or masked"),
+						"active_state":  computed("Activation state of the unit, e.g. active, inactive or failed"),
+						"sub_state":     computed("Type specific activation state of the unit, e.g. running or exited"),
+						"fragment_path": computed("Unit file the unit was loaded from, empty if it has none"),
+						"main_pid": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Main process of a running service, zero otherwise",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *systemdUnitsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state systemdUnitsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	client := d.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSystemd, &resp.Diagnostics) {
+		return
+	}
+
+	values := func(list []types.String) []string {
+		var values []string
+		for _, v := range list {
+			values = append(values, v.ValueString())
+		}
+		return values
+	}
+	units, err := client.SystemdListUnits(ctx, values(state.States), values(state.Patterns))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to list units", fmt.Sprintf("Unable to list systemd units. Unexpected error: %s", err))
+		return
+	}
+
+	state.Units = []systemdUnitDataModel{}
+	for _, unit := range units.Units {
+		state.Units = append(state.Units, systemdUnitDataModel{
+			Name:         types.StringValue(unit.Name),
+			Description:  types.StringValue(unit.Description),
+			LoadState:    types.StringValue(unit.LoadState),
+			ActiveState:  types.StringValue(unit.ActiveState),
+			SubState:     types.StringValue(unit.SubState),
+			FragmentPath: types.StringValue(unit.FragmentPath),
+			MainPID:      types.Int64Value(int64(unit.MainPID)),
+		})
+	}
+
+	diags := resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+func TestAccSystemdUnitsDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "ssh.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled",
+			})
+			testAgent.Systemd.AddUnit(systemd.UnitState{
+				Name: "broken.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed", UnitFileState: "enabled",
+			})
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// The use case, refusing to roll out to a host with failed units
+				Config: providerConfig() + `
+				data "linux_systemd_units" "failed" {
+				  states = ["failed"]
+
+				  lifecycle {
+				    postcondition {
+				      condition     = length(self.units) == 0
+				      error_message = "Failed units: ${join(", ", self.units[*].name)}"
+				    }
+				  }
+				}
+				`,
+				ExpectError: regexp.MustCompile("Failed units: broken.service"),
+			},
+			{
+				Config: providerConfig() + `
+				data "linux_systemd_units" "invalid" {
+				  patterns = ["../*"]
+				}
+				`,
+				ExpectError: regexp.MustCompile("must be a glob pattern of unit names"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_systemd_unit" "test" {
+				  name    = "example.service"
+				  content = "[Unit]\nDescription=Example\n\n[Service]\nExecStart=/usr/bin/example\n"
+				}
+
+				data "linux_systemd_units" "services" {
+				  patterns = ["*.service"]
+
+				  depends_on = [linux_systemd_unit.test]
+				}
+
+				data "linux_systemd_units" "running" {
+				  states = ["running"]
+				}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.linux_systemd_units.services", "units.#", "3"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.services", "units.1.name", "example.service"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.services", "units.1.description", "Example"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.services", "units.1.fragment_path", "/etc/systemd/system/example.service"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.services", "units.1.main_pid", "0"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.running", "units.#", "1"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.running", "units.0.name", "ssh.service"),
+					resource.TestCheckResourceAttr("data.linux_systemd_units.running", "units.0.active_state", "active"),
+					resource.TestMatchResourceAttr("data.linux_systemd_units.running", "units.0.main_pid", regexp.MustCompile(`^[1-9][0-9]*$`)),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,655 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                   = &SysusersResource{}
+	_ resource.ResourceWithImportState    = &SysusersResource{}
+	_ resource.ResourceWithModifyPlan     = &SysusersResource{}
+	_ resource.ResourceWithValidateConfig = &SysusersResource{}
+)
+
+type SysusersResource struct {
+	clients *clientPool
+}
+
+type SysusersResourceModel struct {
+	ID       types.String   `tfsdk:"id"`
+	Host     types.String   `tfsdk:"host"`
+	Name     types.String   `tfsdk:"name"`
+	Users    types.List     `tfsdk:"users"`
+	Groups   types.List     `tfsdk:"groups"`
+	Content  types.String   `tfsdk:"content"`
+	Path     types.String   `tfsdk:"path"`
+	UIDs     types.Map      `tfsdk:"uids"`
+	GIDs     types.Map      `tfsdk:"gids"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// SysusersUserModel is a u line of the sysusers.d file
+type SysusersUserModel struct {
+	Name        types.String `tfsdk:"name"`
+	UID         types.Int64  `tfsdk:"uid"`
+	GID         types.Int64  `tfsdk:"gid"`
+	Description types.String `tfsdk:"description"`
+	Home        types.String `tfsdk:"home"`
+	Shell       types.String `tfsdk:"shell"`
+}
+
+// SysusersGroupModel is a g line of the sysusers.d file, along with an m line for each of its members
+type SysusersGroupModel struct {
+	Name    types.String `tfsdk:"name"`
+	GID     types.Int64  `tfsdk:"gid"`
+	Members types.List   `tfsdk:"members"`
+}
+
+var sysusersUserAttrTypes = map[string]attr.Type{
+	"name":        types.StringType,
+	"uid":         types.Int64Type,
+	"gid":         types.Int64Type,
+	"description": types.StringType,
+	"home":        types.StringType,
+	"shell":       types.StringType,
+}
+
+var sysusersGroupAttrTypes = map[string]attr.Type{
+	"name":    types.StringType,
+	"gid":     types.Int64Type,
+	"members": types.ListType{ElemType: types.StringType},
+}
+
+// absolutePathPattern matches a home directory or shell, which sysusers.d fields can't quote if they contain whitespace
+var absolutePathPattern = regexp.MustCompile(`^/[^\s]*$`)
+
+func NewSysusersResource() resource.Resource {
+	return &SysusersResource{}
+}
+
+func (r *SysusersResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *SysusersResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_sysusers"
+}
+
+func (r *SysusersResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	name := schema.StringAttribute{
+		Required: true,
+		Validators: []validator.String{
+			stringvalidator.RegexMatches(common.SysusersNamePattern, "must be a valid user or group name, such as app"),
+		},
+	}
+	id := func(description string) schema.Int64Attribute {
+		return schema.Int64Attribute{
+			Optional:    true,
+			Description: description,
+			Validators: []validator.Int64{
+				int64validator.Between(1, 4294967294),
+			},
+		}
+	}
+	absolute := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Optional:    true,
+			Description: description,
+			Validators: []validator.String{
+				stringvalidator.RegexMatches(absolutePathPattern, "must be an absolute path"),
+			},
+		}
+	}
+
+	userName := name
+	userName.Description = "Name of the user, which also gets a group of the same name"
+	groupName := name
+	groupName.Description = "Name of the group"
+	userGID := id("GID of the user's group. Requires uid to be set.")
+	userGID.Validators = append(userGID.Validators, int64validator.AlsoRequires(path.MatchRelative().AtParent().AtName("uid")))
+
+	resp.Schema = schema.Schema{
+		Description: "A sysusers.d(5) file, written to /etc/sysusers.d/<name>.conf, which declares system users and groups. " +
+			"The agent applies it with systemd-sysusers as soon as it is written, as well as at every boot, and reports the IDs they were given. " +
+			"Users and groups which already exist are left unchanged, but one removed from the host is created again by the next apply. " +
+			"Destroying the resource removes the file, but leaves the users and groups in place, as files on the host may still be owned by their IDs.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the file",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the file, without the .conf suffix, e.g. app. A file in /etc/sysusers.d overrides one of the same name shipped by a package.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxDropInNameLength),
+					stringvalidator.RegexMatches(common.DropInNamePattern, "must be a file name without slashes, such as app"),
+				},
+			},
+			"users": schema.ListNestedAttribute{
+				Optional:    true,
+				Description: "System users to create",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name":        userName,
+						"uid":         id("UID of the user. If unset, the highest free system ID is used."),
+						"gid":         userGID,
+						"description": schema.StringAttribute{Optional: true, Description: "Description of the user, stored in its GECOS field"},
+						"home":        absolute("Home directory of the user. Defaults to /."),
+						"shell":       absolute("Login shell of the user. Defaults to nologin."),
+					},
+				},
+			},
+			"groups": schema.ListNestedAttribute{
+				Optional:    true,
+				Description: "System groups to create, along with the users which are members of them",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": groupName,
+						"gid":  id("GID of the group. If unset, the highest free system ID is used."),
+						"members": schema.ListAttribute{
+							Optional:    true,
+							ElementType: types.StringType,
+							Description: "Users to add to the group, which are created if they don't exist",
+							Validators: []validator.List{
+								listvalidator.ValueStringsAre(stringvalidator.RegexMatches(common.SysusersNamePattern, "must be a valid user name")),
+							},
+						},
+					},
+				},
+			},
+			"content": schema.StringAttribute{
+				Computed:    true,
+				Description: "The rendered file",
+			},
+			"path": schema.StringAttribute{
+				Computed:    true,
+				Description: "Location of the file on the host",
+			},
+			"uids": schema.MapAttribute{
+				Computed:    true,
+				ElementType: types.Int64Type,
+				Description: "UID of each user the file declares, by name, once it exists",
+			},
+			"gids": schema.MapAttribute{
+				Computed:    true,
+				ElementType: types.Int64Type,
+				Description: "GID of each group the file declares, including the groups of its users, by name, once it exists",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *SysusersResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config SysusersResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if config.Users.IsNull() && config.Groups.IsNull() {
+		resp.Diagnostics.AddError("Missing sysusers.d Entries", "At least one of users or groups must be set")
+		return
+	}
+	users, _ := config.users(ctx, &resp.Diagnostics)
+	seen := make(map[string]bool)
+	for i, u := range users {
+		if u.Name.IsUnknown() {
+			continue
+		}
+		if seen[u.Name.ValueString()] {
+			resp.Diagnostics.AddAttributeError(path.Root("users").AtListIndex(i).AtName("name"), "Duplicate User",
+				fmt.Sprintf("User %s is declared more than once", u.Name.ValueString()))
+		}
+		seen[u.Name.ValueString()] = true
+	}
+}
+
+func (r *SysusersResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleSysusers, &resp.Diagnostics) {
+		return
+	}
+
+	var plan SysusersResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Always plan the rendered content, so that a file edited on the host is rewritten even if the entries haven't changed
+	content, known := plan.render(ctx, &resp.Diagnostics)
+	plan.Content = types.StringUnknown()
+	if known {
+		plan.Content = types.StringValue(content)
+	}
+	if !plan.Name.IsUnknown() {
+		plan.Path = types.StringValue(common.SysusersDir + "/" + plan.Name.ValueString() + ".conf")
+	}
+
+	// The IDs are only known ahead of time if the file is unchanged and every account it declares still exists,
+	// otherwise the file is applied again, which creates those which are missing
+	plan.UIDs = types.MapUnknown(types.Int64Type)
+	plan.GIDs = types.MapUnknown(types.Int64Type)
+	if !req.State.Raw.IsNull() && known {
+		var state SysusersResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if state.Content.ValueString() == content && state.inSync(content) {
+			plan.UIDs = state.UIDs
+			plan.GIDs = state.GIDs
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+func (r *SysusersResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan SysusersResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	// Refuse to overwrite a file written by hand or by another configuration, it should be imported instead
+	_, err := client.SystemdGetSysusers(ctx, plan.Name.ValueString())
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "File already exists",
+			fmt.Sprintf("The sysusers.d file %s already exists on the host. Import it to manage it with Terraform.", plan.Name.ValueString()))
+		return
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to create sysusers.d file", fmt.Sprintf("Unable to check for an existing file %s. Unexpected error: %s", plan.Name.ValueString(), err))
+		return
+	}
+
+	tflog.Debug(ctx, "Writing sysusers.d file", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SysusersResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state SysusersResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	state.Name = state.ID
+	tflog.Debug(ctx, "Fetching sysusers.d file", map[string]any{"id": state.ID.ValueString()})
+	sysusers, err := client.SystemdGetSysusers(ctx, state.ID.ValueString())
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "sysusers.d file no longer exists, removing from state", map[string]any{"id": state.ID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read sysusers.d file", fmt.Sprintf("Unable to read sysusers.d file %s. Unexpected error: %s", state.ID.ValueString(), err))
+		return
+	}
+
+	if state.Content.ValueString() != sysusers.Content {
+		state.fromContent(ctx, sysusers.Content, &resp.Diagnostics)
+	}
+	state.fromResponse(ctx, sysusers, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SysusersResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan SysusersResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing sysusers.d file", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *SysusersResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state SysusersResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Deleting sysusers.d file", map[string]any{"id": state.ID.ValueString()})
+	err := client.SystemdDeleteSysusers(ctx, state.ID.ValueString())
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete sysusers.d file", fmt.Sprintf("Unable to delete sysusers.d file %s. Unexpected error: %s", state.ID.ValueString(), err))
+	}
+}
+
+func (r *SysusersResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// write creates or replaces the file with the planned content, which the agent applies straight away
+func (r *SysusersResource) write(ctx context.Context, client *common.Client, plan *SysusersResourceModel, diags *diag.Diagnostics) {
+	content, _ := plan.render(ctx, diags)
+	if diags.HasError() {
+		return
+	}
+	sysusers, err := client.SystemdWriteSysusers(ctx, plan.Name.ValueString(), content)
+	if err != nil {
+		diags.AddError("Failed to write sysusers.d file", fmt.Sprintf("Unable to write and apply sysusers.d file. Unexpected error: %s", err))
+		return
+	}
+	plan.ID = plan.Name
+	plan.fromResponse(ctx, sysusers, diags)
+}
+
+func (m *SysusersResourceModel) users(ctx context.Context, diags *diag.Diagnostics) ([]SysusersUserModel, bool) {
+	if m.Users.IsUnknown() {
+		return nil, false
+	}
+	var users []SysusersUserModel
+	diags.Append(m.Users.ElementsAs(ctx, &users, false)...)
+	return users, true
+}
+
+func (m *SysusersResourceModel) groups(ctx context.Context, diags *diag.Diagnostics) ([]SysusersGroupModel, bool) {
+	if m.Groups.IsUnknown() {
+		return nil, false
+	}
+	var groups []SysusersGroupModel
+	diags.Append(m.Groups.ElementsAs(ctx, &groups, false)...)
+	return groups, true
+}
+
+// lines returns the users as u lines, followed by the groups as g lines and their members as m lines, and false if
+// any of them is not yet known
+func (m *SysusersResourceModel) lines(ctx context.Context, diags *diag.Diagnostics) ([]common.SysusersLine, bool) {
+	users, usersKnown := m.users(ctx, diags)
+	groups, groupsKnown := m.groups(ctx, diags)
+	if !usersKnown || !groupsKnown || diags.HasError() {
+		return nil, false
+	}
+
+	var lines []common.SysusersLine
+	for _, u := range users {
+		for _, v := range []attr.Value{u.Name, u.UID, u.GID, u.Description, u.Home, u.Shell} {
+			if v.IsUnknown() {
+				return nil, false
+			}
+		}
+		id := ""
+		if !u.UID.IsNull() {
+			id = strconv.FormatInt(u.UID.ValueInt64(), 10)
+			if !u.GID.IsNull() {
+				id += ":" + strconv.FormatInt(u.GID.ValueInt64(), 10)
+			}
+		}
+		lines = append(lines, common.SysusersLine{
+			Type:  "u",
+			Name:  u.Name.ValueString(),
+			ID:    id,
+			GECOS: u.Description.ValueString(),
+			Home:  u.Home.ValueString(),
+			Shell: u.Shell.ValueString(),
+		})
+	}
+	var members []common.SysusersLine
+	for _, g := range groups {
+		if g.Name.IsUnknown() || g.GID.IsUnknown() || g.Members.IsUnknown() {
+			return nil, false
+		}
+		id := ""
+		if !g.GID.IsNull() {
+			id = strconv.FormatInt(g.GID.ValueInt64(), 10)
+		}
+		lines = append(lines, common.SysusersLine{Type: "g", Name: g.Name.ValueString(), ID: id})
+		for _, member := range g.Members.Elements() {
+			member, ok := member.(types.String)
+			if !ok || member.IsUnknown() {
+				return nil, false
+			}
+			members = append(members, common.SysusersLine{Type: "m", Name: member.ValueString(), ID: g.Name.ValueString()})
+		}
+	}
+	return append(lines, members...), true
+}
+
+// render formats the file, returns false if any entry is not yet known
+func (m *SysusersResourceModel) render(ctx context.Context, diags *diag.Diagnostics) (string, bool) {
+	lines, known := m.lines(ctx, diags)
+	if !known {
+		return "", false
+	}
+	return common.RenderSysusers(lines), true
+}
+
+// inSync returns true if every user and group the content declares has an ID, i.e. still exists
+func (m *SysusersResourceModel) inSync(content string) bool {
+	if m.UIDs.IsNull() || m.UIDs.IsUnknown() || m.GIDs.IsNull() || m.GIDs.IsUnknown() {
+		return false
+	}
+	lines, err := common.ParseSysusers(content)
+	if err != nil {
+		return false
+	}
+	uids := m.UIDs.Elements()
+	gids := m.GIDs.Elements()
+	for _, l := range lines {
+		switch l.Type {
+		case "u":
+			if _, ok := uids[l.Name]; !ok {
+				return false
+			}
+		case "g":
+			if _, ok := gids[l.Name]; !ok {
+				return false
+			}
+		case "m":
+			if _, ok := gids[l.ID]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m *SysusersResourceModel) fromResponse(ctx context.Context, sysusers common.SysusersResponse, diags *diag.Diagnostics) {
+	m.Content = types.StringValue(sysusers.Content)
+	m.Path = types.StringValue(sysusers.Path)
+	uids := make(map[string]int64)
+	for _, u := range sysusers.Users {
+		if u.Exists {
+			uids[u.Name] = int64(u.UID)
+		}
+	}
+	gids := make(map[string]int64)
+	for _, g := range sysusers.Groups {
+		if g.Exists {
+			gids[g.Name] = int64(g.GID)
+		}
+	}
+	v, d := types.MapValueFrom(ctx, types.Int64Type, uids)
+	diags.Append(d...)
+	m.UIDs = v
+	v, d = types.MapValueFrom(ctx, types.Int64Type, gids)
+	diags.Append(d...)
+	m.GIDs = v
+}
+
+// fromContent replaces the users and groups with those parsed from the file on the host
+// Lines which cannot be represented, such as r lines or IDs given by path, are only reported as drift in the content.
+func (m *SysusersResourceModel) fromContent(ctx context.Context, content string, diags *diag.Diagnostics) {
+	lines, err := common.ParseSysusers(content)
+	if err != nil {
+		tflog.Warn(ctx, "sysusers.d file on the host is not valid, only reporting drift in its content", map[string]any{"error": err.Error()})
+		return
+	}
+	value := func(s string) types.String {
+		if s == "" {
+			return types.StringNull()
+		}
+		return types.StringValue(s)
+	}
+	id := func(s string) types.Int64 {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return types.Int64Null()
+		}
+		return types.Int64Value(i)
+	}
+
+	var users []SysusersUserModel
+	var groups []SysusersGroupModel
+	members := make(map[string][]string)
+	for _, l := range lines {
+		switch l.Type {
+		case "u":
+			uid, gid, _ := strings.Cut(l.ID, ":")
+			users = append(users, SysusersUserModel{
+				Name:        types.StringValue(l.Name),
+				UID:         id(uid),
+				GID:         id(gid),
+				Description: value(l.GECOS),
+				Home:        value(l.Home),
+				Shell:       value(l.Shell),
+			})
+		case "g":
+			groups = append(groups, SysusersGroupModel{Name: types.StringValue(l.Name), GID: id(l.ID)})
+		case "m":
+			members[l.ID] = append(members[l.ID], l.Name)
+		}
+	}
+	for i, g := range groups {
+		groups[i].Members = types.ListNull(types.StringType)
+		if names, ok := members[g.Name.ValueString()]; ok {
+			v, d := types.ListValueFrom(ctx, types.StringType, names)
+			diags.Append(d...)
+			groups[i].Members = v
+		}
+	}
+
+	m.Users = types.ListNull(types.ObjectType{AttrTypes: sysusersUserAttrTypes})
+	if len(users) > 0 {
+		v, d := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: sysusersUserAttrTypes}, users)
+		diags.Append(d...)
+		m.Users = v
+	}
+	m.Groups = types.ListNull(types.ObjectType{AttrTypes: sysusersGroupAttrTypes})
+	if len(groups) > 0 {
+		v, d := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: sysusersGroupAttrTypes}, groups)
+		diags.Append(d...)
+		m.Groups = v
+	}
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccSysusersResource(t *testing.T) {
+	config := func(shell string) string {
+		return providerConfig() + fmt.Sprintf(`
+	resource "linux_sysusers" "test" {
+	  name = "app"
+	  users = [
+	    {
+	      name        = "app"
+	      description = "App service"
+	      home        = "/srv/app"
+	      shell       = %q
+	    },
+	    {
+	      name = "backup"
+	      uid  = 950
+	      gid  = 950
+	    },
+	  ]
+	  groups = [
+	    {
+	      name    = "app-data"
+	      members = ["app", "backup"]
+	    },
+	  ]
+	}
+
+	resource "linux_tmpfiles" "test" {
+	  name    = "app"
+	  entries = [{ type = "d", path = "/srv/app", mode = "0750", user = "app", group = "app-data" }]
+
+	  depends_on = [linux_sysusers.test]
+	}
+	`, shell)
+	}
+	content := func(shell string) string {
+		return fmt.Sprintf("u app - \"App service\" /srv/app %s\nu backup 950:950\ng app-data\nm app app-data\nm backup app-data\n", shell)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, ok := testAgent.Sysusers.File("app"); ok {
+				return fmt.Errorf("sysusers.d file app still exists")
+			}
+			if _, ok := testAgent.Sysusers.User("app"); !ok {
+				return fmt.Errorf("expected user app to be left in place")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					testAgent.Sysusers.SetFile("app", "u app\n")
+				},
+				Config:      config("/bin/sh"),
+				ExpectError: regexp.MustCompile("File already exists"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Sysusers.Reset()
+				},
+				Config: config("/bin/sh"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_sysusers.test", "path", "/etc/sysusers.d/app.conf"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "content", content("/bin/sh")),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "uids.%", "2"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "uids.app", "999"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "uids.backup", "950"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "gids.%", "3"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "gids.app", "999"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "gids.backup", "950"),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "gids.app-data", "998"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.user", "app"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.group", "app-data"),
+					testAccCheckSysusers("app", content("/bin/sh")),
+					func(*terraform.State) error {
+						group, _ := testAgent.Sysusers.Group("app-data")
+						if len(group.Members) != 2 {
+							return fmt.Errorf("expected app-data to have 2 members, got %v", group.Members)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:      "linux_sysusers.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				// Existing users are left unchanged, only the file is replaced
+				Config: config("/bin/bash"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_sysusers.test", "content", content("/bin/bash")),
+					resource.TestCheckResourceAttr("linux_sysusers.test", "uids.app", "999"),
+					testAccCheckSysusers("app", content("/bin/bash")),
+				),
+			},
+			{
+				// Changes made to the file on the host are reverted
+				PreConfig: func() {
+					testAgent.Sysusers.SetFile("app", "u app\n")
+				},
+				Config: config("/bin/bash"),
+				Check:  testAccCheckSysusers("app", content("/bin/bash")),
+			},
+		},
+	})
+}
+
+func TestAccSysusersResourceInvalid(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_sysusers" "test" {
+				  name = "app"
+				}
+				`,
+				ExpectError: regexp.MustCompile("At least one of users or groups must be set"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_sysusers" "test" {
+				  name  = "app"
+				  users = [{ name = "app", gid = 950 }]
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid Attribute Combination"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_sysusers" "test" {
+				  name  = "app"
+				  users = [{ name = "app" }, { name = "app" }]
+				}
+				`,
+				ExpectError: regexp.MustCompile("Duplicate User"),
+			},
+		},
+	})
+}
+
+func testAccCheckSysusers(name string, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		content, ok := testAgent.Sysusers.File(name)
+		if !ok {
+			return fmt.Errorf("sysusers.d file %s does not exist", name)
+		}
+		if content != expected {
+			return fmt.Errorf("expected sysusers.d file %s to contain %q, got %q", name, expected, content)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,573 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var (
+	_ resource.Resource                   = &TmpfilesResource{}
+	_ resource.ResourceWithImportState    = &TmpfilesResource{}
+	_ resource.ResourceWithModifyPlan     = &TmpfilesResource{}
+	_ resource.ResourceWithValidateConfig = &TmpfilesResource{}
+)
+
+type TmpfilesResource struct {
+	clients *clientPool
+}
+
+type TmpfilesResourceModel struct {
+	ID       types.String   `tfsdk:"id"`
+	Host     types.String   `tfsdk:"host"`
+	Name     types.String   `tfsdk:"name"`
+	Entries  types.List     `tfsdk:"entries"`
+	Content  types.String   `tfsdk:"content"`
+	Path     types.String   `tfsdk:"path"`
+	Paths    types.List     `tfsdk:"paths"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// TmpfilesEntryModel is a line of the tmpfiles.d file
+type TmpfilesEntryModel struct {
+	Type     types.String `tfsdk:"type"`
+	Path     types.String `tfsdk:"path"`
+	Mode     types.String `tfsdk:"mode"`
+	User     types.String `tfsdk:"user"`
+	Group    types.String `tfsdk:"group"`
+	Age      types.String `tfsdk:"age"`
+	Argument types.String `tfsdk:"argument"`
+}
+
+// PathStateModel is the state of a path on the host
+type PathStateModel struct {
+	Path   types.String `tfsdk:"path"`
+	Exists types.Bool   `tfsdk:"exists"`
+	Type   types.String `tfsdk:"type"`
+	Mode   types.String `tfsdk:"mode"`
+	User   types.String `tfsdk:"user"`
+	Group  types.String `tfsdk:"group"`
+}
+
+var tmpfilesEntryAttrTypes = map[string]attr.Type{
+	"type":     types.StringType,
+	"path":     types.StringType,
+	"mode":     types.StringType,
+	"user":     types.StringType,
+	"group":    types.StringType,
+	"age":      types.StringType,
+	"argument": types.StringType,
+}
+
+var pathStateAttrTypes = map[string]attr.Type{
+	"path":   types.StringType,
+	"exists": types.BoolType,
+	"type":   types.StringType,
+	"mode":   types.StringType,
+	"user":   types.StringType,
+	"group":  types.StringType,
+}
+
+func NewTmpfilesResource() resource.Resource {
+	return &TmpfilesResource{}
+}
+
+func (r *TmpfilesResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	clients, ok := req.ProviderData.(*clientPool)
+	if !ok {
+		ProviderDataError(req.ProviderData, &resp.Diagnostics)
+	}
+
+	r.clients = clients
+}
+
+func (r *TmpfilesResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_tmpfiles"
+}
+
+func (r *TmpfilesResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	optional := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Optional:    true,
+			Description: description,
+		}
+	}
+	computed := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Description: description,
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "A tmpfiles.d(5) file, written to /etc/tmpfiles.d/<name>.conf, which declares paths to create, clean up or adjust. " +
+			"The agent applies it with systemd-tmpfiles --create as soon as it is written, as well as at every boot, and reports the resulting paths. " +
+			"A path whose type, mode or owners no longer match is created or adjusted again by the next apply. " +
+			"Destroying the resource removes the file, but leaves the paths it created in place.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the file",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"host": hostResourceAttribute(),
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the file, without the .conf suffix, e.g. app. A file in /etc/tmpfiles.d overrides one of the same name shipped by a package.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtMost(common.MaxDropInNameLength),
+					stringvalidator.RegexMatches(common.DropInNamePattern, "must be a file name without slashes, such as app"),
+				},
+			},
+			"entries": schema.ListNestedAttribute{
+				Required:    true,
+				Description: "Lines of the file, in order",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"type": schema.StringAttribute{
+							Required: true,
+							Description: "Action to take, e.g. d to create a directory, f to create a file, L to create a symlink or z to adjust the mode and owners of a path, " +
+								"optionally followed by modifiers such as + to replace what is already there",
+						},
+						"path": schema.StringAttribute{
+							Required:    true,
+							Description: "Absolute path, which may contain globs for actions which adjust or clean up existing paths",
+						},
+						"mode":     optional("Octal access mode, e.g. 0750. Prefix it with ~ to mask the default mode, or with : to only apply it when the path is created."),
+						"user":     optional("User owning the path, by name or UID. Defaults to root."),
+						"group":    optional("Group owning the path, by name or GID. Defaults to root."),
+						"age":      optional("Remove files below the path which weren't used for this long, when systemd-tmpfiles-clean.timer runs, e.g. 10d"),
+						"argument": optional("Depends on the type, e.g. the target of a symlink or the content written to a file"),
+					},
+				},
+			},
+			"content": computed("The rendered file"),
+			"path":    computed("Location of the file on the host"),
+			"paths": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "State of each path of the entries without globs or specifiers, in order, after the file was applied",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path":   computed("The path"),
+						"exists": schema.BoolAttribute{Computed: true, Description: "Whether the path exists"},
+						"type":   computed("One of directory, file, symlink, fifo, device, socket or other. Empty if the path doesn't exist."),
+						"mode":   computed("Octal access mode, e.g. 0750"),
+						"user":   computed("User owning the path, or its UID if it has no name"),
+						"group":  computed("Group owning the path, or its GID if it has no name"),
+					},
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *TmpfilesResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var entries types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("entries"), &entries)...)
+	if resp.Diagnostics.HasError() || entries.IsUnknown() {
+		return
+	}
+	var models []TmpfilesEntryModel
+	resp.Diagnostics.Append(entries.ElementsAs(ctx, &models, false)...)
+	for i, e := range models {
+		line, known := e.line()
+		if !known {
+			continue
+		}
+		if err := line.Validate(); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("entries").AtListIndex(i), "Invalid tmpfiles.d Entry", fmt.Sprintf("Entry %d is invalid: %s", i, err))
+		}
+	}
+}
+
+func (r *TmpfilesResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+	client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleTmpfiles, &resp.Diagnostics) {
+		return
+	}
+
+	var plan TmpfilesResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Always plan the rendered content, so that a file edited on the host is rewritten even if the entries haven't changed
+	content, known := plan.render(ctx, &resp.Diagnostics)
+	plan.Content = types.StringUnknown()
+	if known {
+		plan.Content = types.StringValue(content)
+	}
+	if !plan.Name.IsUnknown() {
+		plan.Path = types.StringValue(common.TmpfilesDir + "/" + plan.Name.ValueString() + ".conf")
+	}
+
+	// The paths are only known ahead of time if the file is unchanged and they still match it, otherwise the file is
+	// applied again, which creates or adjusts them
+	plan.Paths = types.ListUnknown(types.ObjectType{AttrTypes: pathStateAttrTypes})
+	if !req.State.Raw.IsNull() && known {
+		var state TmpfilesResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if state.Content.ValueString() == content && state.inSync(ctx, &resp.Diagnostics) {
+			plan.Paths = state.Paths
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+func (r *TmpfilesResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan TmpfilesResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	// Refuse to overwrite a file written by hand or by another configuration, it should be imported instead
+	_, err := client.SystemdGetTmpfiles(ctx, plan.Name.ValueString())
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "File already exists",
+			fmt.Sprintf("The tmpfiles.d file %s already exists on the host. Import it to manage it with Terraform.", plan.Name.ValueString()))
+		return
+	}
+	if !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to create tmpfiles.d file", fmt.Sprintf("Unable to check for an existing file %s. Unexpected error: %s", plan.Name.ValueString(), err))
+		return
+	}
+
+	tflog.Debug(ctx, "Writing tmpfiles.d file", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *TmpfilesResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state TmpfilesResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	state.Name = state.ID
+	tflog.Debug(ctx, "Fetching tmpfiles.d file", map[string]any{"id": state.ID.ValueString()})
+	tmpfiles, err := client.SystemdGetTmpfiles(ctx, state.ID.ValueString())
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "tmpfiles.d file no longer exists, removing from state", map[string]any{"id": state.ID.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read tmpfiles.d file", fmt.Sprintf("Unable to read tmpfiles.d file %s. Unexpected error: %s", state.ID.ValueString(), err))
+		return
+	}
+
+	if state.Content.ValueString() != tmpfiles.Content {
+		state.fromContent(ctx, tmpfiles.Content, &resp.Diagnostics)
+	}
+	state.fromResponse(ctx, tmpfiles, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *TmpfilesResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan TmpfilesResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Replacing tmpfiles.d file", map[string]any{"name": plan.Name.ValueString()})
+	r.write(ctx, client, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *TmpfilesResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state TmpfilesResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	tflog.Debug(ctx, "Deleting tmpfiles.d file", map[string]any{"id": state.ID.ValueString()})
+	err := client.SystemdDeleteTmpfiles(ctx, state.ID.ValueString())
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete tmpfiles.d file", fmt.Sprintf("Unable to delete tmpfiles.d file %s. Unexpected error: %s", state.ID.ValueString(), err))
+	}
+}
+
+func (r *TmpfilesResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importHostID(ctx, req, resp)
+}
+
+// write creates or replaces the file with the planned content, which the agent applies straight away
+func (r *TmpfilesResource) write(ctx context.Context, client *common.Client, plan *TmpfilesResourceModel, diags *diag.Diagnostics) {
+	content, _ := plan.render(ctx, diags)
+	if diags.HasError() {
+		return
+	}
+	tmpfiles, err := client.SystemdWriteTmpfiles(ctx, plan.Name.ValueString(), content)
+	if err != nil {
+		diags.AddError("Failed to write tmpfiles.d file", fmt.Sprintf("Unable to write and apply tmpfiles.d file. Unexpected error: %s", err))
+		return
+	}
+	plan.ID = plan.Name
+	plan.fromResponse(ctx, tmpfiles, diags)
+}
+
+// line returns the entry as a line of the file, and false if any of its attributes is not yet known
+func (e TmpfilesEntryModel) line() (common.TmpfilesLine, bool) {
+	fields := []types.String{e.Type, e.Path, e.Mode, e.User, e.Group, e.Age, e.Argument}
+	for _, f := range fields {
+		if f.IsUnknown() {
+			return common.TmpfilesLine{}, false
+		}
+	}
+	return common.TmpfilesLine{
+		Type:     e.Type.ValueString(),
+		Path:     e.Path.ValueString(),
+		Mode:     e.Mode.ValueString(),
+		User:     e.User.ValueString(),
+		Group:    e.Group.ValueString(),
+		Age:      e.Age.ValueString(),
+		Argument: e.Argument.ValueString(),
+	}, true
+}
+
+// lines returns the entries as lines of the file, and false if any of them is not yet known
+func (m *TmpfilesResourceModel) lines(ctx context.Context, diags *diag.Diagnostics) ([]common.TmpfilesLine, bool) {
+	if m.Entries.IsUnknown() {
+		return nil, false
+	}
+	var entries []TmpfilesEntryModel
+	diags.Append(m.Entries.ElementsAs(ctx, &entries, false)...)
+	lines := make([]common.TmpfilesLine, 0, len(entries))
+	for _, e := range entries {
+		line, known := e.line()
+		if !known {
+			return nil, false
+		}
+		lines = append(lines, line)
+	}
+	return lines, !diags.HasError()
+}
+
+// render formats the file, returns false if any entry is not yet known
+func (m *TmpfilesResourceModel) render(ctx context.Context, diags *diag.Diagnostics) (string, bool) {
+	lines, known := m.lines(ctx, diags)
+	if !known {
+		return "", false
+	}
+	return common.RenderTmpfiles(lines), true
+}
+
+// inSync returns true if every path the entries create or adjust has the type, mode and owners they give
+func (m *TmpfilesResourceModel) inSync(ctx context.Context, diags *diag.Diagnostics) bool {
+	lines, known := m.lines(ctx, diags)
+	if !known || m.Paths.IsNull() || m.Paths.IsUnknown() {
+		return false
+	}
+	var paths []PathStateModel
+	diags.Append(m.Paths.ElementsAs(ctx, &paths, false)...)
+	states := make(map[string]PathStateModel, len(paths))
+	for _, p := range paths {
+		states[p.Path.ValueString()] = p
+	}
+
+	for _, l := range lines {
+		state, ok := states[l.Path]
+		if !ok || !l.Literal() || strings.ContainsAny(l.Type, "!") {
+			// Paths with globs aren't reported, and lines marked ! only apply at boot
+			continue
+		}
+		if !state.Exists.ValueBool() {
+			if l.Managed() && !strings.ContainsRune("eqQ", rune(l.Type[0])) {
+				return false
+			}
+			continue
+		}
+		// A mode prefixed with ~ or : isn't enforced on a path which already exists
+		if l.Mode != "" && !strings.ContainsAny(l.Mode[:1], "~:") && !sameMode(l.Mode, state.Mode.ValueString()) {
+			return false
+		}
+		if !sameOwner(l.User, state.User.ValueString()) || !sameOwner(l.Group, state.Group.ValueString()) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMode compares octal modes, which may or may not have a leading zero
+func sameMode(expected string, actual string) bool {
+	e, err1 := strconv.ParseUint(expected, 8, 32)
+	a, err2 := strconv.ParseUint(actual, 8, 32)
+	return err1 == nil && err2 == nil && e == a
+}
+
+// sameOwner returns true if the path is owned by the expected user or group, which is root by default
+// An owner given by ID is reported by name, so it cannot be compared and is assumed to match.
+func sameOwner(expected string, actual string) bool {
+	expected = strings.TrimPrefix(expected, ":")
+	if expected == "" {
+		expected = "root"
+	}
+	if _, err := strconv.Atoi(expected); err == nil {
+		return true
+	}
+	return expected == actual
+}
+
+func (m *TmpfilesResourceModel) fromResponse(ctx context.Context, tmpfiles common.TmpfilesResponse, diags *diag.Diagnostics) {
+	m.Content = types.StringValue(tmpfiles.Content)
+	m.Path = types.StringValue(tmpfiles.Path)
+	paths := make([]PathStateModel, 0, len(tmpfiles.Paths))
+	for _, p := range tmpfiles.Paths {
+		paths = append(paths, PathStateModel{
+			Path:   types.StringValue(p.Path),
+			Exists: types.BoolValue(p.Exists),
+			Type:   types.StringValue(p.Type),
+			Mode:   types.StringValue(p.Mode),
+			User:   types.StringValue(p.User),
+			Group:  types.StringValue(p.Group),
+		})
+	}
+	v, d := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: pathStateAttrTypes}, paths)
+	diags.Append(d...)
+	m.Paths = v
+}
+
+// fromContent replaces the entries with those parsed from the file on the host
+func (m *TmpfilesResourceModel) fromContent(ctx context.Context, content string, diags *diag.Diagnostics) {
+	lines, err := common.ParseTmpfiles(content)
+	if err != nil {
+		tflog.Warn(ctx, "tmpfiles.d file on the host is not valid, only reporting drift in its content", map[string]any{"error": err.Error()})
+		return
+	}
+	value := func(s string) types.String {
+		if s == "" {
+			return types.StringNull()
+		}
+		return types.StringValue(s)
+	}
+	entries := make([]TmpfilesEntryModel, 0, len(lines))
+	for _, l := range lines {
+		entries = append(entries, TmpfilesEntryModel{
+			Type:     types.StringValue(l.Type),
+			Path:     types.StringValue(l.Path),
+			Mode:     value(l.Mode),
+			User:     value(l.User),
+			Group:    value(l.Group),
+			Age:      value(l.Age),
+			Argument: value(l.Argument),
+		})
+	}
+	v, d := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: tmpfilesEntryAttrTypes}, entries)
+	diags.Append(d...)
+	m.Entries = v
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+func TestAccTmpfilesResource(t *testing.T) {
+	config := func(user string) string {
+		return providerConfig() + fmt.Sprintf(`
+	resource "linux_tmpfiles" "test" {
+	  name = "app"
+	  entries = [
+	    {
+	      type  = "d"
+	      path  = "/srv/app"
+	      mode  = "0750"
+	      user  = %q
+	      group = "root"
+	    },
+	    {
+	      type     = "L+"
+	      path     = "/srv/current"
+	      argument = "/srv/app"
+	    },
+	    {
+	      type = "e"
+	      path = "/var/cache/app/*"
+	      age  = "10d"
+	    },
+	  ]
+	}
+	`, user)
+	}
+	content := "d /srv/app 0750 root root -\nL+ /srv/current - - - - /srv/app\ne /var/cache/app/* - - - 10d\n"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, ok := testAgent.Tmpfiles.File("app"); ok {
+				return fmt.Errorf("tmpfiles.d file app still exists")
+			}
+			if !testAgent.Tmpfiles.Path("/srv/app").Exists {
+				return fmt.Errorf("expected /srv/app to be left in place")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					testAgent.Tmpfiles.SetFile("app", "d /srv/app\n")
+				},
+				Config:      config("root"),
+				ExpectError: regexp.MustCompile("File already exists"),
+			},
+			{
+				// Nothing is written if an owner doesn't exist
+				PreConfig: func() {
+					testAgent.Tmpfiles.Reset()
+				},
+				Config:      config("app"),
+				ExpectError: regexp.MustCompile(`failed to resolve user\s+"app"`),
+			},
+			{
+				Config: config("root"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "path", "/etc/tmpfiles.d/app.conf"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "content", content),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.#", "2"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.path", "/srv/app"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.exists", "true"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.type", "directory"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.mode", "0750"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.user", "root"),
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.1.type", "symlink"),
+					testAccCheckTmpfiles("app", content),
+				),
+			},
+			{
+				ResourceName:      "linux_tmpfiles.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				// A path whose mode was changed on the host is adjusted again
+				PreConfig: func() {
+					testAgent.Tmpfiles.SetPath(systemd.PathState{Path: "/srv/app", Exists: true, Type: "directory", Mode: 0o777, User: "root", Group: "root"})
+				},
+				Config: config("root"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_tmpfiles.test", "paths.0.mode", "0750"),
+					func(*terraform.State) error {
+						if mode := testAgent.Tmpfiles.Path("/srv/app").Mode; mode != 0o750 {
+							return fmt.Errorf("expected /srv/app to have mode 0750, got %o", mode)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Changes made to the file on the host are reverted
+				PreConfig: func() {
+					testAgent.Tmpfiles.SetFile("app", "d /srv/app 0700\n")
+				},
+				Config: config("root"),
+				Check:  testAccCheckTmpfiles("app", content),
+			},
+		},
+	})
+}
+
+func TestAccTmpfilesResourceInvalidEntry(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_tmpfiles" "test" {
+				  name    = "app"
+				  entries = [{ type = "d", path = "srv/app" }]
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid tmpfiles.d Entry"),
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_tmpfiles" "test" {
+				  name    = "app"
+				  entries = [{ type = "d", path = "/srv/app", mode = "rwx" }]
+				}
+				`,
+				ExpectError: regexp.MustCompile("Invalid tmpfiles.d Entry"),
+			},
+		},
+	})
+}
+
+func TestAccTmpfilesResourceReadOnly(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAgent.Tmpfiles.SetReadOnly(true)
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// The plan fails, rather than the apply after other resources have changed
+				Config: providerConfig() + `
+				resource "linux_tmpfiles" "test" {
+				  name    = "app"
+				  entries = [{ type = "d", path = "/srv/app" }]
+				}
+				`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`does not have the tmpfiles module\s+enabled`),
+			},
+		},
+	})
+}
+
+func testAccCheckTmpfiles(name string, expected string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		content, ok := testAgent.Tmpfiles.File(name)
+		if !ok {
+			return fmt.Errorf("tmpfiles.d file %s does not exist", name)
+		}
+		if content != expected {
+			return fmt.Errorf("expected tmpfiles.d file %s to contain %q, got %q", name, expected, content)
+		}
+		return nil
+	}
+}
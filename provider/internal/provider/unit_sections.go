@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var unitKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// unitSection is a section of a unit file described by a map attribute, for resources with a structured schema
+type unitSection struct {
+	name  string
+	value *types.Map
+}
+
+func unitSectionAttribute(name string) schema.MapAttribute {
+	return schema.MapAttribute{
+		Optional:    true,
+		ElementType: types.StringType,
+		Description: fmt.Sprintf("Assignments in the [%s] section. A value containing newlines is written as one assignment per line, "+
+			"for keys which may be repeated such as ExecStartPre. Conflicts with content.", name),
+		Validators: []validator.Map{
+			mapvalidator.KeysAre(stringvalidator.RegexMatches(unitKeyPattern, "must be a unit file key, such as ExecStart")),
+		},
+	}
+}
+
+// structuredSections returns true if the file is described by its sections, rather than raw content
+func structuredSections(sections []unitSection) bool {
+	for _, s := range sections {
+		if !s.value.IsNull() {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSections formats the sections as a unit file, keys are sorted so that the output is stable
+// Returns false if any value is not yet known
+func renderSections(ctx context.Context, sections []unitSection, diags *diag.Diagnostics) (string, bool) {
+	var rendered []common.UnitSection
+	for _, attr := range sections {
+		if attr.value.IsNull() {
+			continue
+		}
+		if attr.value.IsUnknown() {
+			return "", false
+		}
+		var values map[string]types.String
+		diags.Append(attr.value.ElementsAs(ctx, &values, false)...)
+		if diags.HasError() {
+			return "", false
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		section := common.UnitSection{Name: attr.name}
+		for _, k := range keys {
+			v := values[k]
+			if v.IsUnknown() {
+				return "", false
+			}
+			// A trailing newline, e.g. from a heredoc, would otherwise add an empty assignment, which resets the key
+			for _, line := range strings.Split(strings.TrimRight(v.ValueString(), "\n"), "\n") {
+				section.Entries = append(section.Entries, common.UnitEntry{Key: k, Value: line})
+			}
+		}
+		rendered = append(rendered, section)
+	}
+	return common.RenderUnit(rendered), true
+}
+
+// sectionsFromContent replaces the sections with those parsed from content
+// Sections which aren't part of the structured schema are ignored, the content attribute still reports them as drift.
+func sectionsFromContent(ctx context.Context, content string, sections []unitSection, diags *diag.Diagnostics) {
+	parsed, err := common.ParseUnit(content)
+	if err != nil {
+		tflog.Warn(ctx, "File on the host is not a valid unit file, only reporting drift in its content", map[string]any{"error": err.Error()})
+		return
+	}
+	for _, attr := range sections {
+		var values map[string]string
+		for _, s := range parsed {
+			if s.Name != attr.name {
+				continue
+			}
+			if values == nil {
+				values = make(map[string]string)
+			}
+			for _, e := range s.Entries {
+				if v, ok := values[e.Key]; ok {
+					values[e.Key] = v + "\n" + e.Value
+				} else {
+					values[e.Key] = e.Value
+				}
+			}
+		}
+		if values == nil {
+			*attr.value = types.MapNull(types.StringType)
+			continue
+		}
+		v, d := types.MapValueFrom(ctx, types.StringType, values)
+		diags.Append(d...)
+		*attr.value = v
+	}
+}
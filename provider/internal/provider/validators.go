@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+var _ validator.String = durationValidator{}
+
+// durationValidator checks that a string is a positive Go duration, e.g. 30s or 5m
+type durationValidator struct{}
+
+func (v durationValidator) Description(_ context.Context) string {
+	return "value must be a positive duration, such as 30s or 5m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	d, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("%s is not positive", d)
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Duration", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = unitFileValidator{}
+
+// unitFileValidator checks that a string parses as a systemd unit file
+type unitFileValidator struct{}
+
+func (v unitFileValidator) Description(_ context.Context) string {
+	return "value must be a systemd unit file, made up of [Section] headers and Key=Value assignments"
+}
+
+func (v unitFileValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v unitFileValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := common.ParseUnit(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Unit File", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = calendarValidator{}
+
+// calendarValidator checks that a string is a systemd calendar event expression, e.g. Mon..Fri *-*-* 09:00
+type calendarValidator struct{}
+
+func (v calendarValidator) Description(_ context.Context) string {
+	return "value must be a systemd calendar event, such as daily or Mon..Fri *-*-* 09:00:00"
+}
+
+func (v calendarValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v calendarValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := common.ValidateCalendar(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Calendar Expression", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = timespanValidator{}
+
+// timespanValidator checks that a string is a systemd time span, e.g. 90 or 1h 30min
+type timespanValidator struct{}
+
+func (v timespanValidator) Description(_ context.Context) string {
+	return "value must be a systemd time span, such as 90, 5min or 1h 30min"
+}
+
+func (v timespanValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v timespanValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := common.ValidateTimespan(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Time Span", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = timestampValidator{}
+
+// timestampValidator checks that a string is an RFC 3339 timestamp
+type timestampValidator struct{}
+
+func (v timestampValidator) Description(_ context.Context) string {
+	return "value must be an RFC 3339 timestamp, such as 2024-06-10T06:00:00Z"
+}
+
+func (v timestampValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v timestampValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := time.Parse(time.RFC3339, req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Timestamp", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = priorityValidator{}
+
+// priorityValidator checks that a string is a syslog priority, by name or number
+type priorityValidator struct{}
+
+func (v priorityValidator) Description(_ context.Context) string {
+	return "value must be a syslog priority, such as err or 3"
+}
+
+func (v priorityValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v priorityValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := common.ParsePriority(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Priority", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = sizeValidator{}
+
+// sizeValidator checks that a string is a size in bytes, e.g. 512M, or infinity
+type sizeValidator struct{}
+
+func (v sizeValidator) Description(_ context.Context) string {
+	return "value must be a size in bytes, such as 512M or 2G, or infinity"
+}
+
+func (v sizeValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v sizeValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := common.ParseSize(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Size", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = cpuQuotaValidator{}
+
+// cpuQuotaValidator checks that a string is a CPU quota, a percentage of one CPU's time, e.g. 150%
+type cpuQuotaValidator struct{}
+
+func (v cpuQuotaValidator) Description(_ context.Context) string {
+	return "value must be a percentage of one CPU's time, such as 50% or 150%"
+}
+
+func (v cpuQuotaValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v cpuQuotaValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := common.ParseCPUQuota(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid CPU Quota", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
+
+var _ validator.String = tasksMaxValidator{}
+
+// tasksMaxValidator checks that a string is a positive number of tasks, or infinity
+type tasksMaxValidator struct{}
+
+func (v tasksMaxValidator) Description(_ context.Context) string {
+	return "value must be a positive number of tasks, or infinity"
+}
+
+func (v tasksMaxValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v tasksMaxValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := common.ParseTasksMax(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid Tasks Limit", fmt.Sprintf("The %s %s: %s", req.Path, v.Description(ctx), err))
+	}
+}
@@ -15,10 +15,11 @@ var (
 )
 
 type zpoolDataSource struct {
-	client *common.Client
+	clients *clientPool
 }
 
 type zpoolDataSourceModel struct {
+	Host   types.String     `tfsdk:"host"`
 	Zpools []zpoolDataModel `tfsdk:"zpools"`
 }
 
@@ -36,11 +37,11 @@ func (d *zpoolDataSource) Configure(ctx context.Context, req datasource.Configur
 		return
 	}
 
-	client, ok := req.ProviderData.(*common.Client)
+	clients, ok := req.ProviderData.(*clientPool)
 	if !ok {
 		UnexpectedDataSourceConfigureType(ctx, req, resp)
 	}
-	d.client = client
+	d.clients = clients
 }
 
 func (d *zpoolDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -49,15 +50,25 @@ func (d *zpoolDataSource) Metadata(_ context.Context, req datasource.MetadataReq
 
 func (d *zpoolDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "Get configuration for a ZPool",
+		Description: "List the zpools on the host",
 		Attributes: map[string]schema.Attribute{
-			"id": schema.StringAttribute{
+			"host": hostDataSourceAttribute(),
+			"zpools": schema.ListNestedAttribute{
 				Computed:    true,
-				Description: "An identifier for the zpool",
+				Description: "Zpools on the host",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "An identifier for the zpool",
+						},
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "Name of the zpool",
+						},
+					},
+				},
 			},
-			"name": schema.StringAttribute{
-				Computed:    true,
-				Description: "Name of the zpool"},
 		},
 	}
 }
@@ -65,7 +76,16 @@ func (d *zpoolDataSource) Schema(ctx context.Context, req datasource.SchemaReque
 func (d *zpoolDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
 	var state zpoolDataSourceModel
 
-	zpools, err := d.client.ZfsGetPools(ctx)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	client := d.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil || !requireModule(ctx, client, common.ModuleZfs, &resp.Diagnostics) {
+		return
+	}
+
+	zpools, err := client.ZfsGetPools(ctx)
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to get zpools", err.Error())
 		return
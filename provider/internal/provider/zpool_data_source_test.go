@@ -2,29 +2,52 @@ package provider
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
 func TestAccZpoolDataSource(t *testing.T) {
 	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			{
-				Config: providerConfig + `
+				Config: providerConfig() + `
 				resource "linux_zpool" "pool1" {
 				  name = "tank"
 				}
 				`,
 			},
 			{
-				Config: providerConfig + `
+				Config: providerConfig() + `
+				resource "linux_zpool" "pool1" {
+				  name = "tank"
+				}
+
 				data "linux_zpools" "pools" {}
 				`,
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("data.zpools.pools", "zpools.#", "1"),
+					resource.TestCheckResourceAttr("data.linux_zpools.pools", "zpools.#", "1"),
+					resource.TestCheckResourceAttr("data.linux_zpools.pools", "zpools.0.name", "tank"),
 				),
 			},
 		},
 	})
 }
+
+func TestAccZpoolDataSourceSlowBackend(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: testAccSlowBackend(500 * time.Millisecond),
+				Config: providerConfig() + `
+				data "linux_zpools" "pools" {}
+				`,
+				Check: resource.TestCheckResourceAttr("data.linux_zpools.pools", "zpools.#", "0"),
+			},
+		},
+	})
+}
@@ -2,11 +2,16 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
-	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/nickrobison/terraform-linux-provider/common"
@@ -15,15 +20,18 @@ import (
 var (
 	_ resource.Resource                = &ZpoolResource{}
 	_ resource.ResourceWithImportState = &ZpoolResource{}
+	_ resource.ResourceWithModifyPlan  = &ZpoolResource{}
 )
 
 type ZpoolResource struct {
-	client *common.Client
+	clients *clientPool
 }
 
 type ZpoolResourceModel struct {
-	ID   types.String `tfsdk:"id"`
-	Name types.String `tfsdk:"name"`
+	ID       types.String   `tfsdk:"id"`
+	Host     types.String   `tfsdk:"host"`
+	Name     types.String   `tfsdk:"name"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
 func NewZpoolResource() resource.Resource {
@@ -35,31 +43,59 @@ func (r *ZpoolResource) Configure(ctx context.Context, req resource.ConfigureReq
 		return
 	}
 
-	client, ok := req.ProviderData.(*common.Client)
+	clients, ok := req.ProviderData.(*clientPool)
 	if !ok {
 		ProviderDataError(req.ProviderData, &resp.Diagnostics)
 	}
 
-	r.client = client
+	r.clients = clients
 }
 
 func (r *ZpoolResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_zpool"
 }
 
-func (r *ZpoolResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *ZpoolResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Zpool",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed:    true,
 				Description: "An identifier for the zpool",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
+			"host": hostResourceAttribute(),
 			"name": schema.StringAttribute{
 				Required:    true,
 				Description: "Zpool name",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(common.ZpoolNamePattern,
+						"must begin with a letter and contain only alphanumerics, underscore, hyphen, colon and period"),
+				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *ZpoolResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.clients == nil {
+		return
+	}
+	if client := r.clients.forPlan(ctx, req.Plan, &resp.Diagnostics); client != nil {
+		requireModule(ctx, client, common.ModuleZfs, &resp.Diagnostics)
 	}
 }
 
@@ -72,21 +108,31 @@ func (r *ZpoolResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
+	timeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, plan.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
 	name := plan.Name.ValueString()
 	request := common.ZpoolCreateRequest{
 		Name: name,
 	}
 	tflog.Debug(ctx, "Attempting to create zpool", map[string]any{"name": name})
 
-	pool, err := r.client.ZfsCreatePool(ctx, request)
+	pool, err := client.ZfsCreatePool(ctx, request)
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to create zpool", fmt.Sprintf("Failed to create zpool. Unexpected error: %s", err.Error()))
 		return
 	}
-	plan = ZpoolResourceModel{
-		ID:   types.StringValue(pool.Name),
-		Name: types.StringValue(pool.Name),
-	}
+	plan.ID = types.StringValue(pool.Name)
+	plan.Name = types.StringValue(pool.Name)
 
 	diags = resp.State.Set(ctx, plan)
 	resp.Diagnostics.Append(diags...)
@@ -104,11 +150,28 @@ func (r *ZpoolResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
+	timeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
 	zpoolName := state.ID.ValueString()
 	tflog.Debug(ctx, "Fetching zpool", map[string]any{"id": zpoolName})
-	err := r.doRead(ctx, zpoolName, &state)
+	err := r.doRead(ctx, client, zpoolName, &state)
+	if errors.Is(err, common.ErrNotFound) {
+		tflog.Warn(ctx, "Zpool no longer exists, removing from state", map[string]any{"id": zpoolName})
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Failed to read zpool", fmt.Sprintf("Unable to read zpool. Unexpected error: %a", err))
+		resp.Diagnostics.AddError("Failed to read zpool", fmt.Sprintf("Unable to read zpool. Unexpected error: %s", err))
 		return
 	}
 
@@ -119,20 +182,56 @@ func (r *ZpoolResource) Read(ctx context.Context, req resource.ReadRequest, resp
 	}
 }
 
-func (r *ZpoolResource) Update(_ context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	// TODO: Implement
+func (r *ZpoolResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Every attribute requires replacement, so only the timeouts can change in place
+	var plan ZpoolResourceModel
+
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
 }
 
-func (r *ZpoolResource) Delete(_ context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	// TODO: Implement
+func (r *ZpoolResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state ZpoolResourceModel
+
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := r.clients.get(ctx, state.Host, &resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	zpoolName := state.ID.ValueString()
+	tflog.Debug(ctx, "Destroying zpool", map[string]any{"id": zpoolName})
+	err := client.ZfsDestroyPool(ctx, zpoolName)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to destroy zpool", fmt.Sprintf("Unable to destroy zpool %s. Unexpected error: %s", zpoolName, err))
+		return
+	}
 }
 
 func (r *ZpoolResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	importHostID(ctx, req, resp)
 }
 
-func (r *ZpoolResource) doRead(ctx context.Context, id string, data *ZpoolResourceModel) error {
-	zpool, err := r.client.ZfsGetPool(ctx, id)
+func (r *ZpoolResource) doRead(ctx context.Context, client *common.Client, id string, data *ZpoolResourceModel) error {
+	zpool, err := client.ZfsGetPool(ctx, id)
 	if err != nil {
 		return err
 	}
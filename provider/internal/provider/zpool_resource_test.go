@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccZpoolResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckZpoolDestroyed,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_zpool" "test" {
+				  name = "tank"
+				}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_zpool.test", "id", "tank"),
+					resource.TestCheckResourceAttr("linux_zpool.test", "name", "tank"),
+				),
+			},
+			{
+				ResourceName:      "linux_zpool.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: providerConfig() + `
+				resource "linux_zpool" "test" {
+				  name = "data"
+				}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_zpool.test", "id", "data"),
+					testAccCheckZpools("data"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccZpoolResourceRemovedOutOfBand(t *testing.T) {
+	config := providerConfig() + `
+	resource "linux_zpool" "test" {
+	  name = "tank"
+	}
+	`
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccCheckZpools("tank"),
+					func(*terraform.State) error {
+						testAgent.Reset()
+						return nil
+					},
+				),
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check:  testAccCheckZpools("tank"),
+			},
+		},
+	})
+}
+
+func TestAccZpoolResourcePartialApply(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckZpoolDestroyed,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: testAccFailZfs("CreatePool", 1, errors.New("pool is busy")),
+				Config: providerConfig() + `
+				resource "linux_zpool" "first" {
+				  name = "tank"
+				}
+
+				resource "linux_zpool" "second" {
+				  name       = "data"
+				  depends_on = [linux_zpool.first]
+				}
+				`,
+				ExpectError: regexp.MustCompile("pool is busy"),
+			},
+			{
+				// The first pool was created before the failure, so it must have been recorded in state
+				PreConfig: testAccFailZfs("CreatePool", 0, nil),
+				Config: providerConfig() + `
+				resource "linux_zpool" "first" {
+				  name = "tank"
+				}
+				`,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccZpoolResourceInvalidName(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig() + `
+				resource "linux_zpool" "test" {
+				  name = "1tank"
+				}
+				`,
+				ExpectError: regexp.MustCompile("must begin with a letter"),
+			},
+		},
+	})
+}
+
+// testAccCheckZpools verifies that the test agent has exactly the given pools
+func testAccCheckZpools(names ...string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		pools := testAgent.Zfs.Pools()
+		if fmt.Sprint(pools) != fmt.Sprint(names) {
+			return fmt.Errorf("expected zpools %v, got %v", names, pools)
+		}
+		return nil
+	}
+}
+
+func testAccCheckZpoolDestroyed(s *terraform.State) error {
+	return testAccCheckZpools()(s)
+}
+
+func TestAccZpoolResourceTimeouts(t *testing.T) {
+	config := func(create string) string {
+		return providerConfig() + fmt.Sprintf(`
+		resource "linux_zpool" "test" {
+		  name = "tank"
+
+		  timeouts {
+		    create = %q
+		  }
+		}
+		`, create)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testAccCheckZpoolDestroyed,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					// Only the create is slow, so that the provider can still configure itself
+					testAgent.Zfs.SetMethodDelay("CreatePool", time.Minute)
+				},
+				Config:      config("500ms"),
+				ExpectError: regexp.MustCompile("deadline exceeded"),
+			},
+			{
+				PreConfig: func() {
+					testAgent.Zfs.SetMethodDelay("CreatePool", 0)
+				},
+				Config: config("1m"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("linux_zpool.test", "timeouts.create", "1m"),
+					testAccCheckZpools("tank"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,105 @@
+// Package apitest runs the agent's API in-process, backed by in-memory fakes of every backend,
+// so that the provider can be tested without a real host.
+package apitest
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/api"
+	"github.com/nickrobison/terraform-linux-provider/server/capabilities"
+	"github.com/nickrobison/terraform-linux-provider/server/health"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+// AgentVersion is reported by the test agent's capabilities endpoint
+const AgentVersion = "test"
+
+// Agent is an agent API listening on a local port
+type Agent struct {
+	Zfs      *Zfs
+	Systemd  *Systemd
+	Config   *Config
+	Tmpfiles *Tmpfiles
+	Sysusers *Sysusers
+	Journal  *Journal
+	Login    *Login
+
+	server *httptest.Server
+}
+
+// NewAgent starts an agent with every module enabled
+// Callers must Close the agent when they are done with it
+func NewAgent() *Agent {
+	middleware.SetupLogging(io.Discard, zerolog.Disabled)
+
+	a := &Agent{Zfs: NewZfs(), Systemd: NewSystemd(), Journal: NewJournal(), Login: NewLogin()}
+	a.Config = NewConfig(a.Systemd)
+	a.Sysusers = NewSysusers()
+	a.Tmpfiles = NewTmpfiles(a.Sysusers)
+	a.server = httptest.NewServer(api.NewServer(api.Dependencies{
+		AgentVersion: AgentVersion,
+		Checks: []health.Dependency{
+			{Name: common.ModuleZfs, Check: a.Zfs.Version},
+			{Name: common.ModuleSystemd, Check: a.Systemd.Version},
+			{Name: common.ModuleJournal, Check: a.Journal.Version},
+			{Name: common.ModuleLogin, Check: a.Login.Version},
+		},
+		Modules: map[string]capabilities.Module{
+			common.ModuleZfs:         capabilities.Static(common.ModuleCapability{Enabled: true, Version: AgentVersion}),
+			common.ModuleSystemd:     capabilities.Static(common.ModuleCapability{Enabled: true, Version: AgentVersion}),
+			common.ModuleJournal:     capabilities.Static(common.ModuleCapability{Enabled: true, Version: AgentVersion}),
+			common.ModuleLogin:       capabilities.Static(common.ModuleCapability{Enabled: true, Version: AgentVersion}),
+			common.ModuleUnitFiles:   capabilities.Static(common.ModuleCapability{Enabled: true}),
+			common.ModuleConfigFiles: capabilities.Static(common.ModuleCapability{Enabled: true}),
+			common.ModuleTmpfiles:    a.Tmpfiles.Capability,
+			common.ModuleSysusers:    capabilities.Static(common.ModuleCapability{Enabled: true}),
+		},
+		Zfs:      a.Zfs,
+		Systemd:  a.Systemd,
+		Config:   a.Config,
+		Tmpfiles: a.Tmpfiles,
+		Sysusers: a.Sysusers,
+		Journal:  a.Journal,
+		Login:    a.Login,
+	}))
+	return a
+}
+
+// URL returns the base URL of the agent
+func (a *Agent) URL() string {
+	return a.server.URL
+}
+
+// Host returns the address the agent is listening on
+func (a *Agent) Host() string {
+	host, _, _ := net.SplitHostPort(a.server.Listener.Addr().String())
+	return host
+}
+
+// Port returns the port the agent is listening on
+func (a *Agent) Port() int {
+	_, port, _ := net.SplitHostPort(a.server.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// Reset removes all state from the fake backends and clears any injected faults
+func (a *Agent) Reset() {
+	a.Zfs.Reset()
+	a.Systemd.Reset()
+	a.Config.Reset()
+	a.Tmpfiles.Reset()
+	a.Sysusers.Reset()
+	a.Journal.Reset()
+	a.Login.Reset()
+}
+
+// Close shuts down the agent
+func (a *Agent) Close() {
+	a.server.Close()
+}
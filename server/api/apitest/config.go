@@ -0,0 +1,149 @@
+package apitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+var _ systemd.ConfigClient = &Config{}
+
+// Config is an in-memory systemd.ConfigClient, which restarts the units of the fake systemd
+type Config struct {
+	faults
+
+	systemd *Systemd
+	mu      sync.Mutex
+	dropIns map[string]map[string]string
+}
+
+// NewConfig returns a fake with no drop-ins
+func NewConfig(systemd *Systemd) *Config {
+	c := &Config{systemd: systemd}
+	c.Reset()
+	return c
+}
+
+// SetDropIn writes a drop-in, bypassing validation and any injected faults, as if it were edited on the host
+func (c *Config) SetDropIn(file string, name string, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dropIns[file] == nil {
+		c.dropIns[file] = make(map[string]string)
+	}
+	c.dropIns[file][name] = content
+}
+
+// DropIn returns the content of the named drop-in of a configuration file, if it exists
+func (c *Config) DropIn(file string, name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, ok := c.dropIns[file][name]
+	return content, ok
+}
+
+// Reset removes every drop-in and clears any injected faults
+func (c *Config) Reset() {
+	c.faults.reset()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropIns = make(map[string]map[string]string)
+}
+
+func (c *Config) GetConfigDropIn(ctx context.Context, file string, name string) (systemd.ConfigDropIn, error) {
+	if err := c.inject(ctx, "GetConfigDropIn"); err != nil {
+		return systemd.ConfigDropIn{}, err
+	}
+	config, err := configFile(file, name)
+	if err != nil {
+		return systemd.ConfigDropIn{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, ok := c.dropIns[file][name]
+	if !ok {
+		return systemd.ConfigDropIn{}, fmt.Errorf("drop-in %s of %s: %w", name, file, bus.ErrNotFound)
+	}
+	return systemd.ConfigDropIn{File: file, Name: name, Path: filepath.Join(config.Dir, name+".conf"), Content: content}, nil
+}
+
+func (c *Config) WriteConfigDropIn(ctx context.Context, file string, name string, content string) (systemd.ConfigDropIn, error) {
+	if err := c.inject(ctx, "WriteConfigDropIn"); err != nil {
+		return systemd.ConfigDropIn{}, err
+	}
+	config, err := configFile(file, name)
+	if err != nil {
+		return systemd.ConfigDropIn{}, err
+	}
+	if err := config.Validate(content); err != nil {
+		return systemd.ConfigDropIn{}, fmt.Errorf("invalid drop-in %s of %s: %s: %w", name, file, err, bus.ErrInvalid)
+	}
+	dropIn := systemd.ConfigDropIn{File: file, Name: name, Path: filepath.Join(config.Dir, name+".conf"), Content: content}
+
+	c.mu.Lock()
+	existing, ok := c.dropIns[file][name]
+	if c.dropIns[file] == nil {
+		c.dropIns[file] = make(map[string]string)
+	}
+	c.dropIns[file][name] = content
+	c.mu.Unlock()
+	if ok && existing == content {
+		return dropIn, nil
+	}
+	dropIn.Restarted, err = c.apply(ctx, config)
+	return dropIn, err
+}
+
+func (c *Config) DeleteConfigDropIn(ctx context.Context, file string, name string) error {
+	if err := c.inject(ctx, "DeleteConfigDropIn"); err != nil {
+		return err
+	}
+	config, err := configFile(file, name)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	_, ok := c.dropIns[file][name]
+	delete(c.dropIns[file], name)
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("drop-in %s of %s: %w", name, file, bus.ErrNotFound)
+	}
+	_, err = c.apply(ctx, config)
+	return err
+}
+
+// apply restarts the unit which reads the configuration file, if it is running
+func (c *Config) apply(ctx context.Context, config common.ConfigFile) (string, error) {
+	if config.Unit == "" {
+		return "", nil
+	}
+	state, err := c.systemd.GetUnitState(ctx, config.Unit)
+	if errors.Is(err, bus.ErrNotFound) || (err == nil && !state.Active()) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.systemd.RestartUnit(ctx, config.Unit); err != nil {
+		return "", err
+	}
+	return config.Unit, nil
+}
+
+func configFile(file string, name string) (common.ConfigFile, error) {
+	config, ok := common.ConfigFiles[file]
+	if !ok {
+		return config, fmt.Errorf("configuration file %s: %w", file, bus.ErrNotFound)
+	}
+	if len(name) > common.MaxDropInNameLength || !common.DropInNamePattern.MatchString(name) {
+		return config, fmt.Errorf("invalid drop-in name %q: %w", name, bus.ErrInvalid)
+	}
+	return config, nil
+}
@@ -0,0 +1,149 @@
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+)
+
+var _ journal.JournalClient = &Journal{}
+
+// Journal is an in-memory journal.JournalClient
+type Journal struct {
+	faults
+
+	mu      sync.Mutex
+	entries []journal.Entry
+}
+
+// NewJournal returns a fake with an empty journal
+func NewJournal() *Journal {
+	j := &Journal{}
+	j.Reset()
+	return j
+}
+
+// AddEntries appends entries to the journal, bypassing any injected faults, as if they were logged on the host
+// Entries without a cursor are given one, and entries without a time are logged now.
+func (j *Journal) AddEntries(entries ...journal.Entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, entry := range entries {
+		if entry.Cursor == "" {
+			entry.Cursor = fmt.Sprintf("s=test;i=%x", len(j.entries)+1)
+		}
+		if entry.Time.IsZero() {
+			entry.Time = time.Now().UTC()
+		}
+		j.entries = append(j.entries, entry)
+	}
+}
+
+// Reset empties the journal and clears any injected faults
+func (j *Journal) Reset() {
+	j.faults.reset()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = nil
+}
+
+func (j *Journal) Version(ctx context.Context) (string, error) {
+	return AgentVersion, nil
+}
+
+func (j *Journal) Entries(ctx context.Context, query journal.Query) ([]journal.Entry, error) {
+	if err := j.inject(ctx, "Entries"); err != nil {
+		return nil, err
+	}
+	if !query.Since.IsZero() && query.Cursor != "" {
+		return nil, fmt.Errorf("since and cursor cannot both be set: %w", bus.ErrInvalid)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := j.entries
+	if query.Cursor != "" {
+		i := slices.IndexFunc(entries, func(e journal.Entry) bool { return e.Cursor == query.Cursor })
+		if i < 0 {
+			return nil, fmt.Errorf("no entry with cursor %q: %w", query.Cursor, bus.ErrInvalid)
+		}
+		entries = entries[i+1:]
+	}
+	boot, err := j.boot(query.Boot)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []journal.Entry{}
+	for _, entry := range entries {
+		if len(query.Units) > 0 && !slices.Contains(query.Units, entry.Unit) {
+			continue
+		}
+		if query.Priority != nil && entry.Priority > *query.Priority {
+			continue
+		}
+		if !query.Since.IsZero() && entry.Time.Before(query.Since) || !query.Until.IsZero() && entry.Time.After(query.Until) {
+			continue
+		}
+		if boot != "" && entry.BootID != boot {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	if query.Lines > 0 && len(matched) > query.Lines {
+		if query.Since.IsZero() && query.Cursor == "" {
+			matched = matched[len(matched)-query.Lines:]
+		} else {
+			matched = matched[:query.Lines]
+		}
+	}
+	return matched, nil
+}
+
+// boot resolves a boot ID or offset to the ID of a boot in the journal, the caller must hold the lock
+// Offsets count back from the latest boot if zero or negative, and forward from the first one if positive.
+func (j *Journal) boot(boot string) (string, error) {
+	if boot == "" {
+		return "", nil
+	}
+	var boots []string
+	for _, entry := range j.entries {
+		if !slices.Contains(boots, entry.BootID) {
+			boots = append(boots, entry.BootID)
+		}
+	}
+	if len(boot) >= 32 {
+		id, offset := boot[:32], boot[32:]
+		i := slices.Index(boots, id)
+		if i < 0 {
+			return "", fmt.Errorf("no such boot ID in journal: %w", bus.ErrNotFound)
+		}
+		if offset == "" {
+			return id, nil
+		}
+		n, _ := strconv.Atoi(offset)
+		return bootAt(boots, i+n)
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(boot, "+"))
+	if err != nil {
+		return "", fmt.Errorf("invalid boot %q: %w", boot, bus.ErrInvalid)
+	}
+	if n > 0 {
+		return bootAt(boots, n-1)
+	}
+	return bootAt(boots, len(boots)-1+n)
+}
+
+func bootAt(boots []string, i int) (string, error) {
+	if i < 0 || i >= len(boots) {
+		return "", fmt.Errorf("no such boot ID in journal: %w", bus.ErrNotFound)
+	}
+	return boots[i], nil
+}
@@ -0,0 +1,130 @@
+package apitest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/login"
+)
+
+var _ login.LoginClient = &Login{}
+
+// TestKernel is the kernel the fake host runs
+const TestKernel = "6.1.0-21-amd64"
+
+// Login is an in-memory login.LoginClient, whose host reboots as soon as a scheduled reboot is due
+type Login struct {
+	faults
+
+	mu      sync.Mutex
+	boot    login.Boot
+	reboots int
+}
+
+// NewLogin returns a fake of a host which doesn't need rebooting
+func NewLogin() *Login {
+	l := &Login{}
+	l.Reset()
+	return l
+}
+
+// SetBoot replaces the current boot, e.g. to give reasons to reboot or hold an inhibitor
+// A boot without an ID keeps the current one.
+func (l *Login) SetBoot(boot login.Boot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if boot.ID == "" {
+		boot.ID = l.boot.ID
+	}
+	l.boot = boot
+}
+
+// Reboots returns the number of times the host has rebooted
+func (l *Login) Reboots() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rebootIfDue()
+	return l.reboots
+}
+
+// Reset boots the host afresh and clears any injected faults
+func (l *Login) Reset() {
+	l.faults.reset()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.boot = login.Boot{ID: newBootID(), BootedAt: time.Now().UTC(), Kernel: TestKernel}
+	l.reboots = 0
+}
+
+func (l *Login) Version(ctx context.Context) (string, error) {
+	return AgentVersion, nil
+}
+
+func (l *Login) Boot(ctx context.Context) (login.Boot, error) {
+	if err := l.inject(ctx, "Boot"); err != nil {
+		return login.Boot{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rebootIfDue()
+	boot := l.boot
+	boot.Reasons = append([]string{}, boot.Reasons...)
+	boot.Packages = append([]string{}, boot.Packages...)
+	boot.Inhibitors = append([]login.Inhibitor{}, boot.Inhibitors...)
+	return boot, nil
+}
+
+func (l *Login) ScheduleReboot(ctx context.Context, at time.Time, ignoreInhibitors bool) error {
+	if err := l.inject(ctx, "ScheduleReboot"); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !ignoreInhibitors {
+		var blocking []string
+		for _, i := range l.boot.Inhibitors {
+			if i.BlocksShutdown() {
+				blocking = append(blocking, i.Who)
+			}
+		}
+		if len(blocking) > 0 {
+			return fmt.Errorf("reboot is blocked by %s: %w", strings.Join(blocking, ", "), bus.ErrConflict)
+		}
+	}
+	l.boot.ScheduledReboot = at.UTC()
+	return nil
+}
+
+func (l *Login) CancelReboot(ctx context.Context) (bool, error) {
+	if err := l.inject(ctx, "CancelReboot"); err != nil {
+		return false, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rebootIfDue()
+	cancelled := !l.boot.ScheduledReboot.IsZero()
+	l.boot.ScheduledReboot = time.Time{}
+	return cancelled, nil
+}
+
+// rebootIfDue starts a new boot once the scheduled reboot is due, the caller must hold the lock
+// The new boot runs the same kernel, and no longer needs rebooting.
+func (l *Login) rebootIfDue() {
+	if l.boot.ScheduledReboot.IsZero() || time.Now().Before(l.boot.ScheduledReboot) {
+		return
+	}
+	l.boot = login.Boot{ID: newBootID(), BootedAt: l.boot.ScheduledReboot, Kernel: l.boot.Kernel}
+	l.reboots++
+}
+
+func newBootID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,535 @@
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+var _ systemd.SystemdClient = &Systemd{}
+
+// Systemd is an in-memory systemd.SystemdClient
+// Writing a unit file loads a unit, which is inactive and disabled until its state is changed
+type Systemd struct {
+	faults
+
+	mu       sync.Mutex
+	units    map[string]string
+	dropIns  map[string]map[string]string
+	states   map[string]systemd.UnitState
+	failing  map[string]string
+	restarts map[string]int
+	timers   map[string]systemd.TimerState
+	// resources holds the cgroup limits set on each unit, and whether they were last set at runtime
+	resources map[string]systemd.UnitResources
+	runtime   map[string]bool
+	reloads   int
+}
+
+// NewSystemd returns a fake with no unit files or units
+func NewSystemd() *Systemd {
+	s := &Systemd{}
+	s.Reset()
+	return s
+}
+
+// SetUnitFile writes a unit file without reloading, bypassing any injected faults, as if it were edited on the host
+func (s *Systemd) SetUnitFile(name string, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.units[name] = content
+}
+
+// SetDropIn writes a drop-in without reloading, bypassing any injected faults, as if it were edited on the host
+func (s *Systemd) SetDropIn(unit string, name string, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dropIns[unit] == nil {
+		s.dropIns[unit] = make(map[string]string)
+	}
+	s.dropIns[unit][name] = content
+}
+
+// DropIn returns the content of the named drop-in of a unit, if it exists
+func (s *Systemd) DropIn(unit string, name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.dropIns[unit][name]
+	return content, ok
+}
+
+// AddUnit loads (or replaces) a unit, bypassing any injected faults, as if it were installed or changed on the host
+func (s *Systemd) AddUnit(state systemd.UnitState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.Name] = state
+}
+
+// UnitState returns the state of the named unit, if it is loaded
+func (s *Systemd) UnitState(name string) (systemd.UnitState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	return state, ok
+}
+
+// SetTimer replaces the schedule of a timer, which otherwise elapses an hour after it was first started
+func (s *Systemd) SetTimer(timer systemd.TimerState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timers[timer.Name] = timer
+}
+
+// SetResources replaces the cgroup limits of a unit, bypassing any injected faults, as if they were changed on the host
+func (s *Systemd) SetResources(resources systemd.UnitResources) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[resources.Name] = resources
+}
+
+// Resources returns the cgroup limits of the named unit, and whether they were last set at runtime
+func (s *Systemd) Resources(name string) (systemd.UnitResources, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unitResources(name), s.runtime[name]
+}
+
+// FailUnit makes starting the named unit fail with the given service result, e.g. exit-code
+// Passing an empty result clears the failure
+func (s *Systemd) FailUnit(name string, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if result == "" {
+		delete(s.failing, name)
+		return
+	}
+	s.failing[name] = result
+}
+
+// Restarts returns the number of times the named unit has been restarted
+func (s *Systemd) Restarts(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts[name]
+}
+
+// UnitFile returns the content of the named unit file, if it exists
+func (s *Systemd) UnitFile(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.units[name]
+	return content, ok
+}
+
+// Reloads returns the number of times the manager configuration has been reloaded
+func (s *Systemd) Reloads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloads
+}
+
+// Reset removes every unit file and unit, and clears any injected faults
+func (s *Systemd) Reset() {
+	s.faults.reset()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.units = make(map[string]string)
+	s.dropIns = make(map[string]map[string]string)
+	s.states = make(map[string]systemd.UnitState)
+	s.failing = make(map[string]string)
+	s.restarts = make(map[string]int)
+	s.timers = make(map[string]systemd.TimerState)
+	s.resources = make(map[string]systemd.UnitResources)
+	s.runtime = make(map[string]bool)
+	s.reloads = 0
+}
+
+func (s *Systemd) GetUnitFile(ctx context.Context, name string) (systemd.UnitFile, error) {
+	if err := s.inject(ctx, "GetUnitFile"); err != nil {
+		return systemd.UnitFile{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.units[name]
+	if !ok {
+		return systemd.UnitFile{}, fmt.Errorf("unit file %s: %w", name, bus.ErrNotFound)
+	}
+	return unitFile(name, content), nil
+}
+
+func (s *Systemd) WriteUnitFile(ctx context.Context, name string, content string) (systemd.UnitFile, error) {
+	if err := s.inject(ctx, "WriteUnitFile"); err != nil {
+		return systemd.UnitFile{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.units[name] = content
+	if _, ok := s.states[name]; !ok {
+		s.states[name] = newUnitState(name)
+	}
+	s.reloads++
+	return unitFile(name, content), nil
+}
+
+func (s *Systemd) DeleteUnitFile(ctx context.Context, name string) error {
+	if err := s.inject(ctx, "DeleteUnitFile"); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.units[name]; !ok {
+		return fmt.Errorf("unit file %s: %w", name, bus.ErrNotFound)
+	}
+	delete(s.units, name)
+	delete(s.states, name)
+	s.reloads++
+	return nil
+}
+
+func (s *Systemd) GetDropIn(ctx context.Context, unit string, name string) (systemd.DropIn, error) {
+	if err := s.inject(ctx, "GetDropIn"); err != nil {
+		return systemd.DropIn{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.dropIns[unit][name]
+	if !ok {
+		return systemd.DropIn{}, fmt.Errorf("drop-in %s of unit %s: %w", name, unit, bus.ErrNotFound)
+	}
+	return s.dropIn(unit, name, content), nil
+}
+
+func (s *Systemd) WriteDropIn(ctx context.Context, unit string, name string, content string) (systemd.DropIn, error) {
+	if err := s.inject(ctx, "WriteDropIn"); err != nil {
+		return systemd.DropIn{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dropIns[unit] == nil {
+		s.dropIns[unit] = make(map[string]string)
+	}
+	s.dropIns[unit][name] = content
+	s.reloads++
+	return s.dropIn(unit, name, content), nil
+}
+
+func (s *Systemd) DeleteDropIn(ctx context.Context, unit string, name string) error {
+	if err := s.inject(ctx, "DeleteDropIn"); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dropIns[unit][name]; !ok {
+		return fmt.Errorf("drop-in %s of unit %s: %w", name, unit, bus.ErrNotFound)
+	}
+	delete(s.dropIns[unit], name)
+	s.reloads++
+	return nil
+}
+
+// dropIn returns the named drop-in, shadowed by the drop-ins of the unit which sort after it, the caller must hold the lock
+func (s *Systemd) dropIn(unit string, name string, content string) systemd.DropIn {
+	var names []string
+	for other := range s.dropIns[unit] {
+		if other > name {
+			names = append(names, other)
+		}
+	}
+	sort.Strings(names)
+	var later []systemd.UnitFile
+	for _, other := range names {
+		later = append(later, systemd.UnitFile{Path: dropInPath(unit, other), Content: s.dropIns[unit][other]})
+	}
+	return systemd.DropIn{
+		Unit:     unit,
+		Name:     name,
+		Path:     dropInPath(unit, name),
+		Content:  content,
+		Shadowed: systemd.ShadowedSettings(content, later),
+	}
+}
+
+func (s *Systemd) GetUnitState(ctx context.Context, name string) (systemd.UnitState, error) {
+	if err := s.inject(ctx, "GetUnitState"); err != nil {
+		return systemd.UnitState{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	if !ok {
+		return systemd.UnitState{}, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+	return state, nil
+}
+
+func (s *Systemd) SetUnitState(ctx context.Context, name string, change systemd.UnitStateChange) (systemd.UnitState, error) {
+	if err := s.inject(ctx, "SetUnitState"); err != nil {
+		return systemd.UnitState{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	if !ok {
+		return systemd.UnitState{}, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+
+	if change.Stops() {
+		state.ActiveState, state.SubState = "inactive", "dead"
+	}
+	if change.Masked != nil && *change.Masked != state.Masked() {
+		if *change.Masked {
+			state.LoadState, state.UnitFileState = "masked", "masked"
+		} else {
+			state.LoadState, state.UnitFileState = "loaded", "disabled"
+		}
+	}
+	if change.Enabled != nil && *change.Enabled != state.Enabled() {
+		if *change.Enabled && !s.hasInstall(name) {
+			return systemd.UnitState{}, fmt.Errorf("unit %s has no [Install] section, so it cannot be enabled: %w", name, bus.ErrInvalid)
+		}
+		state.UnitFileState = map[bool]string{true: "enabled", false: "disabled"}[*change.Enabled]
+	}
+	s.states[name] = state
+	if change.Active != nil && *change.Active && !state.Active() {
+		return s.start(name, "start")
+	}
+	return state, nil
+}
+
+func (s *Systemd) RestartUnit(ctx context.Context, name string) (systemd.UnitState, error) {
+	if err := s.inject(ctx, "RestartUnit"); err != nil {
+		return systemd.UnitState{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.states[name]; !ok {
+		return systemd.UnitState{}, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+	s.restarts[name]++
+	return s.start(name, "restart")
+}
+
+func (s *Systemd) GetTimer(ctx context.Context, name string) (systemd.TimerState, error) {
+	if err := s.inject(ctx, "GetTimer"); err != nil {
+		return systemd.TimerState{}, err
+	}
+	if !strings.HasSuffix(name, ".timer") {
+		return systemd.TimerState{}, fmt.Errorf("unit %s is not a timer: %w", name, bus.ErrInvalid)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.states[name]; !ok {
+		return systemd.TimerState{}, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+	timer, ok := s.timers[name]
+	if !ok {
+		timer = systemd.TimerState{Name: name, Unit: strings.TrimSuffix(name, ".timer") + ".service"}
+	}
+	return timer, nil
+}
+
+func (s *Systemd) GetUnitResources(ctx context.Context, name string) (systemd.UnitResources, error) {
+	if err := s.inject(ctx, "GetUnitResources"); err != nil {
+		return systemd.UnitResources{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getResources(name)
+}
+
+func (s *Systemd) SetUnitResources(ctx context.Context, name string, change systemd.ResourceControlChange) (systemd.UnitResources, error) {
+	if err := s.inject(ctx, "SetUnitResources"); err != nil {
+		return systemd.UnitResources{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.getResources(name); err != nil {
+		return systemd.UnitResources{}, err
+	}
+	resources := s.unitResources(name)
+	for _, limit := range []struct {
+		value  *uint64
+		target *uint64
+	}{
+		{change.MemoryMax, &resources.MemoryMax},
+		{change.CPUQuotaPerSecUSec, &resources.CPUQuotaPerSecUSec},
+		{change.IOWeight, &resources.IOWeight},
+		{change.TasksMax, &resources.TasksMax},
+	} {
+		if limit.value != nil {
+			*limit.target = *limit.value
+		}
+	}
+	s.resources[name] = resources
+	s.runtime[name] = change.Runtime
+	return s.getResources(name)
+}
+
+// getResources returns the cgroup of a unit, running units use a little of each resource. The caller must hold the lock.
+func (s *Systemd) getResources(name string) (systemd.UnitResources, error) {
+	if !common.HasCgroup(name) {
+		return systemd.UnitResources{}, fmt.Errorf("unit %s has no cgroup: %w", name, bus.ErrInvalid)
+	}
+	state, ok := s.states[name]
+	if !ok {
+		return systemd.UnitResources{}, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+	resources := s.unitResources(name)
+	resources.MemoryCurrent, resources.CPUUsageNSec, resources.TasksCurrent = common.Infinity, common.Infinity, common.Infinity
+	if state.Active() {
+		resources.ControlGroup = "/" + name
+		if !strings.HasSuffix(name, ".slice") {
+			resources.ControlGroup = "/system.slice/" + name
+		}
+		resources.MemoryCurrent, resources.CPUUsageNSec, resources.TasksCurrent = 8<<20, 250_000_000, 1
+	}
+	return resources, nil
+}
+
+// unitResources returns the limits set on a unit, which has none until they are set. The caller must hold the lock.
+func (s *Systemd) unitResources(name string) systemd.UnitResources {
+	resources, ok := s.resources[name]
+	if !ok {
+		resources = systemd.UnitResources{
+			Name:               name,
+			MemoryMax:          common.Infinity,
+			CPUQuotaPerSecUSec: common.Infinity,
+			IOWeight:           common.Infinity,
+			TasksMax:           common.Infinity,
+		}
+	}
+	return resources
+}
+
+func (s *Systemd) ListUnits(ctx context.Context, states []string, patterns []string) ([]systemd.UnitStatus, error) {
+	if err := s.inject(ctx, "ListUnits"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	units := []systemd.UnitStatus{}
+	for name, state := range s.states {
+		if len(states) > 0 && !slices.Contains(states, state.LoadState) && !slices.Contains(states, state.ActiveState) && !slices.Contains(states, state.SubState) {
+			continue
+		}
+		if len(patterns) > 0 && !slices.ContainsFunc(patterns, func(p string) bool {
+			ok, _ := path.Match(p, name)
+			return ok
+		}) {
+			continue
+		}
+		units = append(units, s.unitStatus(state))
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units, nil
+}
+
+// unitStatus describes a loaded unit, units without a file in the agent's unit directory are vendor units
+// Running services get a main PID derived from their name. The caller must hold the lock.
+func (s *Systemd) unitStatus(state systemd.UnitState) systemd.UnitStatus {
+	unit := systemd.UnitStatus{
+		Name:         state.Name,
+		LoadState:    state.LoadState,
+		ActiveState:  state.ActiveState,
+		SubState:     state.SubState,
+		FragmentPath: path.Join("/usr/lib/systemd/system", state.Name),
+	}
+	if content, ok := s.units[state.Name]; ok {
+		unit.FragmentPath = unitFile(state.Name, content).Path
+		if sections, err := common.ParseUnit(content); err == nil {
+			for _, section := range sections {
+				if values := section.Values("Description"); section.Name == "Unit" && len(values) > 0 {
+					unit.Description = values[len(values)-1]
+				}
+			}
+		}
+	}
+	if strings.HasSuffix(state.Name, ".service") && state.Active() {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(state.Name))
+		unit.MainPID = 1000 + h.Sum32()%30000
+	}
+	return unit
+}
+
+// start runs the named unit, unless it is masked or set to fail, the caller must hold the lock
+func (s *Systemd) start(name string, operation string) (systemd.UnitState, error) {
+	state := s.states[name]
+	if state.Masked() {
+		return systemd.UnitState{}, fmt.Errorf("unit %s is masked", name)
+	}
+	if result, ok := s.failing[name]; ok {
+		state.ActiveState, state.SubState, state.Result = "failed", "failed", result
+		s.states[name] = state
+		return systemd.UnitState{}, &systemd.UnitFailedError{
+			Unit: name, Operation: operation, JobResult: "failed", Result: result,
+			Journal: []string{fmt.Sprintf("%s: Main process exited, code=exited, status=1/FAILURE", name)},
+		}
+	}
+	state.ActiveState, state.SubState, state.Result = "active", "running", "success"
+	s.states[name] = state
+	if strings.HasSuffix(name, ".timer") {
+		if _, ok := s.timers[name]; !ok {
+			s.timers[name] = systemd.TimerState{
+				Name:       name,
+				Unit:       strings.TrimSuffix(name, ".timer") + ".service",
+				NextElapse: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+			}
+		}
+	}
+	return state, nil
+}
+
+// hasInstall returns true unless the unit's file in the agent's unit directory has no [Install] section
+func (s *Systemd) hasInstall(name string) bool {
+	content, ok := s.units[name]
+	if !ok {
+		return true
+	}
+	sections, err := common.ParseUnit(content)
+	if err != nil {
+		return false
+	}
+	for _, section := range sections {
+		if section.Name == "Install" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Systemd) Version(ctx context.Context) (string, error) {
+	if err := s.inject(ctx, "Version"); err != nil {
+		return "", err
+	}
+	return "test", nil
+}
+
+func unitFile(name string, content string) systemd.UnitFile {
+	return systemd.UnitFile{Name: name, Path: path.Join(systemd.DefaultUnitDir, name), Content: content}
+}
+
+func dropInPath(unit string, name string) string {
+	return path.Join(systemd.DefaultUnitDir, unit+".d", name+".conf")
+}
+
+func newUnitState(name string) systemd.UnitState {
+	return systemd.UnitState{
+		Name:          name,
+		LoadState:     "loaded",
+		ActiveState:   "inactive",
+		SubState:      "dead",
+		UnitFileState: "disabled",
+		Result:        "success",
+	}
+}
@@ -0,0 +1,281 @@
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+var _ systemd.SysusersClient = &Sysusers{}
+
+// firstSystemID is where IDs are allocated from, downwards, as systemd-sysusers does
+const firstSystemID = 999
+
+// Sysusers is an in-memory systemd.SysusersClient, which creates users and groups as systemd-sysusers would
+type Sysusers struct {
+	faults
+
+	mu     sync.Mutex
+	files  map[string]string
+	users  map[string]systemd.UserState
+	groups map[string]systemd.GroupState
+}
+
+// NewSysusers returns a fake with no files, and only the root user and group
+func NewSysusers() *Sysusers {
+	s := &Sysusers{}
+	s.Reset()
+	return s
+}
+
+// SetFile writes a sysusers.d file without applying it, bypassing validation and any injected faults, as if it were
+// edited on the host
+func (s *Sysusers) SetFile(name string, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = content
+}
+
+// File returns the content of the named sysusers.d file, if it exists
+func (s *Sysusers) File(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.files[name]
+	return content, ok
+}
+
+// User returns the named user, if it exists
+func (s *Sysusers) User(name string) (systemd.UserState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[name]
+	return u, ok
+}
+
+// Group returns the named group, if it exists
+func (s *Sysusers) Group(name string) (systemd.GroupState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[name]
+	return g, ok
+}
+
+// Reset removes every file, user and group other than root, and clears any injected faults
+func (s *Sysusers) Reset() {
+	s.faults.reset()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = make(map[string]string)
+	s.users = map[string]systemd.UserState{
+		"root": {Name: "root", Exists: true, Group: "root", Home: "/root", Shell: "/bin/sh"},
+	}
+	s.groups = map[string]systemd.GroupState{
+		"root": {Name: "root", Exists: true, Members: []string{}},
+	}
+}
+
+func (s *Sysusers) GetSysusers(ctx context.Context, name string) (systemd.Sysusers, error) {
+	if err := s.inject(ctx, "GetSysusers"); err != nil {
+		return systemd.Sysusers{}, err
+	}
+	if err := validName(name); err != nil {
+		return systemd.Sysusers{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.files[name]
+	if !ok {
+		return systemd.Sysusers{}, fmt.Errorf("%s/%s.conf: %w", common.SysusersDir, name, bus.ErrNotFound)
+	}
+	return s.state(name, content)
+}
+
+func (s *Sysusers) WriteSysusers(ctx context.Context, name string, content string) (systemd.Sysusers, error) {
+	if err := s.inject(ctx, "WriteSysusers"); err != nil {
+		return systemd.Sysusers{}, err
+	}
+	if err := validName(name); err != nil {
+		return systemd.Sysusers{}, err
+	}
+	lines, err := common.ParseSysusers(content)
+	if err != nil {
+		return systemd.Sysusers{}, fmt.Errorf("invalid sysusers.d file %s: %s: %w", name, err, bus.ErrInvalid)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = content
+	for _, l := range lines {
+		s.apply(l)
+	}
+	return s.state(name, content)
+}
+
+func (s *Sysusers) DeleteSysusers(ctx context.Context, name string) error {
+	if err := s.inject(ctx, "DeleteSysusers"); err != nil {
+		return err
+	}
+	if err := validName(name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[name]; !ok {
+		return fmt.Errorf("%s/%s.conf: %w", common.SysusersDir, name, bus.ErrNotFound)
+	}
+	delete(s.files, name)
+	return nil
+}
+
+// apply creates what the line declares, leaving existing users and groups unchanged
+func (s *Sysusers) apply(l common.SysusersLine) {
+	switch l.Type {
+	case "u":
+		if _, ok := s.users[l.Name]; ok {
+			return
+		}
+		uid, group, _ := strings.Cut(l.ID, ":")
+		id, err := strconv.Atoi(uid)
+		if err != nil {
+			id = s.allocate()
+		}
+		if group == "" {
+			group = l.Name
+			s.addGroup(group, strconv.Itoa(id))
+		} else if _, err := strconv.Atoi(group); err == nil {
+			s.addGroup(l.Name, group)
+			group = l.Name
+		} else {
+			s.addGroup(group, "")
+		}
+		user := systemd.UserState{Name: l.Name, Exists: true, UID: id, Group: group, GID: s.groups[group].GID, Home: l.Home, Shell: l.Shell}
+		if user.Home == "" {
+			user.Home = "/"
+		}
+		if user.Shell == "" {
+			user.Shell = "/usr/sbin/nologin"
+		}
+		s.users[l.Name] = user
+	case "g":
+		s.addGroup(l.Name, l.ID)
+	case "m":
+		s.addGroup(l.ID, "")
+		g := s.groups[l.ID]
+		if !slices.Contains(g.Members, l.Name) {
+			g.Members = append(g.Members, l.Name)
+		}
+		s.groups[l.ID] = g
+	}
+}
+
+func (s *Sysusers) addGroup(name string, id string) {
+	if _, ok := s.groups[name]; ok {
+		return
+	}
+	gid, err := strconv.Atoi(id)
+	if err != nil {
+		gid = s.allocate()
+	}
+	s.groups[name] = systemd.GroupState{Name: name, Exists: true, GID: gid, Members: []string{}}
+}
+
+// allocate returns the highest system ID which no user or group has
+func (s *Sysusers) allocate() int {
+	for id := firstSystemID; ; id-- {
+		used := false
+		for _, u := range s.users {
+			used = used || u.UID == id
+		}
+		for _, g := range s.groups {
+			used = used || g.GID == id
+		}
+		if !used {
+			return id
+		}
+	}
+}
+
+// state returns the file with the users and groups it declares, as systemd.SystemdSysusers reports them
+func (s *Sysusers) state(name string, content string) (systemd.Sysusers, error) {
+	lines, err := common.ParseSysusers(content)
+	if err != nil {
+		return systemd.Sysusers{}, err
+	}
+	sysusers := systemd.Sysusers{
+		Name:    name,
+		Path:    filepath.Join(common.SysusersDir, name+".conf"),
+		Content: content,
+		Users:   []systemd.UserState{},
+		Groups:  []systemd.GroupState{},
+	}
+	addUser := func(name string) {
+		if slices.ContainsFunc(sysusers.Users, func(u systemd.UserState) bool { return u.Name == name }) {
+			return
+		}
+		user, ok := s.users[name]
+		if !ok {
+			user = systemd.UserState{Name: name}
+		}
+		sysusers.Users = append(sysusers.Users, user)
+	}
+	addGroup := func(name string) {
+		if slices.ContainsFunc(sysusers.Groups, func(g systemd.GroupState) bool { return g.Name == name }) {
+			return
+		}
+		group, ok := s.groups[name]
+		if !ok {
+			group = systemd.GroupState{Name: name, Members: []string{}}
+		}
+		group.Members = slices.Clone(group.Members)
+		sysusers.Groups = append(sysusers.Groups, group)
+	}
+	for _, l := range lines {
+		switch l.Type {
+		case "u":
+			addUser(l.Name)
+			if _, group, ok := strings.Cut(l.ID, ":"); ok {
+				if _, err := strconv.Atoi(group); err != nil {
+					addGroup(group)
+					continue
+				}
+			}
+			addGroup(l.Name)
+		case "g":
+			addGroup(l.Name)
+		case "m":
+			addUser(l.Name)
+			addGroup(l.ID)
+		}
+	}
+	return sysusers, nil
+}
+
+// resolve returns true if the user or group, given by name or ID, exists
+func (s *Sysusers) resolve(owner string, group bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owner = strings.TrimPrefix(owner, ":")
+	if _, err := strconv.Atoi(owner); err == nil {
+		return true
+	}
+	if group {
+		_, ok := s.groups[owner]
+		return ok
+	}
+	_, ok := s.users[owner]
+	return ok
+}
+
+func validName(name string) error {
+	if len(name) > common.MaxDropInNameLength || !common.DropInNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: %w", name, bus.ErrInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+)
+
+var _ systemd.TmpfilesClient = &Tmpfiles{}
+
+// Tmpfiles is an in-memory systemd.TmpfilesClient, which resolves owners against the users of the fake sysusers
+type Tmpfiles struct {
+	faults
+
+	sysusers *Sysusers
+	mu       sync.Mutex
+	files    map[string]string
+	paths    map[string]systemd.PathState
+	readOnly bool
+}
+
+// NewTmpfiles returns a fake with no files or paths
+func NewTmpfiles(sysusers *Sysusers) *Tmpfiles {
+	t := &Tmpfiles{sysusers: sysusers}
+	t.Reset()
+	return t
+}
+
+// SetFile writes a tmpfiles.d file without applying it, bypassing validation and any injected faults, as if it were
+// edited on the host
+func (t *Tmpfiles) SetFile(name string, content string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[name] = content
+}
+
+// File returns the content of the named tmpfiles.d file, if it exists
+func (t *Tmpfiles) File(name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	content, ok := t.files[name]
+	return content, ok
+}
+
+// Path returns the state of a path, which only exists once a file creating it was applied
+func (t *Tmpfiles) Path(path string) systemd.PathState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state, ok := t.paths[path]; ok {
+		return state
+	}
+	return systemd.PathState{Path: path}
+}
+
+// SetPath replaces the state of a path, as if it were changed on the host
+func (t *Tmpfiles) SetPath(state systemd.PathState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paths[state.Path] = state
+}
+
+// SetReadOnly disables the module in the agent's capabilities, as if the agent couldn't write /etc/tmpfiles.d
+func (t *Tmpfiles) SetReadOnly(readOnly bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.readOnly = readOnly
+}
+
+// Capability reports the module as enabled unless it was made read-only
+func (t *Tmpfiles) Capability() common.ModuleCapability {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return common.ModuleCapability{Enabled: !t.readOnly}
+}
+
+// Reset removes every file and path, clears any injected faults and makes the module writable again
+func (t *Tmpfiles) Reset() {
+	t.faults.reset()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files = make(map[string]string)
+	t.paths = make(map[string]systemd.PathState)
+	t.readOnly = false
+}
+
+func (t *Tmpfiles) GetTmpfiles(ctx context.Context, name string) (systemd.Tmpfiles, error) {
+	if err := t.inject(ctx, "GetTmpfiles"); err != nil {
+		return systemd.Tmpfiles{}, err
+	}
+	if err := validName(name); err != nil {
+		return systemd.Tmpfiles{}, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	content, ok := t.files[name]
+	if !ok {
+		return systemd.Tmpfiles{}, fmt.Errorf("%s/%s.conf: %w", common.TmpfilesDir, name, bus.ErrNotFound)
+	}
+	return t.state(name, content)
+}
+
+func (t *Tmpfiles) WriteTmpfiles(ctx context.Context, name string, content string) (systemd.Tmpfiles, error) {
+	if err := t.inject(ctx, "WriteTmpfiles"); err != nil {
+		return systemd.Tmpfiles{}, err
+	}
+	if err := validName(name); err != nil {
+		return systemd.Tmpfiles{}, err
+	}
+	lines, err := common.ParseTmpfiles(content)
+	if err != nil {
+		return systemd.Tmpfiles{}, fmt.Errorf("invalid tmpfiles.d file %s: %s: %w", name, err, bus.ErrInvalid)
+	}
+	// Like systemd-tmpfiles, nothing is written unless every owner can be resolved
+	for _, l := range lines {
+		if l.User != "" && !t.sysusers.resolve(l.User, false) {
+			return systemd.Tmpfiles{}, fmt.Errorf("failed to resolve user %q: %w", l.User, bus.ErrInvalid)
+		}
+		if l.Group != "" && !t.sysusers.resolve(l.Group, true) {
+			return systemd.Tmpfiles{}, fmt.Errorf("failed to resolve group %q: %w", l.Group, bus.ErrInvalid)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[name] = content
+	for _, l := range lines {
+		t.apply(l)
+	}
+	return t.state(name, content)
+}
+
+func (t *Tmpfiles) DeleteTmpfiles(ctx context.Context, name string) error {
+	if err := t.inject(ctx, "DeleteTmpfiles"); err != nil {
+		return err
+	}
+	if err := validName(name); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.files[name]; !ok {
+		return fmt.Errorf("%s/%s.conf: %w", common.TmpfilesDir, name, bus.ErrNotFound)
+	}
+	delete(t.files, name)
+	return nil
+}
+
+// apply creates the path of a line which creates one, and sets the mode and owners it gives
+func (t *Tmpfiles) apply(l common.TmpfilesLine) {
+	if !l.Literal() {
+		return
+	}
+	state, exists := t.paths[l.Path]
+	if !exists {
+		if !l.Managed() {
+			return
+		}
+		state = systemd.PathState{Path: l.Path, Exists: true, Type: "file", Mode: 0o644, User: "root", Group: "root"}
+		switch l.Type[0] {
+		case 'd', 'D', 'v', 'q', 'Q':
+			state.Type, state.Mode = "directory", 0o755
+		case 'L':
+			state.Type, state.Mode = "symlink", 0o777
+		case 'p':
+			state.Type = "fifo"
+		case 'c', 'b':
+			state.Type = "device"
+		}
+	}
+	// A mode prefixed with ~ or : only applies to paths which are created
+	if mode, err := strconv.ParseUint(strings.TrimLeft(l.Mode, "~:"), 8, 32); err == nil && (!exists || !strings.ContainsAny(l.Mode[:1], "~:")) {
+		state.Mode = fs.FileMode(mode).Perm()
+	}
+	if l.User != "" {
+		state.User = strings.TrimPrefix(l.User, ":")
+	}
+	if l.Group != "" {
+		state.Group = strings.TrimPrefix(l.Group, ":")
+	}
+	t.paths[l.Path] = state
+}
+
+// state returns the file with the paths it declares, as systemd.SystemdTmpfiles reports them
+func (t *Tmpfiles) state(name string, content string) (systemd.Tmpfiles, error) {
+	lines, err := common.ParseTmpfiles(content)
+	if err != nil {
+		return systemd.Tmpfiles{}, err
+	}
+	tmpfiles := systemd.Tmpfiles{
+		Name:    name,
+		Path:    filepath.Join(common.TmpfilesDir, name+".conf"),
+		Content: content,
+		Paths:   []systemd.PathState{},
+	}
+	seen := make(map[string]bool)
+	for _, l := range lines {
+		if !l.Literal() || seen[l.Path] {
+			continue
+		}
+		seen[l.Path] = true
+		state, ok := t.paths[l.Path]
+		if !ok {
+			state = systemd.PathState{Path: l.Path}
+		}
+		tmpfiles.Paths = append(tmpfiles.Paths, state)
+	}
+	return tmpfiles, nil
+}
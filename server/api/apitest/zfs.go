@@ -0,0 +1,204 @@
+package apitest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/zfs"
+)
+
+var _ zfs.ZfsClient = &Zfs{}
+
+// Zfs is an in-memory zfs.ZfsClient
+// Every method can be made to fail, or to block for a fixed delay, to simulate a misbehaving backend.
+type Zfs struct {
+	faults
+
+	mu    sync.Mutex
+	pools map[string]zfs.ZpoolProperties
+}
+
+// NewZfs returns a fake with no pools
+func NewZfs() *Zfs {
+	z := &Zfs{}
+	z.Reset()
+	return z
+}
+
+// AddPool adds (or replaces) a pool, bypassing any injected faults
+func (z *Zfs) AddPool(pool zfs.ZpoolProperties) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.pools[pool.Name] = pool
+}
+
+// Pools returns the names of every pool, in order
+func (z *Zfs) Pools() []string {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	names := make([]string, 0, len(z.pools))
+	for name := range z.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reset removes every pool and clears any injected faults
+func (z *Zfs) Reset() {
+	z.faults.reset()
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.pools = make(map[string]zfs.ZpoolProperties)
+}
+
+func (z *Zfs) ListPools(ctx context.Context) ([]zfs.ZpoolProperties, error) {
+	if err := z.inject(ctx, "ListPools"); err != nil {
+		return nil, err
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	pools := make([]zfs.ZpoolProperties, 0, len(z.pools))
+	for _, pool := range z.pools {
+		pools = append(pools, pool)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+	return pools, nil
+}
+
+func (z *Zfs) GetPool(ctx context.Context, name string) (zfs.ZpoolProperties, error) {
+	if err := z.inject(ctx, "GetPool"); err != nil {
+		return zfs.ZpoolProperties{}, err
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	pool, ok := z.pools[name]
+	if !ok {
+		return zfs.ZpoolProperties{}, fmt.Errorf("zpool %s: %w", name, bus.ErrNotFound)
+	}
+	return pool, nil
+}
+
+func (z *Zfs) CreatePool(ctx context.Context, name string) (zfs.ZpoolProperties, error) {
+	if err := z.inject(ctx, "CreatePool"); err != nil {
+		return zfs.ZpoolProperties{}, err
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if _, ok := z.pools[name]; ok {
+		return zfs.ZpoolProperties{}, fmt.Errorf("zpool %s already exists: %w", name, bus.ErrInvalid)
+	}
+	pool := zfs.ZpoolProperties{Name: name, Health: "ONLINE"}
+	z.pools[name] = pool
+	return pool, nil
+}
+
+func (z *Zfs) DestroyPool(ctx context.Context, name string) error {
+	if err := z.inject(ctx, "DestroyPool"); err != nil {
+		return err
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if _, ok := z.pools[name]; !ok {
+		return fmt.Errorf("zpool %s: %w", name, bus.ErrNotFound)
+	}
+	delete(z.pools, name)
+	return nil
+}
+
+func (z *Zfs) Version(ctx context.Context) (string, error) {
+	if err := z.inject(ctx, "Version"); err != nil {
+		return "", err
+	}
+	return "test", nil
+}
+
+// faults holds the failures and delays injected into a fake backend
+type faults struct {
+	mu       sync.Mutex
+	failures map[string]failure
+	delay    time.Duration
+	delays   map[string]time.Duration
+}
+
+type failure struct {
+	err   error
+	after int
+}
+
+// Fail makes every subsequent call to the given method return err
+// Passing a nil error clears the failure
+func (f *faults) Fail(method string, err error) {
+	f.FailAfter(method, 0, err)
+}
+
+// FailAfter lets the next n calls to the given method succeed, then makes every subsequent call return err
+// This simulates a backend which fails partway through an apply
+func (f *faults) FailAfter(method string, n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures == nil {
+		f.failures = make(map[string]failure)
+	}
+	if err == nil {
+		delete(f.failures, method)
+		return
+	}
+	f.failures[method] = failure{err: err, after: n}
+}
+
+// SetDelay makes every subsequent call block for d, or until the request is cancelled
+func (f *faults) SetDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = d
+}
+
+// SetMethodDelay makes every subsequent call to the given method block for d, in addition to any delay set by SetDelay
+func (f *faults) SetMethodDelay(method string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.delays == nil {
+		f.delays = make(map[string]time.Duration)
+	}
+	f.delays[method] = d
+}
+
+func (f *faults) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = make(map[string]failure)
+	f.delay = 0
+	f.delays = make(map[string]time.Duration)
+}
+
+// inject applies the configured delay, then returns the failure for method, if any
+func (f *faults) inject(ctx context.Context, method string) error {
+	f.mu.Lock()
+	delay := f.delay + f.delays[method]
+	var err error
+	if fail, ok := f.failures[method]; ok {
+		if fail.after > 0 {
+			fail.after--
+			f.failures[method] = fail
+		} else {
+			err = fail.err
+		}
+	}
+	f.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is the OpenAPI 3 description of the agent API
+// It is written by hand, TestSpec verifies that it matches the registered routes and the common wire types.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// HandleOpenAPI serves the OpenAPI specification
+func HandleOpenAPI() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(Spec)
+	})
+}
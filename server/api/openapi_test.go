@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+	"github.com/nickrobison/terraform-linux-provider/server/login"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+	"github.com/nickrobison/terraform-linux-provider/server/zfs"
+)
+
+// schemaTypes maps each component schema in the spec to the common type it describes
+var schemaTypes = map[string]any{
+	"ErrorResponse":           common.ErrorResponse{},
+	"DependencyStatus":        common.DependencyStatus{},
+	"ReadinessResponse":       common.ReadinessResponse{},
+	"ModuleCapability":        common.ModuleCapability{},
+	"CapabilitiesResponse":    common.CapabilitiesResponse{},
+	"ZpoolCreateRequest":      common.ZpoolCreateRequest{},
+	"ZpoolResponse":           common.ZPoolResponse{},
+	"ZpoolListResponse":       common.ZpoolListResponse{},
+	"UnitListResponse":        common.UnitListResponse{},
+	"UnitStatusResponse":      common.UnitStatusResponse{},
+	"UnitFileRequest":         common.UnitFileRequest{},
+	"UnitFileResponse":        common.UnitFileResponse{},
+	"UnitStateRequest":        common.UnitStateRequest{},
+	"UnitStateResponse":       common.UnitStateResponse{},
+	"DropInResponse":          common.DropInResponse{},
+	"ShadowedSetting":         common.ShadowedSetting{},
+	"TimerResponse":           common.TimerResponse{},
+	"ResourceControlRequest":  common.ResourceControlRequest{},
+	"ResourceControlResponse": common.ResourceControlResponse{},
+	"ConfigDropInResponse":    common.ConfigDropInResponse{},
+	"TmpfilesResponse":        common.TmpfilesResponse{},
+	"PathState":               common.PathState{},
+	"SysusersResponse":        common.SysusersResponse{},
+	"UserState":               common.UserState{},
+	"GroupState":              common.GroupState{},
+	"JournalResponse":         common.JournalResponse{},
+	"JournalEntry":            common.JournalEntry{},
+	"BootResponse":            common.BootResponse{},
+	"InhibitorResponse":       common.InhibitorResponse{},
+	"RebootRequest":           common.RebootRequest{},
+	"MaintenanceWindow":       common.MaintenanceWindow{},
+	"RebootResponse":          common.RebootResponse{},
+}
+
+type openAPI struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type schema struct {
+	Ref                  string            `json:"$ref"`
+	Type                 string            `json:"type"`
+	Pattern              string            `json:"pattern"`
+	Format               string            `json:"format"`
+	Required             []string          `json:"required"`
+	Properties           map[string]schema `json:"properties"`
+	Items                *schema           `json:"items"`
+	AdditionalProperties *schema           `json:"additionalProperties"`
+}
+
+// stubZfs enables the zfs routes, without being called
+type stubZfs struct {
+	zfs.ZfsClient
+}
+
+// stubSystemd enables the systemd routes, without being called
+type stubSystemd struct {
+	systemd.SystemdClient
+}
+
+// stubConfig enables the configuration drop-in routes, without being called
+type stubConfig struct {
+	systemd.ConfigClient
+}
+
+// stubTmpfiles enables the tmpfiles.d routes, without being called
+type stubTmpfiles struct {
+	systemd.TmpfilesClient
+}
+
+// stubSysusers enables the sysusers.d routes, without being called
+type stubSysusers struct {
+	systemd.SysusersClient
+}
+
+// stubJournal enables the journal routes, without being called
+type stubJournal struct {
+	journal.JournalClient
+}
+
+// stubLogin enables the login routes, without being called
+type stubLogin struct {
+	login.LoginClient
+}
+
+type routeRecorder []string
+
+func (r *routeRecorder) Handle(pattern string, _ http.Handler) {
+	*r = append(*r, pattern)
+}
+
+func loadSpec(t *testing.T) openAPI {
+	t.Helper()
+	var spec openAPI
+	if err := json.Unmarshal(Spec, &spec); err != nil {
+		t.Fatalf("invalid spec: %s", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("expected OpenAPI 3, got %s", spec.OpenAPI)
+	}
+	return spec
+}
+
+func TestSpecRoutes(t *testing.T) {
+	spec := loadSpec(t)
+
+	var routes routeRecorder
+	addRoutes(&routes, Dependencies{Zfs: stubZfs{}, Systemd: stubSystemd{}, Config: stubConfig{},
+		Tmpfiles: stubTmpfiles{}, Sysusers: stubSysusers{}, Journal: stubJournal{}, Login: stubLogin{}})
+	var registered []string
+	for _, pattern := range routes {
+		if pattern == "/" {
+			continue
+		}
+		registered = append(registered, pattern)
+	}
+
+	var documented []string
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	if !slices.Equal(registered, documented) {
+		t.Errorf("spec paths do not match the registered routes\nregistered: %v\ndocumented: %v", registered, documented)
+	}
+}
+
+func TestSpecSchemas(t *testing.T) {
+	spec := loadSpec(t)
+
+	names := make(map[reflect.Type]string, len(schemaTypes))
+	for name, v := range schemaTypes {
+		names[reflect.TypeOf(v)] = name
+	}
+	for name := range spec.Components.Schemas {
+		if _, ok := schemaTypes[name]; !ok {
+			t.Errorf("schema %s does not correspond to a common type", name)
+		}
+	}
+
+	for name, v := range schemaTypes {
+		s, ok := spec.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing from the spec", name)
+			continue
+		}
+		compareStruct(t, name, s, reflect.TypeOf(v), names)
+	}
+
+	if p := spec.Components.Schemas["ZpoolCreateRequest"].Properties["name"].Pattern; p != common.ZpoolNamePattern.String() {
+		t.Errorf("expected zpool name pattern %s, got %s", common.ZpoolNamePattern, p)
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	srv := httptest.NewServer(NewServer(Dependencies{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var spec openAPI
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// compareStruct checks that the properties of s match the JSON encoding of the struct type typ
+func compareStruct(t *testing.T, name string, s schema, typ reflect.Type, names map[reflect.Type]string) {
+	t.Helper()
+	if s.Type != "object" {
+		t.Errorf("%s: expected type object, got %q", name, s.Type)
+	}
+
+	var required []string
+	fields := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		field, opts, _ := strings.Cut(tag, ",")
+		if field == "" {
+			field = f.Name
+		}
+		fields[field] = true
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, field)
+		}
+
+		p, ok := s.Properties[field]
+		if !ok {
+			t.Errorf("%s: property %s (%s.%s) is missing from the spec", name, field, typ.Name(), f.Name)
+			continue
+		}
+		compareType(t, name+"."+field, p, f.Type, names)
+	}
+	for field := range s.Properties {
+		if !fields[field] {
+			t.Errorf("%s: property %s does not exist on %s", name, field, typ.Name())
+		}
+	}
+
+	sort.Strings(required)
+	documented := slices.Clone(s.Required)
+	sort.Strings(documented)
+	if !slices.Equal(required, documented) {
+		t.Errorf("%s: expected required properties %v, got %v", name, required, documented)
+	}
+}
+
+func compareType(t *testing.T, name string, s schema, typ reflect.Type, names map[reflect.Type]string) {
+	t.Helper()
+	switch typ.Kind() {
+	case reflect.Pointer:
+		compareType(t, name, s, typ.Elem(), names)
+	case reflect.Struct:
+		if typ == reflect.TypeOf(time.Time{}) {
+			if s.Type != "string" || s.Format != "date-time" {
+				t.Errorf("%s: expected a date-time string, got %q", name, s.Type)
+			}
+			return
+		}
+		expected := "#/components/schemas/" + names[typ]
+		if s.Ref != expected {
+			t.Errorf("%s: expected a reference to %s, got %q", name, expected, s.Ref)
+		}
+	case reflect.Slice:
+		if s.Type != "array" || s.Items == nil {
+			t.Errorf("%s: expected an array, got %q", name, s.Type)
+			return
+		}
+		compareType(t, name+"[]", *s.Items, typ.Elem(), names)
+	case reflect.Map:
+		if s.Type != "object" || s.AdditionalProperties == nil {
+			t.Errorf("%s: expected an object with additionalProperties, got %q", name, s.Type)
+			return
+		}
+		compareType(t, name+"{}", *s.AdditionalProperties, typ.Elem(), names)
+	default:
+		expected := map[reflect.Kind]string{
+			reflect.String: "string",
+			reflect.Bool:   "boolean",
+			reflect.Int:    "integer",
+			reflect.Int32:  "integer",
+			reflect.Int64:  "integer",
+			reflect.Uint32: "integer",
+			reflect.Uint64: "integer",
+		}[typ.Kind()]
+		if s.Type != expected {
+			t.Errorf("%s: expected type %q for %s, got %q", name, expected, typ, s.Type)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package api assembles the agent's HTTP handlers into a single router
+package api
+
+import (
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/server/capabilities"
+	"github.com/nickrobison/terraform-linux-provider/server/health"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+	"github.com/nickrobison/terraform-linux-provider/server/login"
+	"github.com/nickrobison/terraform-linux-provider/server/metrics"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
+	"github.com/nickrobison/terraform-linux-provider/server/zfs"
+)
+
+// Dependencies are the backends served by the API
+// A nil backend client disables its routes. D-Bus backends are always served, returning 503 until their service is on
+// the bus, and report themselves through Modules once it is.
+type Dependencies struct {
+	AgentVersion string
+	Checks       []health.Dependency
+	Modules      map[string]capabilities.Module
+	Zfs          zfs.ZfsClient
+	Systemd      systemd.SystemdClient
+	// Config is only used along with Systemd, which restarts the daemons reading the configuration files
+	Config systemd.ConfigClient
+	// Tmpfiles and Sysusers are disabled if their tool is missing
+	Tmpfiles systemd.TmpfilesClient
+	Sysusers systemd.SysusersClient
+	Journal  journal.JournalClient
+	Login    login.LoginClient
+}
+
+// NewServer returns the agent's root handler
+func NewServer(deps Dependencies) http.Handler {
+	mux := http.NewServeMux()
+
+	addRoutes(mux, deps)
+	var handler http.Handler = mux
+	handler = middleware.Deadline(handler)
+	handler = middleware.Versioning(handler)
+	handler = middleware.LoggingMiddleware(handler)
+	return handler
+}
+
+// router is satisfied by *http.ServeMux, and allows tests to record the registered routes
+type router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+func addRoutes(mux router, deps Dependencies) {
+	handle(mux, "GET /hello", zfs.HandleHello())
+	handle(mux, "GET /healthz", health.HandleHealthz())
+	handle(mux, "GET /readyz", health.HandleReadyz(deps.Checks...))
+	handle(mux, "GET /capabilities", capabilities.HandleCapabilities(deps.AgentVersion, deps.Modules))
+	handle(mux, "GET /openapi.json", HandleOpenAPI())
+	mux.Handle("/", http.NotFoundHandler())
+	mux.Handle("GET /metrics", metrics.Handler())
+	addV1Routes(mux, deps)
+}
+
+// addV1Routes registers version 1 of the module APIs
+// A breaking change adds a new version alongside, and the old one is only removed once it falls out of common.MinAPIVersion
+func addV1Routes(mux router, deps Dependencies) {
+	if deps.Zfs != nil {
+		handleV1(mux, "GET", "/zfs/zpool", zfs.HandleZpoolList(deps.Zfs))
+		handleV1(mux, "POST", "/zfs/zpool", zfs.HandleZpoolCreate(deps.Zfs))
+		handleV1(mux, "GET", "/zfs/zpool/{name}", zfs.HandleZpoolGet(deps.Zfs))
+		handleV1(mux, "DELETE", "/zfs/zpool/{name}", zfs.HandleZpoolDelete(deps.Zfs))
+	}
+	if deps.Systemd != nil {
+		handleV1(mux, "GET", "/systemd/units", systemd.HandleUnitList(deps.Systemd))
+		handleV1(mux, "GET", "/systemd/units/{name}", systemd.HandleUnitFileGet(deps.Systemd))
+		handleV1(mux, "PUT", "/systemd/units/{name}", systemd.HandleUnitFilePut(deps.Systemd))
+		handleV1(mux, "DELETE", "/systemd/units/{name}", systemd.HandleUnitFileDelete(deps.Systemd))
+		handleV1(mux, "GET", "/systemd/units/{name}/state", systemd.HandleUnitStateGet(deps.Systemd))
+		handleV1(mux, "PUT", "/systemd/units/{name}/state", systemd.HandleUnitStatePut(deps.Systemd))
+		handleV1(mux, "POST", "/systemd/units/{name}/restart", systemd.HandleUnitRestart(deps.Systemd))
+		handleV1(mux, "GET", "/systemd/units/{name}/timer", systemd.HandleTimerGet(deps.Systemd))
+		handleV1(mux, "GET", "/systemd/units/{name}/resources", systemd.HandleUnitResourcesGet(deps.Systemd))
+		handleV1(mux, "PUT", "/systemd/units/{name}/resources", systemd.HandleUnitResourcesPut(deps.Systemd))
+		handleV1(mux, "GET", "/systemd/units/{name}/dropins/{dropin}", systemd.HandleDropInGet(deps.Systemd))
+		handleV1(mux, "PUT", "/systemd/units/{name}/dropins/{dropin}", systemd.HandleDropInPut(deps.Systemd))
+		handleV1(mux, "DELETE", "/systemd/units/{name}/dropins/{dropin}", systemd.HandleDropInDelete(deps.Systemd))
+	}
+	if deps.Systemd != nil && deps.Config != nil {
+		handleV1(mux, "GET", "/systemd/config/{file}/dropins/{dropin}", systemd.HandleConfigDropInGet(deps.Config))
+		handleV1(mux, "PUT", "/systemd/config/{file}/dropins/{dropin}", systemd.HandleConfigDropInPut(deps.Config))
+		handleV1(mux, "DELETE", "/systemd/config/{file}/dropins/{dropin}", systemd.HandleConfigDropInDelete(deps.Config))
+	}
+	if deps.Tmpfiles != nil {
+		handleV1(mux, "GET", "/systemd/tmpfiles/{name}", systemd.HandleTmpfilesGet(deps.Tmpfiles))
+		handleV1(mux, "PUT", "/systemd/tmpfiles/{name}", systemd.HandleTmpfilesPut(deps.Tmpfiles))
+		handleV1(mux, "DELETE", "/systemd/tmpfiles/{name}", systemd.HandleTmpfilesDelete(deps.Tmpfiles))
+	}
+	if deps.Sysusers != nil {
+		handleV1(mux, "GET", "/systemd/sysusers/{name}", systemd.HandleSysusersGet(deps.Sysusers))
+		handleV1(mux, "PUT", "/systemd/sysusers/{name}", systemd.HandleSysusersPut(deps.Sysusers))
+		handleV1(mux, "DELETE", "/systemd/sysusers/{name}", systemd.HandleSysusersDelete(deps.Sysusers))
+	}
+	if deps.Journal != nil {
+		handleV1(mux, "GET", "/journal", journal.HandleJournalEntries(deps.Journal))
+	}
+	if deps.Login != nil {
+		handleV1(mux, "GET", "/login/boot", login.HandleBootGet(deps.Login))
+		handleV1(mux, "POST", "/login/reboot", login.HandleRebootPost(deps.Login))
+		handleV1(mux, "DELETE", "/login/reboot", login.HandleRebootDelete(deps.Login))
+	}
+}
+
+func handleV1(mux router, method string, path string, h http.Handler) {
+	handle(mux, method+" /v1"+path, middleware.RequireAPIVersion(1, h))
+}
+
+// handle registers h on the mux, recording request metrics under the route pattern
+func handle(mux router, pattern string, h http.Handler) {
+	mux.Handle(pattern, middleware.Instrument(pattern, h))
+}
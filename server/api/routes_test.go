@@ -0,0 +1,131 @@
+package api_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/api/apitest"
+)
+
+func TestRoutes(t *testing.T) {
+	agent := apitest.NewAgent()
+	defer agent.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/readyz", "", http.StatusOK},
+		{http.MethodGet, "/capabilities", "", http.StatusOK},
+		{http.MethodGet, "/v1/zfs/zpool", "", http.StatusOK},
+		{http.MethodPost, "/v1/zfs/zpool", `{"name": "tank"}`, http.StatusCreated},
+		{http.MethodGet, "/v1/zfs/zpool/tank", "", http.StatusOK},
+		{http.MethodDelete, "/v1/zfs/zpool/tank", "", http.StatusNoContent},
+		{http.MethodGet, "/v1/zfs/zpool/tank", "", http.StatusNotFound},
+		{http.MethodGet, "/unknown", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, agent.URL()+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, resp.StatusCode)
+		}
+		if v := resp.Header.Get(common.APIVersionHeader); v != strconv.Itoa(common.APIVersion) {
+			t.Errorf("%s %s: expected API version header %d, got %q", tt.method, tt.path, common.APIVersion, v)
+		}
+	}
+}
+
+func TestRequestedAPIVersion(t *testing.T) {
+	agent := apitest.NewAgent()
+	defer agent.Close()
+
+	tests := []struct {
+		version string
+		status  int
+	}{
+		{"", http.StatusOK},
+		{"1", http.StatusOK},
+		{"2", http.StatusBadRequest},
+		{"v1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, agent.URL()+"/v1/zfs/zpool", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.version != "" {
+			req.Header.Set(common.APIVersionHeader, tt.version)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("version %q: expected %d, got %d", tt.version, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	agent := apitest.NewAgent()
+	defer agent.Close()
+	agent.Zfs.SetDelay(time.Minute)
+
+	req, err := http.NewRequest(http.MethodGet, agent.URL()+"/v1/zfs/zpool", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(common.TimeoutHeader, "50")
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the backend call to be abandoned at the deadline, took %s", elapsed)
+	}
+}
+
+func TestReadyzRequestTimeout(t *testing.T) {
+	agent := apitest.NewAgent()
+	defer agent.Close()
+	agent.Zfs.SetMethodDelay("Version", time.Minute)
+
+	req, err := http.NewRequest(http.MethodGet, agent.URL()+"/readyz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(common.TimeoutHeader, "50")
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the version check to be abandoned at the deadline, took %s", elapsed)
+	}
+}
@@ -0,0 +1,99 @@
+package bus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/rs/zerolog"
+)
+
+// backendInitTimeout bounds each attempt at initializing a backend
+const backendInitTimeout = 30 * time.Second
+
+// InitFunc prepares a client once its service is on the bus, returning the service's version
+type InitFunc func(ctx context.Context) (string, error)
+
+// Backend is a service the agent depends on, which may join the bus after the agent has started
+// The backend is initialized as soon as its name has an owner, retrying whenever the owner changes until it succeeds,
+// so that a service which is installed or started later is picked up without restarting the agent. An attempt which
+// fails while the service is running, e.g. because a call timed out, is retried with backoff.
+type Backend struct {
+	sup  *Supervisor
+	log  *zerolog.Logger
+	name string
+	init InitFunc
+
+	// initMu serializes attempts, which are started concurrently by resyncs, and guards the retry
+	initMu  sync.Mutex
+	retry   *time.Timer
+	backoff time.Duration
+	mu      sync.RWMutex
+	enabled bool
+	version string
+}
+
+// NewBackend watches name and initializes the backend straight away if the service is already running
+func NewBackend(log *zerolog.Logger, sup *Supervisor, name string, init InitFunc) *Backend {
+	l := log.With().Str("backend", name).Logger()
+	b := &Backend{sup: sup, log: &l, name: name, init: init}
+	sup.Watch(name)
+	sup.OnResync(b.start)
+	b.start()
+	return b
+}
+
+func (b *Backend) start() {
+	b.initMu.Lock()
+	defer b.initMu.Unlock()
+	if b.Enabled() {
+		return
+	}
+	if err := b.sup.Available(b.name); err != nil {
+		b.log.Debug().Msg("Service is not on the bus, waiting for it to start")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backendInitTimeout)
+	defer cancel()
+	version, err := b.init(ctx)
+	if err != nil {
+		b.backoff = min(max(b.backoff*2, minBackoff), maxBackoff)
+		b.log.Warn().Err(err).Dur("backoff", b.backoff).Msg("Failed to initialize backend, retrying")
+		if b.retry != nil {
+			b.retry.Stop()
+		}
+		b.retry = time.AfterFunc(b.backoff, b.start)
+		return
+	}
+	b.backoff = 0
+
+	b.mu.Lock()
+	b.enabled = true
+	b.version = version
+	b.mu.Unlock()
+	b.log.Info().Str("version", version).Msg("Enabled backend")
+}
+
+// Enabled returns true once the backend has been initialized
+func (b *Backend) Enabled() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.enabled
+}
+
+// Ready returns an UnavailableError until the backend has been initialized, and whenever its service is off the bus
+func (b *Backend) Ready() error {
+	if !b.Enabled() {
+		return &UnavailableError{Name: b.name, RetryAfter: defaultRetryAfter}
+	}
+	return b.sup.Available(b.name)
+}
+
+// Capability reports the backend as a module of the agent
+func (b *Backend) Capability() common.ModuleCapability {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return common.ModuleCapability{Enabled: b.enabled, Version: b.version}
+}
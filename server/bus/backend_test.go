@@ -0,0 +1,64 @@
+package bus_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/dbustest"
+	"github.com/rs/zerolog"
+)
+
+const testService = "com.example.Service"
+
+// requestName claims name on conn, as a service starting up would
+func requestName(t *testing.T, conn *dbus.Conn, name string) {
+	t.Helper()
+	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to request %s: %v", name, err)
+	}
+}
+
+// eventually polls check until it returns true, failing the test after a few seconds
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestBackendRetriesFailedInit(t *testing.T) {
+	b := dbustest.NewBus(t)
+	requestName(t, b.Connect(t), testService)
+	sup := b.Supervisor(t)
+
+	// The service owns its name throughout, so only the retry can enable the backend
+	var attempts atomic.Int32
+	log := zerolog.New(zerolog.NewTestWriter(t))
+	backend := bus.NewBackend(&log, sup, testService, func(ctx context.Context) (string, error) {
+		if attempts.Add(1) < 3 {
+			return "", errors.New("call timed out")
+		}
+		return "1.0", nil
+	})
+	if _, ok := bus.IsUnavailable(backend.Ready()); !ok {
+		t.Errorf("expected the backend to be unavailable until it is initialized, got %v", backend.Ready())
+	}
+
+	eventually(t, "the backend to be enabled", backend.Enabled)
+	if attempts.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts.Load())
+	}
+	if capability := backend.Capability(); capability.Version != "1.0" {
+		t.Errorf("expected version 1.0, got %+v", capability)
+	}
+}
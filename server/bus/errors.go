@@ -0,0 +1,67 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// ErrNotFound should be wrapped by clients when the requested object does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalid should be wrapped by clients and handlers when the request itself is malformed
+var ErrInvalid = errors.New("invalid request")
+
+// ErrConflict should be wrapped by clients when the state of the host prevents the request, e.g. a lock held by another process
+var ErrConflict = errors.New("conflict")
+
+// defaultRetryAfter is the hint given to clients when a service has dropped off the bus
+const defaultRetryAfter = 5 * time.Second
+
+// UnavailableError is returned when the bus, or a service on it, is not currently reachable
+type UnavailableError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s is currently unavailable", e.Name)
+}
+
+// unavailableErrors are the D-Bus errors returned when the destination has no owner
+var unavailableErrors = map[string]bool{
+	"org.freedesktop.DBus.Error.ServiceUnknown": true,
+	"org.freedesktop.DBus.Error.NameHasNoOwner": true,
+	"org.freedesktop.DBus.Error.Disconnected":   true,
+}
+
+// IsUnavailable returns true if err indicates that the bus or the target service is down,
+// along with a hint of how long the caller should wait before retrying
+func IsUnavailable(err error) (time.Duration, bool) {
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) {
+		return unavailable.RetryAfter, true
+	}
+	if errors.Is(err, dbus.ErrClosed) {
+		return defaultRetryAfter, true
+	}
+	if name, ok := ErrorName(err); ok && unavailableErrors[name] {
+		return defaultRetryAfter, true
+	}
+	return 0, false
+}
+
+// ErrorName returns the D-Bus error name (e.g. org.freedesktop.DBus.Error.AccessDenied) if err is a D-Bus error
+func ErrorName(err error) (string, bool) {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name, true
+	}
+	var dbusErrPtr *dbus.Error
+	if errors.As(err, &dbusErrPtr) {
+		return dbusErrPtr.Name, true
+	}
+	return "", false
+}
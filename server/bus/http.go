@@ -0,0 +1,44 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// journalError is implemented by errors which carry the journal lines logged by a failed unit
+type journalError interface {
+	JournalLines() []string
+}
+
+// HTTPError writes err as the response
+// Conflicts with the host's state are reported as 409, unavailable backends as 503 along with a Retry-After hint,
+// polkit denials as 403 naming the action, calls abandoned at the client's deadline as 504, and everything else is a 500
+func HTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	resp := common.ErrorResponse{Error: err.Error()}
+	var jErr journalError
+	if errors.As(err, &jErr) {
+		resp.Journal = jErr.JournalLines()
+	}
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	} else if errors.Is(err, ErrInvalid) {
+		status = http.StatusBadRequest
+	} else if errors.Is(err, ErrConflict) {
+		status = http.StatusConflict
+	} else if retryAfter, ok := IsUnavailable(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		status = http.StatusServiceUnavailable
+	} else if authErr, ok := IsAuthorizationError(err); ok {
+		status = http.StatusForbidden
+		resp.Action = authErr.Action
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+	common.Encode(w, r, status, resp)
+}
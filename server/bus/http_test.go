@@ -0,0 +1,69 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// failedUnit carries journal lines, as systemd.UnitFailedError does
+type failedUnit struct{}
+
+func (failedUnit) Error() string { return "start of example.service did not complete" }
+
+func (failedUnit) JournalLines() []string { return []string{"example.service: Main process exited"} }
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter string
+		action     string
+		journal    int
+	}{
+		{name: "generic error", err: errors.New("boom"), status: http.StatusInternalServerError},
+		{name: "not found", err: fmt.Errorf("zpool tank: %w", ErrNotFound), status: http.StatusNotFound},
+		{name: "invalid", err: fmt.Errorf("bad name: %w", ErrInvalid), status: http.StatusBadRequest},
+		{name: "conflict", err: fmt.Errorf("reboot blocked: %w", ErrConflict), status: http.StatusConflict},
+		{name: "unavailable", err: &UnavailableError{Name: "dbus", RetryAfter: 1500 * time.Millisecond}, status: http.StatusServiceUnavailable, retryAfter: "2"},
+		{name: "wrapped unavailable", err: fmt.Errorf("listing: %w", &UnavailableError{Name: "zfs", RetryAfter: time.Second}), status: http.StatusServiceUnavailable, retryAfter: "1"},
+		{name: "closed connection", err: dbus.ErrClosed, status: http.StatusServiceUnavailable, retryAfter: "5"},
+		{name: "service unknown", err: dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}, status: http.StatusServiceUnavailable, retryAfter: "5"},
+		{name: "other dbus error", err: dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs"}, status: http.StatusInternalServerError},
+		{name: "deadline exceeded", err: fmt.Errorf("creating tank: %w", context.DeadlineExceeded), status: http.StatusGatewayTimeout},
+		{name: "not authorized", err: &AuthorizationError{Method: "com.example.Test", Action: "com.example.test", Err: errors.New("denied")}, status: http.StatusForbidden, action: "com.example.test"},
+		{name: "unit failed", err: fmt.Errorf("starting: %w", failedUnit{}), status: http.StatusInternalServerError, journal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPError(w, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tt.retryAfter, got)
+			}
+			var resp common.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Action != tt.action {
+				t.Errorf("expected action %q, got %q", tt.action, resp.Action)
+			}
+			if len(resp.Journal) != tt.journal {
+				t.Errorf("expected %d journal lines, got %v", tt.journal, resp.Journal)
+			}
+		})
+	}
+}
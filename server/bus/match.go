@@ -0,0 +1,66 @@
+package bus
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// busName is the name of the message bus itself, which sends signals such as NameOwnerChanged under it
+const busName = "org.freedesktop.DBus"
+
+var nameOwnerChanged = Match{Sender: busName, Interface: busName, Member: "NameOwnerChanged"}
+
+// Match selects the signals delivered to a subscription, an empty field matching any value
+// See https://dbus.freedesktop.org/doc/dbus-specification.html#message-bus-routing-match-rules
+type Match struct {
+	// Sender is a well-known or unique name, a well-known name matching the signals sent by its current owner
+	Sender        string
+	Path          dbus.ObjectPath
+	PathNamespace dbus.ObjectPath
+	Interface     string
+	Member        string
+}
+
+// options returns the match rule the bus applies to the agent's connection
+func (m Match) options() []dbus.MatchOption {
+	var opts []dbus.MatchOption
+	if m.Sender != "" {
+		opts = append(opts, dbus.WithMatchSender(m.Sender))
+	}
+	if m.Path != "" {
+		opts = append(opts, dbus.WithMatchObjectPath(m.Path))
+	}
+	if m.PathNamespace != "" {
+		opts = append(opts, dbus.WithMatchPathNamespace(m.PathNamespace))
+	}
+	if m.Interface != "" {
+		opts = append(opts, dbus.WithMatchInterface(m.Interface))
+	}
+	if m.Member != "" {
+		opts = append(opts, dbus.WithMatchMember(m.Member))
+	}
+	return opts
+}
+
+// matches returns true if the bus would deliver sig for this match, owner being the unique name owning the sender
+// The connection receives the signals of every subscription, so each is checked again before handing it over.
+func (m Match) matches(sig *dbus.Signal, owner string) bool {
+	if m.Sender != "" && sig.Sender != m.Sender && (owner == "" || sig.Sender != owner) {
+		return false
+	}
+	if m.Path != "" && sig.Path != m.Path {
+		return false
+	}
+	if ns := string(m.PathNamespace); ns != "" && ns != "/" && string(sig.Path) != ns && !strings.HasPrefix(string(sig.Path), ns+"/") {
+		return false
+	}
+	dot := strings.LastIndex(sig.Name, ".")
+	if dot < 0 {
+		return false
+	}
+	if m.Interface != "" && sig.Name[:dot] != m.Interface {
+		return false
+	}
+	return m.Member == "" || sig.Name[dot+1:] == m.Member
+}
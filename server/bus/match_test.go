@@ -0,0 +1,41 @@
+package bus
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestMatch(t *testing.T) {
+	sig := &dbus.Signal{
+		Sender: ":1.42",
+		Path:   "/com/example/items/a",
+		Name:   "org.freedesktop.DBus.Properties.PropertiesChanged",
+	}
+	tests := []struct {
+		name    string
+		match   Match
+		owner   string
+		matches bool
+	}{
+		{"empty", Match{}, "", true},
+		{"unique sender", Match{Sender: ":1.42"}, "", true},
+		{"owned sender", Match{Sender: "com.example.Service"}, ":1.42", true},
+		{"previous owner", Match{Sender: "com.example.Service"}, ":1.43", false},
+		{"unowned sender", Match{Sender: "com.example.Service"}, "", false},
+		{"path", Match{Path: "/com/example/items/a"}, "", true},
+		{"other path", Match{Path: "/com/example/items"}, "", false},
+		{"namespace", Match{PathNamespace: "/com/example"}, "", true},
+		{"namespace itself", Match{PathNamespace: "/com/example/items/a"}, "", true},
+		{"namespace prefix", Match{PathNamespace: "/com/ex"}, "", false},
+		{"root namespace", Match{PathNamespace: "/"}, "", true},
+		{"interface and member", Match{Interface: propertiesInterface, Member: "PropertiesChanged"}, "", true},
+		{"other interface", Match{Interface: ObjectManagerInterface}, "", false},
+		{"other member", Match{Member: "InterfacesAdded"}, "", false},
+	}
+	for _, tt := range tests {
+		if matches := tt.match.matches(sig, tt.owner); matches != tt.matches {
+			t.Errorf("%s: expected matches to return %t", tt.name, tt.matches)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package bus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ObjectManagerInterface = "org.freedesktop.DBus.ObjectManager"
+	propertiesInterface    = "org.freedesktop.DBus.Properties"
+	treeSyncTimeout        = 30 * time.Second
+)
+
+var (
+	signalInterfacesAdded   = ObjectManagerInterface + ".InterfacesAdded"
+	signalInterfacesRemoved = ObjectManagerInterface + ".InterfacesRemoved"
+	signalPropertiesChanged = propertiesInterface + ".PropertiesChanged"
+)
+
+// Properties maps property names to their values, for a single interface
+type Properties = map[string]dbus.Variant
+
+// Interfaces maps interface names to their properties, for a single object
+type Interfaces = map[string]Properties
+
+// ObjectTree is an in-memory mirror of the objects exported by a service implementing org.freedesktop.DBus.ObjectManager
+// It is populated with a single GetManagedObjects call and kept current from the
+// InterfacesAdded, InterfacesRemoved and PropertiesChanged signals, resyncing whenever the bus or the service restarts.
+type ObjectTree struct {
+	sup  *Supervisor
+	log  *zerolog.Logger
+	dest string
+	root dbus.ObjectPath
+
+	mu      sync.RWMutex
+	owner   string
+	objects map[dbus.ObjectPath]Interfaces
+	synced  bool
+}
+
+// NewObjectTree subscribes to changes to the objects under root and performs the initial sync
+func NewObjectTree(log *zerolog.Logger, sup *Supervisor, dest string, root dbus.ObjectPath) (*ObjectTree, error) {
+	l := log.With().Str("destination", dest).Str("root", string(root)).Logger()
+	t := &ObjectTree{
+		sup:     sup,
+		log:     &l,
+		dest:    dest,
+		root:    root,
+		objects: make(map[dbus.ObjectPath]Interfaces),
+	}
+	sup.Watch(dest)
+
+	// Subscribe before syncing so that no changes are missed in between
+	// A single subscription covers both the manager's signals and PropertiesChanged, which handle tells apart by name,
+	// so that each signal is only applied once.
+	err := sup.Subscribe(t.handle, Match{Sender: dest, PathNamespace: root})
+	if err != nil {
+		return nil, err
+	}
+	sup.OnResync(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), treeSyncTimeout)
+		defer cancel()
+		if err := t.Sync(ctx); err != nil {
+			t.log.Error().Err(err).Msg("Failed to resync object tree")
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), treeSyncTimeout)
+	defer cancel()
+	return t, t.Sync(ctx)
+}
+
+// Sync replaces the cached objects with a fresh copy from the service
+func (t *ObjectTree) Sync(ctx context.Context) error {
+	conn, err := t.sup.Conn()
+	if err != nil {
+		t.invalidate()
+		return err
+	}
+	if err := t.sup.Available(t.dest); err != nil {
+		t.invalidate()
+		return err
+	}
+
+	var owner string
+	err = Call(ctx, conn.BusObject(), "org.freedesktop.DBus.GetNameOwner", 0, t.dest).Store(&owner)
+	if err != nil {
+		t.invalidate()
+		return err
+	}
+	var objects map[dbus.ObjectPath]Interfaces
+	err = Call(ctx, conn.Object(t.dest, t.root), ObjectManagerInterface+".GetManagedObjects", 0).Store(&objects)
+	if err != nil {
+		t.invalidate()
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.owner = owner
+	t.objects = objects
+	t.synced = true
+	t.log.Debug().Int("objects", len(objects)).Msg("Synced object tree")
+	return nil
+}
+
+// Snapshot returns a consistent copy of every object in the tree
+func (t *ObjectTree) Snapshot() (map[dbus.ObjectPath]Interfaces, error) {
+	return t.Objects("")
+}
+
+// Objects returns a consistent copy of the objects which implement iface, or every object if iface is empty
+func (t *ObjectTree) Objects(iface string) (map[dbus.ObjectPath]Interfaces, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.synced {
+		return nil, &UnavailableError{Name: t.dest, RetryAfter: defaultRetryAfter}
+	}
+	result := make(map[dbus.ObjectPath]Interfaces)
+	for path, ifaces := range t.objects {
+		if _, ok := ifaces[iface]; iface != "" && !ok {
+			continue
+		}
+		result[path] = copyInterfaces(ifaces)
+	}
+	return result, nil
+}
+
+// invalidate marks the tree as stale, until the next successful sync
+func (t *ObjectTree) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.synced = false
+}
+
+func (t *ObjectTree) handle(sig *dbus.Signal) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.synced || sig.Sender != t.owner {
+		return
+	}
+
+	switch sig.Name {
+	case signalInterfacesAdded:
+		if sig.Path != t.root {
+			return
+		}
+		var path dbus.ObjectPath
+		var added Interfaces
+		if err := dbus.Store(sig.Body, &path, &added); err != nil {
+			t.log.Error().Err(err).Msg("Malformed InterfacesAdded signal")
+			return
+		}
+		ifaces, ok := t.objects[path]
+		if !ok {
+			ifaces = make(Interfaces)
+			t.objects[path] = ifaces
+		}
+		for name, props := range added {
+			ifaces[name] = props
+		}
+	case signalInterfacesRemoved:
+		if sig.Path != t.root {
+			return
+		}
+		var path dbus.ObjectPath
+		var removed []string
+		if err := dbus.Store(sig.Body, &path, &removed); err != nil {
+			t.log.Error().Err(err).Msg("Malformed InterfacesRemoved signal")
+			return
+		}
+		for _, name := range removed {
+			delete(t.objects[path], name)
+		}
+		if len(t.objects[path]) == 0 {
+			delete(t.objects, path)
+		}
+	case signalPropertiesChanged:
+		if sig.Path != t.root && !strings.HasPrefix(string(sig.Path), string(t.root)+"/") {
+			return
+		}
+		var iface string
+		var changed Properties
+		var invalidated []string
+		if err := dbus.Store(sig.Body, &iface, &changed, &invalidated); err != nil {
+			t.log.Error().Err(err).Msg("Malformed PropertiesChanged signal")
+			return
+		}
+		props, ok := t.objects[sig.Path][iface]
+		if !ok {
+			return
+		}
+		for name, value := range changed {
+			props[name] = value
+		}
+		if len(invalidated) > 0 {
+			// Invalidated properties don't carry their new value, so fetch them again
+			go t.refresh(sig.Path, iface)
+		}
+	}
+}
+
+// refresh refetches every property of iface on path
+func (t *ObjectTree) refresh(path dbus.ObjectPath, iface string) {
+	conn, err := t.sup.Conn()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), treeSyncTimeout)
+	defer cancel()
+	var props Properties
+	err = Call(ctx, conn.Object(t.dest, path), propertiesInterface+".GetAll", 0, iface).Store(&props)
+	if err != nil {
+		t.log.Error().Err(err).Str("path", string(path)).Msg("Failed to refresh invalidated properties")
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ifaces, ok := t.objects[path]; ok {
+		if _, ok := ifaces[iface]; ok {
+			ifaces[iface] = props
+		}
+	}
+}
+
+func copyInterfaces(ifaces Interfaces) Interfaces {
+	result := make(Interfaces, len(ifaces))
+	for name, props := range ifaces {
+		p := make(Properties, len(props))
+		for k, v := range props {
+			p[k] = v
+		}
+		result[name] = p
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package bus
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog"
+)
+
+func newSyncedTree(objects map[dbus.ObjectPath]Interfaces) *ObjectTree {
+	log := zerolog.Nop()
+	return &ObjectTree{
+		log:     &log,
+		dest:    "com.example.Service",
+		root:    "/com/example",
+		owner:   ":1.42",
+		objects: objects,
+		synced:  true,
+	}
+}
+
+func TestObjectTreeSignals(t *testing.T) {
+	tree := newSyncedTree(map[dbus.ObjectPath]Interfaces{
+		"/com/example/a": {"com.example.Item": {"Name": dbus.MakeVariant("a")}},
+	})
+
+	tree.handle(&dbus.Signal{
+		Sender: ":1.42",
+		Path:   "/com/example",
+		Name:   signalInterfacesAdded,
+		Body: []interface{}{
+			dbus.ObjectPath("/com/example/b"),
+			map[string]map[string]dbus.Variant{"com.example.Item": {"Name": dbus.MakeVariant("b")}},
+		},
+	})
+	tree.handle(&dbus.Signal{
+		Sender: ":1.42",
+		Path:   "/com/example/a",
+		Name:   signalPropertiesChanged,
+		Body: []interface{}{
+			"com.example.Item",
+			map[string]dbus.Variant{"Name": dbus.MakeVariant("renamed")},
+			[]string{},
+		},
+	})
+	// Signals from a previous owner of the name are ignored
+	tree.handle(&dbus.Signal{
+		Sender: ":1.7",
+		Path:   "/com/example",
+		Name:   signalInterfacesRemoved,
+		Body:   []interface{}{dbus.ObjectPath("/com/example/a"), []string{"com.example.Item"}},
+	})
+
+	objects, err := tree.Objects("com.example.Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objects))
+	}
+	if name := objects["/com/example/a"]["com.example.Item"]["Name"].Value(); name != "renamed" {
+		t.Errorf("expected property to be updated, got %v", name)
+	}
+
+	tree.handle(&dbus.Signal{
+		Sender: ":1.42",
+		Path:   "/com/example",
+		Name:   signalInterfacesRemoved,
+		Body:   []interface{}{dbus.ObjectPath("/com/example/a"), []string{"com.example.Item"}},
+	})
+	objects, err = tree.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/com/example/a"]; ok || len(objects) != 1 {
+		t.Errorf("expected object to be removed, got %v", objects)
+	}
+}
+
+func TestObjectTreeIgnoresOtherManagers(t *testing.T) {
+	tree := newSyncedTree(map[dbus.ObjectPath]Interfaces{})
+
+	// The subscription covers the whole namespace, so a nested ObjectManager's signals are delivered too
+	tree.handle(&dbus.Signal{
+		Sender: ":1.42",
+		Path:   "/com/example/nested",
+		Name:   signalInterfacesAdded,
+		Body: []interface{}{
+			dbus.ObjectPath("/com/example/nested/b"),
+			map[string]map[string]dbus.Variant{"com.example.Item": {"Name": dbus.MakeVariant("b")}},
+		},
+	})
+	objects, err := tree.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("expected signals from another manager to be ignored, got %v", objects)
+	}
+}
+
+func TestObjectTreeSnapshotIsACopy(t *testing.T) {
+	tree := newSyncedTree(map[dbus.ObjectPath]Interfaces{
+		"/com/example/a": {"com.example.Item": {"Name": dbus.MakeVariant("a")}},
+	})
+	snapshot, err := tree.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot["/com/example/a"]["com.example.Item"]["Name"] = dbus.MakeVariant("changed")
+
+	again, _ := tree.Snapshot()
+	if name := again["/com/example/a"]["com.example.Item"]["Name"].Value(); name != "a" {
+		t.Errorf("expected tree to be unaffected by snapshot changes, got %v", name)
+	}
+}
+
+func TestObjectTreeUnavailableUntilSynced(t *testing.T) {
+	tree := newSyncedTree(nil)
+	tree.invalidate()
+	if _, err := tree.Snapshot(); err == nil {
+		t.Fatal("expected error from unsynced tree")
+	} else if _, ok := IsUnavailable(err); !ok {
+		t.Errorf("expected unavailable error, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package bus
+
+import (
+	"context"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Observer is notified of every D-Bus call made through this package
+// Implementations must be safe for concurrent use
+type Observer interface {
+	CallStarted(method string)
+	CallFinished(method string, duration time.Duration, err error)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) CallStarted(string)                        {}
+func (nopObserver) CallFinished(string, time.Duration, error) {}
+
+var observer Observer = nopObserver{}
+
+// SetObserver installs the Observer used for all subsequent calls
+// It is not safe to call this once requests are being served
+func SetObserver(o Observer) {
+	if o == nil {
+		o = nopObserver{}
+	}
+	observer = o
+}
+
+// Call invokes method on obj and reports the outcome to the installed Observer
+// Authorization failures are returned as an AuthorizationError
+func Call(ctx context.Context, obj dbus.BusObject, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	done := observe(method)
+	call := obj.CallWithContext(ctx, method, flags, args...)
+	done(call.Err)
+	call.Err = wrapAuthorization(method, call.Err)
+	return call
+}
+
+func observe(method string) func(err error) {
+	observer.CallStarted(method)
+	start := time.Now()
+	return func(err error) {
+		observer.CallFinished(method, time.Since(start), err)
+	}
+}
@@ -0,0 +1,74 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// authorizationErrors are the D-Bus errors returned when polkit (or the bus policy) denies a call
+var authorizationErrors = map[string]bool{
+	"org.freedesktop.DBus.Error.AccessDenied":                      true,
+	"org.freedesktop.DBus.Error.InteractiveAuthorizationRequired":  true,
+	"org.freedesktop.PolicyKit1.Error.NotAuthorized":               true,
+	"org.freedesktop.PolicyKit1.Error.NotAuthorizedDismissed":      true,
+	"org.freedesktop.PolicyKit1.Error.NotAuthorizedCannotObtain":   true,
+	"org.freedesktop.PolicyKit1.Error.NotAuthorizedNotInteractive": true,
+}
+
+var (
+	actionsMu sync.RWMutex
+	actions   = make(map[string]string)
+)
+
+// RegisterAction records the polkit action ID which guards the given D-Bus method or property
+// This lets authorization failures name the action an administrator needs to grant
+func RegisterAction(method string, action string) {
+	actionsMu.Lock()
+	defer actionsMu.Unlock()
+	actions[method] = action
+}
+
+func actionFor(method string) string {
+	actionsMu.RLock()
+	defer actionsMu.RUnlock()
+	return actions[method]
+}
+
+// AuthorizationError is returned when the agent is not permitted to perform a D-Bus call
+type AuthorizationError struct {
+	Method string
+	Action string
+	Err    error
+}
+
+func (e *AuthorizationError) Error() string {
+	if e.Action == "" {
+		return fmt.Sprintf("not authorized to call %s: %s", e.Method, e.Err)
+	}
+	return fmt.Sprintf("not authorized to call %s, polkit action %s was denied: %s", e.Method, e.Action, e.Err)
+}
+
+func (e *AuthorizationError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthorizationError returns the AuthorizationError if err is one
+func IsAuthorizationError(err error) (*AuthorizationError, bool) {
+	var authErr *AuthorizationError
+	if errors.As(err, &authErr) {
+		return authErr, true
+	}
+	return nil, false
+}
+
+// wrapAuthorization converts polkit denials into an AuthorizationError naming the action, other errors are returned unchanged
+func wrapAuthorization(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if name, ok := ErrorName(err); ok && authorizationErrors[name] {
+		return &AuthorizationError{Method: method, Action: actionFor(method), Err: err}
+	}
+	return err
+}
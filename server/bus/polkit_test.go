@@ -0,0 +1,29 @@
+package bus
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestWrapAuthorization(t *testing.T) {
+	RegisterAction("com.example.Manager.Restart", "com.example.manage")
+
+	denied := dbus.Error{Name: "org.freedesktop.DBus.Error.InteractiveAuthorizationRequired"}
+	err := wrapAuthorization("com.example.Manager.Restart", denied)
+	authErr, ok := IsAuthorizationError(err)
+	if !ok {
+		t.Fatalf("expected authorization error, got %v", err)
+	}
+	if authErr.Action != "com.example.manage" {
+		t.Errorf("expected registered action, got %q", authErr.Action)
+	}
+	if name, _ := ErrorName(err); name != denied.Name {
+		t.Errorf("expected original error to be wrapped, got %q", name)
+	}
+
+	other := dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs"}
+	if _, ok := IsAuthorizationError(wrapAuthorization("com.example.Manager.Restart", other)); ok {
+		t.Errorf("expected other errors to be unchanged")
+	}
+}
@@ -1,13 +1,26 @@
 package bus
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
 	"github.com/godbus/dbus/v5"
 	"github.com/rs/zerolog"
 )
 
-func Decode[T any](log *zerolog.Logger, obj dbus.BusObject, property string) (T, error) {
+// Decode fetches a single property, named as interface.Property, and stores it in a T
+func Decode[T any](ctx context.Context, log *zerolog.Logger, obj dbus.BusObject, property string) (T, error) {
 	var v T
-	prop, err := obj.GetProperty(property)
+	i := strings.LastIndex(property, ".")
+	if i < 0 {
+		return v, fmt.Errorf("property %s is not qualified by its interface", property)
+	}
+	var prop dbus.Variant
+	err := Call(ctx, obj, propertiesInterface+".Get", 0, property[:i], property[i+1:]).Store(&prop)
 	if err != nil {
 		return v, err
 	}
@@ -18,3 +31,82 @@ func Decode[T any](log *zerolog.Logger, obj dbus.BusObject, property string) (T,
 	}
 	return v, nil
 }
+
+// DecodeAll fetches every property of iface on obj with a single GetAll call and stores them in a T
+// See DecodeMap for how properties are matched to fields
+func DecodeAll[T any](ctx context.Context, log *zerolog.Logger, obj dbus.BusObject, iface string) (T, error) {
+	var v T
+	var props map[string]dbus.Variant
+	err := Call(ctx, obj, propertiesInterface+".GetAll", 0, iface).Store(&props)
+	if err != nil {
+		return v, err
+	}
+	log.Debug().Str("interface", iface).Int("properties", len(props)).Msg("Received properties")
+	err = DecodeMap(props, &v)
+	return v, err
+}
+
+// DecodeMap stores props in the fields of the struct pointed to by v
+// Fields are matched using a `dbus:"PropertyName"` tag, untagged fields are ignored.
+// A property which is missing from props is an error, unless the tag is marked `dbus:"PropertyName,optional"`.
+// time.Time and time.Duration fields are decoded from microseconds held in a uint64, as used by systemd, where 0 or the
+// maximum value (infinity) decode to the zero time or duration.
+func DecodeMap(props map[string]dbus.Variant, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode properties into %T, expected a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup("dbus")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		variant, ok := props[name]
+		if !ok {
+			if opts == "optional" {
+				continue
+			}
+			return fmt.Errorf("property %s is missing, expected a value for field %s.%s", name, rt.Name(), field.Name)
+		}
+		var err error
+		switch field.Type {
+		case timeType, durationType:
+			err = storeUsec(variant, rv.Field(i))
+		default:
+			err = variant.Store(rv.Field(i).Addr().Interface())
+		}
+		if err != nil {
+			return fmt.Errorf("property %s has signature %s which cannot be stored in field %s.%s of type %s: %w",
+				name, variant.Signature(), rt.Name(), field.Name, field.Type, err)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// storeUsec stores a count of microseconds in a time.Time, as microseconds since the epoch, or a time.Duration
+func storeUsec(variant dbus.Variant, field reflect.Value) error {
+	usec, ok := variant.Value().(uint64)
+	if !ok {
+		return fmt.Errorf("expected microseconds as a uint64")
+	}
+	if usec == 0 || usec == math.MaxUint64 {
+		field.SetZero()
+		return nil
+	}
+	if field.Type() == timeType {
+		field.Set(reflect.ValueOf(time.UnixMicro(int64(usec)).UTC()))
+	} else {
+		field.SetInt(int64(time.Duration(usec) * time.Microsecond))
+	}
+	return nil
+}
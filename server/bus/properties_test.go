@@ -0,0 +1,120 @@
+package bus
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type testProperties struct {
+	Name     string   `dbus:"Name"`
+	Size     uint64   `dbus:"Size"`
+	Paths    []string `dbus:"Paths,optional"`
+	Ignored  string
+	internal string `dbus:"Internal"`
+}
+
+func TestDecodeMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		props    map[string]dbus.Variant
+		expected testProperties
+		err      string
+	}{
+		{
+			name: "all properties",
+			props: map[string]dbus.Variant{
+				"Name":  dbus.MakeVariant("tank"),
+				"Size":  dbus.MakeVariant(uint64(1024)),
+				"Paths": dbus.MakeVariant([]string{"/dev/sda"}),
+				"Extra": dbus.MakeVariant(true),
+			},
+			expected: testProperties{Name: "tank", Size: 1024, Paths: []string{"/dev/sda"}},
+		},
+		{
+			name: "optional property missing",
+			props: map[string]dbus.Variant{
+				"Name": dbus.MakeVariant("tank"),
+				"Size": dbus.MakeVariant(uint64(1024)),
+			},
+			expected: testProperties{Name: "tank", Size: 1024},
+		},
+		{
+			name: "required property missing",
+			props: map[string]dbus.Variant{
+				"Name": dbus.MakeVariant("tank"),
+			},
+			err: "property Size is missing",
+		},
+		{
+			name: "type mismatch",
+			props: map[string]dbus.Variant{
+				"Name": dbus.MakeVariant("tank"),
+				"Size": dbus.MakeVariant("large"),
+			},
+			err: "property Size has signature s which cannot be stored in field testProperties.Size of type uint64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testProperties
+			err := DecodeMap(tt.props, &got)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tt.expected.Name || got.Size != tt.expected.Size || len(got.Paths) != len(tt.expected.Paths) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeMapRequiresStructPointer(t *testing.T) {
+	var s string
+	if err := DecodeMap(map[string]dbus.Variant{}, &s); err == nil {
+		t.Error("expected error when decoding into a non-struct")
+	}
+}
+
+type testTimes struct {
+	Next     time.Time     `dbus:"Next"`
+	Last     time.Time     `dbus:"Last"`
+	Interval time.Duration `dbus:"Interval"`
+}
+
+func TestDecodeMapUsec(t *testing.T) {
+	var got testTimes
+	err := DecodeMap(map[string]dbus.Variant{
+		"Next":     dbus.MakeVariant(uint64(1700000000123456)),
+		"Last":     dbus.MakeVariant(uint64(0)),
+		"Interval": dbus.MakeVariant(uint64(math.MaxUint64)),
+	}, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Next.Equal(time.UnixMicro(1700000000123456)) {
+		t.Errorf("expected the next time to be decoded from microseconds, got %s", got.Next)
+	}
+	if !got.Last.IsZero() || got.Interval != 0 {
+		t.Errorf("expected zero and infinity to decode to zero values, got %+v", got)
+	}
+
+	err = DecodeMap(map[string]dbus.Variant{
+		"Next":     dbus.MakeVariant("tomorrow"),
+		"Last":     dbus.MakeVariant(uint64(0)),
+		"Interval": dbus.MakeVariant(uint64(1_500_000)),
+	}, &got)
+	if err == nil || !strings.Contains(err.Error(), "property Next has signature s") {
+		t.Errorf("expected a type mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package bus
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog"
+)
+
+const (
+	minBackoff = 250 * time.Millisecond
+	maxBackoff = 30 * time.Second
+	// busCallTimeout bounds the supervisor's own calls to the bus, so that a wedged bus can't stall it
+	busCallTimeout = 5 * time.Second
+)
+
+// Connector opens a new connection to the message bus
+type Connector func() (*dbus.Conn, error)
+
+// Supervisor owns the agent's D-Bus connection
+// It detects when the bus goes away and reconnects with backoff, and tracks whether the services
+// the agent depends on currently have an owner, so that callers can fail fast while they restart.
+type Supervisor struct {
+	connect Connector
+	log     *zerolog.Logger
+
+	mu   sync.RWMutex
+	conn *dbus.Conn
+	// owners maps each watched name to the unique name of its owner, empty while it has none
+	owners map[string]string
+	// ownerChanges counts the owner changes handled, so that a lookup which raced with one is repeated
+	ownerChanges  uint64
+	retryAfter    time.Duration
+	subscriptions []subscription
+	resyncs       []func()
+}
+
+type subscription struct {
+	match   Match
+	handler func(*dbus.Signal)
+}
+
+// NewSupervisor connects to the bus
+// The initial connection must succeed, Run takes care of any subsequent reconnects
+func NewSupervisor(log *zerolog.Logger, connect Connector) (*Supervisor, error) {
+	s := &Supervisor{
+		connect:    connect,
+		log:        log,
+		owners:     make(map[string]string),
+		retryAfter: minBackoff,
+	}
+	conn, err := connect()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attach(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Conn returns the current connection, or an UnavailableError while reconnecting
+func (s *Supervisor) Conn() (*dbus.Conn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.conn == nil {
+		return nil, &UnavailableError{Name: "dbus", RetryAfter: s.retryAfter}
+	}
+	return s.conn, nil
+}
+
+// Watch starts tracking whether the given well-known name has an owner on the bus
+func (s *Supervisor) Watch(name string) {
+	s.mu.Lock()
+	if _, ok := s.owners[name]; ok {
+		s.mu.Unlock()
+		return
+	}
+	s.owners[name] = ""
+	conn, changes := s.conn, s.ownerChanges
+	s.mu.Unlock()
+
+	// The owner is looked up without holding the lock, so that a slow bus doesn't block every other caller
+	for conn != nil {
+		owner := s.nameOwner(conn, name)
+		s.mu.Lock()
+		if s.conn != conn {
+			// The name is looked up again by attach on the new connection
+			s.mu.Unlock()
+			return
+		}
+		if s.ownerChanges == changes {
+			s.owners[name] = owner
+			s.mu.Unlock()
+			return
+		}
+		changes = s.ownerChanges
+		s.mu.Unlock()
+	}
+}
+
+// Available returns an UnavailableError if the bus is disconnected or the watched name has no owner
+func (s *Supervisor) Available(name string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.conn == nil {
+		return &UnavailableError{Name: "dbus", RetryAfter: s.retryAfter}
+	}
+	if owner, ok := s.owners[name]; ok && owner == "" {
+		return &UnavailableError{Name: name, RetryAfter: defaultRetryAfter}
+	}
+	return nil
+}
+
+// Subscribe adds a match rule for the signals selected by match, on the current connection and on every reconnect
+// handler is only called for the signals which match, a well-known sender being watched to match its current owner.
+func (s *Supervisor) Subscribe(handler func(*dbus.Signal), match Match) error {
+	if match.Sender != "" && match.Sender != busName && !strings.HasPrefix(match.Sender, ":") {
+		s.Watch(match.Sender)
+	}
+	s.mu.Lock()
+	s.subscriptions = append(s.subscriptions, subscription{match: match, handler: handler})
+	conn := s.conn
+	s.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), busCallTimeout)
+	defer cancel()
+	return conn.AddMatchSignalContext(ctx, match.options()...)
+}
+
+// OnResync registers fn to be called whenever cached state may be stale,
+// i.e. after a reconnect or when a watched name changes owner
+func (s *Supervisor) OnResync(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resyncs = append(s.resyncs, fn)
+}
+
+// Ping verifies that the message bus itself is responding
+func (s *Supervisor) Ping(ctx context.Context) error {
+	conn, err := s.Conn()
+	if err != nil {
+		return err
+	}
+	return Call(ctx, conn.BusObject(), "org.freedesktop.DBus.Peer.Ping", 0).Err
+}
+
+// Run watches the connection until ctx is cancelled, reconnecting whenever it drops
+func (s *Supervisor) Run(ctx context.Context) {
+	for {
+		conn, _ := s.Conn()
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return
+		case <-conn.Context().Done():
+		}
+
+		s.log.Warn().Msg("Lost connection to D-Bus, reconnecting")
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+
+		if !s.reconnect(ctx) {
+			return
+		}
+		s.log.Info().Msg("Reconnected to D-Bus")
+	}
+}
+
+func (s *Supervisor) reconnect(ctx context.Context) bool {
+	backoff := minBackoff
+	for {
+		s.mu.Lock()
+		s.retryAfter = backoff
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		conn, err := s.connect()
+		if err == nil {
+			err = s.attach(conn)
+			if err == nil {
+				return true
+			}
+			conn.Close()
+		}
+		s.log.Error().Err(err).Dur("backoff", backoff).Msg("Failed to reconnect to D-Bus")
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// attach subscribes to name ownership changes on the new connection and makes it current
+// The calls to the bus are made without holding the lock, and repeated for any subscription or watched name added,
+// or owner change handled, in the meantime.
+func (s *Supervisor) attach(conn *dbus.Conn) error {
+	ctx, cancel := context.WithTimeout(context.Background(), busCallTimeout)
+	defer cancel()
+	if err := conn.AddMatchSignalContext(ctx, nameOwnerChanged.options()...); err != nil {
+		return err
+	}
+	signals := make(chan *dbus.Signal, 64)
+	conn.Signal(signals)
+	go s.dispatch(signals)
+
+	added := 0
+	for {
+		s.mu.RLock()
+		subs := s.subscriptions[added:]
+		names := make([]string, 0, len(s.owners))
+		for name := range s.owners {
+			names = append(names, name)
+		}
+		changes := s.ownerChanges
+		s.mu.RUnlock()
+
+		for _, sub := range subs {
+			if err := conn.AddMatchSignalContext(ctx, sub.match.options()...); err != nil {
+				return err
+			}
+		}
+		added += len(subs)
+		owners := make(map[string]string, len(names))
+		for _, name := range names {
+			owners[name] = s.nameOwner(conn, name)
+		}
+
+		s.mu.Lock()
+		if len(s.subscriptions) == added && len(s.owners) == len(names) && s.ownerChanges == changes {
+			maps.Copy(s.owners, owners)
+			s.conn = conn
+			s.retryAfter = minBackoff
+			s.mu.Unlock()
+			break
+		}
+		s.mu.Unlock()
+	}
+
+	s.resync()
+	return nil
+}
+
+func (s *Supervisor) resync() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, fn := range s.resyncs {
+		go fn()
+	}
+}
+
+// dispatch processes signals until the connection is closed, handing each to the subscriptions it matches
+func (s *Supervisor) dispatch(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if nameOwnerChanged.matches(sig, "") && len(sig.Body) == 3 {
+			s.ownerChanged(sig)
+		}
+
+		var handlers []func(*dbus.Signal)
+		s.mu.RLock()
+		for _, sub := range s.subscriptions {
+			if sub.match.matches(sig, s.owners[sub.match.Sender]) {
+				handlers = append(handlers, sub.handler)
+			}
+		}
+		s.mu.RUnlock()
+		for _, handler := range handlers {
+			handler(sig)
+		}
+	}
+}
+
+func (s *Supervisor) ownerChanged(sig *dbus.Signal) {
+	name, _ := sig.Body[0].(string)
+	owner, _ := sig.Body[2].(string)
+
+	s.mu.Lock()
+	_, watched := s.owners[name]
+	if watched {
+		s.owners[name] = owner
+		s.ownerChanges++
+		s.log.Info().Str("name", name).Bool("available", owner != "").Msg("Service owner changed")
+	}
+	s.mu.Unlock()
+
+	if watched {
+		s.resync()
+	}
+}
+
+// nameOwner returns the unique name of the owner of name, or an empty string if it has none or the lookup fails
+func (s *Supervisor) nameOwner(conn *dbus.Conn, name string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), busCallTimeout)
+	defer cancel()
+	var owner string
+	err := Call(ctx, conn.BusObject(), busName+".GetNameOwner", 0, name).Store(&owner)
+	if errName, _ := ErrorName(err); err != nil && errName != "org.freedesktop.DBus.Error.NameHasNoOwner" {
+		s.log.Warn().Err(err).Str("name", name).Msg("Failed to look up the owner of a watched name")
+	}
+	return owner
+}
@@ -0,0 +1,120 @@
+package bus_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/dbustest"
+)
+
+const testInterface = "com.example.Service"
+
+// expectSignal waits for a signal to be delivered, or checks that none is if member is empty
+func expectSignal(t *testing.T, received <-chan *dbus.Signal, member string) {
+	t.Helper()
+	select {
+	case sig := <-received:
+		if member == "" || sig.Name != testInterface+"."+member {
+			t.Fatalf("unexpected signal %s from %s", sig.Name, sig.Sender)
+		}
+	case <-time.After(time.Second):
+		if member != "" {
+			t.Fatalf("expected a %s signal", member)
+		}
+	}
+}
+
+func TestSupervisorDispatchesMatchingSignals(t *testing.T) {
+	b := dbustest.NewBus(t)
+	service := b.Connect(t)
+	requestName(t, service, testService)
+	sup := b.Supervisor(t)
+
+	received := make(chan *dbus.Signal, 8)
+	err := sup.Subscribe(func(sig *dbus.Signal) { received <- sig },
+		bus.Match{Sender: testService, Interface: testInterface, Member: "Changed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another subscription on the same connection makes the bus deliver signals this one doesn't match
+	if err := sup.Subscribe(func(*dbus.Signal) {}, bus.Match{Interface: testInterface}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Emit("/com/example", testInterface+".Other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Connect(t).Emit("/com/example", testInterface+".Changed"); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(t, received, "")
+	if err := service.Emit("/com/example", testInterface+".Changed"); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(t, received, "Changed")
+}
+
+func TestSupervisorReconnects(t *testing.T) {
+	b := dbustest.NewBus(t)
+	requestName(t, b.Connect(t), testService)
+	sup := b.Supervisor(t)
+	sup.Watch(testService)
+
+	resyncs := make(chan struct{}, 16)
+	sup.OnResync(func() { resyncs <- struct{}{} })
+	received := make(chan *dbus.Signal, 8)
+	err := sup.Subscribe(func(sig *dbus.Signal) { received <- sig },
+		bus.Match{Sender: testService, Interface: testInterface, Member: "Changed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sup.Available(testService); err != nil {
+		t.Fatalf("expected %s to be available, got %s", testService, err)
+	}
+
+	// Requests are answered with a 503 while the daemon is down
+	b.Stop(t)
+	eventually(t, "the connection to drop", func() bool {
+		_, err := sup.Conn()
+		return err != nil
+	})
+	rec := httptest.NewRecorder()
+	bus.HTTPError(rec, httptest.NewRequest(http.MethodGet, "/", nil), sup.Available(testService))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected a 503 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if err := sup.Ping(context.Background()); err == nil {
+		t.Error("expected ping to fail while the daemon is down")
+	}
+
+	for len(resyncs) > 0 {
+		<-resyncs
+	}
+	b.Start(t)
+	eventually(t, "the supervisor to reconnect", func() bool {
+		_, err := sup.Conn()
+		return err == nil
+	})
+	select {
+	case <-resyncs:
+	case <-time.After(time.Second):
+		t.Error("expected a resync after reconnecting")
+	}
+	if err := sup.Ping(context.Background()); err != nil {
+		t.Errorf("expected ping to succeed after reconnecting, got %s", err)
+	}
+
+	// The service comes back on the new daemon, and its signals reach the subscription again
+	service := b.Connect(t)
+	requestName(t, service, testService)
+	eventually(t, testService+" to be available", func() bool { return sup.Available(testService) == nil })
+	if err := service.Emit("/com/example", testInterface+".Changed"); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(t, received, "Changed")
+}
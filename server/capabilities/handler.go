@@ -0,0 +1,33 @@
+package capabilities
+
+import (
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// Module reports whether a backend module is enabled, which may change while the agent runs
+type Module func() common.ModuleCapability
+
+// Static reports a module whose state is fixed when the agent starts
+func Static(capability common.ModuleCapability) Module {
+	return func() common.ModuleCapability {
+		return capability
+	}
+}
+
+// HandleCapabilities reports the agent version and which backend modules are currently enabled on this host
+func HandleCapabilities(agentVersion string, modules map[string]Module) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caps := common.CapabilitiesResponse{
+			AgentVersion:  agentVersion,
+			APIVersion:    common.APIVersion,
+			MinAPIVersion: common.MinAPIVersion,
+			Modules:       make(map[string]common.ModuleCapability, len(modules)),
+		}
+		for name, module := range modules {
+			caps.Modules[name] = module()
+		}
+		common.Encode(w, r, http.StatusOK, caps)
+	})
+}
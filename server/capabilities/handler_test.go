@@ -0,0 +1,40 @@
+package capabilities
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func TestHandleCapabilitiesReportsCurrentState(t *testing.T) {
+	zfs := common.ModuleCapability{}
+	handler := HandleCapabilities("test", map[string]Module{
+		common.ModuleZfs:     func() common.ModuleCapability { return zfs },
+		common.ModuleJournal: Static(common.ModuleCapability{Enabled: true, Version: "252"}),
+	})
+	get := func() common.CapabilitiesResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/capabilities", nil))
+		var caps common.CapabilitiesResponse
+		if err := json.NewDecoder(w.Body).Decode(&caps); err != nil {
+			t.Fatal(err)
+		}
+		return caps
+	}
+
+	caps := get()
+	if caps.HasModule(common.ModuleZfs) || !caps.HasModule(common.ModuleJournal) {
+		t.Fatalf("expected only the journal module to be enabled, got %+v", caps.Modules)
+	}
+
+	// e.g. the ZFS service started after the agent
+	zfs = common.ModuleCapability{Enabled: true, Version: "2.2.0"}
+	caps = get()
+	if !caps.HasModule(common.ModuleZfs) || caps.Modules[common.ModuleZfs].Version != "2.2.0" {
+		t.Errorf("expected the zfs module to be enabled, got %+v", caps.Modules)
+	}
+}
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/rs/zerolog"
+)
+
+// checkTimeout bounds the whole readiness check, a dependency which hasn't answered by then is reported unavailable
+var checkTimeout = 5 * time.Second
+
+// Check verifies that a dependency is reachable, returning its version if it has one
+type Check func(ctx context.Context) (string, error)
+
+type Dependency struct {
+	Name  string
+	Check Check
+	// Enabled, if set, reports whether the dependency is in use, e.g. a module whose service hasn't started
+	// A dependency which isn't enabled is reported as disabled, without running its check or failing readiness.
+	Enabled func() bool
+}
+
+// HandleHealthz reports that the agent process is up and serving requests
+func HandleHealthz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, common.StatusOK)
+	})
+}
+
+// HandleReadyz checks every enabled dependency and only reports ready when all of them are reachable
+func HandleReadyz(deps ...Dependency) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+		log := zerolog.Ctx(r.Context())
+
+		resp := common.ReadinessResponse{
+			Status:       common.StatusOK,
+			Dependencies: checkAll(ctx, deps),
+		}
+
+		status := http.StatusOK
+		for _, dep := range resp.Dependencies {
+			if dep.Status == common.StatusUnavailable {
+				log.Warn().Str("dependency", dep.Name).Str("error", dep.Error).Msg("Dependency is not ready")
+				resp.Status = common.StatusUnavailable
+				status = http.StatusServiceUnavailable
+			}
+		}
+		common.Encode(w, r, status, resp)
+	})
+}
+
+// checkAll runs every check concurrently, without waiting for any which ignore ctx once it is done
+func checkAll(ctx context.Context, deps []Dependency) []common.DependencyStatus {
+	type result struct {
+		i      int
+		status common.DependencyStatus
+	}
+	// Buffered so that checks which finish after the deadline don't block forever
+	results := make(chan result, len(deps))
+	for i, dep := range deps {
+		go func() {
+			results <- result{i: i, status: check(ctx, dep)}
+		}()
+	}
+
+	statuses := make([]common.DependencyStatus, len(deps))
+	done := make([]bool, len(deps))
+	for range deps {
+		select {
+		case r := <-results:
+			statuses[r.i] = r.status
+			done[r.i] = true
+		case <-ctx.Done():
+			for i, dep := range deps {
+				if !done[i] {
+					statuses[i] = common.DependencyStatus{
+						Name:   dep.Name,
+						Status: common.StatusUnavailable,
+						Error:  fmt.Sprintf("check did not complete: %s", ctx.Err()),
+					}
+				}
+			}
+			return statuses
+		}
+	}
+	return statuses
+}
+
+func check(ctx context.Context, dep Dependency) common.DependencyStatus {
+	status := common.DependencyStatus{
+		Name:   dep.Name,
+		Status: common.StatusOK,
+	}
+	if dep.Enabled != nil && !dep.Enabled() {
+		status.Status = common.StatusDisabled
+		return status
+	}
+	version, err := dep.Check(ctx)
+	if err != nil {
+		status.Status = common.StatusUnavailable
+		status.Error = err.Error()
+		return status
+	}
+	status.Version = version
+	return status
+}
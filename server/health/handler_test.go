@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func TestHandleReadyz(t *testing.T) {
+	ok := Dependency{Name: "ok", Check: func(ctx context.Context) (string, error) { return "1.0", nil }}
+	down := Dependency{Name: "down", Check: func(ctx context.Context) (string, error) { return "", errors.New("not running") }}
+	disabled := down
+	disabled.Name = "disabled"
+	disabled.Enabled = func() bool { return false }
+
+	tests := []struct {
+		name   string
+		deps   []Dependency
+		status int
+		result string
+	}{
+		{name: "no dependencies", status: http.StatusOK, result: common.StatusOK},
+		{name: "all ready", deps: []Dependency{ok}, status: http.StatusOK, result: common.StatusOK},
+		{name: "one down", deps: []Dependency{ok, down}, status: http.StatusServiceUnavailable, result: common.StatusUnavailable},
+		{name: "one disabled", deps: []Dependency{ok, disabled}, status: http.StatusOK, result: common.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HandleReadyz(tt.deps...).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			var resp common.ReadinessResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.result {
+				t.Errorf("expected %s, got %s", tt.result, resp.Status)
+			}
+			if len(resp.Dependencies) != len(tt.deps) {
+				t.Fatalf("expected %d dependencies, got %d", len(tt.deps), len(resp.Dependencies))
+			}
+			for i, dep := range tt.deps {
+				got := resp.Dependencies[i]
+				if got.Name != dep.Name {
+					t.Errorf("expected dependency %s, got %s", dep.Name, got.Name)
+				}
+				if dep.Name == "ok" && got.Version != "1.0" {
+					t.Errorf("expected version 1.0, got %s", got.Version)
+				}
+				if dep.Name == "down" && got.Error != "not running" {
+					t.Errorf("expected error to be reported, got %q", got.Error)
+				}
+				if dep.Name == "disabled" && (got.Status != common.StatusDisabled || got.Error != "") {
+					t.Errorf("expected the check to be skipped, got %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleReadyzStuckDependency(t *testing.T) {
+	timeout := checkTimeout
+	checkTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { checkTimeout = timeout })
+
+	// A check which ignores its context, like a D-Bus call without a deadline
+	release := make(chan struct{})
+	defer close(release)
+	stuck := Dependency{Name: "stuck", Check: func(context.Context) (string, error) {
+		<-release
+		return "1.0", nil
+	}}
+	ok := Dependency{Name: "ok", Check: func(ctx context.Context) (string, error) { return "1.0", nil }}
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	HandleReadyz(ok, stuck).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the check to give up after %s, took %s", checkTimeout, elapsed)
+	}
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	var resp common.ReadinessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Dependencies[0]; got.Status != common.StatusOK {
+		t.Errorf("expected ok to be ready, got %+v", got)
+	}
+	if got := resp.Dependencies[1]; got.Name != "stuck" || got.Status != common.StatusUnavailable || !strings.Contains(got.Error, "deadline exceeded") {
+		t.Errorf("expected stuck to time out, got %+v", got)
+	}
+}
@@ -0,0 +1,150 @@
+// Package dbustest runs a private dbus-daemon for tests, so that D-Bus clients can be exercised
+// end to end against fake services without touching the host's system or session bus.
+package dbustest
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+const config = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// Bus is a private message bus which lives for the duration of a test
+type Bus struct {
+	daemon string
+	config string
+	socket string
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	address string
+}
+
+// NewBus starts a dbus-daemon which is stopped when the test completes
+// The test is skipped if dbus-daemon is not installed
+func NewBus(t *testing.T) *Bus {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	dir := t.TempDir()
+	b := &Bus{daemon: daemon, config: filepath.Join(dir, "bus.conf"), socket: filepath.Join(dir, "bus")}
+	err = os.WriteFile(b.config, []byte(strings.Replace(config, "%s", b.socket, 1)), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Start(t)
+	t.Cleanup(func() { b.Stop(t) })
+	return b
+}
+
+// Address returns the address of the running daemon, which changes when it is restarted
+func (b *Bus) Address() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.address
+}
+
+// Start runs the daemon on the bus's socket, if it is not already running
+func (b *Bus) Start(t *testing.T) {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cmd != nil {
+		return
+	}
+
+	// A daemon which was killed leaves its socket behind
+	if err := os.Remove(b.socket); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(b.daemon, "--config-file="+b.config, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start dbus-daemon: %s", err)
+	}
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Fatalf("failed to read dbus-daemon address: %s", err)
+	}
+	b.cmd = cmd
+	b.address = strings.TrimSpace(address)
+}
+
+// Stop kills the daemon, as if the system's dbus-daemon crashed or was restarted, dropping every connection to it
+func (b *Bus) Stop(t *testing.T) {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cmd == nil {
+		return
+	}
+	_ = b.cmd.Process.Kill()
+	_ = b.cmd.Wait()
+	b.cmd = nil
+}
+
+// Restart stops the daemon and starts a new one on the same socket
+func (b *Bus) Restart(t *testing.T) {
+	t.Helper()
+	b.Stop(t)
+	b.Start(t)
+}
+
+// Connect opens a new connection to the bus, which is closed when the test completes
+func (b *Bus) Connect(t *testing.T) *dbus.Conn {
+	t.Helper()
+	conn, err := b.connect()
+	if err != nil {
+		t.Fatalf("failed to connect to test bus: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Supervisor returns a running bus.Supervisor connected to the test bus
+func (b *Bus) Supervisor(t *testing.T) *bus.Supervisor {
+	t.Helper()
+	log := zerolog.New(zerolog.NewTestWriter(t))
+	sup, err := bus.NewSupervisor(&log, b.connect)
+	if err != nil {
+		t.Fatalf("failed to connect to test bus: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go sup.Run(ctx)
+	t.Cleanup(cancel)
+	return sup
+}
+
+func (b *Bus) connect() (*dbus.Conn, error) {
+	return dbus.Connect(b.Address())
+}
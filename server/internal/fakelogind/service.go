@@ -0,0 +1,201 @@
+// Package fakelogind exports an in-memory implementation of the org.freedesktop.login1 manager,
+// with configurable inhibitors and failure injection, for testing the logind D-Bus client.
+package fakelogind
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	Destination = "org.freedesktop.login1"
+	Path        = dbus.ObjectPath("/org/freedesktop/login1")
+	Interface   = "org.freedesktop.login1.Manager"
+
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+)
+
+// Inhibitor is a lock returned by ListInhibitors
+type Inhibitor struct {
+	What string
+	Who  string
+	Why  string
+	Mode string
+	UID  uint32
+	PID  uint32
+}
+
+// ScheduledShutdown is the value of the ScheduledShutdown property, an empty Type means nothing is scheduled
+type ScheduledShutdown struct {
+	Type string
+	// USec is the time of the shutdown in microseconds since the epoch
+	USec uint64
+}
+
+type Option func(*Service)
+
+// WithInhibitors sets the initial inhibitors
+func WithInhibitors(inhibitors ...Inhibitor) Option {
+	return func(s *Service) {
+		s.inhibitors = append(s.inhibitors, inhibitors...)
+	}
+}
+
+// Service is a fake logind manager exported on a test bus connection
+// Scheduled shutdowns never happen, they are only recorded.
+type Service struct {
+	conn *dbus.Conn
+
+	mu         sync.Mutex
+	failures   map[string]*dbus.Error
+	inhibitors []Inhibitor
+	scheduled  ScheduledShutdown
+}
+
+// Start exports the manager on conn and requests the well-known name
+// The name is released when the test completes
+func Start(t *testing.T, conn *dbus.Conn, opts ...Option) *Service {
+	t.Helper()
+	s := &Service{
+		conn:       conn,
+		failures:   make(map[string]*dbus.Error),
+		inhibitors: []Inhibitor{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.export(); err != nil {
+		t.Fatalf("failed to export fake logind manager: %s", err)
+	}
+	if err := s.Restart(); err != nil {
+		t.Fatalf("failed to acquire %s: %s", Destination, err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// SetInhibitors replaces the inhibitors
+func (s *Service) SetInhibitors(inhibitors ...Inhibitor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inhibitors = append([]Inhibitor{}, inhibitors...)
+}
+
+// Schedule sets the scheduled shutdown, as if another client had scheduled it
+func (s *Service) Schedule(scheduled ScheduledShutdown) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduled = scheduled
+}
+
+// Scheduled returns the scheduled shutdown
+func (s *Service) Scheduled() ScheduledShutdown {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scheduled
+}
+
+// Fail makes every subsequent call to the given method (e.g. ScheduleShutdown) return err
+// Passing a nil error clears the failure
+func (s *Service) Fail(method string, err *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, method)
+		return
+	}
+	s.failures[method] = err
+}
+
+// Stop releases the well-known name, as if logind had gone away
+func (s *Service) Stop() {
+	_, _ = s.conn.ReleaseName(Destination)
+}
+
+// Restart reacquires the well-known name
+func (s *Service) Restart() error {
+	reply, err := s.conn.RequestName(Destination, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner && reply != dbus.RequestNameReplyAlreadyOwner {
+		return dbus.NewError("org.freedesktop.DBus.Error.Failed", []interface{}{"name already taken"})
+	}
+	return nil
+}
+
+func (s *Service) failure(method string) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[method]
+}
+
+func (s *Service) export() error {
+	methods := map[string]interface{}{
+		"ListInhibitors": func() ([]Inhibitor, *dbus.Error) {
+			if err := s.failure("ListInhibitors"); err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return append([]Inhibitor{}, s.inhibitors...), nil
+		},
+		"ScheduleShutdown": func(kind string, usec uint64) *dbus.Error {
+			if err := s.failure("ScheduleShutdown"); err != nil {
+				return err
+			}
+			switch kind {
+			case "reboot", "poweroff", "halt", "dry-reboot", "dry-poweroff", "dry-halt":
+			default:
+				return dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{"Unsupported shutdown type: " + kind})
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.scheduled = ScheduledShutdown{Type: kind, USec: usec}
+			return nil
+		},
+		"CancelScheduledShutdown": func() (bool, *dbus.Error) {
+			if err := s.failure("CancelScheduledShutdown"); err != nil {
+				return false, err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			cancelled := s.scheduled.Type != ""
+			s.scheduled = ScheduledShutdown{}
+			return cancelled, nil
+		},
+	}
+	if err := s.conn.ExportMethodTable(methods, Path, Interface); err != nil {
+		return err
+	}
+
+	props := map[string]interface{}{
+		"Get": func(iface string, name string) (dbus.Variant, *dbus.Error) {
+			if err := s.failure(name); err != nil {
+				return dbus.Variant{}, err
+			}
+			if iface != Interface || name != "ScheduledShutdown" {
+				return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+			}
+			return dbus.MakeVariant(s.Scheduled()), nil
+		},
+	}
+	if err := s.conn.ExportMethodTable(props, Path, propertiesInterface); err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Name: string(Path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: Interface, Methods: []introspect.Method{
+				{Name: "ListInhibitors"}, {Name: "ScheduleShutdown"}, {Name: "CancelScheduledShutdown"},
+			}},
+		},
+	}
+	return s.conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable")
+}
@@ -0,0 +1,589 @@
+// Package fakesystemd exports an in-memory implementation of the org.freedesktop.systemd1 manager,
+// with configurable units and failure injection, for testing the systemd D-Bus client.
+package fakesystemd
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	Destination = "org.freedesktop.systemd1"
+	Path        = dbus.ObjectPath("/org/freedesktop/systemd1")
+	Interface   = "org.freedesktop.systemd1.Manager"
+	// UnitInterface is implemented by each unit object
+	UnitInterface = "org.freedesktop.systemd1.Unit"
+	// ServiceInterface is implemented by each service unit object
+	ServiceInterface = "org.freedesktop.systemd1.Service"
+	// TimerInterface is implemented by each timer unit object
+	TimerInterface = "org.freedesktop.systemd1.Timer"
+	// SliceInterface is implemented by each slice unit object
+	SliceInterface = "org.freedesktop.systemd1.Slice"
+
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+)
+
+// Unit is the state of a single fake unit
+type Unit struct {
+	Name          string
+	Description   string
+	FragmentPath  string
+	LoadState     string
+	ActiveState   string
+	SubState      string
+	UnitFileState string
+	// Result and MainPID are only reported for service units
+	Result  string
+	MainPID uint32
+	// NoInstall makes enabling the unit a no-op, as for a unit file without an [Install] section
+	NoInstall bool
+	// FailStart makes starting the unit fail with the given service result, e.g. exit-code
+	FailStart string
+	// DropInPaths are the drop-ins applied to the unit, in order
+	DropInPaths []string
+	// Trigger, NextElapse and LastTrigger are only reported for timer units, times are in microseconds since the epoch
+	Trigger     string
+	NextElapse  uint64
+	LastTrigger uint64
+	// Resources are only reported for services and slices
+	Resources Resources
+	// RuntimeResources is true if the last change to the resources was runtime only
+	RuntimeResources bool
+}
+
+// Resources are the cgroup properties of a unit, where math.MaxUint64 is no limit, or no usage being available
+type Resources struct {
+	ControlGroup       string
+	MemoryMax          uint64
+	CPUQuotaPerSecUSec uint64
+	IOWeight           uint64
+	TasksMax           uint64
+	MemoryCurrent      uint64
+	CPUUsageNSec       uint64
+	TasksCurrent       uint64
+}
+
+// NoResources has no limits and no usage, as for a unit which isn't running
+var NoResources = Resources{
+	MemoryMax:          math.MaxUint64,
+	CPUQuotaPerSecUSec: math.MaxUint64,
+	IOWeight:           math.MaxUint64,
+	TasksMax:           math.MaxUint64,
+	MemoryCurrent:      math.MaxUint64,
+	CPUUsageNSec:       math.MaxUint64,
+	TasksCurrent:       math.MaxUint64,
+}
+
+// NewUnit returns a loaded, inactive and disabled unit
+func NewUnit(name string) Unit {
+	return Unit{
+		Name:          name,
+		LoadState:     "loaded",
+		ActiveState:   "inactive",
+		SubState:      "dead",
+		UnitFileState: "disabled",
+		Result:        "success",
+		Resources:     NoResources,
+	}
+}
+
+func (u Unit) properties(iface string) (map[string]dbus.Variant, bool) {
+	switch {
+	case iface == UnitInterface:
+		return map[string]dbus.Variant{
+			"Id":            dbus.MakeVariant(u.Name),
+			"Description":   dbus.MakeVariant(u.Description),
+			"FragmentPath":  dbus.MakeVariant(u.FragmentPath),
+			"LoadState":     dbus.MakeVariant(u.LoadState),
+			"ActiveState":   dbus.MakeVariant(u.ActiveState),
+			"SubState":      dbus.MakeVariant(u.SubState),
+			"UnitFileState": dbus.MakeVariant(u.UnitFileState),
+			"DropInPaths":   dbus.MakeVariant(append([]string{}, u.DropInPaths...)),
+		}, true
+	case iface == ServiceInterface && strings.HasSuffix(u.Name, ".service"):
+		props := u.Resources.properties()
+		props["Result"] = dbus.MakeVariant(u.Result)
+		props["MainPID"] = dbus.MakeVariant(u.MainPID)
+		return props, true
+	case iface == SliceInterface && strings.HasSuffix(u.Name, ".slice"):
+		return u.Resources.properties(), true
+	case iface == TimerInterface && strings.HasSuffix(u.Name, ".timer"):
+		return map[string]dbus.Variant{
+			"Unit":                   dbus.MakeVariant(u.Trigger),
+			"NextElapseUSecRealtime": dbus.MakeVariant(u.NextElapse),
+			"LastTriggerUSec":        dbus.MakeVariant(u.LastTrigger),
+		}, true
+	}
+	return nil, false
+}
+
+func (r Resources) properties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"ControlGroup":       dbus.MakeVariant(r.ControlGroup),
+		"MemoryMax":          dbus.MakeVariant(r.MemoryMax),
+		"CPUQuotaPerSecUSec": dbus.MakeVariant(r.CPUQuotaPerSecUSec),
+		"IOWeight":           dbus.MakeVariant(r.IOWeight),
+		"TasksMax":           dbus.MakeVariant(r.TasksMax),
+		"MemoryCurrent":      dbus.MakeVariant(r.MemoryCurrent),
+		"CPUUsageNSec":       dbus.MakeVariant(r.CPUUsageNSec),
+		"TasksCurrent":       dbus.MakeVariant(r.TasksCurrent),
+	}
+}
+
+// UnitPath returns the object path of the named unit, escaped as systemd does
+func UnitPath(name string) dbus.ObjectPath {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return Path + "/unit/" + dbus.ObjectPath(b.String())
+}
+
+// listedUnit is an entry returned by ListUnitsByPatterns
+type listedUnit struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Following   string
+	Path        dbus.ObjectPath
+	JobID       uint32
+	JobType     string
+	JobPath     dbus.ObjectPath
+}
+
+// matches returns true if u is in any of the states and its name matches any of the patterns, empty lists match anything
+func (u Unit) matches(states []string, patterns []string) bool {
+	if len(states) > 0 && !slices.Contains(states, u.LoadState) && !slices.Contains(states, u.ActiveState) && !slices.Contains(states, u.SubState) {
+		return false
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, u.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// unitProperty is an entry of the array taken by SetUnitProperties
+type unitProperty struct {
+	Name  string
+	Value dbus.Variant
+}
+
+// unitFileChange is a symlink created or removed by the unit file methods
+type unitFileChange struct {
+	Type        string
+	Filename    string
+	Destination string
+}
+
+type Option func(*Service)
+
+// WithVersion sets the version reported by the manager
+func WithVersion(version string) Option {
+	return func(s *Service) {
+		s.version = version
+	}
+}
+
+// WithUnits sets the initial units
+func WithUnits(units ...Unit) Option {
+	return func(s *Service) {
+		for _, u := range units {
+			s.units[u.Name] = u
+		}
+	}
+}
+
+// Service is a fake systemd manager exported on a test bus connection
+// Jobs complete immediately, their JobRemoved signal is emitted after the reply to the call which queued them
+type Service struct {
+	conn    *dbus.Conn
+	version string
+
+	mu          sync.Mutex
+	reloads     int
+	failures    map[string]*dbus.Error
+	units       map[string]Unit
+	jobs        uint32
+	restarts    map[string]int
+	subscribers int
+}
+
+// Start exports the manager on conn and requests the well-known name
+// The name is released when the test completes
+func Start(t *testing.T, conn *dbus.Conn, opts ...Option) *Service {
+	t.Helper()
+	s := &Service{
+		conn:     conn,
+		version:  "255",
+		failures: make(map[string]*dbus.Error),
+		units:    make(map[string]Unit),
+		restarts: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.export(); err != nil {
+		t.Fatalf("failed to export fake systemd manager: %s", err)
+	}
+	for name := range s.units {
+		if err := s.exportUnit(name); err != nil {
+			t.Fatalf("failed to export fake unit %s: %s", name, err)
+		}
+	}
+	if err := s.Restart(); err != nil {
+		t.Fatalf("failed to acquire %s: %s", Destination, err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// Reloads returns the number of times the manager configuration has been reloaded
+func (s *Service) Reloads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloads
+}
+
+// AddUnit adds (or replaces) a unit
+func (s *Service) AddUnit(u Unit) error {
+	s.mu.Lock()
+	_, exists := s.units[u.Name]
+	s.units[u.Name] = u
+	s.mu.Unlock()
+
+	if exists {
+		return nil
+	}
+	return s.exportUnit(u.Name)
+}
+
+// Unit returns the current state of the named unit
+func (s *Service) Unit(name string) (Unit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.units[name]
+	return u, ok
+}
+
+// Restarts returns the number of times the named unit has been restarted
+func (s *Service) Restarts(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts[name]
+}
+
+// Subscribers returns the number of calls to Subscribe
+func (s *Service) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscribers
+}
+
+// Fail makes every subsequent call to the given method (e.g. Reload or Version) return err
+// Passing a nil error clears the failure
+func (s *Service) Fail(method string, err *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, method)
+		return
+	}
+	s.failures[method] = err
+}
+
+// Stop releases the well-known name, as if the manager had gone away
+func (s *Service) Stop() {
+	_, _ = s.conn.ReleaseName(Destination)
+}
+
+// Restart reacquires the well-known name
+func (s *Service) Restart() error {
+	reply, err := s.conn.RequestName(Destination, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner && reply != dbus.RequestNameReplyAlreadyOwner {
+		return dbus.NewError("org.freedesktop.DBus.Error.Failed", []interface{}{"name already taken"})
+	}
+	return nil
+}
+
+func (s *Service) failure(method string) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[method]
+}
+
+func (s *Service) export() error {
+	methods := map[string]interface{}{
+		"Reload": func() *dbus.Error {
+			if err := s.failure("Reload"); err != nil {
+				return err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.reloads++
+			return nil
+		},
+		"Subscribe": func() *dbus.Error {
+			if err := s.failure("Subscribe"); err != nil {
+				return err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.subscribers++
+			return nil
+		},
+		"LoadUnit": func(name string) (dbus.ObjectPath, *dbus.Error) {
+			if err := s.failure("LoadUnit"); err != nil {
+				return "", err
+			}
+			if _, ok := s.Unit(name); !ok {
+				return "", dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit " + name + " not found."})
+			}
+			return UnitPath(name), nil
+		},
+		"ListUnitsByPatterns": func(states []string, patterns []string) ([]listedUnit, *dbus.Error) {
+			if err := s.failure("ListUnitsByPatterns"); err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			listed := []listedUnit{}
+			for _, u := range s.units {
+				if u.matches(states, patterns) {
+					listed = append(listed, listedUnit{
+						Name: u.Name, Description: u.Description, LoadState: u.LoadState, ActiveState: u.ActiveState, SubState: u.SubState,
+						Path: UnitPath(u.Name), JobPath: "/",
+					})
+				}
+			}
+			sort.Slice(listed, func(i, j int) bool { return listed[i].Name < listed[j].Name })
+			return listed, nil
+		},
+		"StartUnit": func(name string, mode string) (dbus.ObjectPath, *dbus.Error) {
+			return s.job("StartUnit", name, start)
+		},
+		"StopUnit": func(name string, mode string) (dbus.ObjectPath, *dbus.Error) {
+			return s.job("StopUnit", name, func(u *Unit) string {
+				u.ActiveState, u.SubState = "inactive", "dead"
+				return "done"
+			})
+		},
+		"RestartUnit": func(name string, mode string) (dbus.ObjectPath, *dbus.Error) {
+			return s.job("RestartUnit", name, func(u *Unit) string {
+				s.restarts[name]++
+				return start(u)
+			})
+		},
+		"SetUnitProperties": func(name string, runtime bool, props []unitProperty) *dbus.Error {
+			if err := s.failure("SetUnitProperties"); err != nil {
+				return err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			u, ok := s.units[name]
+			if !ok {
+				return dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit " + name + " not found."})
+			}
+			for _, p := range props {
+				v, ok := p.Value.Value().(uint64)
+				if !ok {
+					return dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{"Invalid value for " + p.Name})
+				}
+				switch p.Name {
+				case "MemoryMax":
+					u.Resources.MemoryMax = v
+				case "CPUQuotaPerSecUSec":
+					u.Resources.CPUQuotaPerSecUSec = v
+				case "IOWeight":
+					if v != math.MaxUint64 && (v < 1 || v > 10000) {
+						return dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{"Value specified in IOWeight is out of range"})
+					}
+					u.Resources.IOWeight = v
+				case "TasksMax":
+					u.Resources.TasksMax = v
+				default:
+					return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{"Cannot set property " + p.Name + ", or unknown property."})
+				}
+			}
+			u.RuntimeResources = runtime
+			s.units[name] = u
+			return nil
+		},
+		"EnableUnitFiles": func(names []string, runtime bool, force bool) (bool, []unitFileChange, *dbus.Error) {
+			installInfo := true
+			changes, err := s.unitFiles("EnableUnitFiles", names, func(u *Unit) {
+				if u.NoInstall {
+					installInfo = false
+					return
+				}
+				u.UnitFileState = "enabled"
+			})
+			return installInfo, changes, err
+		},
+		"DisableUnitFiles": func(names []string, runtime bool) ([]unitFileChange, *dbus.Error) {
+			return s.unitFiles("DisableUnitFiles", names, func(u *Unit) {
+				u.UnitFileState = "disabled"
+			})
+		},
+		"MaskUnitFiles": func(names []string, runtime bool, force bool) ([]unitFileChange, *dbus.Error) {
+			return s.unitFiles("MaskUnitFiles", names, func(u *Unit) {
+				u.LoadState, u.UnitFileState = "masked", "masked"
+			})
+		},
+		"UnmaskUnitFiles": func(names []string, runtime bool) ([]unitFileChange, *dbus.Error) {
+			return s.unitFiles("UnmaskUnitFiles", names, func(u *Unit) {
+				u.LoadState, u.UnitFileState = "loaded", "disabled"
+			})
+		},
+	}
+	if err := s.conn.ExportMethodTable(methods, Path, Interface); err != nil {
+		return err
+	}
+
+	props := map[string]interface{}{
+		"Get": func(iface string, name string) (dbus.Variant, *dbus.Error) {
+			if err := s.failure(name); err != nil {
+				return dbus.Variant{}, err
+			}
+			if iface != Interface || name != "Version" {
+				return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+			}
+			return dbus.MakeVariant(s.version), nil
+		},
+	}
+	if err := s.conn.ExportMethodTable(props, Path, propertiesInterface); err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Name: string(Path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: Interface, Methods: []introspect.Method{
+				{Name: "Reload"}, {Name: "Subscribe"}, {Name: "LoadUnit"}, {Name: "ListUnitsByPatterns"},
+				{Name: "StartUnit"}, {Name: "StopUnit"}, {Name: "RestartUnit"},
+				{Name: "SetUnitProperties"}, {Name: "EnableUnitFiles"}, {Name: "DisableUnitFiles"}, {Name: "MaskUnitFiles"}, {Name: "UnmaskUnitFiles"},
+			}},
+		},
+	}
+	return s.conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable")
+}
+
+func start(u *Unit) string {
+	if u.FailStart != "" {
+		u.ActiveState, u.SubState, u.Result = "failed", "failed", u.FailStart
+		return "failed"
+	}
+	u.ActiveState, u.SubState, u.Result = "active", "running", "success"
+	return "done"
+}
+
+// job applies run to the named unit, and emits the JobRemoved signal with the result it returns
+func (s *Service) job(method string, name string, run func(u *Unit) string) (dbus.ObjectPath, *dbus.Error) {
+	if err := s.failure(method); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.units[name]
+	if !ok {
+		return "", dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit " + name + " not found."})
+	}
+	if u.LoadState == "masked" && method != "StopUnit" {
+		return "", dbus.NewError("org.freedesktop.systemd1.UnitMasked", []interface{}{"Unit " + name + " is masked."})
+	}
+	result := run(&u)
+	s.units[name] = u
+
+	s.jobs++
+	id := s.jobs
+	job := Path + dbus.ObjectPath(fmt.Sprintf("/job/%d", id))
+	go func() {
+		_ = s.conn.Emit(Path, Interface+".JobRemoved", id, job, name, result)
+	}()
+	return job, nil
+}
+
+// unitFiles applies change to each of the named units
+func (s *Service) unitFiles(method string, names []string, change func(u *Unit)) ([]unitFileChange, *dbus.Error) {
+	if err := s.failure(method); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []unitFileChange{}
+	for _, name := range names {
+		u, ok := s.units[name]
+		if !ok {
+			return nil, dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit file " + name + " does not exist."})
+		}
+		before := u.UnitFileState
+		change(&u)
+		s.units[name] = u
+		if u.UnitFileState != before {
+			changes = append(changes, unitFileChange{Type: "symlink", Filename: name})
+		}
+	}
+	return changes, nil
+}
+
+func (s *Service) exportUnit(name string) error {
+	props := map[string]interface{}{
+		"Get": func(iface string, prop string) (dbus.Variant, *dbus.Error) {
+			all, err := s.unitProperties(name, iface, "Get")
+			if err != nil {
+				return dbus.Variant{}, err
+			}
+			v, ok := all[prop]
+			if !ok {
+				return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{prop})
+			}
+			return v, nil
+		},
+		"GetAll": func(iface string) (map[string]dbus.Variant, *dbus.Error) {
+			return s.unitProperties(name, iface, "GetAll")
+		},
+	}
+	return s.conn.ExportMethodTable(props, UnitPath(name), propertiesInterface)
+}
+
+func (s *Service) unitProperties(name string, iface string, method string) (map[string]dbus.Variant, *dbus.Error) {
+	if err := s.failure(method); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.units[name]
+	if !ok {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownObject", []interface{}{name})
+	}
+	props, ok := u.properties(iface)
+	if !ok {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{iface})
+	}
+	return props, nil
+}
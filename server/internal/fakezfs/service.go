@@ -0,0 +1,337 @@
+// Package fakezfs exports an in-memory implementation of the com.nickrobison.dbus.zfs1 service,
+// with configurable pools and failure injection, for testing the ZFS D-Bus client.
+package fakezfs
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	Destination = "com.nickrobison.dbus.zfs1"
+	Path        = dbus.ObjectPath("/com/nickrobison/dbus/zfs1")
+	Interface   = "com.nickrobison.dbus.ZFS1"
+	// PoolInterface is implemented by each pool object
+	PoolInterface = Interface + ".Pool"
+
+	propertiesInterface    = "org.freedesktop.DBus.Properties"
+	objectManagerInterface = "org.freedesktop.DBus.ObjectManager"
+)
+
+// Pool is the state of a single fake zpool
+type Pool struct {
+	Name           string
+	Size           uint64
+	Allocated      uint64
+	Free           uint64
+	Health         string
+	ReadErrors     uint64
+	WriteErrors    uint64
+	ChecksumErrors uint64
+}
+
+func (p Pool) properties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Name":           dbus.MakeVariant(p.Name),
+		"Size":           dbus.MakeVariant(p.Size),
+		"Allocated":      dbus.MakeVariant(p.Allocated),
+		"Free":           dbus.MakeVariant(p.Free),
+		"Health":         dbus.MakeVariant(p.Health),
+		"ReadErrors":     dbus.MakeVariant(p.ReadErrors),
+		"WriteErrors":    dbus.MakeVariant(p.WriteErrors),
+		"ChecksumErrors": dbus.MakeVariant(p.ChecksumErrors),
+	}
+}
+
+type Option func(*Service)
+
+// WithObjectManager exports org.freedesktop.DBus.ObjectManager on the root object,
+// and emits the matching signals as pools are added, removed and updated
+func WithObjectManager() Option {
+	return func(s *Service) {
+		s.objectManager = true
+	}
+}
+
+// WithVersion sets the version reported by the service
+func WithVersion(version string) Option {
+	return func(s *Service) {
+		s.version = version
+	}
+}
+
+// WithPools adds the given pools before the service is exported
+func WithPools(pools ...Pool) Option {
+	return func(s *Service) {
+		for _, p := range pools {
+			s.pools[p.Name] = p
+		}
+	}
+}
+
+// Service is a fake ZFS service exported on a test bus connection
+type Service struct {
+	conn          *dbus.Conn
+	objectManager bool
+	version       string
+
+	mu       sync.Mutex
+	pools    map[string]Pool
+	failures map[string]*dbus.Error
+}
+
+// Start exports the service on conn and requests the well-known name
+// The name is released when the test completes
+func Start(t *testing.T, conn *dbus.Conn, opts ...Option) *Service {
+	t.Helper()
+	s := &Service{
+		conn:     conn,
+		version:  "0.1.0",
+		pools:    make(map[string]Pool),
+		failures: make(map[string]*dbus.Error),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.exportRoot(); err != nil {
+		t.Fatalf("failed to export fake zfs service: %s", err)
+	}
+	s.mu.Lock()
+	for _, p := range s.pools {
+		if err := s.exportPool(p.Name); err != nil {
+			s.mu.Unlock()
+			t.Fatalf("failed to export pool %s: %s", p.Name, err)
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.Restart(); err != nil {
+		t.Fatalf("failed to acquire %s: %s", Destination, err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// PoolPath returns the object path of the named pool
+func PoolPath(name string) dbus.ObjectPath {
+	return Path + "/pool/" + dbus.ObjectPath(name)
+}
+
+// AddPool adds (or replaces) a pool
+func (s *Service) AddPool(p Pool) error {
+	s.mu.Lock()
+	_, exists := s.pools[p.Name]
+	s.pools[p.Name] = p
+	s.mu.Unlock()
+
+	if exists {
+		return s.emitChanged(p)
+	}
+	if err := s.exportPool(p.Name); err != nil {
+		return err
+	}
+	if s.objectManager {
+		return s.conn.Emit(Path, objectManagerInterface+".InterfacesAdded", PoolPath(p.Name),
+			map[string]map[string]dbus.Variant{PoolInterface: p.properties()})
+	}
+	return nil
+}
+
+// RemovePool removes the named pool
+func (s *Service) RemovePool(name string) error {
+	s.mu.Lock()
+	delete(s.pools, name)
+	s.mu.Unlock()
+
+	path := PoolPath(name)
+	_ = s.conn.Export(nil, path, propertiesInterface)
+	if s.objectManager {
+		return s.conn.Emit(Path, objectManagerInterface+".InterfacesRemoved", path, []string{PoolInterface})
+	}
+	return nil
+}
+
+// Fail makes every subsequent call to the given method (e.g. Pools, GetAll or Version) return err
+// Passing a nil error clears the failure
+func (s *Service) Fail(method string, err *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, method)
+		return
+	}
+	s.failures[method] = err
+}
+
+// Stop releases the well-known name, as if the service had exited
+func (s *Service) Stop() {
+	_, _ = s.conn.ReleaseName(Destination)
+}
+
+// Restart reacquires the well-known name, as if the service had been restarted
+func (s *Service) Restart() error {
+	reply, err := s.conn.RequestName(Destination, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner && reply != dbus.RequestNameReplyAlreadyOwner {
+		return dbus.NewError("org.freedesktop.DBus.Error.Failed", []interface{}{"name already taken"})
+	}
+	return nil
+}
+
+func (s *Service) failure(method string) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[method]
+}
+
+func (s *Service) exportRoot() error {
+	methods := map[string]interface{}{
+		"Pools": func() ([]dbus.ObjectPath, *dbus.Error) {
+			if err := s.failure("Pools"); err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			names := make([]string, 0, len(s.pools))
+			for name := range s.pools {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			paths := make([]dbus.ObjectPath, len(names))
+			for i, name := range names {
+				paths[i] = PoolPath(name)
+			}
+			return paths, nil
+		},
+	}
+	methods["CreatePool"] = func(name string) (dbus.ObjectPath, *dbus.Error) {
+		if err := s.failure("CreatePool"); err != nil {
+			return "", err
+		}
+		s.mu.Lock()
+		_, exists := s.pools[name]
+		s.mu.Unlock()
+		if exists {
+			return "", dbus.NewError("com.nickrobison.dbus.ZFS1.Error.PoolExists", []interface{}{name})
+		}
+		if err := s.AddPool(Pool{Name: name, Health: "ONLINE"}); err != nil {
+			return "", dbus.MakeFailedError(err)
+		}
+		return PoolPath(name), nil
+	}
+	methods["DestroyPool"] = func(name string) *dbus.Error {
+		if err := s.failure("DestroyPool"); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		_, exists := s.pools[name]
+		s.mu.Unlock()
+		if !exists {
+			return dbus.NewError("com.nickrobison.dbus.ZFS1.Error.NoSuchPool", []interface{}{name})
+		}
+		if err := s.RemovePool(name); err != nil {
+			return dbus.MakeFailedError(err)
+		}
+		return nil
+	}
+	if err := s.conn.ExportMethodTable(methods, Path, Interface); err != nil {
+		return err
+	}
+
+	props := map[string]interface{}{
+		"Get": func(iface string, name string) (dbus.Variant, *dbus.Error) {
+			if err := s.failure(name); err != nil {
+				return dbus.Variant{}, err
+			}
+			if iface != Interface || name != "Version" {
+				return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+			}
+			return dbus.MakeVariant(s.version), nil
+		},
+		"GetAll": func(iface string) (map[string]dbus.Variant, *dbus.Error) {
+			if err := s.failure("GetAll"); err != nil {
+				return nil, err
+			}
+			return map[string]dbus.Variant{"Version": dbus.MakeVariant(s.version)}, nil
+		},
+	}
+	if err := s.conn.ExportMethodTable(props, Path, propertiesInterface); err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Name: string(Path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: Interface, Methods: []introspect.Method{{Name: "Pools"}, {Name: "CreatePool"}, {Name: "DestroyPool"}}},
+		},
+	}
+	if s.objectManager {
+		managed := map[string]interface{}{
+			"GetManagedObjects": func() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+				if err := s.failure("GetManagedObjects"); err != nil {
+					return nil, err
+				}
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant, len(s.pools))
+				for name, p := range s.pools {
+					objects[PoolPath(name)] = map[string]map[string]dbus.Variant{PoolInterface: p.properties()}
+				}
+				return objects, nil
+			},
+		}
+		if err := s.conn.ExportMethodTable(managed, Path, objectManagerInterface); err != nil {
+			return err
+		}
+		node.Interfaces = append(node.Interfaces, introspect.Interface{Name: objectManagerInterface})
+	}
+	return s.conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable")
+}
+
+func (s *Service) exportPool(name string) error {
+	props := map[string]interface{}{
+		"Get": func(iface string, prop string) (dbus.Variant, *dbus.Error) {
+			all, err := s.poolProperties(name, "Get")
+			if err != nil {
+				return dbus.Variant{}, err
+			}
+			v, ok := all[prop]
+			if !ok || iface != PoolInterface {
+				return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{prop})
+			}
+			return v, nil
+		},
+		"GetAll": func(iface string) (map[string]dbus.Variant, *dbus.Error) {
+			if iface != PoolInterface {
+				return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{iface})
+			}
+			return s.poolProperties(name, "GetAll")
+		},
+	}
+	return s.conn.ExportMethodTable(props, PoolPath(name), propertiesInterface)
+}
+
+func (s *Service) poolProperties(name string, method string) (map[string]dbus.Variant, *dbus.Error) {
+	if err := s.failure(method); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pools[name]
+	if !ok {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownObject", []interface{}{name})
+	}
+	return p.properties(), nil
+}
+
+func (s *Service) emitChanged(p Pool) error {
+	return s.conn.Emit(PoolPath(p.Name), propertiesInterface+".PropertiesChanged", PoolInterface, p.properties(), []string{})
+}
@@ -0,0 +1,53 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Query selects journal entries, zero fields match every entry
+type Query struct {
+	// Units matches entries logged by, or about, any of the units
+	Units []string
+	// Priority matches entries of this priority or more severe, from 0 (emerg) to 7 (debug), nil matches every priority
+	Priority *int
+	Since    time.Time
+	Until    time.Time
+	// Boot matches entries of a single boot, by ID or offset, e.g. -1 for the previous boot
+	Boot string
+	// Cursor matches entries after the one with this cursor
+	Cursor string
+	// Lines is the most entries returned, the most recent ones unless Since or Cursor is set, in which case the first
+	// ones after it. Zero returns every matching entry.
+	Lines int
+}
+
+// Entry is a single journal entry
+type Entry struct {
+	Cursor string
+	Time   time.Time
+	BootID string
+	// Unit is the systemd unit which logged the entry, empty for the kernel and processes outside of a unit
+	Unit       string
+	Priority   int
+	Identifier string
+	PID        int
+	Hostname   string
+	Message    string
+}
+
+// String formats the entry as journalctl's short-iso output does
+func (e Entry) String() string {
+	line := e.Time.Format("2006-01-02T15:04:05-0700") + " " + e.Hostname + " " + e.Identifier
+	if e.PID != 0 {
+		line += fmt.Sprintf("[%d]", e.PID)
+	}
+	return line + ": " + e.Message
+}
+
+type JournalClient interface {
+	// Entries returns the entries matching the query, oldest first
+	Entries(ctx context.Context, query Query) ([]Entry, error)
+	Version(ctx context.Context) (string, error)
+}
@@ -0,0 +1,93 @@
+package journal
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleJournalEntries(client JournalClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		query, err := parseQuery(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+
+		entries, err := client.Entries(ctx, query)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot read the journal")
+			bus.HTTPError(w, r, err)
+			return
+		}
+		resp := common.JournalResponse{Entries: make([]common.JournalEntry, 0, len(entries)), Cursor: query.Cursor}
+		for _, entry := range entries {
+			resp.Entries = append(resp.Entries, common.JournalEntry{
+				Cursor:     entry.Cursor,
+				Time:       entry.Time,
+				BootID:     entry.BootID,
+				Unit:       entry.Unit,
+				Priority:   entry.Priority,
+				Identifier: entry.Identifier,
+				PID:        entry.PID,
+				Hostname:   entry.Hostname,
+				Message:    entry.Message,
+			})
+			resp.Cursor = entry.Cursor
+		}
+		common.Encode(w, r, http.StatusOK, resp)
+	})
+}
+
+// parseQuery validates the request's query parameters, which are those of common.JournalQuery
+func parseQuery(r *http.Request) (Query, error) {
+	params := r.URL.Query()
+	query := Query{
+		Units:  params["unit"],
+		Boot:   params.Get("boot"),
+		Cursor: params.Get("cursor"),
+		Lines:  common.DefaultJournalLines,
+	}
+	for _, unit := range query.Units {
+		if len(unit) > common.MaxUnitNameLength || !common.UnitNamePattern.MatchString(unit) {
+			return query, fmt.Errorf("invalid unit name %q: %w", unit, bus.ErrInvalid)
+		}
+	}
+	if p := params.Get("priority"); p != "" {
+		priority, err := common.ParsePriority(p)
+		if err != nil {
+			return query, fmt.Errorf("%w: %w", err, bus.ErrInvalid)
+		}
+		query.Priority = &priority
+	}
+	for name, t := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if v := params.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return query, fmt.Errorf("%s must be an RFC 3339 timestamp: %w", name, bus.ErrInvalid)
+			}
+			*t = parsed
+		}
+	}
+	if query.Boot != "" && !common.BootIDPattern.MatchString(query.Boot) {
+		return query, fmt.Errorf("invalid boot %q: %w", query.Boot, bus.ErrInvalid)
+	}
+	if !query.Since.IsZero() && query.Cursor != "" {
+		return query, fmt.Errorf("since and cursor cannot both be set: %w", bus.ErrInvalid)
+	}
+	if v := params.Get("lines"); v != "" {
+		lines, err := strconv.Atoi(v)
+		if err != nil || lines < 1 || lines > common.MaxJournalLines {
+			return query, fmt.Errorf("lines must be from 1 to %d: %w", common.MaxJournalLines, bus.ErrInvalid)
+		}
+		query.Lines = lines
+	}
+	return query, nil
+}
@@ -0,0 +1,67 @@
+package journal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func TestHandleJournalEntries(t *testing.T) {
+	var args []string
+	mux := http.NewServeMux()
+	mux.Handle("GET /journal", HandleJournalEntries(newFixtureClient(t, "entries.json", &args)))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/journal?unit=nginx.service&priority=err&until=2024-06-10T07:00:00Z&boot=-1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.JournalResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 4 || resp.Entries[1].Message != "nginx: [emerg] bind() to 0.0.0.0:80 failed (98: Address already in use)" {
+		t.Errorf("unexpected entries: %+v", resp.Entries)
+	}
+	if resp.Cursor != resp.Entries[3].Cursor {
+		t.Errorf("expected the cursor of the last entry, got %q", resp.Cursor)
+	}
+	expected := []string{"--output=json", "--no-pager", "--quiet", "--unit=nginx.service", "--priority=3",
+		"--until=@1718002800.000000", "--boot=-1", "--lines=100"}
+	if !slices.Equal(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestHandleJournalEntriesValidation(t *testing.T) {
+	var args []string
+	mux := http.NewServeMux()
+	mux.Handle("GET /journal", HandleJournalEntries(newFixtureClient(t, "entries.json", &args)))
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unit", "unit=nginx"},
+		{"priority", "priority=loud"},
+		{"priority number", "priority=8"},
+		{"since", "since=yesterday"},
+		{"boot", "boot=last"},
+		{"since and cursor", "since=2024-06-10T07:00:00Z&cursor=s%3D1a"},
+		{"no lines", "lines=0"},
+		{"too many lines", "lines=10001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/journal?"+tt.query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
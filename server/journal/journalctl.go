@@ -0,0 +1,194 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+// defaultPriority is the priority journald records for entries which don't give one, info
+const defaultPriority = 6
+
+// Journalctl reads the journal with journalctl, in its JSON output format
+// The agent's user must be in the systemd-journal group to read the logs of system units.
+type Journalctl struct {
+	log  *zerolog.Logger
+	path string
+	// dir is a journal directory to read instead of the system journal, e.g. one copied from another host
+	dir string
+	// run executes journalctl with the given arguments, returning its standard output
+	run func(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// NewJournalctl returns a client which reads the journal in dir, or the system journal if dir is empty
+func NewJournalctl(dir string) (JournalClient, error) {
+	path, err := exec.LookPath("journalctl")
+	if err != nil {
+		return nil, err
+	}
+	log := middleware.Logger()
+	log = log.With().Str("journal_dir", dir).Logger()
+	j := &Journalctl{log: &log, path: path, dir: dir}
+	j.run = j.exec
+	return j, nil
+}
+
+func (j *Journalctl) Entries(ctx context.Context, query Query) ([]Entry, error) {
+	if !query.Since.IsZero() && query.Cursor != "" {
+		return nil, fmt.Errorf("since and cursor cannot both be set: %w", bus.ErrInvalid)
+	}
+	out, err := j.run(ctx, j.args(query)...)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := decodeEntries(out)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode journalctl output: %w", err)
+	}
+	j.log.Debug().Int("entries", len(entries)).Msg("Read journal")
+	return entries, nil
+}
+
+// Version returns the version of systemd which journalctl belongs to
+func (j *Journalctl) Version(ctx context.Context) (string, error) {
+	out, err := j.run(ctx, "--version")
+	if err != nil {
+		return "", err
+	}
+	// The first line is e.g. systemd 252 (252.39-1~deb12u1)
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return "", fmt.Errorf("unexpected journalctl version %q", out)
+	}
+	return fields[1], nil
+}
+
+// args returns the journalctl arguments which select the entries matching query
+func (j *Journalctl) args(query Query) []string {
+	args := []string{"--output=json", "--no-pager", "--quiet"}
+	if j.dir != "" {
+		args = append(args, "--directory="+j.dir)
+	}
+	for _, unit := range query.Units {
+		args = append(args, "--unit="+unit)
+	}
+	if query.Priority != nil {
+		args = append(args, "--priority="+strconv.Itoa(*query.Priority))
+	}
+	// journalctl parses @ followed by fractional seconds since the epoch, without any time zone ambiguity
+	timestamp := func(t time.Time) string {
+		return fmt.Sprintf("@%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+	}
+	if !query.Since.IsZero() {
+		args = append(args, "--since="+timestamp(query.Since))
+	}
+	if !query.Until.IsZero() {
+		args = append(args, "--until="+timestamp(query.Until))
+	}
+	if query.Boot != "" {
+		args = append(args, "--boot="+query.Boot)
+	}
+	if query.Cursor != "" {
+		args = append(args, "--after-cursor="+query.Cursor)
+	}
+	if query.Lines > 0 {
+		args = append(args, "--lines="+strconv.Itoa(query.Lines))
+	}
+	return args
+}
+
+func (j *Journalctl) exec(ctx context.Context, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, j.path, args...).Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return out, err
+	}
+	// journalctl only reports what went wrong on stderr
+	msg := strings.TrimSpace(string(exitErr.Stderr))
+	switch {
+	case strings.Contains(msg, "No such boot ID"):
+		return nil, fmt.Errorf("%s: %w", msg, bus.ErrNotFound)
+	case strings.Contains(msg, "Failed to seek to cursor"), strings.Contains(msg, "Failed to parse"):
+		return nil, fmt.Errorf("%s: %w", msg, bus.ErrInvalid)
+	}
+	return nil, fmt.Errorf("journalctl failed: %s", msg)
+}
+
+// decodeEntries parses the output of journalctl --output=json, a JSON object per entry
+func decodeEntries(out []byte) ([]Entry, error) {
+	entries := []Entry{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var fields map[string]json.RawMessage
+		err := dec.Decode(&fields)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, toEntry(fields))
+	}
+}
+
+func toEntry(fields map[string]json.RawMessage) Entry {
+	entry := Entry{
+		Cursor:     field(fields, "__CURSOR"),
+		BootID:     field(fields, "_BOOT_ID"),
+		Unit:       field(fields, "_SYSTEMD_UNIT"),
+		Priority:   defaultPriority,
+		Identifier: field(fields, "SYSLOG_IDENTIFIER"),
+		Hostname:   field(fields, "_HOSTNAME"),
+		Message:    field(fields, "MESSAGE"),
+	}
+	if usec, err := strconv.ParseInt(field(fields, "__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		entry.Time = time.UnixMicro(usec).UTC()
+	}
+	if p, err := strconv.Atoi(field(fields, "PRIORITY")); err == nil {
+		entry.Priority = p
+	}
+	if entry.Identifier == "" {
+		entry.Identifier = field(fields, "_COMM")
+	}
+	if pid, err := strconv.Atoi(field(fields, "_PID")); err == nil {
+		entry.PID = pid
+	}
+	return entry
+}
+
+// field returns the value of a field as a string
+// journalctl writes a field which isn't valid UTF-8 as an array of bytes, and a field with several values as an array
+// of them, in which case the first is returned.
+func field(fields map[string]json.RawMessage, name string) string {
+	raw, ok := fields[name]
+	if !ok {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	if json.Unmarshal(raw, &b) == nil {
+		return string(b)
+	}
+	var values []json.RawMessage
+	if json.Unmarshal(raw, &values) == nil && len(values) > 0 {
+		return field(map[string]json.RawMessage{name: values[0]}, name)
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+// newFixtureClient returns a client whose journalctl prints the named fixture, recording the arguments it was run with
+func newFixtureClient(t *testing.T, fixture string, args *[]string) *Journalctl {
+	t.Helper()
+	out, err := os.ReadFile("testdata/" + fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := zerolog.Nop()
+	return &Journalctl{log: &log, run: func(ctx context.Context, a ...string) ([]byte, error) {
+		*args = a
+		return out, nil
+	}}
+}
+
+func TestEntries(t *testing.T) {
+	var args []string
+	client := newFixtureClient(t, "entries.json", &args)
+	entries, err := client.Entries(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+
+	failed := entries[1]
+	if failed.Unit != "nginx.service" || failed.Priority != 3 || failed.Identifier != "nginx" || failed.PID != 4242 ||
+		failed.Hostname != "web1" || failed.BootID != "6b1e0b5c3e2a4f7d9c8b1a2e3f4d5c6b" {
+		t.Errorf("unexpected entry: %+v", failed)
+	}
+	if !failed.Time.Equal(time.UnixMicro(1718000000001500)) {
+		t.Errorf("unexpected time %s", failed.Time)
+	}
+	if failed.Cursor != "s=1a;i=2;b=6b1e0b5c3e2a4f7d9c8b1a2e3f4d5c6b;m=2;t=2;x=2" {
+		t.Errorf("unexpected cursor %q", failed.Cursor)
+	}
+
+	binary := entries[2]
+	if binary.Message != "copied \xff\xfe bytes" {
+		t.Errorf("expected the binary message to be decoded, got %q", binary.Message)
+	}
+	if binary.Identifier != "backup" || binary.Priority != defaultPriority {
+		t.Errorf("expected the command and default priority, got %+v", binary)
+	}
+
+	kernel := entries[3]
+	if kernel.Identifier != "kernel" || kernel.Unit != "" || kernel.PID != 0 {
+		t.Errorf("expected the first of several values, got %+v", kernel)
+	}
+	if s := kernel.String(); s != "2024-06-10T06:13:20+0000 web1 kernel: EXT4-fs (sda1): mounted filesystem with ordered data mode" {
+		t.Errorf("unexpected line %q", s)
+	}
+}
+
+func TestEntriesArgs(t *testing.T) {
+	var args []string
+	client := newFixtureClient(t, "entries.json", &args)
+	client.dir = "/var/log/journal/remote"
+	priority := 3
+	_, err := client.Entries(context.Background(), Query{
+		Units:    []string{"nginx.service", "backup.service"},
+		Priority: &priority,
+		Since:    time.Unix(1718000000, 1500000),
+		Until:    time.Unix(1718003600, 0),
+		Boot:     "-1",
+		Lines:    50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--output=json", "--no-pager", "--quiet", "--directory=/var/log/journal/remote",
+		"--unit=nginx.service", "--unit=backup.service", "--priority=3", "--since=@1718000000.001500",
+		"--until=@1718003600.000000", "--boot=-1", "--lines=50"}
+	if !slices.Equal(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	_, err = client.Entries(context.Background(), Query{Cursor: "s=1a;i=2", Lines: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(args, "--after-cursor=s=1a;i=2") {
+		t.Errorf("expected the cursor, got %v", args)
+	}
+
+	_, err = client.Entries(context.Background(), Query{Cursor: "s=1a;i=2", Since: time.Now()})
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected since and cursor to be rejected, got %v", err)
+	}
+}
+
+func TestJournalctl(t *testing.T) {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		t.Skip("journalctl is not installed")
+	}
+	client, err := NewJournalctl(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := client.Version(context.Background())
+	if err != nil || version == "" {
+		t.Errorf("expected a version, got %q: %v", version, err)
+	}
+
+	priority := 3
+	entries, err := client.Entries(context.Background(), Query{
+		Units:    []string{"nginx.service"},
+		Priority: &priority,
+		Since:    time.Now().Add(-time.Hour),
+		Lines:    10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected an empty journal, got %v", entries)
+	}
+
+	_, err = client.Entries(context.Background(), Query{Cursor: "not-a-cursor"})
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid cursor, got %v", err)
+	}
+}
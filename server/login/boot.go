@@ -0,0 +1,164 @@
+package login
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The files read to describe the current boot, relative to the root
+const (
+	bootIDFile         = "proc/sys/kernel/random/boot_id"
+	osReleaseFile      = "proc/sys/kernel/osrelease"
+	statFile           = "proc/stat"
+	rebootRequiredFile = "run/reboot-required"
+	rebootPackagesFile = "run/reboot-required.pkgs"
+	modulesDir         = "lib/modules"
+)
+
+// readBoot reads the current boot from procfs, and looks for the signs that the host needs rebooting:
+// /run/reboot-required, written by Debian and Ubuntu packages, and a kernel being installed other than the running one
+func readBoot(root string) (Boot, error) {
+	var boot Boot
+	id, err := os.ReadFile(filepath.Join(root, bootIDFile))
+	if err != nil {
+		return boot, err
+	}
+	// The journal writes boot IDs without dashes, as do we, so that they can be used to query it
+	boot.ID = strings.ReplaceAll(strings.TrimSpace(string(id)), "-", "")
+
+	kernel, err := os.ReadFile(filepath.Join(root, osReleaseFile))
+	if err != nil {
+		return boot, err
+	}
+	boot.Kernel = strings.TrimSpace(string(kernel))
+
+	boot.BootedAt, err = bootTime(filepath.Join(root, statFile))
+	if err != nil {
+		return boot, err
+	}
+
+	boot.Reasons = []string{}
+	boot.Packages = []string{}
+	if _, err := os.Stat(filepath.Join(root, rebootRequiredFile)); err == nil {
+		boot.Reasons = append(boot.Reasons, "/"+rebootRequiredFile+" exists")
+		packages, err := os.ReadFile(filepath.Join(root, rebootPackagesFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return boot, err
+		}
+		for _, p := range strings.Fields(string(packages)) {
+			if !slices.Contains(boot.Packages, p) {
+				boot.Packages = append(boot.Packages, p)
+			}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return boot, err
+	}
+
+	reason, err := kernelReason(filepath.Join(root, modulesDir), boot.Kernel)
+	if err != nil {
+		return boot, err
+	}
+	if reason != "" {
+		boot.Reasons = append(boot.Reasons, reason)
+	}
+	return boot, nil
+}
+
+// bootTime returns the time the host booted, from the btime line of /proc/stat
+func bootTime(path string) (time.Time, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid btime in %s: %w", path, err)
+			}
+			return time.Unix(sec, 0).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in %s", path)
+}
+
+// kernelReason returns why the host needs rebooting into another kernel, if it does
+// Package managers which upgrade the kernel in place remove the running kernel's modules, others install the new kernel
+// alongside it. Hosts without a modules directory, such as containers, never need rebooting for a kernel.
+func kernelReason(dir string, running string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var installed []string
+	for _, entry := range entries {
+		// Only directories holding a kernel image or module list are installed kernels, rather than leftovers
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "modules.dep")); entry.IsDir() && err == nil {
+			installed = append(installed, entry.Name())
+		}
+	}
+	if len(installed) == 0 {
+		return "", nil
+	}
+	if !slices.Contains(installed, running) {
+		return fmt.Sprintf("the modules of running kernel %s are no longer installed", running), nil
+	}
+	newest := slices.MaxFunc(installed, compareKernels)
+	if compareKernels(newest, running) > 0 {
+		return fmt.Sprintf("kernel %s is installed, but %s is running", newest, running), nil
+	}
+	return "", nil
+}
+
+// compareKernels orders kernel releases, comparing runs of digits by their value, e.g. 6.1.0-9 before 6.1.0-21
+func compareKernels(a string, b string) int {
+	x, y := splitDigits(a), splitDigits(b)
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] == y[i] {
+			continue
+		}
+		m, errM := strconv.ParseUint(x[i], 10, 64)
+		n, errN := strconv.ParseUint(y[i], 10, 64)
+		if errM == nil && errN == nil {
+			if m < n {
+				return -1
+			}
+			if m > n {
+				return 1
+			}
+			continue
+		}
+		return strings.Compare(x[i], y[i])
+	}
+	return len(x) - len(y)
+}
+
+// splitDigits splits s into alternating runs of digits and other characters
+func splitDigits(s string) []string {
+	var parts []string
+	start := 0
+	for i, c := range s {
+		if i > start && unicode.IsDigit(c) != unicode.IsDigit(rune(s[start])) {
+			parts = append(parts, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
@@ -0,0 +1,117 @@
+package login
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testBootID = "0f6bc4e0a5d2483d9c0e8a6b1d9f1c2e"
+
+// writeRoot writes the files below a temporary root, which stands in for / on the host
+func writeRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// bootFiles are those of a host running kernel 6.1.0-21-amd64, which doesn't need rebooting
+func bootFiles() map[string]string {
+	return map[string]string{
+		bootIDFile:                               "0f6bc4e0-a5d2-483d-9c0e-8a6b1d9f1c2e\n",
+		osReleaseFile:                            "6.1.0-21-amd64\n",
+		statFile:                                 "cpu  1 2 3 4\nintr 12345\nctxt 6789\nbtime 1718002800\nprocesses 42\n",
+		"lib/modules/6.1.0-21-amd64/modules.dep": "",
+		"lib/modules/6.1.0-9-amd64/modules.dep":  "",
+	}
+}
+
+func TestReadBoot(t *testing.T) {
+	boot, err := readBoot(writeRoot(t, bootFiles()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boot.ID != testBootID {
+		t.Errorf("expected boot ID %s, got %s", testBootID, boot.ID)
+	}
+	if boot.Kernel != "6.1.0-21-amd64" {
+		t.Errorf("expected kernel 6.1.0-21-amd64, got %s", boot.Kernel)
+	}
+	if expected := time.Date(2024, time.June, 10, 7, 0, 0, 0, time.UTC); !boot.BootedAt.Equal(expected) {
+		t.Errorf("expected to have booted at %s, got %s", expected, boot.BootedAt)
+	}
+	if len(boot.Reasons) != 0 || len(boot.Packages) != 0 {
+		t.Errorf("expected no reboot to be required, got %v and %v", boot.Reasons, boot.Packages)
+	}
+}
+
+func TestReadBootRebootRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		reasons  []string
+		packages []string
+	}{
+		{
+			name:     "reboot-required",
+			files:    map[string]string{rebootRequiredFile: "*** System restart required ***\n", rebootPackagesFile: "linux-base\nlibc6\nlinux-base\n"},
+			reasons:  []string{"/run/reboot-required exists"},
+			packages: []string{"linux-base", "libc6"},
+		},
+		{
+			name:    "newer kernel",
+			files:   map[string]string{"lib/modules/6.1.0-22-amd64/modules.dep": ""},
+			reasons: []string{"kernel 6.1.0-22-amd64 is installed, but 6.1.0-21-amd64 is running"},
+		},
+		{
+			name:    "kernel removed",
+			files:   map[string]string{osReleaseFile: "6.1.0-18-amd64\n"},
+			reasons: []string{"the modules of running kernel 6.1.0-18-amd64 are no longer installed"},
+		},
+		{
+			name:  "leftover modules",
+			files: map[string]string{"lib/modules/6.1.0-30-amd64/updates/dkms.ko": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := bootFiles()
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			boot, err := readBoot(writeRoot(t, files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(boot.Reasons, tt.reasons) {
+				t.Errorf("expected reasons %q, got %q", tt.reasons, boot.Reasons)
+			}
+			if !slices.Equal(boot.Packages, tt.packages) {
+				t.Errorf("expected packages %q, got %q", tt.packages, boot.Packages)
+			}
+		})
+	}
+}
+
+func TestCompareKernels(t *testing.T) {
+	ordered := []string{"5.15.0-91-generic", "6.1.0-9-amd64", "6.1.0-21-amd64", "6.1.0-21-amd64+1", "6.8.0", "6.10.2"}
+	for i := 1; i < len(ordered); i++ {
+		if compareKernels(ordered[i-1], ordered[i]) >= 0 || compareKernels(ordered[i], ordered[i-1]) <= 0 {
+			t.Errorf("expected %s to be older than %s", ordered[i-1], ordered[i])
+		}
+	}
+	if compareKernels("6.1.0-21-amd64", "6.1.0-21-amd64") != 0 {
+		t.Error("expected a kernel to equal itself")
+	}
+}
@@ -0,0 +1,50 @@
+package login
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Boot is the host's current boot, and the conditions which call for the next one
+type Boot struct {
+	// ID is the kernel's random boot ID, as 32 hex digits
+	ID       string
+	BootedAt time.Time
+	// Kernel is the release of the running kernel
+	Kernel string
+	// Reasons explain why the host needs rebooting, it doesn't if there are none
+	Reasons []string
+	// Packages are those which asked for the reboot, as listed in /run/reboot-required.pkgs
+	Packages   []string
+	Inhibitors []Inhibitor
+	// ScheduledReboot is zero unless logind has a reboot scheduled
+	ScheduledReboot time.Time
+}
+
+// Inhibitor is a lock taken with logind, see systemd-inhibit(1)
+type Inhibitor struct {
+	What string
+	Who  string
+	Why  string
+	Mode string
+	UID  uint32
+	PID  uint32
+}
+
+// BlocksShutdown returns true if the inhibitor stops the host from shutting down or rebooting
+func (i Inhibitor) BlocksShutdown() bool {
+	return i.Mode == "block" && slices.Contains(strings.Split(i.What, ":"), "shutdown")
+}
+
+type LoginClient interface {
+	// Boot returns the current boot, along with any reasons to reboot the host
+	Boot(ctx context.Context) (Boot, error)
+	// ScheduleReboot has logind reboot the host at the given time, replacing any scheduled reboot
+	// Unless ignoreInhibitors is set, the reboot is refused if an inhibitor blocks shutdown.
+	ScheduleReboot(ctx context.Context, at time.Time, ignoreInhibitors bool) error
+	// CancelReboot cancels the scheduled reboot, returning false if there wasn't one
+	CancelReboot(ctx context.Context) (bool, error)
+	Version(ctx context.Context) (string, error)
+}
@@ -0,0 +1,189 @@
+package login
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+// rebootAction guards scheduling and cancelling a reboot
+// logind also checks reboot-multiple-sessions while other users are logged in, and reboot-ignore-inhibit while an
+// inhibitor is held, but a method can only name a single action.
+const rebootAction = "org.freedesktop.login1.reboot"
+
+func init() {
+	for _, m := range []string{"ScheduleShutdown", "CancelScheduledShutdown"} {
+		bus.RegisterAction(prefix+m, rebootAction)
+	}
+}
+
+var (
+	destination = "org.freedesktop.login1"
+	pathname    = "/org/freedesktop/login1"
+	iface       = "org.freedesktop.login1.Manager"
+	prefix      = iface + "."
+
+	// logind reports no version of its own, so that of the systemd release it belongs to is read from the manager
+	systemdDestination = "org.freedesktop.systemd1"
+	systemdPathname    = "/org/freedesktop/systemd1"
+	systemdIface       = "org.freedesktop.systemd1.Manager"
+)
+
+type LogindDbusClient struct {
+	sup     *bus.Supervisor
+	log     *zerolog.Logger
+	backend *bus.Backend
+	// root is the directory procfs, /run and /lib/modules are read relative to, / on a real host
+	root string
+}
+
+// NewLoginClient returns a client which is enabled once logind is on the bus, reading the current boot from the
+// files below root. Until then every call returns a bus.UnavailableError.
+func NewLoginClient(sup *bus.Supervisor, root string) *LogindDbusClient {
+	log := middleware.Logger()
+	log.Info().Msg("Initializing logind DBus connection")
+	sup.Watch(systemdDestination)
+
+	client := &LogindDbusClient{sup: sup, log: &log, root: root}
+	client.backend = bus.NewBackend(&log, sup, destination, client.init)
+	return client
+}
+
+// init checks logind answers, and returns the version of the systemd release it belongs to
+func (c *LogindDbusClient) init(ctx context.Context) (string, error) {
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return "", err
+	}
+	var inhibitors []Inhibitor
+	err = bus.Call(ctx, conn.Object(destination, dbus.ObjectPath(pathname)), prefix+"ListInhibitors", 0).Store(&inhibitors)
+	if err != nil {
+		return "", err
+	}
+	if err := c.sup.Available(systemdDestination); err != nil {
+		return "", err
+	}
+	return bus.Decode[string](ctx, c.log, conn.Object(systemdDestination, dbus.ObjectPath(systemdPathname)), systemdIface+".Version")
+}
+
+// Capability reports whether the module is enabled, i.e. logind has been on the bus since the agent started
+func (c *LogindDbusClient) Capability() common.ModuleCapability {
+	return c.backend.Capability()
+}
+
+// object resolves the manager against the current connection, so that calls survive a bus restart
+func (c *LogindDbusClient) object(dest string, path string) (dbus.BusObject, error) {
+	if err := c.backend.Ready(); err != nil {
+		return nil, err
+	}
+	if err := c.sup.Available(dest); err != nil {
+		return nil, err
+	}
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Object(dest, dbus.ObjectPath(path)), nil
+}
+
+// scheduledShutdown is the ScheduledShutdown property, whose type is empty if nothing is scheduled
+type scheduledShutdown struct {
+	Type string
+	USec uint64
+}
+
+func (c *LogindDbusClient) Boot(ctx context.Context) (Boot, error) {
+	boot, err := readBoot(c.root)
+	if err != nil {
+		return boot, err
+	}
+	boot.Inhibitors, err = c.inhibitors(ctx)
+	if err != nil {
+		return boot, err
+	}
+	obj, err := c.object(destination, pathname)
+	if err != nil {
+		return boot, err
+	}
+	scheduled, err := bus.Decode[scheduledShutdown](ctx, c.log, obj, prefix+"ScheduledShutdown")
+	if err != nil {
+		return boot, err
+	}
+	// A dry reboot is one which only logs the users out
+	if scheduled.Type == "reboot" && scheduled.USec > 0 {
+		boot.ScheduledReboot = time.UnixMicro(int64(scheduled.USec)).UTC()
+	}
+	return boot, nil
+}
+
+func (c *LogindDbusClient) inhibitors(ctx context.Context) ([]Inhibitor, error) {
+	obj, err := c.object(destination, pathname)
+	if err != nil {
+		return nil, err
+	}
+	inhibitors := []Inhibitor{}
+	err = bus.Call(ctx, obj, prefix+"ListInhibitors", 0).Store(&inhibitors)
+	return inhibitors, err
+}
+
+func (c *LogindDbusClient) ScheduleReboot(ctx context.Context, at time.Time, ignoreInhibitors bool) error {
+	if !ignoreInhibitors {
+		inhibitors, err := c.inhibitors(ctx)
+		if err != nil {
+			return err
+		}
+		if blocking := blockingInhibitors(inhibitors); blocking != "" {
+			return fmt.Errorf("reboot is blocked by %s: %w", blocking, bus.ErrConflict)
+		}
+	}
+	obj, err := c.object(destination, pathname)
+	if err != nil {
+		return err
+	}
+	c.log.Info().Time("at", at).Bool("ignore_inhibitors", ignoreInhibitors).Msg("Scheduling reboot")
+	return bus.Call(ctx, obj, prefix+"ScheduleShutdown", 0, "reboot", uint64(at.UnixMicro())).Err
+}
+
+// blockingInhibitors describes the inhibitors which block shutdown, or returns an empty string if none do
+func blockingInhibitors(inhibitors []Inhibitor) string {
+	var blocking []string
+	for _, i := range inhibitors {
+		if i.BlocksShutdown() {
+			blocking = append(blocking, fmt.Sprintf("%s (%s, PID %d)", i.Who, i.Why, i.PID))
+		}
+	}
+	return strings.Join(blocking, ", ")
+}
+
+func (c *LogindDbusClient) CancelReboot(ctx context.Context) (bool, error) {
+	obj, err := c.object(destination, pathname)
+	if err != nil {
+		return false, err
+	}
+	// Leave a power off scheduled by an administrator alone
+	scheduled, err := bus.Decode[scheduledShutdown](ctx, c.log, obj, prefix+"ScheduledShutdown")
+	if err != nil || scheduled.Type != "reboot" {
+		return false, err
+	}
+	var cancelled bool
+	err = bus.Call(ctx, obj, prefix+"CancelScheduledShutdown", 0).Store(&cancelled)
+	if err == nil && cancelled {
+		c.log.Info().Msg("Cancelled scheduled reboot")
+	}
+	return cancelled, err
+}
+
+func (c *LogindDbusClient) Version(ctx context.Context) (string, error) {
+	obj, err := c.object(systemdDestination, systemdPathname)
+	if err != nil {
+		return "", err
+	}
+	return bus.Decode[string](ctx, c.log, obj, systemdIface+".Version")
+}
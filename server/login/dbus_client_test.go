@@ -0,0 +1,161 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/dbustest"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakelogind"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakesystemd"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	middleware.SetupLogging(io.Discard, zerolog.Disabled)
+}
+
+// newTestClient starts a private bus with fake logind and systemd managers, and returns a client connected to it,
+// reading the boot files of a host which doesn't need rebooting
+func newTestClient(t *testing.T, opts ...fakelogind.Option) (LoginClient, *fakelogind.Service) {
+	t.Helper()
+	b := dbustest.NewBus(t)
+	conn := b.Connect(t)
+	fakesystemd.Start(t, conn, fakesystemd.WithVersion("252"))
+	service := fakelogind.Start(t, conn, opts...)
+	return NewLoginClient(b.Supervisor(t), writeRoot(t, bootFiles())), service
+}
+
+func TestVersion(t *testing.T) {
+	client, _ := newTestClient(t)
+	version, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "252" {
+		t.Errorf("expected version 252, got %s", version)
+	}
+}
+
+func TestLogindStartsAfterAgent(t *testing.T) {
+	b := dbustest.NewBus(t)
+	conn := b.Connect(t)
+	fakesystemd.Start(t, conn, fakesystemd.WithVersion("252"))
+	client := NewLoginClient(b.Supervisor(t), writeRoot(t, bootFiles()))
+	if client.Capability().Enabled {
+		t.Fatal("expected the module to be disabled until logind starts")
+	}
+	_, err := client.Boot(context.Background())
+	if _, ok := bus.IsUnavailable(err); !ok {
+		t.Fatalf("expected logind to be unavailable, got %v", err)
+	}
+
+	fakelogind.Start(t, conn)
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Capability().Enabled {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the module to be enabled once logind starts")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if version := client.Capability().Version; version != "252" {
+		t.Errorf("expected version 252, got %s", version)
+	}
+	if _, err := client.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScheduleReboot(t *testing.T) {
+	ctx := context.Background()
+	inhibitor := fakelogind.Inhibitor{What: "sleep:idle", Who: "GNOME", Why: "Playing video", Mode: "block", UID: 1000, PID: 4242}
+	client, service := newTestClient(t, fakelogind.WithInhibitors(inhibitor))
+
+	at := time.Date(2024, time.June, 10, 2, 0, 0, 0, time.UTC)
+	if err := client.ScheduleReboot(ctx, at, false); err != nil {
+		t.Fatal(err)
+	}
+	if scheduled := service.Scheduled(); scheduled.Type != "reboot" || scheduled.USec != uint64(at.UnixMicro()) {
+		t.Errorf("expected a reboot to be scheduled at %s, got %+v", at, scheduled)
+	}
+
+	boot, err := client.Boot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boot.ID != testBootID || !boot.ScheduledReboot.Equal(at) {
+		t.Errorf("expected boot %s with a reboot scheduled at %s, got %+v", testBootID, at, boot)
+	}
+	if len(boot.Inhibitors) != 1 || boot.Inhibitors[0] != Inhibitor(inhibitor) {
+		t.Errorf("expected inhibitor %+v, got %+v", inhibitor, boot.Inhibitors)
+	}
+
+	cancelled, err := client.CancelReboot(ctx)
+	if err != nil || !cancelled {
+		t.Fatalf("expected the reboot to be cancelled, got %t, %v", cancelled, err)
+	}
+	if boot, _ := client.Boot(ctx); !boot.ScheduledReboot.IsZero() {
+		t.Errorf("expected no reboot to be scheduled, got %s", boot.ScheduledReboot)
+	}
+	if cancelled, _ := client.CancelReboot(ctx); cancelled {
+		t.Error("expected nothing to cancel")
+	}
+}
+
+func TestScheduleRebootInhibited(t *testing.T) {
+	ctx := context.Background()
+	client, service := newTestClient(t, fakelogind.WithInhibitors(
+		fakelogind.Inhibitor{What: "shutdown:sleep", Who: "backup", Why: "Backup in progress", Mode: "block", PID: 1234},
+	))
+
+	err := client.ScheduleReboot(ctx, time.Now(), false)
+	if !errors.Is(err, bus.ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if err.Error() != "reboot is blocked by backup (Backup in progress, PID 1234): conflict" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if scheduled := service.Scheduled(); scheduled.Type != "" {
+		t.Errorf("expected no reboot to be scheduled, got %+v", scheduled)
+	}
+
+	if err := client.ScheduleReboot(ctx, time.Now(), true); err != nil {
+		t.Fatal(err)
+	}
+	if scheduled := service.Scheduled(); scheduled.Type != "reboot" {
+		t.Errorf("expected a reboot to be scheduled, got %+v", scheduled)
+	}
+}
+
+func TestCancelRebootLeavesPowerOff(t *testing.T) {
+	ctx := context.Background()
+	client, service := newTestClient(t)
+	// An administrator schedules a power off with shutdown(8)
+	service.Schedule(fakelogind.ScheduledShutdown{Type: "poweroff", USec: uint64(time.Now().Add(time.Hour).UnixMicro())})
+	cancelled, err := client.CancelReboot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled || service.Scheduled().Type != "poweroff" {
+		t.Errorf("expected the power off to be left alone, got %t, %+v", cancelled, service.Scheduled())
+	}
+}
+
+func TestScheduleRebootNotAuthorized(t *testing.T) {
+	client, service := newTestClient(t)
+	service.Fail("ScheduleShutdown", dbus.NewError("org.freedesktop.DBus.Error.InteractiveAuthorizationRequired", []interface{}{"Interactive authentication required."}))
+
+	err := client.ScheduleReboot(context.Background(), time.Now(), false)
+	authErr, ok := bus.IsAuthorizationError(err)
+	if !ok {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if authErr.Action != rebootAction {
+		t.Errorf("expected action %s, got %s", rebootAction, authErr.Action)
+	}
+}
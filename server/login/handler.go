@@ -0,0 +1,111 @@
+package login
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+// minRebootDelay gives the agent time to reply before the host starts shutting down
+const minRebootDelay = 5 * time.Second
+
+func HandleBootGet(client LoginClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		boot, err := client.Boot(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot read the current boot")
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toBootResponse(boot))
+	})
+}
+
+// HandleRebootPost schedules a reboot after the requested delay, within the maintenance window if one is given
+// The window is in the agent's local time, which is that of the host.
+func HandleRebootPost(client LoginClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		req, err := common.DecodeRequest[common.RebootRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+		if req.DelaySec < 0 {
+			bus.HTTPError(w, r, fmt.Errorf("delay must not be negative: %w", bus.ErrInvalid))
+			return
+		}
+
+		now := time.Now()
+		at := now.Add(time.Duration(req.DelaySec) * time.Second)
+		if req.Window != nil {
+			at, err = req.Window.Next(at)
+			if err != nil {
+				bus.HTTPError(w, r, fmt.Errorf("%s: %w", err, bus.ErrInvalid))
+				return
+			}
+		}
+		// logind schedules to the microsecond
+		at = later(at, now.Add(minRebootDelay)).Truncate(time.Microsecond)
+
+		boot, err := client.Boot(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot read the current boot")
+			bus.HTTPError(w, r, err)
+			return
+		}
+		if err := client.ScheduleReboot(ctx, at, req.IgnoreInhibitors); err != nil {
+			log.Error().Err(err).Msg("Cannot schedule reboot")
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusAccepted, common.RebootResponse{BootID: boot.ID, ScheduledAt: at.UTC()})
+	})
+}
+
+func HandleRebootDelete(client LoginClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		if _, err := client.CancelReboot(ctx); err != nil {
+			log.Error().Err(err).Msg("Cannot cancel the scheduled reboot")
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func later(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func toBootResponse(boot Boot) common.BootResponse {
+	resp := common.BootResponse{
+		BootID:         boot.ID,
+		BootedAt:       boot.BootedAt,
+		Kernel:         boot.Kernel,
+		RebootRequired: len(boot.Reasons) > 0,
+		Reasons:        append([]string{}, boot.Reasons...),
+		Packages:       append([]string{}, boot.Packages...),
+		Inhibitors:     make([]common.InhibitorResponse, 0, len(boot.Inhibitors)),
+	}
+	for _, i := range boot.Inhibitors {
+		resp.Inhibitors = append(resp.Inhibitors, common.InhibitorResponse{What: i.What, Who: i.Who, Why: i.Why, Mode: i.Mode, UID: i.UID, PID: i.PID})
+	}
+	if !boot.ScheduledReboot.IsZero() {
+		scheduled := boot.ScheduledReboot
+		resp.ScheduledReboot = &scheduled
+	}
+	return resp
+}
@@ -0,0 +1,140 @@
+package login
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakelogind"
+)
+
+func newTestMux(client LoginClient) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /login/boot", HandleBootGet(client))
+	mux.Handle("POST /login/reboot", HandleRebootPost(client))
+	mux.Handle("DELETE /login/reboot", HandleRebootDelete(client))
+	return mux
+}
+
+func TestRebootHandlers(t *testing.T) {
+	client, service := newTestClient(t)
+	mux := newTestMux(client)
+
+	before := time.Now()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login/reboot", strings.NewReader(`{"delay_sec": 600}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var reboot common.RebootResponse
+	if err := json.NewDecoder(w.Body).Decode(&reboot); err != nil {
+		t.Fatal(err)
+	}
+	if reboot.BootID != testBootID {
+		t.Errorf("expected boot ID %s, got %s", testBootID, reboot.BootID)
+	}
+	if reboot.ScheduledAt.Before(before.Add(10*time.Minute)) || reboot.ScheduledAt.After(time.Now().Add(10*time.Minute)) {
+		t.Errorf("expected the reboot to be scheduled in 10 minutes, got %s", reboot.ScheduledAt)
+	}
+	if scheduled := service.Scheduled(); scheduled.USec != uint64(reboot.ScheduledAt.UnixMicro()) {
+		t.Errorf("expected logind to reboot at %s, got %+v", reboot.ScheduledAt, scheduled)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login/boot", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var boot common.BootResponse
+	if err := json.NewDecoder(w.Body).Decode(&boot); err != nil {
+		t.Fatal(err)
+	}
+	if boot.BootID != testBootID || boot.Kernel != "6.1.0-21-amd64" || boot.RebootRequired {
+		t.Errorf("unexpected boot: %+v", boot)
+	}
+	if boot.ScheduledReboot == nil || !boot.ScheduledReboot.Equal(reboot.ScheduledAt) {
+		t.Errorf("expected a reboot to be scheduled at %s, got %v", reboot.ScheduledAt, boot.ScheduledReboot)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/login/reboot", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+	if scheduled := service.Scheduled(); scheduled.Type != "" {
+		t.Errorf("expected the reboot to be cancelled, got %+v", scheduled)
+	}
+}
+
+func TestHandleRebootPostWindow(t *testing.T) {
+	client, _ := newTestClient(t)
+	mux := newTestMux(client)
+
+	// A window which ends a minute ago only opens again tomorrow
+	now := time.Now()
+	start, end := now.Add(-2*time.Hour).Format("15:04"), now.Add(-time.Minute).Format("15:04")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login/reboot",
+		strings.NewReader(`{"window": {"start": "`+start+`", "end": "`+end+`"}}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var reboot common.RebootResponse
+	if err := json.NewDecoder(w.Body).Decode(&reboot); err != nil {
+		t.Fatal(err)
+	}
+	if wait := time.Until(reboot.ScheduledAt); wait < 21*time.Hour || wait > 23*time.Hour {
+		t.Errorf("expected the reboot to wait for the window to open, got %s", reboot.ScheduledAt)
+	}
+	if local := reboot.ScheduledAt.Local().Format("15:04"); local != start {
+		t.Errorf("expected the reboot to be scheduled at %s, got %s", start, local)
+	}
+
+	// No delay still gives the agent time to reply
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login/reboot", strings.NewReader(`{}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	if err := json.NewDecoder(w.Body).Decode(&reboot); err != nil {
+		t.Fatal(err)
+	}
+	if wait := time.Until(reboot.ScheduledAt); wait <= 0 || wait > minRebootDelay {
+		t.Errorf("expected the reboot to be scheduled in %s, got %s", minRebootDelay, reboot.ScheduledAt)
+	}
+}
+
+func TestHandleRebootPostValidation(t *testing.T) {
+	client, service := newTestClient(t, fakelogind.WithInhibitors(
+		fakelogind.Inhibitor{What: "shutdown", Who: "backup", Why: "Backup in progress", Mode: "block", PID: 1234},
+	))
+	mux := newTestMux(client)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"malformed", `{"delay_sec": "soon"}`, http.StatusBadRequest},
+		{"negative delay", `{"delay_sec": -1}`, http.StatusBadRequest},
+		{"empty window", `{"window": {"start": "02:00", "end": "02:00"}}`, http.StatusBadRequest},
+		{"invalid window", `{"window": {"start": "2am", "end": "4am"}}`, http.StatusBadRequest},
+		{"inhibited", `{}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login/reboot", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+		})
+	}
+	if scheduled := service.Scheduled(); scheduled.Type != "" {
+		t.Errorf("expected no reboot to be scheduled, got %+v", scheduled)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"net"
@@ -11,50 +12,161 @@ import (
 	"time"
 
 	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/api"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/capabilities"
+	"github.com/nickrobison/terraform-linux-provider/server/health"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+	"github.com/nickrobison/terraform-linux-provider/server/login"
+	"github.com/nickrobison/terraform-linux-provider/server/metrics"
 	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/nickrobison/terraform-linux-provider/server/systemd"
 	"github.com/nickrobison/terraform-linux-provider/server/zfs"
 	"github.com/rs/zerolog"
 )
 
+var (
+	version string = "dev"
+)
+
 func run(ctx context.Context, w io.Writer, args []string) error {
 	middleware.SetupLogging(w, zerolog.DebugLevel)
 	log := middleware.Logger()
 
 	log.Print("Hello world!")
 
-	// Connect to DBUS
-	// Should be session bus
-	conn, err := dbus.ConnectSessionBus()
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	busName := flags.String("bus", "system", "message bus to connect to, either system or session")
+	listen := flags.String("listen", net.JoinHostPort("localhost", "8080"), "TCP address to serve the API on, or empty to disable")
+	socket := flags.String("socket", "", "path of a unix socket to also serve the API on, e.g. for clients tunnelling over SSH")
+	unitDir := flags.String("unit-dir", systemd.DefaultUnitDir, "directory the unit files managed by the agent are written to")
+	journalDir := flags.String("journal-dir", "", "journal directory to read instead of the system journal")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	connect, err := busConnector(*busName)
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() == 0 {
+		log.Warn().Msg("Running as root, consider running the agent as a dedicated user authorized via polkit rules")
+	}
+
+	bus.SetObserver(metrics.BusObserver())
+
+	sup, err := bus.NewSupervisor(&log, connect)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to connect to bus")
 		return err
 	}
+	go sup.Run(ctx)
+
+	deps := []health.Dependency{
+		{
+			Name: "dbus",
+			Check: func(ctx context.Context) (string, error) {
+				return "", sup.Ping(ctx)
+			},
+		},
+	}
+	backends := api.Dependencies{
+		AgentVersion: version,
+		Modules:      make(map[string]capabilities.Module),
+	}
 
-	zfsClient, err := zfs.NewZfsClient(conn)
+	// D-Bus backends are enabled as soon as their service is on the bus, which may be after the agent starts
+	zfsClient := zfs.NewZfsClient(sup)
+	err = metrics.Register(zfs.NewPoolCollector(zfsClient))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to connect to dbus object")
+		log.Error().Err(err).Msg("Failed to register zpool metrics")
 		return err
 	}
-	zfsVersion, err := zfsClient.Version()
+	backends.Modules[common.ModuleZfs] = zfsClient.Capability
+	backends.Zfs = zfsClient
+	deps = append(deps, backendDependency(common.ModuleZfs, zfsClient.Capability, zfsClient.Version))
+
+	// The journal client is created first, the systemd module reads the journal of units which fail to start
+	journalClient, err := journal.NewJournalctl(*journalDir)
 	if err != nil {
-		log.Fatal().Err(err)
+		log.Warn().Err(err).Msg("journalctl is not available, disabling journal module")
+		backends.Modules[common.ModuleJournal] = capabilities.Static(common.ModuleCapability{Enabled: false})
+	} else {
+		journalVersion, err := journalClient.Version(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get journalctl version")
+			return err
+		}
+
+		log.Info().Msgf("Initialized journal client with version %s", journalVersion)
+		backends.Modules[common.ModuleJournal] = capabilities.Static(common.ModuleCapability{Enabled: true, Version: journalVersion})
+		backends.Journal = journalClient
+		deps = append(deps, health.Dependency{
+			Name:  common.ModuleJournal,
+			Check: journalClient.Version,
+		})
 	}
 
-	log.Info().Msgf("Initialized Zfs client with version %s", zfsVersion)
+	// File modules are checked for write access on every request, so that a plan fails cleanly rather than the apply
+	disabled := capabilities.Static(common.ModuleCapability{Enabled: false})
+	for _, module := range []string{common.ModuleSystemd, common.ModuleUnitFiles, common.ModuleConfigFiles, common.ModuleTmpfiles, common.ModuleSysusers} {
+		backends.Modules[module] = disabled
+	}
+	systemdClient, err := systemd.NewSystemdClient(sup, *unitDir, journalClient)
+	if err != nil {
+		log.Warn().Err(err).Msg("Cannot manage unit files, disabling systemd module")
+	} else {
+		backends.Modules[common.ModuleSystemd] = systemdClient.Capability
+		backends.Systemd = systemdClient
+		backends.Modules[common.ModuleUnitFiles] = systemdClient.UnitFilesCapability
+		warnReadOnly(common.ModuleUnitFiles, systemdClient.UnitFilesWritable)
+		config := systemd.NewConfigDropIns(systemdClient, "/")
+		backends.Modules[common.ModuleConfigFiles] = config.Capability
+		backends.Config = config
+		warnReadOnly(common.ModuleConfigFiles, config.Writable)
+		deps = append(deps, backendDependency(common.ModuleSystemd, systemdClient.Capability, systemdClient.Version))
+	}
+	if tmpfiles, err := systemd.NewSystemdTmpfiles("/"); err != nil {
+		log.Warn().Err(err).Msg("systemd-tmpfiles is not available, disabling tmpfiles module")
+	} else {
+		backends.Modules[common.ModuleTmpfiles] = tmpfiles.Capability
+		backends.Tmpfiles = tmpfiles
+		warnReadOnly(common.ModuleTmpfiles, tmpfiles.Writable)
+	}
+	if sysusers, err := systemd.NewSystemdSysusers("/"); err != nil {
+		log.Warn().Err(err).Msg("systemd-sysusers is not available, disabling sysusers module")
+	} else {
+		backends.Modules[common.ModuleSysusers] = sysusers.Capability
+		backends.Sysusers = sysusers
+		warnReadOnly(common.ModuleSysusers, sysusers.Writable)
+	}
 
-	srv := newServer(zfsClient)
+	loginClient := login.NewLoginClient(sup, "/")
+	backends.Modules[common.ModuleLogin] = loginClient.Capability
+	backends.Login = loginClient
+	deps = append(deps, backendDependency(common.ModuleLogin, loginClient.Capability, loginClient.Version))
+
+	backends.Checks = deps
+	srv := api.NewServer(backends)
 	httpServer := &http.Server{
-		Addr:    net.JoinHostPort("localhost", "8080"),
 		Handler: srv,
 	}
 
-	go func() {
-		log.Info().Msgf("Listenting on %s", httpServer.Addr)
-		err := httpServer.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			log.Error().Err(err).Msg("Failed to start server")
-		}
-	}()
+	listeners, err := listenAll(*listen, *socket)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to listen")
+		return err
+	}
+	for _, l := range listeners {
+		go func() {
+			log.Info().Msgf("Listening on %s", l.Addr())
+			err := httpServer.Serve(l)
+			if err != nil && err != http.ErrServerClosed {
+				log.Error().Err(err).Msg("Failed to start server")
+			}
+		}()
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -74,6 +186,71 @@ func run(ctx context.Context, w io.Writer, args []string) error {
 	return nil
 }
 
+// listenAll opens the TCP and unix socket listeners, either of which may be disabled by passing an empty address
+func listenAll(addr string, socket string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	if addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if socket != "" {
+		// Remove the socket left behind by a previous run which didn't shut down cleanly
+		if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		// Allow members of the agent's group, such as the SSH user the provider logs in as, to connect
+		if err := os.Chmod(socket, 0o660); err != nil {
+			l.Close()
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("no listeners configured, set --listen or --socket")
+	}
+	return listeners, nil
+}
+
+// warnReadOnly logs why a file module starts out disabled, it is enabled once the agent is granted write access
+func warnReadOnly(module string, writable func() error) {
+	if err := writable(); err != nil {
+		log := middleware.Logger()
+		log.Warn().Err(err).Str("module", module).Msg("Module is disabled until the agent can write its files")
+	}
+}
+
+// backendDependency checks a D-Bus backend once it is enabled, so that a service which isn't installed on this host
+// doesn't keep the agent from being ready
+func backendDependency(name string, module capabilities.Module, check health.Check) health.Dependency {
+	return health.Dependency{
+		Name:    name,
+		Check:   check,
+		Enabled: func() bool { return module().Enabled },
+	}
+}
+
+func busConnector(name string) (bus.Connector, error) {
+	switch name {
+	case "system":
+		return func() (*dbus.Conn, error) {
+			return dbus.ConnectSystemBus()
+		}, nil
+	case "session":
+		return func() (*dbus.Conn, error) {
+			return dbus.ConnectSessionBus()
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown bus %q, expected system or session", name)
+	}
+}
+
 func main() {
 	ctx := context.Background()
 	err := run(ctx, os.Stdout, os.Args)
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const Namespace = "linux_agent"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests, by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	dbusCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "dbus",
+		Name:      "calls_total",
+		Help:      "Number of D-Bus method calls and property reads, by method.",
+	}, []string{"method"})
+
+	dbusErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "dbus",
+		Name:      "errors_total",
+		Help:      "Number of D-Bus method calls and property reads that returned an error, by method.",
+	}, []string{"method"})
+
+	dbusDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "dbus",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of D-Bus method calls and property reads, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	dbusInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "dbus",
+		Name:      "calls_in_flight",
+		Help:      "Number of D-Bus method calls and property reads awaiting a reply.",
+	})
+
+	systemdJobs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "systemd",
+		Name:      "jobs_pending",
+		Help:      "Number of systemd jobs queued by the agent which it is waiting on to finish.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbusCalls,
+		dbusErrors,
+		dbusDuration,
+		dbusInFlight,
+		systemdJobs,
+	)
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format
+// A failing collector only drops its own metrics, the rest of the scrape still succeeds
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Register adds a domain collector (e.g. pool capacity) to the agent registry
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+// ObserveRequest records a completed HTTP request
+func ObserveRequest(route string, method string, code int, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// JobStarted marks a systemd job as pending
+func JobStarted() {
+	systemdJobs.Inc()
+}
+
+// JobFinished marks a pending systemd job as removed, or no longer waited on
+func JobFinished() {
+	systemdJobs.Dec()
+}
+
+type busObserver struct{}
+
+// BusObserver returns a bus.Observer which records D-Bus call metrics
+func BusObserver() bus.Observer {
+	return busObserver{}
+}
+
+func (busObserver) CallStarted(method string) {
+	dbusInFlight.Inc()
+}
+
+func (busObserver) CallFinished(method string, duration time.Duration, err error) {
+	dbusInFlight.Dec()
+	dbusCalls.WithLabelValues(method).Inc()
+	dbusDuration.WithLabelValues(method).Observe(duration.Seconds())
+	if err != nil {
+		dbusErrors.WithLabelValues(method).Inc()
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveRequest(t *testing.T) {
+	ObserveRequest("/test", http.MethodGet, http.StatusTeapot, time.Millisecond)
+
+	got := testutil.ToFloat64(httpRequests.WithLabelValues("/test", http.MethodGet, "418"))
+	if got != 1 {
+		t.Errorf("expected 1 request, got %v", got)
+	}
+}
+
+func TestBusObserver(t *testing.T) {
+	o := BusObserver()
+	method := "com.example.Test"
+
+	o.CallStarted(method)
+	if inFlight := testutil.ToFloat64(dbusInFlight); inFlight != 1 {
+		t.Errorf("expected 1 call in flight, got %v", inFlight)
+	}
+	o.CallFinished(method, time.Millisecond, nil)
+	o.CallStarted(method)
+	o.CallFinished(method, time.Millisecond, errors.New("boom"))
+
+	if inFlight := testutil.ToFloat64(dbusInFlight); inFlight != 0 {
+		t.Errorf("expected no calls in flight, got %v", inFlight)
+	}
+	if calls := testutil.ToFloat64(dbusCalls.WithLabelValues(method)); calls != 2 {
+		t.Errorf("expected 2 calls, got %v", calls)
+	}
+	if errs := testutil.ToFloat64(dbusErrors.WithLabelValues(method)); errs != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	JobStarted()
+	if pending := testutil.ToFloat64(systemdJobs); pending != 1 {
+		t.Errorf("expected 1 pending job, got %v", pending)
+	}
+	JobFinished()
+	if pending := testutil.ToFloat64(systemdJobs); pending != 0 {
+		t.Errorf("expected no pending jobs, got %v", pending)
+	}
+	if inFlight := testutil.ToFloat64(dbusInFlight); inFlight != 0 {
+		t.Errorf("expected jobs not to count as calls in flight, got %v", inFlight)
+	}
+}
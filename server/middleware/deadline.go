@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// Deadline applies the timeout sent by the client to the request context
+// Backend calls made with the request context, such as D-Bus method calls, are then abandoned once the client has given up
+func Deadline(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get(common.TimeoutHeader)
+		if v == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			common.Encode(w, r, http.StatusBadRequest, common.ErrorResponse{
+				Error: fmt.Sprintf("invalid %s header %q, expected milliseconds", common.TimeoutHeader, v),
+			})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+		defer cancel()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
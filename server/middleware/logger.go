@@ -32,8 +32,10 @@ func Logger() zerolog.Logger {
 	return log
 }
 
+// LoggingMiddleware attaches the request logger to the context, so handlers can use zerolog.Ctx
 func LoggingMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h.ServeHTTP(w, r)
+		l := log.With().Str("method", r.Method).Str("path", r.URL.Path).Logger()
+		h.ServeHTTP(w, r.WithContext(l.WithContext(r.Context())))
 	})
 }
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/metrics"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so that handlers can still flush a streamed response
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Instrument records request counts and latency for the given route
+// The route is passed explicitly so that path parameters don't explode the label cardinality
+func Instrument(route string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		h.ServeHTTP(rec, r)
+		metrics.ObserveRequest(route, r.Method, rec.status, time.Since(start))
+	})
+}
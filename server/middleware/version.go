@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+// Versioning advertises the range of API versions served by the agent on every response
+func Versioning(h http.Handler) http.Handler {
+	max := strconv.Itoa(common.APIVersion)
+	min := strconv.Itoa(common.MinAPIVersion)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(common.APIVersionHeader, max)
+		w.Header().Set(common.MinAPIVersionHeader, min)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// RequireAPIVersion rejects requests to a route of the given API version which ask for a different version
+// Requests without a version header, e.g. from curl, are served as is
+func RequireAPIVersion(version int, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := r.Header.Get(common.APIVersionHeader)
+		if requested != "" && requested != strconv.Itoa(version) {
+			common.Encode(w, r, http.StatusBadRequest, common.ErrorResponse{
+				Error: fmt.Sprintf("requested API version %s does not match version %d of %s, the agent serves versions %d to %d",
+					requested, version, r.URL.Path, common.MinAPIVersion, common.APIVersion),
+			})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
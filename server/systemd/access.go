@@ -0,0 +1,37 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"golang.org/x/sys/unix"
+)
+
+// writable returns an error unless the agent can write to every path, or to the nearest existing parent of a path
+// which doesn't exist yet, as it would create it
+// The check uses the agent's effective credentials, so capabilities granted by its unit count as well as its user.
+func writable(paths ...string) error {
+	for _, path := range paths {
+		for {
+			err := unix.Faccessat(unix.AT_FDCWD, path, unix.W_OK, unix.AT_EACCESS)
+			if errors.Is(err, fs.ErrNotExist) && filepath.Dir(path) != path {
+				path = filepath.Dir(path)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s is not writable by the agent: %w", path, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// fileCapability reports a module which writes files as enabled while the agent can write them
+// It is checked on every request, so that granting the agent access enables the module without a restart.
+func fileCapability(check func() error) common.ModuleCapability {
+	return common.ModuleCapability{Enabled: check() == nil}
+}
@@ -0,0 +1,40 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	// A missing path is writable if the agent can create it
+	if err := writable(dir, filepath.Join(dir, "etc", "tmpfiles.d")); err != nil {
+		t.Errorf("expected %s to be writable, got %s", dir, err)
+	}
+
+	config := NewConfigDropIns(nil, dir)
+	if capability := config.Capability(); !capability.Enabled {
+		t.Error("expected config drop-ins below a writable root to be enabled")
+	}
+}
+
+func TestWritableDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to any directory")
+	}
+	dir := t.TempDir()
+	readOnly := filepath.Join(dir, "etc")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writable(dir, filepath.Join(readOnly, "sysusers.d"))
+	if err == nil || !strings.Contains(err.Error(), readOnly) {
+		t.Errorf("expected an error naming %s, got %v", readOnly, err)
+	}
+	if capability := NewConfigDropIns(nil, dir).Capability(); capability.Enabled {
+		t.Error("expected config drop-ins below a read-only directory to be disabled")
+	}
+}
@@ -0,0 +1,182 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// UnitFile is a unit file in the agent's unit directory
+type UnitFile struct {
+	Name    string
+	Path    string
+	Content string
+}
+
+// DropIn is a drop-in override in the <unit>.d directory of the agent's unit directory
+type DropIn struct {
+	Unit    string
+	Name    string
+	Path    string
+	Content string
+	// Shadowed lists the settings which other drop-ins override
+	Shadowed []ShadowedSetting
+}
+
+// ShadowedSetting is a key set by a drop-in, which a drop-in applied after it sets again
+type ShadowedSetting struct {
+	Path    string
+	Section string
+	Key     string
+}
+
+// UnitState is the load, activation and enablement state of a unit
+type UnitState struct {
+	Name          string `dbus:"Id"`
+	LoadState     string `dbus:"LoadState"`
+	ActiveState   string `dbus:"ActiveState"`
+	SubState      string `dbus:"SubState"`
+	UnitFileState string `dbus:"UnitFileState"`
+	// Result is the result of the last run of a service, and is empty for other types of unit
+	Result string
+}
+
+// Enabled returns true if the unit is started at boot, or by whichever unit it is installed into
+func (s UnitState) Enabled() bool {
+	return s.UnitFileState == "enabled" || s.UnitFileState == "enabled-runtime"
+}
+
+// Active returns true if the unit is running, or on its way to running
+func (s UnitState) Active() bool {
+	return slices.Contains([]string{"active", "activating", "reloading"}, s.ActiveState)
+}
+
+// Masked returns true if the unit is linked to /dev/null, so that it cannot be started
+func (s UnitState) Masked() bool {
+	return s.LoadState == "masked" || strings.HasPrefix(s.UnitFileState, "masked")
+}
+
+// TimerState is the schedule of a timer unit
+type TimerState struct {
+	Name string
+	// Unit is the unit which the timer activates
+	Unit string `dbus:"Unit"`
+	// NextElapse is when the timer next elapses on the realtime clock, zero if it isn't scheduled by a calendar event
+	NextElapse time.Time `dbus:"NextElapseUSecRealtime"`
+	// LastTrigger is zero if the timer has never elapsed
+	LastTrigger time.Time `dbus:"LastTriggerUSec"`
+}
+
+// UnitStatus is a unit loaded by the manager, as listed by ListUnits
+type UnitStatus struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	// FragmentPath is the unit file the unit was loaded from, empty if it has none, e.g. for device units
+	FragmentPath string
+	// MainPID is the main process of a running service, zero for other units
+	MainPID uint32
+}
+
+// UnitStateChange is the desired state of a unit, nil fields are left as they are
+type UnitStateChange struct {
+	Enabled *bool
+	Active  *bool
+	Masked  *bool
+}
+
+// Stops returns true if the unit should not be running, since a masked unit which is still running would be surprising
+func (c UnitStateChange) Stops() bool {
+	if c.Active != nil {
+		return !*c.Active
+	}
+	return c.Masked != nil && *c.Masked
+}
+
+// UnitResources is the cgroup of a unit, with its limits and usage as systemd reports them
+// Limits which are not set, and usage which is not available, are math.MaxUint64.
+type UnitResources struct {
+	Name string
+	// ControlGroup is the path of the cgroup below the cgroup root, empty if the unit isn't running
+	ControlGroup       string `dbus:"ControlGroup"`
+	MemoryMax          uint64 `dbus:"MemoryMax"`
+	CPUQuotaPerSecUSec uint64 `dbus:"CPUQuotaPerSecUSec"`
+	IOWeight           uint64 `dbus:"IOWeight"`
+	TasksMax           uint64 `dbus:"TasksMax"`
+	MemoryCurrent      uint64 `dbus:"MemoryCurrent"`
+	CPUUsageNSec       uint64 `dbus:"CPUUsageNSec"`
+	TasksCurrent       uint64 `dbus:"TasksCurrent"`
+}
+
+// ResourceControlChange sets cgroup limits of a unit, nil fields are left as they are and math.MaxUint64 removes a limit
+type ResourceControlChange struct {
+	// Runtime changes are lost at reboot, otherwise systemd persists them as drop-ins in /etc/systemd/system.control
+	Runtime            bool
+	MemoryMax          *uint64
+	CPUQuotaPerSecUSec *uint64
+	IOWeight           *uint64
+	TasksMax           *uint64
+}
+
+// UnitFailedError is returned when a unit fails to start, stop or restart
+type UnitFailedError struct {
+	Unit string
+	// Operation is start, stop or restart
+	Operation string
+	// JobResult is the result of the systemd job, e.g. failed, timeout or dependency
+	JobResult string
+	// Result is the unit's own result, e.g. exit-code, if it is a service
+	Result string
+	// Journal is the unit's last few journal lines
+	Journal []string
+}
+
+func (e *UnitFailedError) Error() string {
+	msg := fmt.Sprintf("%s of %s did not complete, job result %s", e.Operation, e.Unit, e.JobResult)
+	if e.Result != "" {
+		msg += ", unit result " + e.Result
+	}
+	return msg
+}
+
+// JournalLines returns the unit's last few journal lines, which the API reports alongside the error
+func (e *UnitFailedError) JournalLines() []string {
+	return e.Journal
+}
+
+type SystemdClient interface {
+	// GetUnitFile returns an error wrapping bus.ErrNotFound if there is no unit file with the given name
+	GetUnitFile(ctx context.Context, name string) (UnitFile, error)
+	// WriteUnitFile creates or replaces a unit file, then reloads the manager so that systemd sees the change
+	WriteUnitFile(ctx context.Context, name string, content string) (UnitFile, error)
+	// DeleteUnitFile returns an error wrapping bus.ErrNotFound if there is no unit file with the given name
+	DeleteUnitFile(ctx context.Context, name string) error
+	// GetDropIn returns an error wrapping bus.ErrNotFound if the unit has no drop-in with the given name
+	GetDropIn(ctx context.Context, unit string, name string) (DropIn, error)
+	// WriteDropIn creates or replaces a drop-in, then reloads the manager so that systemd sees the change
+	WriteDropIn(ctx context.Context, unit string, name string, content string) (DropIn, error)
+	// DeleteDropIn returns an error wrapping bus.ErrNotFound if the unit has no drop-in with the given name
+	DeleteDropIn(ctx context.Context, unit string, name string) error
+	// GetUnitState returns an error wrapping bus.ErrNotFound if systemd has no such unit
+	GetUnitState(ctx context.Context, name string) (UnitState, error)
+	// SetUnitState changes the state of a unit, waiting for any job to finish
+	// Returns a UnitFailedError if the unit fails to start or stop
+	SetUnitState(ctx context.Context, name string, change UnitStateChange) (UnitState, error)
+	// RestartUnit restarts a unit, or starts it if it isn't running, waiting for the job to finish
+	RestartUnit(ctx context.Context, name string) (UnitState, error)
+	// ListUnits returns the units in any of the given load, active or sub states whose names match any of the glob
+	// patterns, e.g. *.service. Empty states or patterns match every unit.
+	ListUnits(ctx context.Context, states []string, patterns []string) ([]UnitStatus, error)
+	// GetTimer returns an error wrapping bus.ErrNotFound if systemd has no such timer
+	GetTimer(ctx context.Context, name string) (TimerState, error)
+	// GetUnitResources returns an error wrapping bus.ErrNotFound if systemd has no such unit, and bus.ErrInvalid if
+	// the type of unit has no cgroup
+	GetUnitResources(ctx context.Context, name string) (UnitResources, error)
+	// SetUnitResources changes the cgroup limits of a unit with SetUnitProperties, which applies them straight away
+	SetUnitResources(ctx context.Context, name string, change ResourceControlChange) (UnitResources, error)
+	Version(ctx context.Context) (string, error)
+}
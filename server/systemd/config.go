@@ -0,0 +1,174 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+// ConfigDropIn is a drop-in of a configuration file, such as journald.conf
+type ConfigDropIn struct {
+	File    string
+	Name    string
+	Path    string
+	Content string
+	// Restarted is the unit which was restarted to apply the drop-in, empty if none was
+	Restarted string
+}
+
+// ConfigClient manages drop-ins of the configuration files in common.ConfigFiles
+type ConfigClient interface {
+	// GetConfigDropIn returns an error wrapping bus.ErrNotFound if the file has no drop-in with the given name
+	GetConfigDropIn(ctx context.Context, file string, name string) (ConfigDropIn, error)
+	// WriteConfigDropIn creates or replaces a drop-in, which may only assign the file's known keys, then restarts the
+	// file's unit if the content changed and the unit is running
+	WriteConfigDropIn(ctx context.Context, file string, name string, content string) (ConfigDropIn, error)
+	// DeleteConfigDropIn returns an error wrapping bus.ErrNotFound if the file has no drop-in with the given name
+	DeleteConfigDropIn(ctx context.Context, file string, name string) error
+}
+
+// ConfigDropIns writes drop-ins of configuration files below root, which is / other than in tests
+type ConfigDropIns struct {
+	systemd SystemdClient
+	log     *zerolog.Logger
+	root    string
+}
+
+// NewConfigDropIns returns a client which restarts the daemons reading the configuration files with systemd
+func NewConfigDropIns(systemd SystemdClient, root string) *ConfigDropIns {
+	log := middleware.Logger()
+	log = log.With().Str("config_root", root).Logger()
+	return &ConfigDropIns{systemd: systemd, log: &log, root: root}
+}
+
+// Writable returns an error unless the agent can write the drop-ins of every configuration file
+func (c *ConfigDropIns) Writable() error {
+	var paths []string
+	for _, config := range common.ConfigFiles {
+		paths = append(paths, filepath.Join(c.root, config.Dir))
+	}
+	slices.Sort(paths)
+	return writable(paths...)
+}
+
+// Capability reports the drop-ins as enabled while they are writable
+func (c *ConfigDropIns) Capability() common.ModuleCapability {
+	return fileCapability(c.Writable)
+}
+
+// path returns the configuration file and the location of its named drop-in
+func (c *ConfigDropIns) path(file string, name string) (common.ConfigFile, string, error) {
+	config, ok := common.ConfigFiles[file]
+	if !ok {
+		return config, "", fmt.Errorf("configuration file %s: %w", file, bus.ErrNotFound)
+	}
+	if len(name) > common.MaxDropInNameLength || !common.DropInNamePattern.MatchString(name) {
+		return config, "", fmt.Errorf("invalid drop-in name %q: %w", name, bus.ErrInvalid)
+	}
+	return config, filepath.Join(c.root, config.Dir, name+".conf"), nil
+}
+
+func (c *ConfigDropIns) GetConfigDropIn(ctx context.Context, file string, name string) (ConfigDropIn, error) {
+	_, path, err := c.path(file, name)
+	if err != nil {
+		return ConfigDropIn{}, err
+	}
+	content, err := readRegular(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ConfigDropIn{}, fmt.Errorf("drop-in %s of %s: %w", name, file, bus.ErrNotFound)
+	}
+	if err != nil {
+		return ConfigDropIn{}, err
+	}
+	return ConfigDropIn{File: file, Name: name, Path: path, Content: content}, nil
+}
+
+func (c *ConfigDropIns) WriteConfigDropIn(ctx context.Context, file string, name string, content string) (ConfigDropIn, error) {
+	config, path, err := c.path(file, name)
+	if err != nil {
+		return ConfigDropIn{}, err
+	}
+	if err := config.Validate(content); err != nil {
+		return ConfigDropIn{}, fmt.Errorf("invalid drop-in %s of %s: %s: %w", name, file, err, bus.ErrInvalid)
+	}
+	if err := ctx.Err(); err != nil {
+		return ConfigDropIn{}, err
+	}
+
+	dropIn := ConfigDropIn{File: file, Name: name, Path: path, Content: content}
+	existing, err := readRegular(path)
+	if err == nil && existing == content {
+		return dropIn, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return ConfigDropIn{}, err
+	}
+	if err := writeFile(path, content); err != nil {
+		return ConfigDropIn{}, err
+	}
+	c.log.Info().Str("file", file).Str("name", name).Msg("Wrote configuration drop-in")
+
+	dropIn.Restarted, err = c.apply(ctx, config)
+	return dropIn, err
+}
+
+func (c *ConfigDropIns) DeleteConfigDropIn(ctx context.Context, file string, name string) error {
+	config, path, err := c.path(file, name)
+	if err != nil {
+		return err
+	}
+	if _, err := readRegular(path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("drop-in %s of %s: %w", name, file, bus.ErrNotFound)
+	} else if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	// Only succeeds once the directory is empty, drop-ins written by others are left alone
+	_ = os.Remove(filepath.Dir(path))
+	c.log.Info().Str("file", file).Str("name", name).Msg("Deleted configuration drop-in")
+	_, err = c.apply(ctx, config)
+	return err
+}
+
+// apply restarts the unit which reads the configuration file, returning its name, unless it isn't running
+// A unit which is stopped reads the new configuration when it next starts.
+func (c *ConfigDropIns) apply(ctx context.Context, config common.ConfigFile) (string, error) {
+	if config.Unit == "" {
+		return "", nil
+	}
+	state, err := c.systemd.GetUnitState(ctx, config.Unit)
+	if errors.Is(err, bus.ErrNotFound) || (err == nil && !state.Active()) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.systemd.RestartUnit(ctx, config.Unit); err != nil {
+		return "", err
+	}
+	return config.Unit, nil
+}
+
+// readRegular returns the content of a regular file, treating anything else, such as a symlink, as missing
+func readRegular(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fs.ErrNotExist
+	}
+	content, err := os.ReadFile(path)
+	return string(content), err
+}
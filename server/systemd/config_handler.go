@@ -0,0 +1,71 @@
+package systemd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleConfigDropInGet(client ConfigClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		file, name := r.PathValue("file"), r.PathValue("dropin")
+		dropIn, err := client.GetConfigDropIn(ctx, file, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get drop-in %s of %s", name, file)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toConfigDropInResponse(dropIn))
+	})
+}
+
+func HandleConfigDropInPut(client ConfigClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		file, name := r.PathValue("file"), r.PathValue("dropin")
+		req, err := common.DecodeRequest[common.UnitFileRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+
+		dropIn, err := client.WriteConfigDropIn(ctx, file, name, req.Content)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot write drop-in %s of %s", name, file)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toConfigDropInResponse(dropIn))
+	})
+}
+
+func HandleConfigDropInDelete(client ConfigClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		file, name := r.PathValue("file"), r.PathValue("dropin")
+		err := client.DeleteConfigDropIn(ctx, file, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot delete drop-in %s of %s", name, file)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func toConfigDropInResponse(dropIn ConfigDropIn) common.ConfigDropInResponse {
+	return common.ConfigDropInResponse{
+		File:      dropIn.File,
+		Name:      dropIn.Name,
+		Path:      dropIn.Path,
+		Content:   dropIn.Content,
+		Restarted: dropIn.Restarted,
+	}
+}
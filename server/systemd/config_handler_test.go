@@ -0,0 +1,63 @@
+package systemd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func newConfigTestMux(client ConfigClient) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /systemd/config/{file}/dropins/{dropin}", HandleConfigDropInGet(client))
+	mux.Handle("PUT /systemd/config/{file}/dropins/{dropin}", HandleConfigDropInPut(client))
+	mux.Handle("DELETE /systemd/config/{file}/dropins/{dropin}", HandleConfigDropInDelete(client))
+	return mux
+}
+
+func TestConfigDropInHandlers(t *testing.T) {
+	systemd, _, _ := newTestClient(t)
+	mux := newConfigTestMux(NewConfigDropIns(systemd, t.TempDir()))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/config/journald/dropins/50-forward",
+		strings.NewReader(`{"content": "[Journal]\nForwardToSyslog=no\n"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/config/journald/dropins/50-forward", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.ConfigDropInResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.File != "journald" || resp.Name != "50-forward" || resp.Content != "[Journal]\nForwardToSyslog=no\n" {
+		t.Errorf("unexpected drop-in: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/config/journald/dropins/50-forward",
+		strings.NewReader(`{"content": "[Journal]\nForwardToSyslogd=no\n"}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unknown key ForwardToSyslogd") {
+		t.Errorf("expected an unknown key to be rejected, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/systemd/config/journald/dropins/50-forward", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/config/logind/dropins/50-forward", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown file, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,117 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakesystemd"
+)
+
+func TestConfigDropIns(t *testing.T) {
+	journald := fakesystemd.NewUnit("systemd-journald.service")
+	journald.ActiveState, journald.SubState = "active", "running"
+	systemd, service, _ := newTestClient(t, fakesystemd.WithUnits(journald))
+	root := t.TempDir()
+	client := NewConfigDropIns(systemd, root)
+	ctx := context.Background()
+
+	content := "[Journal]\nStorage=persistent\nSystemMaxUse=1G\n"
+	dropIn, err := client.WriteConfigDropIn(ctx, "journald", "50-retention", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "etc/systemd/journald.conf.d/50-retention.conf")
+	if dropIn.Path != path || dropIn.Restarted != "systemd-journald.service" {
+		t.Errorf("unexpected drop-in: %+v", dropIn)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != content {
+		t.Errorf("expected the drop-in to be written, got %q: %v", b, err)
+	}
+	if service.Restarts("systemd-journald.service") != 1 {
+		t.Errorf("expected journald to be restarted once, got %d", service.Restarts("systemd-journald.service"))
+	}
+
+	// Rewriting the same content doesn't restart journald again
+	dropIn, err = client.WriteConfigDropIn(ctx, "journald", "50-retention", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropIn.Restarted != "" || service.Restarts("systemd-journald.service") != 1 {
+		t.Errorf("expected an unchanged drop-in not to restart journald, got %+v", dropIn)
+	}
+
+	dropIn, err = client.GetConfigDropIn(ctx, "journald", "50-retention")
+	if err != nil || dropIn.Content != content {
+		t.Errorf("unexpected drop-in %+v: %v", dropIn, err)
+	}
+
+	// coredump.conf is read on each crash, so there is nothing to restart
+	dropIn, err = client.WriteConfigDropIn(ctx, "coredump", "50-storage", "[Coredump]\nStorage=none\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropIn.Restarted != "" {
+		t.Errorf("expected nothing to be restarted, got %s", dropIn.Restarted)
+	}
+
+	if err := client.DeleteConfigDropIn(ctx, "journald", "50-retention"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the empty drop-in directory to be removed, got %v", err)
+	}
+	if service.Restarts("systemd-journald.service") != 2 {
+		t.Errorf("expected journald to be restarted after the delete, got %d", service.Restarts("systemd-journald.service"))
+	}
+	if _, err := client.GetConfigDropIn(ctx, "journald", "50-retention"); !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected the drop-in to be gone, got %v", err)
+	}
+	if err := client.DeleteConfigDropIn(ctx, "journald", "50-retention"); !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected deleting a missing drop-in to fail, got %v", err)
+	}
+}
+
+func TestConfigDropInsStoppedUnit(t *testing.T) {
+	systemd, service, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("systemd-journald.service")))
+	client := NewConfigDropIns(systemd, t.TempDir())
+
+	dropIn, err := client.WriteConfigDropIn(context.Background(), "journald", "50-storage", "[Journal]\nStorage=volatile\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropIn.Restarted != "" || service.Restarts("systemd-journald.service") != 0 {
+		t.Errorf("expected a stopped journald not to be started, got %+v", dropIn)
+	}
+}
+
+func TestConfigDropInsValidation(t *testing.T) {
+	systemd, _, _ := newTestClient(t)
+	client := NewConfigDropIns(systemd, t.TempDir())
+
+	tests := []struct {
+		name    string
+		file    string
+		dropIn  string
+		content string
+		err     error
+	}{
+		{name: "unknown file", file: "logind", dropIn: "override", content: "[Login]\n", err: bus.ErrNotFound},
+		{name: "escaped path", file: "journald", dropIn: "../../passwd", content: "[Journal]\n", err: bus.ErrInvalid},
+		{name: "wrong section", file: "journald", dropIn: "override", content: "[Coredump]\nStorage=none\n", err: bus.ErrInvalid},
+		{name: "unknown key", file: "journald", dropIn: "override", content: "[Journal]\nSystemMaxUsage=1G\n", err: bus.ErrInvalid},
+		{name: "invalid size", file: "journald", dropIn: "override", content: "[Journal]\nSystemMaxUse=lots\n", err: bus.ErrInvalid},
+		{name: "invalid storage", file: "coredump", dropIn: "override", content: "[Coredump]\nStorage=persistent\n", err: bus.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.WriteConfigDropIn(context.Background(), tt.file, tt.dropIn, tt.content)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
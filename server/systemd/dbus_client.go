@@ -0,0 +1,575 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+// DefaultUnitDir is where administrators' unit files live, taking precedence over those installed by packages
+const DefaultUnitDir = "/etc/systemd/system"
+
+// The polkit actions which guard the manager methods used by the agent
+const (
+	reloadAction          = "org.freedesktop.systemd1.reload-daemon"
+	manageUnitsAction     = "org.freedesktop.systemd1.manage-units"
+	manageUnitFilesAction = "org.freedesktop.systemd1.manage-unit-files"
+)
+
+func init() {
+	bus.RegisterAction(prefix+"Reload", reloadAction)
+	for _, m := range []string{"StartUnit", "StopUnit", "RestartUnit", "SetUnitProperties"} {
+		bus.RegisterAction(prefix+m, manageUnitsAction)
+	}
+	for _, m := range []string{"EnableUnitFiles", "DisableUnitFiles", "MaskUnitFiles", "UnmaskUnitFiles"} {
+		bus.RegisterAction(prefix+m, manageUnitFilesAction)
+	}
+}
+
+var (
+	destination = "org.freedesktop.systemd1"
+	pathname    = "/org/freedesktop/systemd1"
+	iface       = "org.freedesktop.systemd1.Manager"
+	prefix      = iface + "."
+
+	unitInterface    = "org.freedesktop.systemd1.Unit"
+	serviceInterface = "org.freedesktop.systemd1.Service"
+	timerInterface   = "org.freedesktop.systemd1.Timer"
+)
+
+type SystemdDbusClient struct {
+	sup     *bus.Supervisor
+	log     *zerolog.Logger
+	backend *bus.Backend
+	unitDir string
+	jobs    *jobTracker
+	// journal reads the logs of units which fail, it is nil if the journal can't be read
+	journal journal.JournalClient
+}
+
+// NewSystemdClient returns a client managing unit files in unitDir, which is enabled once systemd is on the bus
+// Only a missing unit directory is an error, until systemd is reachable every call returns a bus.UnavailableError.
+// The journal client, which may be nil, reads the logs included when a unit fails.
+func NewSystemdClient(sup *bus.Supervisor, unitDir string, journalClient journal.JournalClient) (*SystemdDbusClient, error) {
+	log := middleware.Logger()
+	log.Info().Msg("Initializing systemd DBus connection")
+	info, err := os.Stat(unitDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("unit directory %s is not a directory", unitDir)
+	}
+
+	log = log.With().Str("unit_dir", unitDir).Logger()
+	client := &SystemdDbusClient{sup: sup, log: &log, unitDir: unitDir, jobs: newJobTracker(), journal: journalClient}
+	err = sup.Subscribe(client.jobs.handle, bus.Match{
+		Sender:    destination,
+		Path:      dbus.ObjectPath(pathname),
+		Interface: iface,
+		Member:    "JobRemoved",
+	})
+	if err != nil {
+		return nil, err
+	}
+	// systemd only emits signals while a client is subscribed, which it forgets when the client disconnects
+	sup.OnResync(client.resubscribe)
+	client.backend = bus.NewBackend(&log, sup, destination, client.init)
+	return client, nil
+}
+
+// UnitFilesWritable returns an error unless the agent can write unit files and drop-ins to the unit directory
+func (c *SystemdDbusClient) UnitFilesWritable() error {
+	return writable(c.unitDir)
+}
+
+// UnitFilesCapability reports writing unit files as enabled while the unit directory is writable
+func (c *SystemdDbusClient) UnitFilesCapability() common.ModuleCapability {
+	return fileCapability(c.UnitFilesWritable)
+}
+
+// init subscribes to the manager's signals, and returns its version
+func (c *SystemdDbusClient) init(ctx context.Context) (string, error) {
+	if err := c.subscribe(ctx); err != nil {
+		return "", err
+	}
+	return c.version(ctx)
+}
+
+// resubscribe renews the subscription after a reconnect or a restart of systemd, once the backend is enabled
+func (c *SystemdDbusClient) resubscribe() {
+	if !c.backend.Enabled() || c.sup.Available(destination) != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), common.DefaultRequestTimeout)
+	defer cancel()
+	if err := c.subscribe(ctx); err != nil {
+		c.log.Error().Err(err).Msg("Failed to subscribe to systemd signals")
+	}
+}
+
+func (c *SystemdDbusClient) subscribe(ctx context.Context) error {
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return err
+	}
+	return bus.Call(ctx, conn.Object(destination, dbus.ObjectPath(pathname)), prefix+"Subscribe", 0).Err
+}
+
+// Capability reports whether the module is enabled, i.e. systemd has been on the bus since the agent started
+func (c *SystemdDbusClient) Capability() common.ModuleCapability {
+	return c.backend.Capability()
+}
+
+// object resolves path against the current connection, so that calls survive a bus restart
+func (c *SystemdDbusClient) object(path dbus.ObjectPath) (dbus.BusObject, error) {
+	if err := c.backend.Ready(); err != nil {
+		return nil, err
+	}
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Object(destination, path), nil
+}
+
+// unitPath returns the location of the named unit file, rejecting any name which could escape the unit directory
+func (c *SystemdDbusClient) unitPath(name string) (string, error) {
+	if len(name) > common.MaxUnitNameLength || !common.UnitNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid unit name %q: %w", name, bus.ErrInvalid)
+	}
+	return filepath.Join(c.unitDir, name), nil
+}
+
+func (c *SystemdDbusClient) GetUnitFile(ctx context.Context, name string) (UnitFile, error) {
+	path, err := c.unitPath(name)
+	if err != nil {
+		return UnitFile{}, err
+	}
+	// Only regular files are unit files managed by the agent, symlinks are masked or linked units
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return UnitFile{}, fmt.Errorf("unit file %s: %w", name, bus.ErrNotFound)
+	}
+	if err != nil {
+		return UnitFile{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return UnitFile{}, err
+	}
+	return UnitFile{Name: name, Path: path, Content: string(content)}, nil
+}
+
+func (c *SystemdDbusClient) WriteUnitFile(ctx context.Context, name string, content string) (UnitFile, error) {
+	path, err := c.unitPath(name)
+	if err != nil {
+		return UnitFile{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return UnitFile{}, err
+	}
+	if err := writeFile(path, content); err != nil {
+		return UnitFile{}, err
+	}
+	c.log.Info().Str("name", name).Msg("Wrote unit file")
+
+	if err := c.Reload(ctx); err != nil {
+		return UnitFile{}, err
+	}
+	return UnitFile{Name: name, Path: path, Content: content}, nil
+}
+
+func (c *SystemdDbusClient) DeleteUnitFile(ctx context.Context, name string) error {
+	if _, err := c.GetUnitFile(ctx, name); err != nil {
+		return err
+	}
+	path, _ := c.unitPath(name)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	c.log.Info().Str("name", name).Msg("Deleted unit file")
+	return c.Reload(ctx)
+}
+
+// loadUnit returns the object of the named unit, or an error wrapping bus.ErrNotFound if systemd has no such unit
+func (c *SystemdDbusClient) loadUnit(ctx context.Context, name string) (dbus.BusObject, error) {
+	if _, err := c.unitPath(name); err != nil {
+		return nil, err
+	}
+	manager, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return nil, err
+	}
+	var path dbus.ObjectPath
+	err = bus.Call(ctx, manager, prefix+"LoadUnit", 0, name).Store(&path)
+	if errName, ok := bus.ErrorName(err); ok && errName == "org.freedesktop.systemd1.NoSuchUnit" {
+		return nil, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.object(path)
+}
+
+func (c *SystemdDbusClient) GetUnitState(ctx context.Context, name string) (UnitState, error) {
+	obj, err := c.loadUnit(ctx, name)
+	if err != nil {
+		return UnitState{}, err
+	}
+	state, err := bus.DecodeAll[UnitState](ctx, c.log, obj, unitInterface)
+	if err != nil {
+		return UnitState{}, err
+	}
+	if state.LoadState == "not-found" {
+		return UnitState{}, fmt.Errorf("unit %s: %w", name, bus.ErrNotFound)
+	}
+	if strings.HasSuffix(name, ".service") {
+		service, err := bus.DecodeAll[serviceProperties](ctx, c.log, obj, serviceInterface)
+		if err != nil {
+			return UnitState{}, err
+		}
+		state.Result = service.Result
+	}
+	return state, nil
+}
+
+func (c *SystemdDbusClient) GetTimer(ctx context.Context, name string) (TimerState, error) {
+	if !strings.HasSuffix(name, ".timer") {
+		return TimerState{}, fmt.Errorf("unit %s is not a timer: %w", name, bus.ErrInvalid)
+	}
+	// Checks that the timer is loaded, since systemd also returns the properties of units it can't find
+	if _, err := c.GetUnitState(ctx, name); err != nil {
+		return TimerState{}, err
+	}
+	obj, err := c.loadUnit(ctx, name)
+	if err != nil {
+		return TimerState{}, err
+	}
+	timer, err := bus.DecodeAll[TimerState](ctx, c.log, obj, timerInterface)
+	if err != nil {
+		return TimerState{}, err
+	}
+	timer.Name = name
+	return timer, nil
+}
+
+// cgroupInterface returns the interface of the unit's type, which holds its cgroup properties, e.g.
+// org.freedesktop.systemd1.Service
+func cgroupInterface(name string) (string, error) {
+	if !common.HasCgroup(name) {
+		return "", fmt.Errorf("unit %s has no cgroup: %w", name, bus.ErrInvalid)
+	}
+	suffix := name[strings.LastIndex(name, ".")+1:]
+	return "org.freedesktop.systemd1." + strings.ToUpper(suffix[:1]) + suffix[1:], nil
+}
+
+func (c *SystemdDbusClient) GetUnitResources(ctx context.Context, name string) (UnitResources, error) {
+	iface, err := cgroupInterface(name)
+	if err != nil {
+		return UnitResources{}, err
+	}
+	// Checks that the unit is loaded, since systemd also returns the properties of units it can't find
+	if _, err := c.GetUnitState(ctx, name); err != nil {
+		return UnitResources{}, err
+	}
+	obj, err := c.loadUnit(ctx, name)
+	if err != nil {
+		return UnitResources{}, err
+	}
+	resources, err := bus.DecodeAll[UnitResources](ctx, c.log, obj, iface)
+	if err != nil {
+		return UnitResources{}, err
+	}
+	resources.Name = name
+	return resources, nil
+}
+
+// unitProperty is a single entry of the a(sv) array taken by SetUnitProperties
+type unitProperty struct {
+	Name  string
+	Value dbus.Variant
+}
+
+func (c *SystemdDbusClient) SetUnitResources(ctx context.Context, name string, change ResourceControlChange) (UnitResources, error) {
+	if _, err := c.GetUnitResources(ctx, name); err != nil {
+		return UnitResources{}, err
+	}
+	var props []unitProperty
+	for _, p := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"MemoryMax", change.MemoryMax},
+		{"CPUQuotaPerSecUSec", change.CPUQuotaPerSecUSec},
+		{"IOWeight", change.IOWeight},
+		{"TasksMax", change.TasksMax},
+	} {
+		if p.value != nil {
+			props = append(props, unitProperty{Name: p.name, Value: dbus.MakeVariant(*p.value)})
+		}
+	}
+	if len(props) > 0 {
+		manager, err := c.object(dbus.ObjectPath(pathname))
+		if err != nil {
+			return UnitResources{}, err
+		}
+		err = bus.Call(ctx, manager, prefix+"SetUnitProperties", 0, name, change.Runtime, props).Err
+		if errName, ok := bus.ErrorName(err); ok && errName == "org.freedesktop.DBus.Error.InvalidArgs" {
+			return UnitResources{}, fmt.Errorf("cannot set resources of unit %s: %s: %w", name, err, bus.ErrInvalid)
+		}
+		if err != nil {
+			return UnitResources{}, err
+		}
+		c.log.Info().Str("name", name).Bool("runtime", change.Runtime).Msg("Changed unit resources")
+	}
+	return c.GetUnitResources(ctx, name)
+}
+
+// serviceProperties are the properties of the Service interface used by the agent
+type serviceProperties struct {
+	Result  string `dbus:"Result,optional"`
+	MainPID uint32 `dbus:"MainPID,optional"`
+}
+
+// unitProperties are the properties of the Unit interface which ListUnitsByPatterns doesn't return
+type unitProperties struct {
+	FragmentPath string `dbus:"FragmentPath,optional"`
+}
+
+// listedUnit is a single entry of the array returned by ListUnitsByPatterns
+type listedUnit struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Following   string
+	Path        dbus.ObjectPath
+	JobID       uint32
+	JobType     string
+	JobPath     dbus.ObjectPath
+}
+
+func (c *SystemdDbusClient) ListUnits(ctx context.Context, states []string, patterns []string) ([]UnitStatus, error) {
+	manager, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return nil, err
+	}
+	var listed []listedUnit
+	if states == nil {
+		states = []string{}
+	}
+	if patterns == nil {
+		patterns = []string{}
+	}
+	err = bus.Call(ctx, manager, prefix+"ListUnitsByPatterns", 0, states, patterns).Store(&listed)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]UnitStatus, 0, len(listed))
+	for _, l := range listed {
+		unit := UnitStatus{Name: l.Name, Description: l.Description, LoadState: l.LoadState, ActiveState: l.ActiveState, SubState: l.SubState}
+		if err := c.unitDetails(ctx, l.Path, &unit); err != nil {
+			// The unit may have been garbage collected since it was listed
+			if errName, ok := bus.ErrorName(err); ok && errName == "org.freedesktop.DBus.Error.UnknownObject" {
+				c.log.Debug().Str("name", l.Name).Msg("Unit disappeared while listing units")
+				continue
+			}
+			return nil, err
+		}
+		units = append(units, unit)
+	}
+	return units, nil
+}
+
+// unitDetails fills in the properties of a listed unit which need a call to the unit's own object
+func (c *SystemdDbusClient) unitDetails(ctx context.Context, path dbus.ObjectPath, unit *UnitStatus) error {
+	obj, err := c.object(path)
+	if err != nil {
+		return err
+	}
+	props, err := bus.DecodeAll[unitProperties](ctx, c.log, obj, unitInterface)
+	if err != nil {
+		return err
+	}
+	unit.FragmentPath = props.FragmentPath
+	if strings.HasSuffix(unit.Name, ".service") {
+		service, err := bus.DecodeAll[serviceProperties](ctx, c.log, obj, serviceInterface)
+		if err != nil {
+			return err
+		}
+		unit.MainPID = service.MainPID
+	}
+	return nil
+}
+
+func (c *SystemdDbusClient) SetUnitState(ctx context.Context, name string, change UnitStateChange) (UnitState, error) {
+	state, err := c.GetUnitState(ctx, name)
+	if err != nil {
+		return UnitState{}, err
+	}
+	manager, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return UnitState{}, err
+	}
+
+	// Stop before masking, and only start once the unit file is unmasked and enabled
+	if change.Stops() && state.Active() {
+		if err := c.runJob(ctx, "StopUnit", "stop", name); err != nil {
+			return UnitState{}, err
+		}
+	}
+	reload := false
+	if change.Masked != nil && *change.Masked != state.Masked() {
+		if *change.Masked {
+			err = bus.Call(ctx, manager, prefix+"MaskUnitFiles", 0, []string{name}, false, true).Err
+		} else {
+			err = bus.Call(ctx, manager, prefix+"UnmaskUnitFiles", 0, []string{name}, false).Err
+		}
+		if err != nil {
+			return UnitState{}, err
+		}
+		reload = true
+	}
+	if change.Enabled != nil && *change.Enabled != state.Enabled() {
+		if *change.Enabled {
+			var carriesInstallInfo bool
+			var changes []unitFileChange
+			err = bus.Call(ctx, manager, prefix+"EnableUnitFiles", 0, []string{name}, false, true).Store(&carriesInstallInfo, &changes)
+			if err == nil && !carriesInstallInfo {
+				err = fmt.Errorf("unit %s has no [Install] section, so it cannot be enabled: %w", name, bus.ErrInvalid)
+			}
+		} else {
+			var changes []unitFileChange
+			err = bus.Call(ctx, manager, prefix+"DisableUnitFiles", 0, []string{name}, false).Store(&changes)
+		}
+		if err != nil {
+			return UnitState{}, err
+		}
+		reload = true
+	}
+	if reload {
+		if err := c.Reload(ctx); err != nil {
+			return UnitState{}, err
+		}
+	}
+	if change.Active != nil && *change.Active && !state.Active() {
+		if err := c.runJob(ctx, "StartUnit", "start", name); err != nil {
+			return UnitState{}, err
+		}
+	}
+	c.log.Info().Str("name", name).Msg("Changed unit state")
+	return c.GetUnitState(ctx, name)
+}
+
+// unitFileChange is a change made by the Enable, Disable, Mask or Unmask methods, e.g. a symlink being created
+type unitFileChange struct {
+	Type        string
+	Filename    string
+	Destination string
+}
+
+func (c *SystemdDbusClient) RestartUnit(ctx context.Context, name string) (UnitState, error) {
+	if _, err := c.GetUnitState(ctx, name); err != nil {
+		return UnitState{}, err
+	}
+	if err := c.runJob(ctx, "RestartUnit", "restart", name); err != nil {
+		return UnitState{}, err
+	}
+	c.log.Info().Str("name", name).Msg("Restarted unit")
+	return c.GetUnitState(ctx, name)
+}
+
+// runJob queues a job with the given manager method, and waits for it to finish
+// A job which doesn't complete is returned as a UnitFailedError, with the unit's result and its last journal lines
+func (c *SystemdDbusClient) runJob(ctx context.Context, method string, operation string, name string) error {
+	manager, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return err
+	}
+	var job dbus.ObjectPath
+	err = bus.Call(ctx, manager, prefix+method, 0, name, "replace").Store(&job)
+	if err != nil {
+		return err
+	}
+	result, err := c.jobs.wait(ctx, job)
+	if err != nil {
+		return err
+	}
+	if result == "done" {
+		return nil
+	}
+
+	failed := &UnitFailedError{Unit: name, Operation: operation, JobResult: result}
+	if state, err := c.GetUnitState(ctx, name); err == nil {
+		failed.Result = state.Result
+	}
+	failed.Journal, err = c.journalTail(ctx, name, failureJournalLines)
+	if err != nil {
+		c.log.Warn().Err(err).Str("name", name).Msg("Cannot read the journal of the failed unit")
+	}
+	return failed
+}
+
+// Reload reloads the manager configuration, equivalent to systemctl daemon-reload
+func (c *SystemdDbusClient) Reload(ctx context.Context) error {
+	obj, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return err
+	}
+	return bus.Call(ctx, obj, prefix+"Reload", 0).Err
+}
+
+func (c *SystemdDbusClient) Version(ctx context.Context) (string, error) {
+	if err := c.backend.Ready(); err != nil {
+		return "", err
+	}
+	return c.version(ctx)
+}
+
+// version reads the manager's version, without waiting for the backend to be enabled
+func (c *SystemdDbusClient) version(ctx context.Context) (string, error) {
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return "", err
+	}
+	return bus.Decode[string](ctx, c.log, conn.Object(destination, dbus.ObjectPath(pathname)), prefix+"Version")
+}
+
+// writeFile atomically replaces the file at path, so that systemd never reads a partially written unit
+func writeFile(path string, content string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,540 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/dbustest"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakesystemd"
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+const exampleUnit = "[Unit]\nDescription=Example\n\n[Service]\nExecStart=/bin/true\n"
+
+func init() {
+	middleware.SetupLogging(io.Discard, zerolog.Disabled)
+}
+
+// newTestClient starts a private bus with the fake systemd manager and returns a client connected to it,
+// managing unit files in a temporary directory
+func newTestClient(t *testing.T, opts ...fakesystemd.Option) (SystemdClient, *fakesystemd.Service, string) {
+	t.Helper()
+	b := dbustest.NewBus(t)
+	service := fakesystemd.Start(t, b.Connect(t), opts...)
+	dir := t.TempDir()
+	client, err := NewSystemdClient(b.Supervisor(t), dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, service, dir
+}
+
+func TestVersion(t *testing.T) {
+	client, _, _ := newTestClient(t, fakesystemd.WithVersion("256.1"))
+	version, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "256.1" {
+		t.Errorf("expected version 256.1, got %s", version)
+	}
+}
+
+func TestSystemdStartsAfterAgent(t *testing.T) {
+	b := dbustest.NewBus(t)
+	client, err := NewSystemdClient(b.Supervisor(t), t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Capability().Enabled {
+		t.Fatal("expected the module to be disabled until systemd starts")
+	}
+	_, err = client.ListUnits(context.Background(), nil, nil)
+	if _, ok := bus.IsUnavailable(err); !ok {
+		t.Fatalf("expected systemd to be unavailable, got %v", err)
+	}
+
+	fakesystemd.Start(t, b.Connect(t), fakesystemd.WithVersion("256.1"))
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Capability().Enabled {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the module to be enabled once systemd starts")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if version := client.Capability().Version; version != "256.1" {
+		t.Errorf("expected version 256.1, got %s", version)
+	}
+}
+
+func TestNewSystemdClientMissingUnitDir(t *testing.T) {
+	b := dbustest.NewBus(t)
+	fakesystemd.Start(t, b.Connect(t))
+	_, err := NewSystemdClient(b.Supervisor(t), filepath.Join(t.TempDir(), "missing"), nil)
+	if err == nil {
+		t.Fatal("expected an error for a missing unit directory")
+	}
+}
+
+func TestUnitFileLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client, service, dir := newTestClient(t)
+
+	_, err := client.GetUnitFile(ctx, "example.service")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	unit, err := client.WriteUnitFile(ctx, "example.service", exampleUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "example.service")
+	if unit.Path != path {
+		t.Errorf("expected path %s, got %s", path, unit.Path)
+	}
+	if service.Reloads() != 1 {
+		t.Errorf("expected the manager to be reloaded once, got %d", service.Reloads())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("expected mode 0644, got %s", info.Mode())
+	}
+
+	// Changes made on the host are visible, so that the provider can detect drift
+	if err := os.WriteFile(path, []byte("[Unit]\nDescription=Edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unit, err = client.GetUnitFile(ctx, "example.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unit.Content != "[Unit]\nDescription=Edited\n" {
+		t.Errorf("expected the edited content, got %q", unit.Content)
+	}
+
+	if err := client.DeleteUnitFile(ctx, "example.service"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the unit file to be removed, got %v", err)
+	}
+	if service.Reloads() != 2 {
+		t.Errorf("expected the manager to be reloaded twice, got %d", service.Reloads())
+	}
+	err = client.DeleteUnitFile(ctx, "example.service")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestUnitFileIgnoresSymlinks(t *testing.T) {
+	client, _, dir := newTestClient(t)
+	if err := os.Symlink("/dev/null", filepath.Join(dir, "masked.service")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.GetUnitFile(context.Background(), "masked.service")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestUnitFileInvalidName(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	for _, name := range []string{"../passwd", "example", "example.conf", "a/b.service", ""} {
+		_, err := client.WriteUnitFile(context.Background(), name, exampleUnit)
+		if !errors.Is(err, bus.ErrInvalid) {
+			t.Errorf("expected an invalid name error for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestReloadDenied(t *testing.T) {
+	client, service, _ := newTestClient(t)
+	service.Fail("Reload", dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"denied"}))
+
+	_, err := client.WriteUnitFile(context.Background(), "example.service", exampleUnit)
+	authErr, ok := bus.IsAuthorizationError(err)
+	if !ok {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if authErr.Action != reloadAction {
+		t.Errorf("expected action %s, got %s", reloadAction, authErr.Action)
+	}
+}
+
+func TestUnitStateLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client, service, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("example.service")))
+	if service.Subscribers() != 1 {
+		t.Errorf("expected the client to subscribe to manager signals, got %d subscriptions", service.Subscribers())
+	}
+
+	state, err := client.GetUnitState(ctx, "example.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Enabled() || state.Active() || state.Masked() || state.Result != "success" {
+		t.Errorf("expected an inactive, disabled unit, got %+v", state)
+	}
+
+	enable := true
+	state, err = client.SetUnitState(ctx, "example.service", UnitStateChange{Enabled: &enable, Active: &enable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Enabled() || !state.Active() || state.SubState != "running" {
+		t.Errorf("expected an active, enabled unit, got %+v", state)
+	}
+	if service.Reloads() != 1 {
+		t.Errorf("expected the manager to be reloaded once, got %d", service.Reloads())
+	}
+
+	state, err = client.RestartUnit(ctx, "example.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Active() || service.Restarts("example.service") != 1 {
+		t.Errorf("expected the unit to be restarted, got %+v", state)
+	}
+
+	// Masking stops the unit first, and unchanged fields don't touch the unit files
+	mask := true
+	stop := false
+	state, err = client.SetUnitState(ctx, "example.service", UnitStateChange{Active: &stop, Masked: &mask})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Active() || !state.Masked() {
+		t.Errorf("expected an inactive, masked unit, got %+v", state)
+	}
+	if service.Reloads() != 2 {
+		t.Errorf("expected the manager to be reloaded twice, got %d", service.Reloads())
+	}
+}
+
+func TestUnitStateNotFound(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	_, err := client.GetUnitState(context.Background(), "missing.service")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestUnitStateNoInstallSection(t *testing.T) {
+	unit := fakesystemd.NewUnit("example.service")
+	unit.NoInstall = true
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(unit))
+
+	enable := true
+	_, err := client.SetUnitState(context.Background(), "example.service", UnitStateChange{Enabled: &enable})
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid request, got %v", err)
+	}
+}
+
+func TestUnitStartFailure(t *testing.T) {
+	unit := fakesystemd.NewUnit("example.service")
+	unit.FailStart = "exit-code"
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(unit))
+	logs := &unitJournal{entries: []journal.Entry{{Identifier: "systemd", Message: "Main process exited, code=exited, status=1/FAILURE"}}}
+	client.(*SystemdDbusClient).journal = logs
+
+	start := true
+	_, err := client.SetUnitState(context.Background(), "example.service", UnitStateChange{Active: &start})
+	var failed *UnitFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected a unit failure, got %v", err)
+	}
+	if failed.Operation != "start" || failed.JobResult != "failed" || failed.Result != "exit-code" {
+		t.Errorf("unexpected failure: %+v", failed)
+	}
+	if len(failed.Journal) != 1 {
+		t.Errorf("expected the journal lines to be included, got %v", failed.Journal)
+	}
+	if !slices.Equal(logs.query.Units, []string{"example.service"}) || logs.query.Lines != failureJournalLines {
+		t.Errorf("expected the unit's last %d journal lines to be read, got %+v", failureJournalLines, logs.query)
+	}
+}
+
+// unitJournal is a journal client which returns the same entries for every query, recording the last one
+type unitJournal struct {
+	entries []journal.Entry
+	query   journal.Query
+}
+
+func (j *unitJournal) Entries(ctx context.Context, query journal.Query) ([]journal.Entry, error) {
+	j.query = query
+	return j.entries, nil
+}
+
+func (j *unitJournal) Version(ctx context.Context) (string, error) {
+	return "256", nil
+}
+
+func TestUnitStateDenied(t *testing.T) {
+	client, service, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("example.service")))
+	service.Fail("StartUnit", dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"denied"}))
+
+	start := true
+	_, err := client.SetUnitState(context.Background(), "example.service", UnitStateChange{Active: &start})
+	authErr, ok := bus.IsAuthorizationError(err)
+	if !ok {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if authErr.Action != manageUnitsAction {
+		t.Errorf("expected action %s, got %s", manageUnitsAction, authErr.Action)
+	}
+}
+
+func TestDropInLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client, service, dir := newTestClient(t)
+
+	_, err := client.GetDropIn(ctx, "example.service", "override")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	content := "[Service]\nEnvironment=DEBUG=1\n"
+	dropIn, err := client.WriteDropIn(ctx, "example.service", "override", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "example.service.d", "override.conf")
+	if dropIn.Path != path {
+		t.Errorf("expected path %s, got %s", path, dropIn.Path)
+	}
+	if len(dropIn.Shadowed) != 0 {
+		t.Errorf("expected no shadowed settings for a unit systemd doesn't know, got %v", dropIn.Shadowed)
+	}
+	if service.Reloads() != 1 {
+		t.Errorf("expected the manager to be reloaded once, got %d", service.Reloads())
+	}
+	dropIn, err = client.GetDropIn(ctx, "example.service", "override")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropIn.Content != content {
+		t.Errorf("expected %q, got %q", content, dropIn.Content)
+	}
+
+	if err := client.DeleteDropIn(ctx, "example.service", "override"); err != nil {
+		t.Fatal(err)
+	}
+	// The empty directory is removed along with the last drop-in
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("expected the drop-in directory to be removed, got %v", err)
+	}
+	err = client.DeleteDropIn(ctx, "example.service", "override")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestDropInShadowed(t *testing.T) {
+	ctx := context.Background()
+	client, service, dir := newTestClient(t)
+
+	ours := filepath.Join(dir, "example.service.d", "50-limits.conf")
+	earlier := filepath.Join(dir, "example.service.d", "10-vendor.conf")
+	later := filepath.Join(t.TempDir(), "example.service.d", "90-local.conf")
+	// A file with the same name in a directory of higher priority replaces ours
+	replaced := filepath.Join(t.TempDir(), "example.service.d", "50-limits.conf")
+	for path, content := range map[string]string{
+		earlier:  "[Service]\nMemoryMax=1G\n",
+		later:    "[Service]\nMemoryMax=2G\nMemoryMax=3G\nCPUQuota=50%\n",
+		replaced: "[Service]\nTasksMax=10\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unit := fakesystemd.NewUnit("example.service")
+	unit.DropInPaths = []string{earlier, ours, replaced, later}
+	if err := service.AddUnit(unit); err != nil {
+		t.Fatal(err)
+	}
+
+	dropIn, err := client.WriteDropIn(ctx, "example.service", "50-limits", "[Service]\nMemoryMax=512M\nTasksMax=100\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ShadowedSetting{
+		{Path: replaced, Section: "Service", Key: "TasksMax"},
+		{Path: later, Section: "Service", Key: "MemoryMax"},
+	}
+	if !slices.Equal(dropIn.Shadowed, expected) {
+		t.Errorf("expected shadowed settings %v, got %v", expected, dropIn.Shadowed)
+	}
+}
+
+func TestDropInInvalidName(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	for _, name := range []string{"../override", "a/b", ".hidden", ""} {
+		_, err := client.WriteDropIn(context.Background(), "example.service", name, exampleUnit)
+		if !errors.Is(err, bus.ErrInvalid) {
+			t.Errorf("expected an invalid name error for %q, got %v", name, err)
+		}
+	}
+	_, err := client.WriteDropIn(context.Background(), "../passwd", "override", exampleUnit)
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid unit name error, got %v", err)
+	}
+}
+
+func TestGetTimer(t *testing.T) {
+	ctx := context.Background()
+	timer := fakesystemd.NewUnit("backup.timer")
+	timer.Trigger = "backup.service"
+	timer.NextElapse = uint64(time.Date(2030, 1, 1, 4, 0, 0, 0, time.UTC).UnixMicro())
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(timer, fakesystemd.NewUnit("backup.service")))
+
+	state, err := client.GetTimer(ctx, "backup.timer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Unit != "backup.service" || !state.NextElapse.Equal(time.Date(2030, 1, 1, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected timer state: %+v", state)
+	}
+	if !state.LastTrigger.IsZero() {
+		t.Errorf("expected a timer which never elapsed to have no last trigger, got %s", state.LastTrigger)
+	}
+
+	_, err = client.GetTimer(ctx, "backup.service")
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid request for a service, got %v", err)
+	}
+	_, err = client.GetTimer(ctx, "missing.timer")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestListUnits(t *testing.T) {
+	ctx := context.Background()
+	web := fakesystemd.NewUnit("web.service")
+	web.Description, web.FragmentPath = "Web server", "/etc/systemd/system/web.service"
+	web.ActiveState, web.SubState, web.MainPID = "active", "running", 4242
+	broken := fakesystemd.NewUnit("broken.service")
+	broken.ActiveState, broken.SubState = "failed", "failed"
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(web, broken, fakesystemd.NewUnit("backup.timer")))
+
+	units, err := client.ListUnits(ctx, nil, []string{"*.service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 2 {
+		t.Fatalf("expected 2 services, got %+v", units)
+	}
+	expected := UnitStatus{
+		Name: "web.service", Description: "Web server", LoadState: "loaded", ActiveState: "active", SubState: "running",
+		FragmentPath: "/etc/systemd/system/web.service", MainPID: 4242,
+	}
+	if units[1] != expected {
+		t.Errorf("expected %+v, got %+v", expected, units[1])
+	}
+
+	units, err = client.ListUnits(ctx, []string{"failed"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 1 || units[0].Name != "broken.service" || units[0].MainPID != 0 {
+		t.Errorf("expected only the failed unit, got %+v", units)
+	}
+
+	units, err = client.ListUnits(ctx, []string{"running"}, []string{"*.timer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 0 {
+		t.Errorf("expected no units, got %+v", units)
+	}
+}
+
+func TestUnitResources(t *testing.T) {
+	ctx := context.Background()
+	web := fakesystemd.NewUnit("web.service")
+	web.Resources.ControlGroup = "/system.slice/web.service"
+	web.Resources.MemoryCurrent, web.Resources.TasksCurrent = 64<<20, 12
+	client, service, _ := newTestClient(t, fakesystemd.WithUnits(web, fakesystemd.NewUnit("app.slice"), fakesystemd.NewUnit("backup.timer")))
+
+	resources, err := client.GetUnitResources(ctx, "web.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources.Name != "web.service" || resources.ControlGroup != "/system.slice/web.service" || resources.MemoryCurrent != 64<<20 ||
+		resources.TasksCurrent != 12 || resources.MemoryMax != math.MaxUint64 || resources.CPUUsageNSec != math.MaxUint64 {
+		t.Errorf("unexpected resources: %+v", resources)
+	}
+
+	memory, quota, unset := uint64(512<<20), uint64(500000), uint64(math.MaxUint64)
+	resources, err = client.SetUnitResources(ctx, "web.service", ResourceControlChange{MemoryMax: &memory, CPUQuotaPerSecUSec: &quota, TasksMax: &unset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources.MemoryMax != memory || resources.CPUQuotaPerSecUSec != quota || resources.IOWeight != math.MaxUint64 {
+		t.Errorf("expected the limits to be set, got %+v", resources)
+	}
+	if unit, _ := service.Unit("web.service"); unit.RuntimeResources {
+		t.Error("expected the limits to be persisted")
+	}
+
+	weight := uint64(200)
+	resources, err = client.SetUnitResources(ctx, "app.slice", ResourceControlChange{Runtime: true, IOWeight: &weight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources.IOWeight != weight {
+		t.Errorf("expected the slice's IO weight to be set, got %+v", resources)
+	}
+	if unit, _ := service.Unit("app.slice"); !unit.RuntimeResources {
+		t.Error("expected a runtime only change")
+	}
+
+	weight = 20000
+	_, err = client.SetUnitResources(ctx, "app.slice", ResourceControlChange{IOWeight: &weight})
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid request for an out of range weight, got %v", err)
+	}
+	_, err = client.GetUnitResources(ctx, "backup.timer")
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid request for a timer, got %v", err)
+	}
+	_, err = client.GetUnitResources(ctx, "missing.service")
+	if !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestUnitResourcesDenied(t *testing.T) {
+	client, service, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("web.service")))
+	service.Fail("SetUnitProperties", dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"denied"}))
+
+	memory := uint64(512 << 20)
+	_, err := client.SetUnitResources(context.Background(), "web.service", ResourceControlChange{MemoryMax: &memory})
+	authErr, ok := bus.IsAuthorizationError(err)
+	if !ok {
+		t.Fatalf("expected an authorization error, got %v", err)
+	}
+	if authErr.Action != manageUnitsAction {
+		t.Errorf("expected action %s, got %s", manageUnitsAction, authErr.Action)
+	}
+}
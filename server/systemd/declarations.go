@@ -0,0 +1,238 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+// declarations are the files in a directory such as /etc/tmpfiles.d, which a systemd tool applies as soon as they are
+// written, e.g. systemd-tmpfiles
+type declarations struct {
+	log *zerolog.Logger
+	// root is the directory the tool operates on, / other than in tests
+	root string
+	dir  string
+	// writes are the other paths below root which the tool writes, e.g. /etc for /etc/passwd
+	writes []string
+	// run executes the tool on the declarations in file, returning an error with its output if it fails
+	run func(ctx context.Context, file string) error
+}
+
+// Writable returns an error unless the agent can write the declarations and the files the tool writes
+func (d *declarations) Writable() error {
+	paths := []string{filepath.Join(d.root, d.dir)}
+	for _, p := range d.writes {
+		paths = append(paths, filepath.Join(d.root, p))
+	}
+	return writable(paths...)
+}
+
+// Capability reports the declarations as enabled while they are writable
+func (d *declarations) Capability() common.ModuleCapability {
+	return fileCapability(d.Writable)
+}
+
+// path returns the location of the named file, within the root
+func (d *declarations) path(name string) (string, error) {
+	if len(name) > common.MaxDropInNameLength || !common.DropInNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid name %q: %w", name, bus.ErrInvalid)
+	}
+	return filepath.Join(d.root, d.dir, name+".conf"), nil
+}
+
+func (d *declarations) read(name string) (string, string, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return "", "", err
+	}
+	content, err := readRegular(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", fmt.Errorf("%s: %w", filepath.Join(d.dir, name+".conf"), bus.ErrNotFound)
+	}
+	return path, content, err
+}
+
+// write replaces the named file, then applies it
+// If the tool fails, the previous content is restored, so that a rejected file isn't applied at the next boot.
+func (d *declarations) write(ctx context.Context, name string, content string) (string, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return "", err
+	}
+	previous, err := readRegular(path)
+	existed := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := writeFile(path, content); err != nil {
+		return "", err
+	}
+
+	if err := d.run(ctx, path); err != nil {
+		if existed {
+			err = errors.Join(err, writeFile(path, previous))
+		} else {
+			err = errors.Join(err, os.Remove(path))
+		}
+		return "", err
+	}
+	d.log.Info().Str("path", path).Msg("Applied declarations")
+	return path, nil
+}
+
+// remove deletes the named file, leaving anything it created in place
+func (d *declarations) remove(name string) error {
+	path, _, err := d.read(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	d.log.Info().Str("path", path).Msg("Deleted declarations")
+	return nil
+}
+
+// runTool returns a function executing the tool at path on a file, operating on root rather than / if it is another
+// directory
+func runTool(path string, root string, args ...string) func(ctx context.Context, file string) error {
+	args = slices.Clone(args)
+	if root != "/" {
+		args = append(args, "--root="+root)
+	}
+	return func(ctx context.Context, file string) error {
+		out, err := exec.CommandContext(ctx, path, append(slices.Clone(args), file)...).CombinedOutput()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		msg := strings.TrimSpace(string(out))
+		// EX_DATAERR, the file refers to something which doesn't exist, such as an unknown user
+		if exitErr.ExitCode() == 65 {
+			return fmt.Errorf("%s: %w", msg, bus.ErrInvalid)
+		}
+		return fmt.Errorf("%s failed: %s", filepath.Base(path), msg)
+	}
+}
+
+// account is an entry of /etc/passwd or /etc/group
+type account struct {
+	name string
+	id   int
+	// gid is the primary group of a user
+	gid   int
+	home  string
+	shell string
+	// members are the supplementary members of a group
+	members []string
+}
+
+// accounts are the users and groups defined on the host
+type accounts struct {
+	users  []account
+	groups []account
+}
+
+// readAccounts reads the users and groups below root
+// The files are read directly rather than through NSS, so that users from a directory service, which the tools
+// never create, aren't mistaken for local ones.
+func readAccounts(root string) (accounts, error) {
+	users, err := readAccountFile(filepath.Join(root, "etc/passwd"), func(f []string) (account, bool) {
+		if len(f) < 7 {
+			return account{}, false
+		}
+		id, err1 := strconv.Atoi(f[2])
+		gid, err2 := strconv.Atoi(f[3])
+		return account{name: f[0], id: id, gid: gid, home: f[5], shell: f[6]}, err1 == nil && err2 == nil
+	})
+	if err != nil {
+		return accounts{}, err
+	}
+	groups, err := readAccountFile(filepath.Join(root, "etc/group"), func(f []string) (account, bool) {
+		if len(f) < 4 {
+			return account{}, false
+		}
+		id, err := strconv.Atoi(f[2])
+		g := account{name: f[0], id: id, members: []string{}}
+		if f[3] != "" {
+			g.members = strings.Split(f[3], ",")
+		}
+		return g, err == nil
+	})
+	return accounts{users: users, groups: groups}, err
+}
+
+func readAccountFile(path string, parse func([]string) (account, bool)) ([]account, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []account
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if a, ok := parse(strings.Split(line, ":")); ok {
+			entries = append(entries, a)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func (a accounts) user(name string) (account, bool) {
+	return find(a.users, func(u account) bool { return u.name == name })
+}
+
+func (a accounts) group(name string) (account, bool) {
+	return find(a.groups, func(g account) bool { return g.name == name })
+}
+
+// userName returns the name of the user with the given UID, or the UID itself if it has none
+func (a accounts) userName(uid int) string {
+	if u, ok := find(a.users, func(u account) bool { return u.id == uid }); ok {
+		return u.name
+	}
+	return strconv.Itoa(uid)
+}
+
+// groupName returns the name of the group with the given GID, or the GID itself if it has none
+func (a accounts) groupName(gid int) string {
+	if g, ok := find(a.groups, func(g account) bool { return g.id == gid }); ok {
+		return g.name
+	}
+	return strconv.Itoa(gid)
+}
+
+func find(entries []account, match func(account) bool) (account, bool) {
+	for _, e := range entries {
+		if match(e) {
+			return e, true
+		}
+	}
+	return account{}, false
+}
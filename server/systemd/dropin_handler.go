@@ -0,0 +1,104 @@
+package systemd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleDropInGet(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		unit, name, err := dropInName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		dropIn, err := client.GetDropIn(ctx, unit, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get drop-in %s of unit %s", name, unit)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toDropInResponse(dropIn))
+	})
+}
+
+func HandleDropInPut(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		unit, name, err := dropInName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		req, err := common.DecodeRequest[common.UnitFileRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+		if _, err := common.ParseUnit(req.Content); err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("invalid drop-in %s: %s: %w", name, err, bus.ErrInvalid))
+			return
+		}
+
+		dropIn, err := client.WriteDropIn(ctx, unit, name, req.Content)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot write drop-in %s of unit %s", name, unit)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toDropInResponse(dropIn))
+	})
+}
+
+func HandleDropInDelete(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		unit, name, err := dropInName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		err = client.DeleteDropIn(ctx, unit, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot delete drop-in %s of unit %s", name, unit)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// dropInName returns the unit and drop-in names from the request path
+func dropInName(r *http.Request) (string, string, error) {
+	unit, err := unitName(r)
+	if err != nil {
+		return "", "", err
+	}
+	name := r.PathValue("dropin")
+	if len(name) > common.MaxDropInNameLength || !common.DropInNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid drop-in name %q: %w", name, bus.ErrInvalid)
+	}
+	return unit, name, nil
+}
+
+func toDropInResponse(dropIn DropIn) common.DropInResponse {
+	shadowed := make([]common.ShadowedSetting, 0, len(dropIn.Shadowed))
+	for _, s := range dropIn.Shadowed {
+		shadowed = append(shadowed, common.ShadowedSetting{Path: s.Path, Section: s.Section, Key: s.Key})
+	}
+	return common.DropInResponse{
+		Unit:     dropIn.Unit,
+		Name:     dropIn.Name,
+		Path:     dropIn.Path,
+		Content:  dropIn.Content,
+		Shadowed: shadowed,
+	}
+}
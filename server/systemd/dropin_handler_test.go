@@ -0,0 +1,74 @@
+package systemd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func newDropInTestMux(client SystemdClient) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /systemd/units/{name}/dropins/{dropin}", HandleDropInGet(client))
+	mux.Handle("PUT /systemd/units/{name}/dropins/{dropin}", HandleDropInPut(client))
+	mux.Handle("DELETE /systemd/units/{name}/dropins/{dropin}", HandleDropInDelete(client))
+	return mux
+}
+
+func TestDropInHandlers(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	mux := newDropInTestMux(client)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/example.service/dropins/override",
+		strings.NewReader(`{"content": "[Service]\nEnvironment=DEBUG=1\n"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/units/example.service/dropins/override", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.DropInResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Unit != "example.service" || resp.Name != "override" || resp.Shadowed == nil {
+		t.Errorf("unexpected drop-in: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/systemd/units/example.service/dropins/override", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHandleDropInPutValidation(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	mux := newDropInTestMux(client)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "not a unit name", path: "passwd/dropins/override", body: `{"content": "[Service]\n"}`},
+		{name: "escaped path", path: "example.service/dropins/..%2F..%2Fpasswd", body: `{"content": "[Service]\n"}`},
+		{name: "invalid content", path: "example.service/dropins/override", body: `{"content": "MemoryMax=1G\n"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/"+tt.path, strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+)
+
+// dropInPath returns the location of the named drop-in of a unit, rejecting any name which could escape its directory
+func (c *SystemdDbusClient) dropInPath(unit string, name string) (string, error) {
+	dir, err := c.unitPath(unit)
+	if err != nil {
+		return "", err
+	}
+	if len(name) > common.MaxDropInNameLength || !common.DropInNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid drop-in name %q: %w", name, bus.ErrInvalid)
+	}
+	return filepath.Join(dir+".d", name+".conf"), nil
+}
+
+func (c *SystemdDbusClient) GetDropIn(ctx context.Context, unit string, name string) (DropIn, error) {
+	path, err := c.dropInPath(unit, name)
+	if err != nil {
+		return DropIn{}, err
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return DropIn{}, fmt.Errorf("drop-in %s of unit %s: %w", name, unit, bus.ErrNotFound)
+	}
+	if err != nil {
+		return DropIn{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return DropIn{}, err
+	}
+	dropIn := DropIn{Unit: unit, Name: name, Path: path, Content: string(content)}
+	dropIn.Shadowed, err = c.shadowed(ctx, dropIn)
+	return dropIn, err
+}
+
+func (c *SystemdDbusClient) WriteDropIn(ctx context.Context, unit string, name string, content string) (DropIn, error) {
+	path, err := c.dropInPath(unit, name)
+	if err != nil {
+		return DropIn{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return DropIn{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return DropIn{}, err
+	}
+	if err := writeFile(path, content); err != nil {
+		return DropIn{}, err
+	}
+	c.log.Info().Str("unit", unit).Str("name", name).Msg("Wrote drop-in")
+
+	if err := c.Reload(ctx); err != nil {
+		return DropIn{}, err
+	}
+	dropIn := DropIn{Unit: unit, Name: name, Path: path, Content: content}
+	dropIn.Shadowed, err = c.shadowed(ctx, dropIn)
+	return dropIn, err
+}
+
+func (c *SystemdDbusClient) DeleteDropIn(ctx context.Context, unit string, name string) error {
+	path, err := c.dropInPath(unit, name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return fmt.Errorf("drop-in %s of unit %s: %w", name, unit, bus.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	// Only succeeds once the directory is empty, other drop-ins are left alone
+	_ = os.Remove(filepath.Dir(path))
+	c.log.Info().Str("unit", unit).Str("name", name).Msg("Deleted drop-in")
+	return c.Reload(ctx)
+}
+
+// shadowed finds the settings of the drop-in which systemd overrides with a later drop-in
+// systemd applies the drop-ins listed in the unit's DropInPaths property in order of their file names, and a file with
+// the same name in a directory of higher priority replaces ours entirely, so every other drop-in whose name sorts at
+// or after ours may override it.
+func (c *SystemdDbusClient) shadowed(ctx context.Context, dropIn DropIn) ([]ShadowedSetting, error) {
+	obj, err := c.loadUnit(ctx, dropIn.Unit)
+	if errors.Is(err, bus.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths, err := bus.Decode[[]string](ctx, c.log, obj, unitInterface+".DropInPaths")
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(dropIn.Path)
+	var later []UnitFile
+	for _, path := range paths {
+		if path == dropIn.Path || filepath.Base(path) < base {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			c.log.Warn().Err(err).Str("path", path).Msg("Cannot read drop-in")
+			continue
+		}
+		later = append(later, UnitFile{Path: path, Content: string(content)})
+	}
+	return ShadowedSettings(dropIn.Content, later), nil
+}
+
+// ShadowedSettings returns the settings of content which are set again by any of the later drop-ins
+// Drop-ins which can't be parsed are ignored, as systemd ignores their invalid lines.
+func ShadowedSettings(content string, later []UnitFile) []ShadowedSetting {
+	sections, err := common.ParseUnit(content)
+	if err != nil {
+		return nil
+	}
+	ours := make(map[string]bool)
+	for _, s := range sections {
+		for _, e := range s.Entries {
+			ours[s.Name+"."+e.Key] = true
+		}
+	}
+
+	var shadowed []ShadowedSetting
+	for _, f := range later {
+		parsed, err := common.ParseUnit(f.Content)
+		if err != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, s := range parsed {
+			for _, e := range s.Entries {
+				key := s.Name + "." + e.Key
+				if !ours[key] || seen[key] {
+					continue
+				}
+				seen[key] = true
+				shadowed = append(shadowed, ShadowedSetting{Path: f.Path, Section: s.Name, Key: e.Key})
+			}
+		}
+	}
+	return shadowed
+}
@@ -0,0 +1,85 @@
+package systemd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/metrics"
+)
+
+// finishedJobTTL is how long the result of a job nobody was waiting for is kept,
+// in case the JobRemoved signal arrives before the reply to the call which queued the job
+const finishedJobTTL = time.Minute
+
+// jobTracker delivers the results of systemd jobs, from the manager's JobRemoved signal
+type jobTracker struct {
+	mu       sync.Mutex
+	waiters  map[dbus.ObjectPath]chan string
+	finished map[dbus.ObjectPath]finishedJob
+}
+
+type finishedJob struct {
+	result string
+	at     time.Time
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{
+		waiters:  make(map[dbus.ObjectPath]chan string),
+		finished: make(map[dbus.ObjectPath]finishedJob),
+	}
+}
+
+func (t *jobTracker) handle(sig *dbus.Signal) {
+	if sig.Name != prefix+"JobRemoved" {
+		return
+	}
+	var id uint32
+	var job dbus.ObjectPath
+	var unit, result string
+	if err := dbus.Store(sig.Body, &id, &job, &unit, &result); err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ch, ok := t.waiters[job]; ok {
+		ch <- result
+		delete(t.waiters, job)
+		return
+	}
+	now := time.Now()
+	for path, f := range t.finished {
+		if now.Sub(f.at) > finishedJobTTL {
+			delete(t.finished, path)
+		}
+	}
+	t.finished[job] = finishedJob{result: result, at: now}
+}
+
+// wait blocks until the job is removed, returning its result, e.g. done or failed
+func (t *jobTracker) wait(ctx context.Context, job dbus.ObjectPath) (string, error) {
+	t.mu.Lock()
+	if f, ok := t.finished[job]; ok {
+		delete(t.finished, job)
+		t.mu.Unlock()
+		return f.result, nil
+	}
+	ch := make(chan string, 1)
+	t.waiters[job] = ch
+	t.mu.Unlock()
+	metrics.JobStarted()
+	defer metrics.JobFinished()
+
+	select {
+	case result := <-ch:
+		return result, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.waiters, job)
+		t.mu.Unlock()
+		return "", ctx.Err()
+	}
+}
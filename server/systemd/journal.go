@@ -0,0 +1,27 @@
+package systemd
+
+import (
+	"context"
+
+	"github.com/nickrobison/terraform-linux-provider/server/journal"
+)
+
+// failureJournalLines is the number of journal lines included when a unit fails
+const failureJournalLines = 10
+
+// journalTail returns the last lines logged by the unit, formatting each entry as journalctl's short-iso output does
+// Without a journal client, e.g. when journalctl isn't installed, failures are reported without their journal.
+func (c *SystemdDbusClient) journalTail(ctx context.Context, unit string, lines int) ([]string, error) {
+	if c.journal == nil {
+		return nil, nil
+	}
+	entries, err := c.journal.Entries(ctx, journal.Query{Units: []string{unit}, Lines: lines})
+	if err != nil {
+		return nil, err
+	}
+	var tail []string
+	for _, entry := range entries {
+		tail = append(tail, entry.String())
+	}
+	return tail, nil
+}
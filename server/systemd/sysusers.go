@@ -0,0 +1,161 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+)
+
+// Sysusers is a sysusers.d file written by the agent
+type Sysusers struct {
+	Name    string
+	Path    string
+	Content string
+	// Users and Groups are those the file's lines declare, in order
+	Users  []UserState
+	Groups []GroupState
+}
+
+// UserState is a user account, which Exists once systemd-sysusers has created it
+type UserState struct {
+	Name   string
+	Exists bool
+	UID    int
+	Group  string
+	GID    int
+	Home   string
+	Shell  string
+}
+
+// GroupState is a group, which Exists once systemd-sysusers has created it
+type GroupState struct {
+	Name    string
+	Exists  bool
+	GID     int
+	Members []string
+}
+
+// SysusersClient manages the agent's files in /etc/sysusers.d
+type SysusersClient interface {
+	// GetSysusers returns an error wrapping bus.ErrNotFound if there is no file with the given name
+	GetSysusers(ctx context.Context, name string) (Sysusers, error)
+	// WriteSysusers creates or replaces a file, then creates the users and groups it declares, as systemd-sysusers
+	// does at boot. The file is left unchanged if they cannot be created.
+	WriteSysusers(ctx context.Context, name string, content string) (Sysusers, error)
+	// DeleteSysusers removes the file, leaving the users and groups it created in place, as their IDs may still own
+	// files on the host
+	// Returns an error wrapping bus.ErrNotFound if there is no file with the given name.
+	DeleteSysusers(ctx context.Context, name string) error
+}
+
+// SystemdSysusers applies sysusers.d files with systemd-sysusers
+type SystemdSysusers struct {
+	declarations
+}
+
+// NewSystemdSysusers returns a client which writes files to root/etc/sysusers.d, root being / other than in tests
+func NewSystemdSysusers(root string) (*SystemdSysusers, error) {
+	path, err := exec.LookPath("systemd-sysusers")
+	if err != nil {
+		return nil, err
+	}
+	log := middleware.Logger()
+	log = log.With().Str("sysusers_root", root).Logger()
+	return &SystemdSysusers{declarations{
+		log:  &log,
+		root: root,
+		dir:  common.SysusersDir,
+		// systemd-sysusers replaces /etc/passwd, /etc/group and their shadow files through temporary files in /etc
+		writes: []string{"/etc"},
+		run:    runTool(path, root),
+	}}, nil
+}
+
+func (s *SystemdSysusers) GetSysusers(ctx context.Context, name string) (Sysusers, error) {
+	path, content, err := s.read(name)
+	if err != nil {
+		return Sysusers{}, err
+	}
+	return s.state(name, path, content)
+}
+
+func (s *SystemdSysusers) WriteSysusers(ctx context.Context, name string, content string) (Sysusers, error) {
+	if _, err := common.ParseSysusers(content); err != nil {
+		return Sysusers{}, fmt.Errorf("invalid sysusers.d file %s: %s: %w", name, err, bus.ErrInvalid)
+	}
+	path, err := s.write(ctx, name, content)
+	if err != nil {
+		return Sysusers{}, err
+	}
+	return s.state(name, path, content)
+}
+
+func (s *SystemdSysusers) DeleteSysusers(ctx context.Context, name string) error {
+	return s.remove(name)
+}
+
+// state returns the file along with the current state of the users and groups it declares
+// A u line declares both a user and its group, and an m line the user and the group it joins.
+func (s *SystemdSysusers) state(name string, path string, content string) (Sysusers, error) {
+	lines, err := common.ParseSysusers(content)
+	if err != nil {
+		return Sysusers{}, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	accounts, err := readAccounts(s.root)
+	if err != nil {
+		return Sysusers{}, err
+	}
+
+	sysusers := Sysusers{Name: name, Path: path, Content: content, Users: []UserState{}, Groups: []GroupState{}}
+	var users, groups []string
+	for _, l := range lines {
+		switch l.Type {
+		case "u":
+			users = append(users, l.Name)
+			// A user given an existing group by name, e.g. 990:adm, joins it rather than getting a group of its own
+			if _, group, ok := strings.Cut(l.ID, ":"); ok && !isNumeric(group) {
+				groups = append(groups, group)
+			} else {
+				groups = append(groups, l.Name)
+			}
+		case "g":
+			groups = append(groups, l.Name)
+		case "m":
+			users = append(users, l.Name)
+			groups = append(groups, l.ID)
+		}
+	}
+	for _, user := range users {
+		if slices.ContainsFunc(sysusers.Users, func(u UserState) bool { return u.Name == user }) {
+			continue
+		}
+		state := UserState{Name: user}
+		if u, ok := accounts.user(user); ok {
+			state = UserState{Name: user, Exists: true, UID: u.id, Group: accounts.groupName(u.gid), GID: u.gid, Home: u.home, Shell: u.shell}
+		}
+		sysusers.Users = append(sysusers.Users, state)
+	}
+	for _, group := range groups {
+		if slices.ContainsFunc(sysusers.Groups, func(g GroupState) bool { return g.Name == group }) {
+			continue
+		}
+		state := GroupState{Name: group, Members: []string{}}
+		if g, ok := accounts.group(group); ok {
+			state = GroupState{Name: group, Exists: true, GID: g.id, Members: g.members}
+		}
+		sysusers.Groups = append(sysusers.Groups, state)
+	}
+	return sysusers, nil
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
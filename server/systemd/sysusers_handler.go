@@ -0,0 +1,78 @@
+package systemd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleSysusersGet(client SysusersClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		sysusers, err := client.GetSysusers(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get sysusers.d file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toSysusersResponse(sysusers))
+	})
+}
+
+func HandleSysusersPut(client SysusersClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		req, err := common.DecodeRequest[common.UnitFileRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+
+		sysusers, err := client.WriteSysusers(ctx, name, req.Content)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot write sysusers.d file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toSysusersResponse(sysusers))
+	})
+}
+
+func HandleSysusersDelete(client SysusersClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		err := client.DeleteSysusers(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot delete sysusers.d file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func toSysusersResponse(sysusers Sysusers) common.SysusersResponse {
+	resp := common.SysusersResponse{
+		Name:    sysusers.Name,
+		Path:    sysusers.Path,
+		Content: sysusers.Content,
+		Users:   make([]common.UserState, 0, len(sysusers.Users)),
+		Groups:  make([]common.GroupState, 0, len(sysusers.Groups)),
+	}
+	for _, u := range sysusers.Users {
+		resp.Users = append(resp.Users, common.UserState(u))
+	}
+	for _, g := range sysusers.Groups {
+		resp.Groups = append(resp.Groups, common.GroupState(g))
+	}
+	return resp
+}
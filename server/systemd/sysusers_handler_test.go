@@ -0,0 +1,50 @@
+package systemd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func TestSysusersHandlers(t *testing.T) {
+	root := newTestRoot(t, "root:x:0:0:root:/root:/bin/sh\n", "root:x:0:\n")
+	client := newTestSysusers(t, root)
+	mux := http.NewServeMux()
+	mux.Handle("GET /systemd/sysusers/{name}", HandleSysusersGet(client))
+	mux.Handle("PUT /systemd/sysusers/{name}", HandleSysusersPut(client))
+	mux.Handle("DELETE /systemd/sysusers/{name}", HandleSysusersDelete(client))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/sysusers/backup",
+		strings.NewReader(`{"content": "g backup 950\n"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/sysusers/backup", nil))
+	var resp common.SysusersResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Users) != 0 || len(resp.Groups) != 1 || resp.Groups[0].GID != 950 || !resp.Groups[0].Exists {
+		t.Errorf("unexpected sysusers.d file: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/sysusers/backup",
+		strings.NewReader(`{"content": "g backup 950:950\n"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected a GID with a group to be rejected, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/systemd/sysusers/backup", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+}
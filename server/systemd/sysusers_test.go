@@ -0,0 +1,87 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+)
+
+func newTestSysusers(t *testing.T, root string) SysusersClient {
+	t.Helper()
+	client, err := NewSystemdSysusers(root)
+	if err != nil {
+		t.Skipf("systemd-sysusers is not available: %s", err)
+	}
+	return client
+}
+
+func TestSysusers(t *testing.T) {
+	root := newTestRoot(t, "root:x:0:0:root:/root:/bin/sh\n", "root:x:0:\nadm:x:4:\n")
+	client := newTestSysusers(t, root)
+	ctx := context.Background()
+
+	content := "u app 990 \"Web application\" /srv/app\ng deploy -\nm app adm\n"
+	sysusers, err := client.WriteSysusers(ctx, "app", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sysusers.Path != filepath.Join(root, "etc/sysusers.d/app.conf") || len(sysusers.Users) != 1 || len(sysusers.Groups) != 3 {
+		t.Fatalf("unexpected sysusers.d file: %+v", sysusers)
+	}
+	app := sysusers.Users[0]
+	if !app.Exists || app.UID != 990 || app.Group != "app" || app.GID != 990 || app.Home != "/srv/app" {
+		t.Errorf("unexpected user: %+v", app)
+	}
+	if deploy := sysusers.Groups[1]; deploy.Name != "deploy" || !deploy.Exists || deploy.GID == 0 {
+		t.Errorf("unexpected group: %+v", deploy)
+	}
+	if adm := sysusers.Groups[2]; adm.GID != 4 || !slices.Contains(adm.Members, "app") {
+		t.Errorf("expected app to join adm, got %+v", adm)
+	}
+
+	// Users outlive the file, as they may still own files
+	if err := client.DeleteSysusers(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+	if passwd, err := os.ReadFile(filepath.Join(root, "etc/passwd")); err != nil || !slices.Contains(splitLines(string(passwd)), "app:x:990:990:Web application:/srv/app:/usr/sbin/nologin") {
+		t.Errorf("expected the user to be left in place, got %q: %v", passwd, err)
+	}
+	if _, err := client.GetSysusers(ctx, "app"); !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected the file to be gone, got %v", err)
+	}
+}
+
+func TestSysusersPending(t *testing.T) {
+	root := newTestRoot(t, "root:x:0:0:root:/root:/bin/sh\n", "root:x:0:\n")
+	client := newTestSysusers(t, root)
+
+	// A file written by hand is reported with the users it would create
+	dir := filepath.Join(root, "etc/sysusers.d")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.conf"), []byte("u app -\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sysusers, err := client.GetSysusers(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sysusers.Users) != 1 || sysusers.Users[0].Exists || sysusers.Groups[0].Exists {
+		t.Errorf("expected the user not to exist yet, got %+v", sysusers)
+	}
+
+	if _, err := client.WriteSysusers(context.Background(), "app", "u 9app -\n"); !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid name to be rejected, got %v", err)
+	}
+}
+
+func splitLines(s string) []string {
+	return slices.DeleteFunc(strings.Split(s, "\n"), func(l string) bool { return l == "" })
+}
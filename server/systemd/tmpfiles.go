@@ -0,0 +1,154 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+)
+
+// Tmpfiles is a tmpfiles.d file written by the agent
+type Tmpfiles struct {
+	Name    string
+	Path    string
+	Content string
+	// Paths are the literal paths of the file's lines, in order
+	Paths []PathState
+}
+
+// PathState is the type, mode and ownership of a path
+type PathState struct {
+	Path   string
+	Exists bool
+	// Type is one of directory, file, symlink, fifo, device, socket or other
+	Type string
+	// Mode holds the permissions, along with the setuid, setgid and sticky bits
+	Mode  fs.FileMode
+	User  string
+	Group string
+}
+
+// TmpfilesClient manages the agent's files in /etc/tmpfiles.d
+type TmpfilesClient interface {
+	// GetTmpfiles returns an error wrapping bus.ErrNotFound if there is no file with the given name
+	GetTmpfiles(ctx context.Context, name string) (Tmpfiles, error)
+	// WriteTmpfiles creates or replaces a file, then creates the paths it declares, as systemd-tmpfiles --create does
+	// at boot. The file is left unchanged if its paths cannot be created.
+	WriteTmpfiles(ctx context.Context, name string, content string) (Tmpfiles, error)
+	// DeleteTmpfiles removes the file, leaving the paths it created in place
+	// Returns an error wrapping bus.ErrNotFound if there is no file with the given name.
+	DeleteTmpfiles(ctx context.Context, name string) error
+}
+
+// SystemdTmpfiles applies tmpfiles.d files with systemd-tmpfiles
+type SystemdTmpfiles struct {
+	declarations
+}
+
+// NewSystemdTmpfiles returns a client which writes files to root/etc/tmpfiles.d, root being / other than in tests
+func NewSystemdTmpfiles(root string) (*SystemdTmpfiles, error) {
+	path, err := exec.LookPath("systemd-tmpfiles")
+	if err != nil {
+		return nil, err
+	}
+	log := middleware.Logger()
+	log = log.With().Str("tmpfiles_root", root).Logger()
+	return &SystemdTmpfiles{declarations{
+		log:  &log,
+		root: root,
+		dir:  common.TmpfilesDir,
+		run:  runTool(path, root, "--create"),
+	}}, nil
+}
+
+func (t *SystemdTmpfiles) GetTmpfiles(ctx context.Context, name string) (Tmpfiles, error) {
+	path, content, err := t.read(name)
+	if err != nil {
+		return Tmpfiles{}, err
+	}
+	return t.state(name, path, content)
+}
+
+func (t *SystemdTmpfiles) WriteTmpfiles(ctx context.Context, name string, content string) (Tmpfiles, error) {
+	if _, err := common.ParseTmpfiles(content); err != nil {
+		return Tmpfiles{}, fmt.Errorf("invalid tmpfiles.d file %s: %s: %w", name, err, bus.ErrInvalid)
+	}
+	path, err := t.write(ctx, name, content)
+	if err != nil {
+		return Tmpfiles{}, err
+	}
+	return t.state(name, path, content)
+}
+
+func (t *SystemdTmpfiles) DeleteTmpfiles(ctx context.Context, name string) error {
+	return t.remove(name)
+}
+
+// state returns the file along with the current state of its paths
+func (t *SystemdTmpfiles) state(name string, path string, content string) (Tmpfiles, error) {
+	lines, err := common.ParseTmpfiles(content)
+	if err != nil {
+		return Tmpfiles{}, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	accounts, err := readAccounts(t.root)
+	if err != nil {
+		return Tmpfiles{}, err
+	}
+
+	tmpfiles := Tmpfiles{Name: name, Path: path, Content: content, Paths: []PathState{}}
+	seen := make(map[string]bool)
+	for _, l := range lines {
+		if !l.Literal() || seen[l.Path] {
+			continue
+		}
+		seen[l.Path] = true
+		state, err := pathState(filepath.Join(t.root, l.Path), accounts)
+		if err != nil {
+			return Tmpfiles{}, err
+		}
+		state.Path = l.Path
+		tmpfiles.Paths = append(tmpfiles.Paths, state)
+	}
+	return tmpfiles, nil
+}
+
+// pathState returns the state of the path, without following a symlink
+func pathState(path string, accounts accounts) (PathState, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return PathState{Path: path}, nil
+	}
+	if err != nil {
+		return PathState{}, err
+	}
+	state := PathState{Path: path, Exists: true, Mode: info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)}
+	switch m := info.Mode(); {
+	case m.IsDir():
+		state.Type = "directory"
+	case m.IsRegular():
+		state.Type = "file"
+	case m&fs.ModeSymlink != 0:
+		state.Type = "symlink"
+	case m&fs.ModeNamedPipe != 0:
+		state.Type = "fifo"
+	case m&fs.ModeDevice != 0:
+		state.Type = "device"
+	case m&fs.ModeSocket != 0:
+		state.Type = "socket"
+	default:
+		state.Type = "other"
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		state.User = accounts.userName(int(stat.Uid))
+		state.Group = accounts.groupName(int(stat.Gid))
+	}
+	return state, nil
+}
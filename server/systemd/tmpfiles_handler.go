@@ -0,0 +1,94 @@
+package systemd
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleTmpfilesGet(client TmpfilesClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		tmpfiles, err := client.GetTmpfiles(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get tmpfiles.d file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toTmpfilesResponse(tmpfiles))
+	})
+}
+
+func HandleTmpfilesPut(client TmpfilesClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		req, err := common.DecodeRequest[common.UnitFileRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+
+		tmpfiles, err := client.WriteTmpfiles(ctx, name, req.Content)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot write tmpfiles.d file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toTmpfilesResponse(tmpfiles))
+	})
+}
+
+func HandleTmpfilesDelete(client TmpfilesClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		err := client.DeleteTmpfiles(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot delete tmpfiles.d file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func toTmpfilesResponse(tmpfiles Tmpfiles) common.TmpfilesResponse {
+	resp := common.TmpfilesResponse{
+		Name:    tmpfiles.Name,
+		Path:    tmpfiles.Path,
+		Content: tmpfiles.Content,
+		Paths:   make([]common.PathState, 0, len(tmpfiles.Paths)),
+	}
+	for _, p := range tmpfiles.Paths {
+		state := common.PathState{Path: p.Path, Exists: p.Exists, Type: p.Type, User: p.User, Group: p.Group}
+		if p.Exists {
+			state.Mode = octalMode(p.Mode)
+		}
+		resp.Paths = append(resp.Paths, state)
+	}
+	return resp
+}
+
+// octalMode formats the permissions as chmod expects them, e.g. 1777 for a sticky directory
+func octalMode(mode fs.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return fmt.Sprintf("%04o", bits)
+}
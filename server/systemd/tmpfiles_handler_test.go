@@ -0,0 +1,54 @@
+package systemd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func TestTmpfilesHandlers(t *testing.T) {
+	root := newTestRoot(t, "root:x:0:0:root:/root:/bin/sh\n", "root:x:0:\n")
+	client := newTestTmpfiles(t, root)
+	mux := http.NewServeMux()
+	mux.Handle("GET /systemd/tmpfiles/{name}", HandleTmpfilesGet(client))
+	mux.Handle("PUT /systemd/tmpfiles/{name}", HandleTmpfilesPut(client))
+	mux.Handle("DELETE /systemd/tmpfiles/{name}", HandleTmpfilesDelete(client))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/tmpfiles/scratch",
+		strings.NewReader(`{"content": "d /srv/scratch 1777 root root 1d\n"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.TmpfilesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	expected := common.PathState{Path: "/srv/scratch", Exists: true, Type: "directory", Mode: "1777", User: "root", Group: "root"}
+	if len(resp.Paths) != 1 || resp.Paths[0] != expected {
+		t.Errorf("unexpected paths: %+v", resp.Paths)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/tmpfiles/scratch",
+		strings.NewReader(`{"content": "d srv/scratch\n"}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "is not absolute") {
+		t.Errorf("expected a relative path to be rejected, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/systemd/tmpfiles/scratch", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/tmpfiles/scratch", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body)
+	}
+}
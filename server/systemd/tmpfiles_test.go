@@ -0,0 +1,118 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+)
+
+// newTestRoot returns a directory standing in for / with the given users and groups, written as in /etc/passwd and
+// /etc/group
+func newTestRoot(t *testing.T, passwd string, group string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"passwd": passwd, "group": group} {
+		if err := os.WriteFile(filepath.Join(root, "etc", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func newTestTmpfiles(t *testing.T, root string) TmpfilesClient {
+	t.Helper()
+	// Creating paths owned by other users needs root
+	if os.Geteuid() != 0 {
+		t.Skip("systemd-tmpfiles must run as root")
+	}
+	client, err := NewSystemdTmpfiles(root)
+	if err != nil {
+		t.Skipf("systemd-tmpfiles is not available: %s", err)
+	}
+	return client
+}
+
+func TestTmpfiles(t *testing.T) {
+	root := newTestRoot(t, "root:x:0:0:root:/root:/bin/sh\napp:x:990:990:App:/srv/app:/usr/sbin/nologin\n",
+		"root:x:0:\napp:x:990:\n")
+	client := newTestTmpfiles(t, root)
+	ctx := context.Background()
+
+	content := "d /srv/app/cache 0750 app app 10d\nL+ /srv/app/current - - - - /opt/app/42\nd /srv/app/* 0750\n"
+	tmpfiles, err := client.WriteTmpfiles(ctx, "app", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpfiles.Path != filepath.Join(root, "etc/tmpfiles.d/app.conf") || len(tmpfiles.Paths) != 2 {
+		t.Fatalf("unexpected tmpfiles.d file: %+v", tmpfiles)
+	}
+	cache := tmpfiles.Paths[0]
+	if cache.Path != "/srv/app/cache" || !cache.Exists || cache.Type != "directory" || cache.Mode != 0o750 ||
+		cache.User != "app" || cache.Group != "app" {
+		t.Errorf("unexpected state of the cache directory: %+v", cache)
+	}
+	if current := tmpfiles.Paths[1]; current.Type != "symlink" || current.User != "root" {
+		t.Errorf("unexpected state of the symlink: %+v", current)
+	}
+
+	// Ownership changed on the host is reported, and restored when the file is applied again
+	if err := os.Chown(filepath.Join(root, "srv/app/cache"), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	tmpfiles, err = client.GetTmpfiles(ctx, "app")
+	if err != nil || tmpfiles.Content != content || tmpfiles.Paths[0].User != "root" {
+		t.Errorf("unexpected tmpfiles.d file %+v: %v", tmpfiles, err)
+	}
+	tmpfiles, err = client.WriteTmpfiles(ctx, "app", content)
+	if err != nil || tmpfiles.Paths[0].User != "app" {
+		t.Errorf("expected the owner to be restored, got %+v: %v", tmpfiles, err)
+	}
+
+	if err := client.DeleteTmpfiles(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "srv/app/cache")); err != nil {
+		t.Errorf("expected the created directory to be left in place, got %v", err)
+	}
+	if _, err := client.GetTmpfiles(ctx, "app"); !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected the file to be gone, got %v", err)
+	}
+	if err := client.DeleteTmpfiles(ctx, "app"); !errors.Is(err, bus.ErrNotFound) {
+		t.Errorf("expected deleting a missing file to fail, got %v", err)
+	}
+}
+
+func TestTmpfilesRejected(t *testing.T) {
+	root := newTestRoot(t, "root:x:0:0:root:/root:/bin/sh\n", "root:x:0:\n")
+	client := newTestTmpfiles(t, root)
+	ctx := context.Background()
+
+	if _, err := client.WriteTmpfiles(ctx, "app", "d /srv/app 0755\n"); err != nil {
+		t.Fatal(err)
+	}
+	// systemd-tmpfiles cannot resolve the user, so the previous content is kept
+	_, err := client.WriteTmpfiles(ctx, "app", "d /srv/app 0755 nobody-here -\n")
+	if !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an unknown user to be rejected, got %v", err)
+	}
+	if tmpfiles, err := client.GetTmpfiles(ctx, "app"); err != nil || tmpfiles.Content != "d /srv/app 0755\n" {
+		t.Errorf("expected the previous file to be restored, got %+v: %v", tmpfiles, err)
+	}
+
+	if _, err := client.WriteTmpfiles(ctx, "other", "y /srv/app\n"); !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an unknown type to be rejected, got %v", err)
+	}
+	if _, err := client.WriteTmpfiles(ctx, "../app", "d /srv/app\n"); !errors.Is(err, bus.ErrInvalid) {
+		t.Errorf("expected an invalid name to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "etc/tmpfiles.d/other.conf")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected an invalid file not to be written, got %v", err)
+	}
+}
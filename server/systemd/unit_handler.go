@@ -0,0 +1,95 @@
+package systemd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleUnitFileGet(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		unit, err := client.GetUnitFile(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get unit file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toResponse(unit))
+	})
+}
+
+func HandleUnitFilePut(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		req, err := common.DecodeRequest[common.UnitFileRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+		if _, err := common.ParseUnit(req.Content); err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("invalid unit file %s: %s: %w", name, err, bus.ErrInvalid))
+			return
+		}
+
+		unit, err := client.WriteUnitFile(ctx, name, req.Content)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot write unit file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toResponse(unit))
+	})
+}
+
+func HandleUnitFileDelete(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		err = client.DeleteUnitFile(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot delete unit file %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// unitName returns the unit name from the request path, which must be a valid unit name so that it can't
+// be used to reach files outside of the unit directory
+func unitName(r *http.Request) (string, error) {
+	name := r.PathValue("name")
+	if len(name) > common.MaxUnitNameLength || !common.UnitNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid unit name %q: %w", name, bus.ErrInvalid)
+	}
+	return name, nil
+}
+
+func toResponse(unit UnitFile) common.UnitFileResponse {
+	return common.UnitFileResponse{
+		Name:    unit.Name,
+		Path:    unit.Path,
+		Content: unit.Content,
+	}
+}
@@ -0,0 +1,101 @@
+package systemd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/common"
+)
+
+func newTestMux(client SystemdClient) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /systemd/units/{name}", HandleUnitFileGet(client))
+	mux.Handle("PUT /systemd/units/{name}", HandleUnitFilePut(client))
+	mux.Handle("DELETE /systemd/units/{name}", HandleUnitFileDelete(client))
+	return mux
+}
+
+func TestUnitFileHandlers(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	mux := newTestMux(client)
+
+	body, _ := json.Marshal(common.UnitFileRequest{Content: exampleUnit})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/example.service", strings.NewReader(string(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/units/example.service", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.UnitFileResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Name != "example.service" || resp.Content != exampleUnit {
+		t.Errorf("unexpected unit file: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/systemd/units/example.service", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/units/example.service", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHandleUnitFilePutValidation(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	mux := newTestMux(client)
+
+	tests := []struct {
+		name string
+		unit string
+		body string
+	}{
+		{name: "not a unit name", unit: "passwd", body: `{"content": "[Unit]\n"}`},
+		{name: "escaped path", unit: "..%2Fpasswd.service", body: `{"content": "[Unit]\n"}`},
+		{name: "invalid content", unit: "example.service", body: `{"content": "ExecStart=/bin/true\n"}`},
+		{name: "not json", unit: "example.service", body: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/"+tt.unit, strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestHandleUnitFilePutDenied(t *testing.T) {
+	client, service, _ := newTestClient(t)
+	service.Fail("Reload", dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"denied"}))
+
+	w := httptest.NewRecorder()
+	newTestMux(client).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/example.service",
+		strings.NewReader(`{"content": "[Unit]\nDescription=Example\n"}`)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Action != reloadAction {
+		t.Errorf("expected action %s, got %s", reloadAction, resp.Action)
+	}
+}
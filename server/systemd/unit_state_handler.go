@@ -0,0 +1,239 @@
+package systemd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/rs/zerolog"
+)
+
+func HandleUnitList(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		query := r.URL.Query()
+		states, patterns := query["state"], query["pattern"]
+		for _, state := range states {
+			if !common.UnitStatePattern.MatchString(state) {
+				bus.HTTPError(w, r, fmt.Errorf("invalid unit state %q: %w", state, bus.ErrInvalid))
+				return
+			}
+		}
+		for _, pattern := range patterns {
+			if len(pattern) > common.MaxUnitNameLength || !common.UnitGlobPattern.MatchString(pattern) {
+				bus.HTTPError(w, r, fmt.Errorf("invalid unit pattern %q: %w", pattern, bus.ErrInvalid))
+				return
+			}
+		}
+
+		units, err := client.ListUnits(ctx, states, patterns)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot list units")
+			bus.HTTPError(w, r, err)
+			return
+		}
+		resp := common.UnitListResponse{Units: make([]common.UnitStatusResponse, 0, len(units))}
+		for _, unit := range units {
+			resp.Units = append(resp.Units, common.UnitStatusResponse{
+				Name:         unit.Name,
+				Description:  unit.Description,
+				LoadState:    unit.LoadState,
+				ActiveState:  unit.ActiveState,
+				SubState:     unit.SubState,
+				FragmentPath: unit.FragmentPath,
+				MainPID:      unit.MainPID,
+			})
+		}
+		common.Encode(w, r, http.StatusOK, resp)
+	})
+}
+
+func HandleUnitStateGet(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		state, err := client.GetUnitState(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get state of unit %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toStateResponse(state))
+	})
+}
+
+func HandleUnitStatePut(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		req, err := common.DecodeRequest[common.UnitStateRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+		if req.Masked != nil && *req.Masked && ((req.Enabled != nil && *req.Enabled) || (req.Active != nil && *req.Active)) {
+			bus.HTTPError(w, r, fmt.Errorf("unit %s cannot be masked while enabled or active: %w", name, bus.ErrInvalid))
+			return
+		}
+
+		state, err := client.SetUnitState(ctx, name, UnitStateChange{Enabled: req.Enabled, Active: req.Active, Masked: req.Masked})
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot change state of unit %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toStateResponse(state))
+	})
+}
+
+func HandleUnitRestart(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		state, err := client.RestartUnit(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot restart unit %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toStateResponse(state))
+	})
+}
+
+func HandleTimerGet(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		timer, err := client.GetTimer(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get timer %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		resp := common.TimerResponse{Name: timer.Name, Unit: timer.Unit}
+		if !timer.NextElapse.IsZero() {
+			resp.NextElapse = &timer.NextElapse
+		}
+		if !timer.LastTrigger.IsZero() {
+			resp.LastTrigger = &timer.LastTrigger
+		}
+		common.Encode(w, r, http.StatusOK, resp)
+	})
+}
+
+func HandleUnitResourcesGet(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		resources, err := client.GetUnitResources(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get resources of unit %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toResourcesResponse(resources))
+	})
+}
+
+func HandleUnitResourcesPut(client SystemdClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name, err := unitName(r)
+		if err != nil {
+			bus.HTTPError(w, r, err)
+			return
+		}
+		req, err := common.DecodeRequest[common.ResourceControlRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+		if req.IOWeight != nil && *req.IOWeight != common.Infinity && (*req.IOWeight < 1 || *req.IOWeight > common.MaxIOWeight) {
+			bus.HTTPError(w, r, fmt.Errorf("IO weight %d is not between 1 and %d: %w", *req.IOWeight, common.MaxIOWeight, bus.ErrInvalid))
+			return
+		}
+		for key, v := range map[string]*uint64{"CPU quota": req.CPUQuotaPerSecUSec, "tasks max": req.TasksMax} {
+			if v != nil && *v == 0 {
+				bus.HTTPError(w, r, fmt.Errorf("%s must be more than 0: %w", key, bus.ErrInvalid))
+				return
+			}
+		}
+
+		resources, err := client.SetUnitResources(ctx, name, ResourceControlChange{
+			Runtime:            req.Runtime,
+			MemoryMax:          req.MemoryMax,
+			CPUQuotaPerSecUSec: req.CPUQuotaPerSecUSec,
+			IOWeight:           req.IOWeight,
+			TasksMax:           req.TasksMax,
+		})
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot change resources of unit %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toResourcesResponse(resources))
+	})
+}
+
+// toResourcesResponse leaves out usage which systemd reports as unavailable
+func toResourcesResponse(resources UnitResources) common.ResourceControlResponse {
+	usage := func(v uint64) *uint64 {
+		if v == common.Infinity {
+			return nil
+		}
+		return &v
+	}
+	return common.ResourceControlResponse{
+		Name:               resources.Name,
+		ControlGroup:       resources.ControlGroup,
+		MemoryMax:          resources.MemoryMax,
+		CPUQuotaPerSecUSec: resources.CPUQuotaPerSecUSec,
+		IOWeight:           resources.IOWeight,
+		TasksMax:           resources.TasksMax,
+		MemoryCurrent:      usage(resources.MemoryCurrent),
+		CPUUsageNSec:       usage(resources.CPUUsageNSec),
+		TasksCurrent:       usage(resources.TasksCurrent),
+	}
+}
+
+func toStateResponse(state UnitState) common.UnitStateResponse {
+	return common.UnitStateResponse{
+		Name:          state.Name,
+		LoadState:     state.LoadState,
+		ActiveState:   state.ActiveState,
+		SubState:      state.SubState,
+		UnitFileState: state.UnitFileState,
+		Result:        state.Result,
+		Enabled:       state.Enabled(),
+		Active:        state.Active(),
+		Masked:        state.Masked(),
+	}
+}
@@ -0,0 +1,170 @@
+package systemd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakesystemd"
+)
+
+func newStateTestMux(client SystemdClient) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /systemd/units", HandleUnitList(client))
+	mux.Handle("GET /systemd/units/{name}/state", HandleUnitStateGet(client))
+	mux.Handle("PUT /systemd/units/{name}/state", HandleUnitStatePut(client))
+	mux.Handle("POST /systemd/units/{name}/restart", HandleUnitRestart(client))
+	mux.Handle("GET /systemd/units/{name}/resources", HandleUnitResourcesGet(client))
+	mux.Handle("PUT /systemd/units/{name}/resources", HandleUnitResourcesPut(client))
+	return mux
+}
+
+func TestUnitStateHandlers(t *testing.T) {
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("example.service")))
+	mux := newStateTestMux(client)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/example.service/state",
+		strings.NewReader(`{"enabled": true, "active": true}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.UnitStateResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Enabled || !resp.Active || resp.ActiveState != "active" || resp.UnitFileState != "enabled" {
+		t.Errorf("unexpected state: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/systemd/units/example.service/restart", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/units/missing.service/state", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestHandleUnitStatePutValidation(t *testing.T) {
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("example.service")))
+	mux := newStateTestMux(client)
+
+	tests := []struct {
+		name string
+		unit string
+		body string
+	}{
+		{name: "not a unit name", unit: "passwd", body: `{"active": true}`},
+		{name: "masked and active", unit: "example.service", body: `{"masked": true, "active": true}`},
+		{name: "masked and enabled", unit: "example.service", body: `{"masked": true, "enabled": true}`},
+		{name: "not json", unit: "example.service", body: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/"+tt.unit+"/state", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestHandleUnitList(t *testing.T) {
+	failed := fakesystemd.NewUnit("broken.service")
+	failed.ActiveState, failed.SubState = "failed", "failed"
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(failed, fakesystemd.NewUnit("example.service")))
+	mux := newStateTestMux(client)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/units?state=failed&pattern=*.service", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.UnitListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Units) != 1 || resp.Units[0].Name != "broken.service" {
+		t.Errorf("expected only the failed unit, got %+v", resp.Units)
+	}
+
+	for _, query := range []string{"state=Failed", "pattern=../*", "pattern="} {
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/systemd/units?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body)
+		}
+	}
+}
+
+func TestUnitResourcesHandlers(t *testing.T) {
+	web := fakesystemd.NewUnit("web.service")
+	web.Resources.ControlGroup, web.Resources.MemoryCurrent = "/system.slice/web.service", 64<<20
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(web, fakesystemd.NewUnit("backup.timer")))
+	mux := newStateTestMux(client)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/web.service/resources",
+		strings.NewReader(`{"runtime": true, "memory_max": 536870912, "io_weight": 50}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.ResourceControlResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.MemoryMax != 512<<20 || resp.IOWeight != 50 || resp.TasksMax != common.Infinity || resp.ControlGroup != "/system.slice/web.service" {
+		t.Errorf("unexpected resources: %+v", resp)
+	}
+	// Usage which systemd doesn't have is left out
+	if resp.MemoryCurrent == nil || *resp.MemoryCurrent != 64<<20 || resp.CPUUsageNSec != nil || resp.TasksCurrent != nil {
+		t.Errorf("unexpected usage: %+v", resp)
+	}
+
+	for path, code := range map[string]int{
+		"/systemd/units/web.service/resources":     http.StatusOK,
+		"/systemd/units/backup.timer/resources":    http.StatusBadRequest,
+		"/systemd/units/missing.service/resources": http.StatusNotFound,
+	} {
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d: %s", path, code, w.Code, w.Body)
+		}
+	}
+}
+
+func TestHandleUnitResourcesPutValidation(t *testing.T) {
+	client, _, _ := newTestClient(t, fakesystemd.WithUnits(fakesystemd.NewUnit("web.service")))
+	mux := newStateTestMux(client)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "zero weight", body: `{"io_weight": 0}`},
+		{name: "weight too high", body: `{"io_weight": 10001}`},
+		{name: "zero quota", body: `{"cpu_quota_per_sec_usec": 0}`},
+		{name: "zero tasks", body: `{"tasks_max": 0}`},
+		{name: "negative memory", body: `{"memory_max": -1}`},
+		{name: "not json", body: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/systemd/units/web.service/resources", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
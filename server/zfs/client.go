@@ -5,6 +5,10 @@ import (
 )
 
 type ZfsClient interface {
-	ListPools(ctx context.Context) ([]*ZpoolObject, error)
-	Version() (string, error)
+	ListPools(ctx context.Context) ([]ZpoolProperties, error)
+	// GetPool returns an error wrapping bus.ErrNotFound if there is no pool with the given name
+	GetPool(ctx context.Context, name string) (ZpoolProperties, error)
+	CreatePool(ctx context.Context, name string) (ZpoolProperties, error)
+	DestroyPool(ctx context.Context, name string) error
+	Version(ctx context.Context) (string, error)
 }
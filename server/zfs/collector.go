@@ -0,0 +1,88 @@
+package zfs
+
+import (
+	"context"
+	"time"
+
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/metrics"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolStates are the health states reported by zpool status
+var poolStates = []string{"ONLINE", "DEGRADED", "FAULTED", "OFFLINE", "REMOVED", "UNAVAIL", "SUSPENDED"}
+
+const collectTimeout = 5 * time.Second
+
+type poolCollector struct {
+	client    ZfsClient
+	size      *prometheus.Desc
+	allocated *prometheus.Desc
+	free      *prometheus.Desc
+	health    *prometheus.Desc
+	errors    *prometheus.Desc
+}
+
+// NewPoolCollector returns a collector which reports capacity, health and error counters for every zpool
+// Values are read from the ZFS service on each scrape
+func NewPoolCollector(client ZfsClient) prometheus.Collector {
+	desc := func(name string, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "zpool", name), help, append([]string{"pool"}, labels...), nil)
+	}
+	return &poolCollector{
+		client:    client,
+		size:      desc("size_bytes", "Total capacity of the zpool."),
+		allocated: desc("allocated_bytes", "Bytes allocated in the zpool."),
+		free:      desc("free_bytes", "Bytes free in the zpool."),
+		health:    desc("health", "Current health state of the zpool, 1 for the active state.", "state"),
+		errors:    desc("errors_total", "Error counters of the zpool, by type.", "type"),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.allocated
+	ch <- c.free
+	ch <- c.health
+	ch <- c.errors
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	log := middleware.Logger()
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	pools, err := c.client.ListPools(ctx)
+	// Failing the scrape would hide every other metric on hosts where the ZFS service isn't running
+	if _, ok := bus.IsUnavailable(err); ok {
+		log.Debug().Err(err).Msg("ZFS service is unavailable, skipping zpool metrics")
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot list zpools for metrics")
+		ch <- prometheus.NewInvalidMetric(c.size, err)
+		return
+	}
+
+	for _, pool := range pools {
+		c.collectPool(ch, pool)
+	}
+}
+
+func (c *poolCollector) collectPool(ch chan<- prometheus.Metric, props ZpoolProperties) {
+	name := props.Name
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(props.Size), name)
+	ch <- prometheus.MustNewConstMetric(c.allocated, prometheus.GaugeValue, float64(props.Allocated), name)
+	ch <- prometheus.MustNewConstMetric(c.free, prometheus.GaugeValue, float64(props.Free), name)
+	for _, state := range poolStates {
+		v := 0.0
+		if state == props.Health {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.health, prometheus.GaugeValue, v, name, state)
+	}
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(props.ReadErrors), name, "read")
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(props.WriteErrors), name, "write")
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(props.ChecksumErrors), name, "checksum")
+}
@@ -3,66 +3,230 @@ package zfs
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
+	"github.com/nickrobison/terraform-linux-provider/common"
 	"github.com/nickrobison/terraform-linux-provider/server/bus"
 	"github.com/nickrobison/terraform-linux-provider/server/middleware"
 	"github.com/rs/zerolog"
 )
 
+// manageAction is the polkit action which guards changes to pools
+const manageAction = "com.nickrobison.dbus.zfs1.manage"
+
+func init() {
+	bus.RegisterAction(prefix+"CreatePool", manageAction)
+	bus.RegisterAction(prefix+"DestroyPool", manageAction)
+}
+
 var (
 	destination = "com.nickrobison.dbus.zfs1"
 	pathname    = "/com/nickrobison/dbus/zfs1"
-	prefix      = "com.nickrobison.dbus.ZFS1."
+	iface       = "com.nickrobison.dbus.ZFS1"
+	prefix      = iface + "."
 )
 
 type ZfsDebusClient struct {
-	conn *dbus.Conn
-	log  *zerolog.Logger
-	obj  dbus.BusObject
+	sup     *bus.Supervisor
+	log     *zerolog.Logger
+	backend *bus.Backend
+	// tree is only set if the service implements the ObjectManager interface
+	tree *bus.ObjectTree
 }
 
-func NewZfsClient(conn *dbus.Conn) (ZfsClient, error) {
+// NewZfsClient returns a client which is enabled once the ZFS service is on the bus, which may be after the agent starts
+// Until then every call returns a bus.UnavailableError.
+func NewZfsClient(sup *bus.Supervisor) *ZfsDebusClient {
 	log := middleware.Logger()
 	log.Info().Msg("Initializing ZFS DBus connection")
+	log = log.With().Str("path", pathname).Logger()
+	client := &ZfsDebusClient{sup: sup, log: &log}
+	client.backend = bus.NewBackend(&log, sup, destination, client.init)
+	return client
+}
+
+// init checks the service implements the ZFS interface, and caches its pools if it supports ObjectManager
+func (c *ZfsDebusClient) init(ctx context.Context) (string, error) {
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return "", err
+	}
 	obj := conn.Object(destination, dbus.ObjectPath(pathname))
 	node, err := introspect.Call(obj)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	data, err := json.MarshalIndent(node, "", "    ")
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	c.log.Debug().Msgf("%s\n", string(data))
+	if !hasInterface(node, iface) {
+		return "", fmt.Errorf("object %s does not implement %s", pathname, iface)
+	}
+
+	// The tree resyncs itself whenever the service restarts, so it is only created once
+	if hasInterface(node, bus.ObjectManagerInterface) && c.tree == nil {
+		c.log.Info().Msg("ZFS service supports ObjectManager, caching pool objects")
+		tree, err := bus.NewObjectTree(c.log, c.sup, destination, dbus.ObjectPath(pathname))
+		if tree != nil {
+			c.tree = tree
+		}
+		if err != nil {
+			return "", err
+		}
 	}
-	log.Debug().Msgf("%s\n", string(data))
 
-	log = log.With().Interface("path", obj.Path()).Logger()
+	return c.version(ctx)
+}
 
-	return &ZfsDebusClient{conn: conn, obj: obj, log: &log}, nil
+// Capability reports whether the module is enabled, i.e. the ZFS service has been on the bus since the agent started
+func (c *ZfsDebusClient) Capability() common.ModuleCapability {
+	return c.backend.Capability()
 }
 
-func (c *ZfsDebusClient) ListPools(ctx context.Context) ([]*ZpoolObject, error) {
+// object resolves path against the current connection, so that calls survive a bus or service restart
+func (c *ZfsDebusClient) object(path dbus.ObjectPath) (dbus.BusObject, error) {
+	if err := c.backend.Ready(); err != nil {
+		return nil, err
+	}
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Object(destination, path), nil
+}
+
+func (c *ZfsDebusClient) ListPools(ctx context.Context) ([]ZpoolProperties, error) {
+	// The tree is only safe to read once the backend is enabled
+	if err := c.backend.Ready(); err != nil {
+		return nil, err
+	}
+	if c.tree != nil {
+		return c.listCachedPools()
+	}
+
 	m := prefix + "Pools"
+	obj, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return nil, err
+	}
 	var poolObjs []dbus.ObjectPath
-	err := c.obj.CallWithContext(ctx, m, 0).Store(&poolObjs)
+	err = bus.Call(ctx, obj, m, 0).Store(&poolObjs)
 	if err != nil {
 		return nil, err
 	}
 	c.log.Debug().Interface("paths", poolObjs).Msg("Received zpools")
 
-	pools := make([]*ZpoolObject, len(poolObjs))
+	pools := make([]ZpoolProperties, len(poolObjs))
 	for i, p := range poolObjs {
-		obj := c.conn.Object(destination, p)
-		pools[i] = NewZpoolObject(obj, c.log)
+		obj, err := c.object(p)
+		if err != nil {
+			return nil, err
+		}
+		pools[i], err = NewZpoolObject(obj, c.log).Properties(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get properties for pool %s: %w", p, err)
+		}
 	}
 
 	return pools, nil
 
 }
 
-func (c *ZfsDebusClient) Version() (string, error) {
-	name := prefix + "Version"
-	version, err := bus.Decode[string](c.log, c.obj, name)
-	return version, err
+// listCachedPools answers from the object tree, without any D-Bus round trips
+func (c *ZfsDebusClient) listCachedPools() ([]ZpoolProperties, error) {
+	objects, err := c.tree.Objects(poolInterface)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]dbus.ObjectPath, 0, len(objects))
+	for p := range objects {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	pools := make([]ZpoolProperties, len(paths))
+	for i, p := range paths {
+		err := bus.DecodeMap(objects[p][poolInterface], &pools[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode cached properties for pool %s: %w", p, err)
+		}
+	}
+	return pools, nil
+}
+
+func (c *ZfsDebusClient) GetPool(ctx context.Context, name string) (ZpoolProperties, error) {
+	pools, err := c.ListPools(ctx)
+	if err != nil {
+		return ZpoolProperties{}, err
+	}
+	for _, p := range pools {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return ZpoolProperties{}, fmt.Errorf("zpool %s: %w", name, bus.ErrNotFound)
+}
+
+func (c *ZfsDebusClient) CreatePool(ctx context.Context, name string) (ZpoolProperties, error) {
+	obj, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return ZpoolProperties{}, err
+	}
+	var path dbus.ObjectPath
+	err = bus.Call(ctx, obj, prefix+"CreatePool", 0, name).Store(&path)
+	if err != nil {
+		return ZpoolProperties{}, err
+	}
+	c.log.Info().Str("name", name).Str("pool", string(path)).Msg("Created zpool")
+
+	// Read the new pool directly, the object tree may not have caught up yet
+	obj, err = c.object(path)
+	if err != nil {
+		return ZpoolProperties{}, err
+	}
+	return NewZpoolObject(obj, c.log).Properties(ctx)
+}
+
+func (c *ZfsDebusClient) DestroyPool(ctx context.Context, name string) error {
+	obj, err := c.object(dbus.ObjectPath(pathname))
+	if err != nil {
+		return err
+	}
+	err = bus.Call(ctx, obj, prefix+"DestroyPool", 0, name).Err
+	if err != nil {
+		return err
+	}
+	c.log.Info().Str("name", name).Msg("Destroyed zpool")
+	return nil
+}
+
+func (c *ZfsDebusClient) Version(ctx context.Context) (string, error) {
+	if err := c.backend.Ready(); err != nil {
+		return "", err
+	}
+	return c.version(ctx)
+}
+
+// version reads the version of the service, without waiting for the backend to be enabled
+func (c *ZfsDebusClient) version(ctx context.Context) (string, error) {
+	conn, err := c.sup.Conn()
+	if err != nil {
+		return "", err
+	}
+	obj := conn.Object(destination, dbus.ObjectPath(pathname))
+	return bus.Decode[string](ctx, c.log, obj, prefix+"Version")
+}
+
+func hasInterface(node *introspect.Node, name string) bool {
+	for _, i := range node.Interfaces {
+		if i.Name == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,205 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/dbustest"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakezfs"
+	"github.com/nickrobison/terraform-linux-provider/server/middleware"
+	"github.com/rs/zerolog"
+)
+
+var (
+	tank = fakezfs.Pool{Name: "tank", Size: 1000, Allocated: 400, Free: 600, Health: "ONLINE"}
+	data = fakezfs.Pool{Name: "data", Size: 2000, Allocated: 100, Free: 1900, Health: "DEGRADED", ChecksumErrors: 3}
+)
+
+func init() {
+	middleware.SetupLogging(io.Discard, zerolog.Disabled)
+}
+
+// newTestClient starts a private bus with the fake ZFS service and returns a client connected to it
+func newTestClient(t *testing.T, opts ...fakezfs.Option) (ZfsClient, *fakezfs.Service) {
+	t.Helper()
+	b := dbustest.NewBus(t)
+	service := fakezfs.Start(t, b.Connect(t), opts...)
+	return NewZfsClient(b.Supervisor(t)), service
+}
+
+// eventually retries check until it passes, for state which is updated asynchronously by signals
+func eventually(t *testing.T, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func errorf(format string, args ...any) error {
+	return fmt.Errorf(format, args...)
+}
+
+func TestVersion(t *testing.T) {
+	client, _ := newTestClient(t, fakezfs.WithVersion("2.2.0"))
+	version, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "2.2.0" {
+		t.Errorf("expected version 2.2.0, got %s", version)
+	}
+}
+
+func TestListPools(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []fakezfs.Option
+	}{
+		{name: "pools method"},
+		{name: "object manager", opts: []fakezfs.Option{fakezfs.WithObjectManager()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, append(tt.opts, fakezfs.WithPools(tank, data))...)
+			pools, err := client.ListPools(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pools) != 2 {
+				t.Fatalf("expected 2 pools, got %d", len(pools))
+			}
+			// Pools are ordered by object path
+			if pools[0].Name != "data" || pools[0].Health != "DEGRADED" || pools[0].ChecksumErrors != 3 {
+				t.Errorf("unexpected properties for data: %+v", pools[0])
+			}
+			if pools[1].Name != "tank" || pools[1].Size != 1000 || pools[1].Free != 600 {
+				t.Errorf("unexpected properties for tank: %+v", pools[1])
+			}
+		})
+	}
+}
+
+func TestListPoolsFollowsChanges(t *testing.T) {
+	client, service := newTestClient(t, fakezfs.WithObjectManager(), fakezfs.WithPools(tank))
+
+	if err := service.AddPool(data); err != nil {
+		t.Fatal(err)
+	}
+	degraded := tank
+	degraded.Health = "DEGRADED"
+	if err := service.AddPool(degraded); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() error {
+		pools, err := client.ListPools(context.Background())
+		if err != nil {
+			return err
+		}
+		if len(pools) != 2 || pools[1].Health != "DEGRADED" {
+			return errorf("expected added pool and updated health, got %+v", pools)
+		}
+		return nil
+	})
+
+	if err := service.RemovePool("data"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error {
+		pools, err := client.ListPools(context.Background())
+		if err != nil {
+			return err
+		}
+		if len(pools) != 1 {
+			return errorf("expected pool to be removed, got %+v", pools)
+		}
+		return nil
+	})
+}
+
+func TestListPoolsFailure(t *testing.T) {
+	client, service := newTestClient(t, fakezfs.WithPools(tank))
+	service.Fail("GetAll", dbus.MakeFailedError(errorf("pool is busy")))
+
+	_, err := client.ListPools(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if _, ok := bus.IsUnavailable(err); ok {
+		t.Errorf("expected a plain failure, got %v", err)
+	}
+}
+
+func TestServiceRestart(t *testing.T) {
+	client, service := newTestClient(t, fakezfs.WithPools(tank))
+
+	service.Stop()
+	eventually(t, func() error {
+		_, err := client.ListPools(context.Background())
+		if _, ok := bus.IsUnavailable(err); !ok {
+			return errorf("expected service to be unavailable, got %v", err)
+		}
+		return nil
+	})
+
+	if err := service.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() error {
+		_, err := client.ListPools(context.Background())
+		return err
+	})
+}
+
+func TestServiceStartsAfterAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []fakezfs.Option
+	}{
+		{name: "pools method"},
+		{name: "object manager", opts: []fakezfs.Option{fakezfs.WithObjectManager()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := dbustest.NewBus(t)
+			client := NewZfsClient(b.Supervisor(t))
+			if client.Capability().Enabled {
+				t.Fatal("expected the module to be disabled until the service starts")
+			}
+			_, err := client.ListPools(context.Background())
+			if _, ok := bus.IsUnavailable(err); !ok {
+				t.Fatalf("expected service to be unavailable, got %v", err)
+			}
+
+			fakezfs.Start(t, b.Connect(t), append(tt.opts, fakezfs.WithVersion("2.2.0"), fakezfs.WithPools(tank))...)
+			eventually(t, func() error {
+				pools, err := client.ListPools(context.Background())
+				if err != nil {
+					return err
+				}
+				if len(pools) != 1 {
+					return errorf("expected 1 pool, got %d", len(pools))
+				}
+				return nil
+			})
+			if caps := client.Capability(); !caps.Enabled || caps.Version != "2.2.0" {
+				t.Errorf("expected the module to be enabled with version 2.2.0, got %+v", caps)
+			}
+		})
+	}
+}
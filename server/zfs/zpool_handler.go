@@ -1,9 +1,11 @@
 package zfs
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/bus"
 	"github.com/rs/zerolog"
 )
 
@@ -14,22 +16,74 @@ func HandleZpoolList(client ZfsClient) http.Handler {
 		objects, err := client.ListPools(ctx)
 		if err != nil {
 			log.Error().Err(err).Msg("Cannot list zpools")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			bus.HTTPError(w, r, err)
 			return
 		}
 
 		pools := make([]common.ZPoolResponse, len(objects))
 		for i, v := range objects {
-			name, err := v.Name()
-			if err != nil {
-				log.Error().Err(err).Msgf("Cannot get name for pool %s", v.obj.Path())
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			pools[i] = common.ZPoolResponse{
-				Name: name,
-			}
+			pools[i] = toResponse(v)
 		}
-		common.Encode(w, r, 200, pools)
+		common.Encode(w, r, 200, common.ZpoolListResponse{Pools: pools})
 	})
 }
+
+func HandleZpoolGet(client ZfsClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		pool, err := client.GetPool(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot get zpool %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusOK, toResponse(pool))
+	})
+}
+
+func HandleZpoolCreate(client ZfsClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		req, err := common.DecodeRequest[common.ZpoolCreateRequest](r)
+		if err != nil {
+			bus.HTTPError(w, r, fmt.Errorf("cannot decode request: %s: %w", err, bus.ErrInvalid))
+			return
+		}
+		if !common.ZpoolNamePattern.MatchString(req.Name) {
+			bus.HTTPError(w, r, fmt.Errorf("invalid zpool name %q: %w", req.Name, bus.ErrInvalid))
+			return
+		}
+
+		pool, err := client.CreatePool(ctx, req.Name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot create zpool %s", req.Name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		common.Encode(w, r, http.StatusCreated, toResponse(pool))
+	})
+}
+
+func HandleZpoolDelete(client ZfsClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := zerolog.Ctx(ctx)
+		name := r.PathValue("name")
+		err := client.DestroyPool(ctx, name)
+		if err != nil {
+			log.Error().Err(err).Msgf("Cannot destroy zpool %s", name)
+			bus.HTTPError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func toResponse(pool ZpoolProperties) common.ZPoolResponse {
+	return common.ZPoolResponse{
+		Name: pool.Name,
+	}
+}
@@ -0,0 +1,120 @@
+package zfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/nickrobison/terraform-linux-provider/common"
+	"github.com/nickrobison/terraform-linux-provider/server/internal/fakezfs"
+)
+
+func TestHandleZpoolList(t *testing.T) {
+	client, service := newTestClient(t, fakezfs.WithPools(tank, data))
+	handler := HandleZpoolList(client)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/zfs/zpool", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp common.ZpoolListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Pools) != 2 || resp.Pools[0].Name != "data" || resp.Pools[1].Name != "tank" {
+		t.Errorf("unexpected pools: %+v", resp.Pools)
+	}
+
+	tests := []struct {
+		name   string
+		setup  func()
+		status int
+	}{
+		{
+			name:   "backend failure",
+			setup:  func() { service.Fail("Pools", dbus.MakeFailedError(errorf("boom"))) },
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "access denied",
+			setup: func() {
+				service.Fail("Pools", dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"denied"}))
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "service down",
+			setup: func() {
+				service.Fail("Pools", nil)
+				service.Stop()
+			},
+			status: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			eventually(t, func() error {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/zfs/zpool", nil))
+				if w.Code != tt.status {
+					return errorf("expected %d, got %d: %s", tt.status, w.Code, w.Body)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestZpoolLifecycle(t *testing.T) {
+	client, _ := newTestClient(t, fakezfs.WithObjectManager())
+	create := HandleZpoolCreate(client)
+	mux := http.NewServeMux()
+	mux.Handle("GET /zfs/zpool/{name}", HandleZpoolGet(client))
+	mux.Handle("DELETE /zfs/zpool/{name}", HandleZpoolDelete(client))
+
+	w := httptest.NewRecorder()
+	create.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/zfs/zpool", strings.NewReader(`{"name": "tank"}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	eventually(t, func() error {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/zfs/zpool/tank", nil))
+		if w.Code != http.StatusOK {
+			return errorf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+		return nil
+	})
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/zfs/zpool/tank", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+
+	eventually(t, func() error {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/zfs/zpool/tank", nil))
+		if w.Code != http.StatusNotFound {
+			return errorf("expected 404, got %d: %s", w.Code, w.Body)
+		}
+		return nil
+	})
+}
+
+func TestHandleZpoolCreateValidation(t *testing.T) {
+	client, _ := newTestClient(t)
+	for _, body := range []string{`{"name": "1tank"}`, `{"name": "tank/../etc"}`, `not json`} {
+		w := httptest.NewRecorder()
+		HandleZpoolCreate(client).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/zfs/zpool", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
@@ -1,20 +1,37 @@
 package zfs
 
 import (
+	"context"
+
 	"github.com/godbus/dbus/v5"
 	"github.com/nickrobison/terraform-linux-provider/server/bus"
 	"github.com/rs/zerolog"
 )
 
+var poolInterface = prefix + "Pool"
+
 type ZpoolObject struct {
 	obj    dbus.BusObject
 	logger *zerolog.Logger
 }
 
-func (o ZpoolObject) Name() (string, error) {
-	property := prefix + "Pool.Name"
-	name, err := bus.Decode[string](o.logger, o.obj, property)
-	return name, err
+// ZpoolProperties are the properties exposed by the ZFS service for each pool
+type ZpoolProperties struct {
+	Name string `dbus:"Name"`
+	// Size, Allocated and Free are in bytes
+	Size      uint64 `dbus:"Size"`
+	Allocated uint64 `dbus:"Allocated"`
+	Free      uint64 `dbus:"Free"`
+	// Health is the pool state as reported by zpool status (e.g. ONLINE, DEGRADED)
+	Health         string `dbus:"Health"`
+	ReadErrors     uint64 `dbus:"ReadErrors"`
+	WriteErrors    uint64 `dbus:"WriteErrors"`
+	ChecksumErrors uint64 `dbus:"ChecksumErrors"`
+}
+
+// Properties fetches every pool property in a single round trip
+func (o ZpoolObject) Properties(ctx context.Context) (ZpoolProperties, error) {
+	return bus.DecodeAll[ZpoolProperties](ctx, o.logger, o.obj, poolInterface)
 }
 
 func NewZpoolObject(obj dbus.BusObject, logger *zerolog.Logger) *ZpoolObject {